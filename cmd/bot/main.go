@@ -1,41 +1,229 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
 	"senpai-waifu-bot/internal/config"
 	"senpai-waifu-bot/internal/database"
 	"senpai-waifu-bot/internal/handlers"
+	"senpai-waifu-bot/internal/health"
+	"senpai-waifu-bot/internal/httpserver"
+	"senpai-waifu-bot/internal/logger"
+	"senpai-waifu-bot/internal/metrics"
+	"senpai-waifu-bot/internal/migrations"
+	"senpai-waifu-bot/internal/store"
+)
+
+// healthMongo names the MongoDB subsystem in health reports
+const healthMongo = "mongo"
+
+// Retry bounds for subsystems that were down at startup
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = 30 * time.Second
 )
 
 func main() {
 	// Load configuration
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	
+	// Create logger
+	log, err := logger.New(cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer log.Sync()
+	
+	// The Bot API library logs its own retries through the standard logger interface
+	tgbotapi.SetLogger(zap.NewStdLog(log.Named("telegram")))
+	
+	// Health and metrics are served from the start, so a degraded start is visible
+	checker := health.New(2 * time.Minute)
+	checker.RequireFresh(handlers.HealthTelegram, 3*handlers.TelegramCheckInterval)
+	servers := httpserver.NewGroup(log.Named("http"))
+	if cfg.HealthListen != "" {
+		servers.Handle(cfg.HealthListen, "/healthz", checker.LiveHandler())
+		servers.Handle(cfg.HealthListen, "/readyz", checker.ReadyHandler())
+	}
+	if cfg.MetricsListen != "" {
+		servers.Handle(cfg.MetricsListen, "/metrics", metrics.Handler())
+	}
+	if err := servers.Start(); err != nil {
+		log.Fatal("failed to start http servers", zap.Error(err))
+	}
+	
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	
+	// SIGHUP reloads the configuration; it is caught from here on so it never kills the process
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	
+	// Startup self-check: try every subsystem once and report all that are down, then
+	// keep retrying those until they come up
+	var api *tgbotapi.BotAPI
+	connectMongo := func() error {
+		err := database.Connect(cfg, log)
+		checker.Report(healthMongo, err)
+		return err
+	}
+	connectTelegram := func() error {
+		var err error
+		api, err = tgbotapi.NewBotAPI(cfg.BotToken)
+		checker.Report(handlers.HealthTelegram, err)
+		return err
+	}
+	mongoErr := connectMongo()
+	telegramErr := connectTelegram()
+	reportStartup(log, checker)
 	
-	// Connect to database
-	if err := database.Connect(cfg); err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	if mongoErr != nil {
+		mongoErr = retry(ctx, log, healthMongo, connectMongo)
 	}
-	defer database.Disconnect()
+	if telegramErr != nil && mongoErr == nil {
+		telegramErr = retry(ctx, log, handlers.HealthTelegram, connectTelegram)
+	}
+	if mongoErr != nil || telegramErr != nil {
+		log.Info("interrupted before startup completed")
+		shutdownServers(servers, cfg.ShutdownTimeout, log)
+		database.Disconnect()
+		return
+	}
+	checker.AddProbe(healthMongo, database.Ping)
+	warnPendingMigrations(ctx, cfg, log)
+	
+	api.Debug = false
+	log.Info("authorized on account", zap.String("username", api.Self.UserName))
 	
 	// Create bot
-	bot, err := handlers.NewBot(cfg)
-	if err != nil {
-		log.Fatalf("Failed to create bot: %v", err)
+	bot := handlers.NewBot(cfg, api, api.Self, store.NewCachedStore(store.NewMongoStore(), cfg.CacheTTL), log, checker)
+	if cfg.UpdateMode == config.UpdateModeWebhook {
+		if err := servers.Handle(cfg.WebhookListen, cfg.WebhookPath, bot.WebhookHandler()); err != nil {
+			log.Fatal("failed to serve webhook", zap.Error(err))
+		}
 	}
 	
-	// Start bot in a goroutine
-	go bot.Start()
+	go reloadOnSignal(ctx, hup, bot, log)
 	
-	// Wait for interrupt signal
-	log.Println("🤖 Bot is running. Press Ctrl+C to stop.")
+	// Run until interrupted
+	log.Info("bot is running, press Ctrl+C to stop")
+	if err := bot.Start(ctx); err != nil {
+		log.Error("bot stopped", zap.Error(err))
+	}
+	stop()
 	
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	// Stop accepting webhook requests, let in-flight handlers finish, then close the database
+	log.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := servers.Shutdown(shutdownCtx); err != nil {
+		log.Warn("failed to stop http servers", zap.Error(err))
+	}
+	if err := bot.Shutdown(shutdownCtx); err != nil {
+		log.Warn("shutdown incomplete", zap.Error(err))
+	}
+	
+	if err := database.Disconnect(); err != nil {
+		log.Warn("failed to disconnect from MongoDB", zap.Error(err))
+	}
+	log.Info("bye")
+}
+
+// reloadOnSignal reloads the configuration on every signal from hup until ctx is done.
+// A configuration that fails to load or validate is rejected and the current one kept.
+func reloadOnSignal(ctx context.Context, hup <-chan os.Signal, bot *handlers.Bot, log *zap.Logger) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+		
+		next, err := config.Load()
+		if err != nil {
+			log.Error("config reload failed, keeping the current settings", zap.Error(err))
+			continue
+		}
+		bot.ApplyConfig(next)
+	}
+}
+
+// reportStartup logs the outcome of the startup self-check, listing every degraded subsystem
+func reportStartup(log *zap.Logger, checker *health.Checker) {
+	degraded := checker.Degraded()
+	if len(degraded) == 0 {
+		log.Info("startup self-check passed")
+		return
+	}
 	
-	log.Println("🛑 Shutting down bot...")
+	fields := make([]zap.Field, 0, len(degraded))
+	for name, err := range degraded {
+		fields = append(fields, zap.NamedError(name, err))
+	}
+	log.Warn("starting degraded, retrying failed subsystems", fields...)
+}
+
+// warnPendingMigrations logs the schema migrations that have not been applied; the bot still
+// starts, as migrations are run separately with the migrate command
+func warnPendingMigrations(ctx context.Context, cfg *config.Config, log *zap.Logger) {
+	pending, err := migrations.NewRunner(database.DB, cfg.Database.Collections, log).Pending(ctx)
+	if err != nil {
+		log.Warn("failed to check schema migrations", zap.Error(err))
+		return
+	}
+	if len(pending) == 0 {
+		return
+	}
+	
+	versions := make([]int, len(pending))
+	for i, m := range pending {
+		versions[i] = m.Version
+	}
+	log.Warn("database schema has pending migrations, run migrate up", zap.Ints("versions", versions))
+}
+
+// retry calls connect with exponential backoff until it succeeds or ctx is cancelled
+func retry(ctx context.Context, log *zap.Logger, name string, connect func() error) error {
+	delay := minRetryDelay
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		
+		err := connect()
+		if err == nil {
+			log.Info("subsystem recovered", zap.String("subsystem", name))
+			return nil
+		}
+		
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+		log.Warn("subsystem still degraded", zap.String("subsystem", name), zap.Duration("retry_in", delay), zap.Error(err))
+	}
+}
+
+// shutdownServers stops the HTTP servers within timeout
+func shutdownServers(servers *httpserver.Group, timeout time.Duration, log *zap.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := servers.Shutdown(ctx); err != nil {
+		log.Warn("failed to stop http servers", zap.Error(err))
+	}
 }
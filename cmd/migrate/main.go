@@ -0,0 +1,109 @@
+// Command migrate applies the MongoDB schema migrations. It reads the same configuration as
+// the bot.
+//
+//	migrate status            list every migration and whether it has been applied
+//	migrate up [version]      apply the pending migrations, up to version if given
+//	migrate -dry-run up       log what the pending migrations would change
+//	migrate unlock            release the lock left behind by a killed run
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/config"
+	"senpai-waifu-bot/internal/database"
+	"senpai-waifu-bot/internal/logger"
+	"senpai-waifu-bot/internal/migrations"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "log what would change without writing anything")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: migrate [-dry-run] status | up [version] | unlock")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*dryRun, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(dryRun bool, args []string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		return errors.New("missing command")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	log, err := logger.New(cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		return fmt.Errorf("create logger: %w", err)
+	}
+	defer log.Sync()
+
+	client, err := database.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect to MongoDB: %w", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	runner := migrations.NewRunner(client.Database(cfg.Database.Name), cfg.Database.Collections, log)
+	switch args[0] {
+	case "status":
+		return printStatus(ctx, runner)
+	case "up":
+		target := 0
+		if len(args) > 1 {
+			if target, err = strconv.Atoi(args[1]); err != nil || target < 1 {
+				return fmt.Errorf("invalid version %q", args[1])
+			}
+		}
+		ran, err := runner.Up(ctx, target, dryRun)
+		if err != nil {
+			return err
+		}
+		log.Info("migrations finished", zap.Int("ran", len(ran)), zap.Bool("dry_run", dryRun))
+		return nil
+	case "unlock":
+		return runner.Unlock(ctx)
+	default:
+		flag.Usage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// printStatus writes a table of every migration and when it was applied
+func printStatus(ctx context.Context, runner *migrations.Runner) error {
+	statuses, err := runner.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+	return w.Flush()
+}
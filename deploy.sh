@@ -146,10 +146,6 @@ setup_env() {
 BOT_TOKEN=your_bot_token_here
 BOT_USERNAME=your_bot_username
 
-# Telegram API
-API_ID=your_api_id
-API_HASH=your_api_hash
-
 # Owner and Sudo
 OWNER_ID=your_telegram_user_id
 SUDO_USERS=
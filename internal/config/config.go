@@ -1,157 +1,335 @@
 package config
 
 import (
-	"log"
-	"os"
-	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
-	"github.com/joho/godotenv"
+	"senpai-waifu-bot/internal/imaging"
+	"senpai-waifu-bot/internal/models"
 )
 
-// Config holds all configuration for the bot
+// Config holds all configuration for the bot. It is built from defaults, then an optional
+// YAML file, then environment variables, each layer overriding the one before.
+//
+// SudoUsers, VideoURLs and Spawn can be reloaded at runtime; read them through Live.
 type Config struct {
 	// Bot Credentials
-	BotToken    string
-	BotUsername string
-
-	// Telegram API
-	APIID   int64
-	APIHash string
+	BotToken    string `yaml:"bot_token"`
+	BotUsername string `yaml:"bot_username"`
 
 	// Owner and Sudo
-	OwnerID    int64
-	SudoUsers  []int64
+	OwnerID   int64   `yaml:"owner_id"`
+	SudoUsers []int64 `yaml:"sudo_users"`
 
 	// Group IDs
-	GroupID         int64
-	CharaChannelID  int64
+	GroupID        int64 `yaml:"group_id"`
+	CharaChannelID int64 `yaml:"chara_channel_id"`
+	// Post every audited admin action to the log group as well
+	AuditToLogGroup bool `yaml:"audit_to_log_group"`
 
 	// Database
-	MongoURL string
+	MongoURL string   `yaml:"mongo_url"`
+	Database Database `yaml:"database"`
+	// How long hot lookups such as rarity settings and characters are cached; 0 disables caching
+	CacheTTL time.Duration `yaml:"cache_ttl"`
 
 	// Media
-	VideoURLs []string
+	VideoURLs []string `yaml:"video_urls"`
 
 	// Community Links
-	SupportChat string
-	UpdateChat  string
+	SupportChat string `yaml:"support_chat"`
+	UpdateChat  string `yaml:"update_chat"`
+
+	// Replies in chats that have not chosen a language or small caps setting of their own
+	Language  string `yaml:"language"`
+	SmallCaps bool   `yaml:"small_caps"`
+
+	// Game tunables
+	Economy Economy `yaml:"economy"`
+	Spawn   Spawn   `yaml:"spawn"`
+	Guess   Guess   `yaml:"guess"`
+	Images  Images  `yaml:"images"`
+
+	// Updates: "polling" (default) or "webhook"
+	UpdateMode    string `yaml:"update_mode"`
+	WebhookURL    string `yaml:"webhook_url"`
+	WebhookPath   string `yaml:"webhook_path"`
+	WebhookListen string `yaml:"webhook_listen"`
+	WebhookSecret string `yaml:"webhook_secret"`
+
+	// Addresses serving /metrics and /healthz + /readyz; empty disables them. Endpoints
+	// given the same address, including the webhook, share one server.
+	MetricsListen string `yaml:"metrics_listen"`
+	HealthListen  string `yaml:"health_listen"`
+
+	// How long shutdown waits for in-flight updates
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// Logging: level is a zap level name, format is "json" or "console"
+	LogLevel  string `yaml:"log_level"`
+	LogFormat string `yaml:"log_format"`
+
+	live atomic.Pointer[Runtime]
 }
 
-var (
-	// AppConfig is the global configuration instance
-	AppConfig *Config
-)
+// Database names the MongoDB database and its collections
+type Database struct {
+	Name        string      `yaml:"name"`
+	Collections Collections `yaml:"collections"`
+}
 
-// Load loads configuration from environment variables
-func Load() *Config {
-	// Try to load .env file (optional)
-	_ = godotenv.Load()
-
-	config := &Config{
-		BotToken:    getEnv("BOT_TOKEN", ""),
-		BotUsername: getEnv("BOT_USERNAME", "Senpai_Waifu_Grabbing_Bot"),
-		APIHash:     getEnv("API_HASH", ""),
-		MongoURL:    getEnv("MONGO_URL", ""),
-		SupportChat: getEnv("SUPPORT_CHAT", "THE_DRAGON_SUPPORT"),
-		UpdateChat:  getEnv("UPDATE_CHAT", "Senpai_Updates"),
-	}
+// Collections names every MongoDB collection the bot uses
+type Collections struct {
+	Characters        string `yaml:"characters"`
+	Users             string `yaml:"users"`
+	UserTotals        string `yaml:"user_totals"`
+	GroupUserTotals   string `yaml:"group_user_totals"`
+	TopGlobalGroups   string `yaml:"top_global_groups"`
+	PMUsers           string `yaml:"pm_users"`
+	DailyUserGuesses  string `yaml:"daily_user_guesses"`
+	DailyGroupGuesses string `yaml:"daily_group_guesses"`
+	RedeemCodes       string `yaml:"redeem_codes"`
+	ClaimCodes        string `yaml:"claim_codes"`
+	RaritySettings    string `yaml:"rarity_settings"`
+	LockedCharacters  string `yaml:"locked_characters"`
+	SortPreferences   string `yaml:"sort_preferences"`
+	Ledger            string `yaml:"ledger"`
+	ActiveSpawns      string `yaml:"active_spawns"`
+	MessageCounters   string `yaml:"message_counters"`
+	PendingPayments   string `yaml:"pending_payments"`
+	PendingTrades     string `yaml:"pending_trades"`
+	PendingGifts      string `yaml:"pending_gifts"`
+	ChatLocales       string `yaml:"chat_locales"`
+	Roles             string `yaml:"roles"`
+	AuditLog          string `yaml:"audit_log"`
+	Counters          string `yaml:"counters"`
+	Migrations        string `yaml:"migrations"`
+}
 
-	// Parse integers
-	config.APIID = parseInt64(getEnv("API_ID", "0"))
-	config.OwnerID = parseInt64(getEnv("OWNER_ID", "0"))
-	config.GroupID = parseInt64(getEnv("GROUP_ID", "0"))
-	config.CharaChannelID = parseInt64(getEnv("CHARA_CHANNEL_ID", "0"))
-
-	// Parse sudo users
-	sudoUsersStr := getEnv("SUDO_USERS", "")
-	if sudoUsersStr != "" {
-		users := strings.Split(sudoUsersStr, ",")
-		for _, u := range users {
-			u = strings.TrimSpace(u)
-			if u != "" {
-				if id, err := strconv.ParseInt(u, 10, 64); err == nil {
-					config.SudoUsers = append(config.SudoUsers, id)
-				}
-			}
-		}
-	}
+// Economy holds the coin rewards, prices and cooldowns
+type Economy struct {
+	// Coins for a correct /guess
+	GuessReward int64 `yaml:"guess_reward"`
+	// Range of the daily /claim coin code
+	ClaimMin int64 `yaml:"claim_min"`
+	ClaimMax int64 `yaml:"claim_max"`
+	// Cost of /refresh in the shop
+	ShopRefreshCost int64 `yaml:"shop_refresh_cost"`
+	// Price range of each rarity sold in the shop, e.g. 4: [400000, 500000]
+	ShopPrices map[int][2]int64 `yaml:"shop_prices"`
+	// Range of the random shop discount, in percent
+	ShopDiscountMin int `yaml:"shop_discount_min"`
+	ShopDiscountMax int `yaml:"shop_discount_max"`
+	// Per-user cooldowns after a transfer
+	PayCooldown   time.Duration `yaml:"pay_cooldown"`
+	TradeCooldown time.Duration `yaml:"trade_cooldown"`
+	GiftCooldown  time.Duration `yaml:"gift_cooldown"`
+	// Cost of each /hint on a spawn, from the first hint to the last
+	HintCosts []int64 `yaml:"hint_costs"`
+	// Hints a spawn can get; 0 disables /hint
+	HintsPerSpawn int `yaml:"hints_per_spawn"`
+	// Percent of the guess reward lost for each hint used on the spawn
+	HintRewardCut int `yaml:"hint_reward_cut"`
+}
 
-	// Add owner to sudo users if not already present
-	if config.OwnerID != 0 {
-		found := false
-		for _, id := range config.SudoUsers {
-			if id == config.OwnerID {
-				found = true
-				break
-			}
-		}
-		if !found {
-			config.SudoUsers = append(config.SudoUsers, config.OwnerID)
-		}
-	}
+// Spawn holds the spawn defaults for chats that have not set their own
+type Spawn struct {
+	// Messages between spawns
+	DefaultFrequency int `yaml:"default_frequency"`
+	// How spawns are scheduled: "messages" (default), "interval" or "adaptive"
+	DefaultMode string `yaml:"default_mode"`
+	// Time between spawns in interval mode
+	DefaultInterval time.Duration `yaml:"default_interval"`
+	// Bounds on the time between spawns in messages and adaptive mode; 0 leaves a side open
+	MinGap time.Duration `yaml:"min_gap"`
+	MaxGap time.Duration `yaml:"max_gap"`
+	// How long a spawn can be guessed before it flees; 0 keeps it until the next spawn
+	Expiry time.Duration `yaml:"expiry"`
+	// IANA time zone of quiet hours set without one, such as Asia/Kolkata (the default)
+	QuietTimezone string `yaml:"quiet_timezone"`
+	// Relative chance of each rarity to spawn; a spawn first picks a rarity by weight, then
+	// one of its characters. Rarities without a weight never spawn.
+	RarityWeights map[int]int `yaml:"rarity_weights"`
+}
 
-	// Parse video URLs
-	videoURLsStr := getEnv("VIDEO_URL", "")
-	if videoURLsStr != "" {
-		urls := strings.Split(videoURLsStr, ",")
-		for _, url := range urls {
-			url = strings.TrimSpace(url)
-			if url != "" {
-				config.VideoURLs = append(config.VideoURLs, url)
-			}
-		}
-	}
+// Guess holds how forgiving /guess is of typos
+type Guess struct {
+	// One typo is forgiven for every this many letters of a name part; 0 requires exact guesses
+	LettersPerTypo int `yaml:"letters_per_typo"`
+	// Most typos forgiven in one name part
+	MaxTypos int `yaml:"max_typos"`
+}
 
-	// Validate critical config
-	config.Validate()
+// Images holds how spawn images are disguised so that they do not give the character away
+type Images struct {
+	// "silhouette" (default), "blur" or "off" to post the original image
+	Style string `yaml:"style"`
+	// Directory caching disguised images; empty disables the cache
+	CacheDir string `yaml:"cache_dir"`
+	// Most bytes the cache may hold; the least recently used images are removed beyond it, and
+	// 0 leaves the cache unbounded
+	CacheMaxBytes int64 `yaml:"cache_max_bytes"`
+	// Largest image downloaded, in bytes
+	MaxBytes int64 `yaml:"max_bytes"`
+	// How long an image download may take
+	Timeout time.Duration `yaml:"timeout"`
+}
 
-	AppConfig = config
-	return config
+// Runtime holds the settings that can change without a restart
+type Runtime struct {
+	SudoUsers []int64
+	VideoURLs []string
+	Spawn     Spawn
 }
 
-// Validate checks if required configuration is present
-func (c *Config) Validate() {
-	errors := []string{}
+// Update modes
+const (
+	UpdateModePolling = "polling"
+	UpdateModeWebhook = "webhook"
+)
 
-	if c.BotToken == "" {
-		errors = append(errors, "BOT_TOKEN is required")
-	}
-	if c.APIID == 0 {
-		errors = append(errors, "API_ID is required")
-	}
-	if c.APIHash == "" {
-		errors = append(errors, "API_HASH is required")
-	}
-	if c.OwnerID == 0 {
-		errors = append(errors, "OWNER_ID is required")
-	}
-	if c.MongoURL == "" {
-		errors = append(errors, "MONGO_URL is required")
-	}
-	if c.GroupID == 0 {
-		errors = append(errors, "GROUP_ID is required")
+// Defaults returns the configuration used for every setting not given in the file or environment
+func Defaults() *Config {
+	return &Config{
+		BotUsername: "Senpai_Waifu_Grabbing_Bot",
+		SupportChat: "THE_DRAGON_SUPPORT",
+		UpdateChat:  "Senpai_Updates",
+
+		Language:  "en",
+		SmallCaps: true,
+
+		Database: Database{
+			Name: "Character_catcher",
+			Collections: Collections{
+				Characters:        "anime_characters_lol",
+				Users:             "user_collection_lmaoooo",
+				UserTotals:        "user_totals_lmaoooo",
+				GroupUserTotals:   "group_user_totalsssssss",
+				TopGlobalGroups:   "top_global_groups",
+				PMUsers:           "total_pm_users",
+				DailyUserGuesses:  "daily_user_guesses",
+				DailyGroupGuesses: "daily_group_guesses",
+				RedeemCodes:       "redeem_codes",
+				ClaimCodes:        "claim_codes",
+				RaritySettings:    "rarity_settings",
+				LockedCharacters:  "locked_characters",
+				SortPreferences:   "sort_preferences",
+				Ledger:            "ledger",
+				ActiveSpawns:      "active_spawns",
+				MessageCounters:   "message_counters",
+				PendingPayments:   "pending_payments",
+				PendingTrades:     "pending_trades",
+				PendingGifts:      "pending_gifts",
+				ChatLocales:       "chat_locales",
+				Roles:             "roles",
+				AuditLog:          "audit_log",
+				Counters:          "counters",
+				Migrations:        "schema_migrations",
+			},
+		},
+
+		Economy: Economy{
+			GuessReward:     100,
+			ClaimMin:        1000,
+			ClaimMax:        3000,
+			ShopRefreshCost: 20000,
+			ShopPrices: map[int][2]int64{
+				4:  {400000, 500000}, // Special
+				5:  {600000, 700000}, // Ancient
+				6:  {650000, 750000}, // Celestial
+				14: {450000, 550000}, // Kawaii
+			},
+			ShopDiscountMin: 5,
+			ShopDiscountMax: 15,
+			PayCooldown:     60 * time.Second,
+			TradeCooldown:   60 * time.Second,
+			GiftCooldown:    30 * time.Second,
+			HintCosts:       []int64{25, 50, 100, 200},
+			HintsPerSpawn:   4,
+			HintRewardCut:   20,
+		},
+
+		CacheTTL: 5 * time.Minute,
+
+		Spawn: Spawn{
+			DefaultFrequency: 100,
+			DefaultMode:      models.SpawnModeMessages,
+			DefaultInterval:  30 * time.Minute,
+			Expiry:           10 * time.Minute,
+			QuietTimezone:    "Asia/Kolkata",
+			RarityWeights: map[int]int{
+				1:  400, // Common
+				2:  250, // Rare
+				3:  120, // Legendary
+				4:  40,  // Special
+				5:  20,  // Ancient
+				6:  10,  // Celestial
+				7:  60,  // Epic
+				8:  8,   // Cosmic
+				9:  8,   // Nightmare
+				10: 15,  // Frostborn
+				11: 15,  // Valentine
+				12: 15,  // Spring
+				13: 15,  // Tropical
+				14: 15,  // Kawaii
+				15: 9,   // Hybrid
+			},
+		},
+		Guess: Guess{
+			LettersPerTypo: 5,
+			MaxTypos:       2,
+		},
+		Images: Images{
+			Style:         imaging.StyleSilhouette,
+			CacheDir:      "cache/spawn-images",
+			CacheMaxBytes: 256 << 20,
+			MaxBytes:      10 << 20,
+			Timeout:       10 * time.Second,
+		},
+
+		UpdateMode:    UpdateModePolling,
+		WebhookPath:   "/telegram/webhook",
+		WebhookListen: ":8080",
+		HealthListen:  ":8081",
+
+		// Heroku allows 30 seconds between SIGTERM and SIGKILL
+		ShutdownTimeout: 25 * time.Second,
+
+		LogLevel:  "info",
+		LogFormat: "json",
 	}
-	if c.CharaChannelID == 0 {
-		errors = append(errors, "CHARA_CHANNEL_ID is required")
+}
+
+// Live returns the current reloadable settings. A Config built in code rather than by
+// Load has not published any yet, so its own fields are used.
+func (c *Config) Live() *Runtime {
+	if live := c.live.Load(); live != nil {
+		return live
 	}
+	return c.runtime()
+}
 
-	if len(errors) > 0 {
-		log.Println("❌ Configuration Error(s):")
-		for _, err := range errors {
-			log.Printf("   - %s\n", err)
-		}
-		log.Println("\n💡 Please set the required environment variables and try again.")
-		os.Exit(1)
+// runtime copies the reloadable settings out of the loaded fields
+func (c *Config) runtime() *Runtime {
+	return &Runtime{
+		SudoUsers: append([]int64{}, c.SudoUsers...),
+		VideoURLs: append([]string{}, c.VideoURLs...),
+		Spawn:     c.Spawn,
 	}
 }
 
+// Apply takes the reloadable settings from next, which must have been loaded and validated
+func (c *Config) Apply(next *Config) {
+	c.live.Store(next.Live())
+}
+
 // IsSudo checks if a user is sudo or owner
 func (c *Config) IsSudo(userID int64) bool {
 	if userID == c.OwnerID {
 		return true
 	}
-	for _, id := range c.SudoUsers {
+	for _, id := range c.Live().SudoUsers {
 		if id == userID {
 			return true
 		}
@@ -159,20 +337,26 @@ func (c *Config) IsSudo(userID int64) bool {
 	return false
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
+// normalize canonicalizes the loaded values and publishes the reloadable ones
+func (c *Config) normalize() {
+	c.UpdateMode = strings.ToLower(c.UpdateMode)
+	c.LogLevel = strings.ToLower(c.LogLevel)
+	c.LogFormat = strings.ToLower(c.LogFormat)
+	c.Language = strings.ToLower(c.Language)
 
-func parseInt64(s string) int64 {
-	if s == "" {
-		return 0
-	}
-	i, err := strconv.ParseInt(s, 10, 64)
-	if err != nil {
-		return 0
+	// Add owner to sudo users if not already present
+	if c.OwnerID != 0 {
+		found := false
+		for _, id := range c.SudoUsers {
+			if id == c.OwnerID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.SudoUsers = append(c.SudoUsers, c.OwnerID)
+		}
 	}
-	return i
+
+	c.live.Store(c.runtime())
 }
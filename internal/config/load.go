@@ -0,0 +1,231 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile is read when CONFIG_FILE is not set and the file exists
+const DefaultFile = "config.yaml"
+
+// Load builds the configuration from defaults, the YAML file named by CONFIG_FILE (or
+// config.yaml if present) and environment variables, then validates it. Every problem
+// found is reported in the returned error.
+func Load() (*Config, error) {
+	// Try to load .env file (optional)
+	_ = godotenv.Load()
+
+	cfg := Defaults()
+
+	path, required := os.Getenv("CONFIG_FILE"), true
+	if path == "" {
+		path, required = DefaultFile, false
+	}
+	if err := cfg.loadFile(path, required); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	cfg.loadEnv(&problems)
+	cfg.normalize()
+	problems = append(problems, cfg.problems()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// loadFile overlays the YAML file at path. A missing file is only an error when required.
+// Unknown keys are rejected so that typos do not silently fall back to defaults.
+func (c *Config) loadFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	// Decoding merges into maps, but a shop price table in the file replaces the default one
+	defaultPrices := c.Economy.ShopPrices
+	c.Economy.ShopPrices = nil
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	if c.Economy.ShopPrices == nil {
+		c.Economy.ShopPrices = defaultPrices
+	}
+	return nil
+}
+
+// loadEnv overlays the environment variables that are set, recording those that do not parse
+func (c *Config) loadEnv(problems *[]string) {
+	e := envLoader{problems: problems}
+
+	e.str("BOT_TOKEN", &c.BotToken)
+	e.str("BOT_USERNAME", &c.BotUsername)
+	e.int64("OWNER_ID", &c.OwnerID)
+	e.int64s("SUDO_USERS", &c.SudoUsers)
+	e.int64("GROUP_ID", &c.GroupID)
+	e.int64("CHARA_CHANNEL_ID", &c.CharaChannelID)
+	e.bool("AUDIT_TO_LOG_GROUP", &c.AuditToLogGroup)
+
+	e.str("MONGO_URL", &c.MongoURL)
+	e.str("DB_NAME", &c.Database.Name)
+	e.duration("CACHE_TTL", &c.CacheTTL)
+
+	e.strs("VIDEO_URL", &c.VideoURLs)
+	e.str("SUPPORT_CHAT", &c.SupportChat)
+	e.str("UPDATE_CHAT", &c.UpdateChat)
+	e.str("BOT_LANGUAGE", &c.Language)
+	e.bool("SMALL_CAPS", &c.SmallCaps)
+
+	e.int64("GUESS_REWARD", &c.Economy.GuessReward)
+	e.int64("CLAIM_MIN", &c.Economy.ClaimMin)
+	e.int64("CLAIM_MAX", &c.Economy.ClaimMax)
+	e.int64("SHOP_REFRESH_COST", &c.Economy.ShopRefreshCost)
+	e.duration("PAY_COOLDOWN", &c.Economy.PayCooldown)
+	e.duration("TRADE_COOLDOWN", &c.Economy.TradeCooldown)
+	e.duration("GIFT_COOLDOWN", &c.Economy.GiftCooldown)
+	e.int64s("HINT_COSTS", &c.Economy.HintCosts)
+	e.int("HINTS_PER_SPAWN", &c.Economy.HintsPerSpawn)
+	e.int("HINT_REWARD_CUT", &c.Economy.HintRewardCut)
+	e.int("SPAWN_FREQUENCY", &c.Spawn.DefaultFrequency)
+	e.str("SPAWN_MODE", &c.Spawn.DefaultMode)
+	e.duration("SPAWN_INTERVAL", &c.Spawn.DefaultInterval)
+	e.duration("SPAWN_MIN_GAP", &c.Spawn.MinGap)
+	e.duration("SPAWN_MAX_GAP", &c.Spawn.MaxGap)
+	e.duration("SPAWN_EXPIRY", &c.Spawn.Expiry)
+	e.str("SPAWN_QUIET_TIMEZONE", &c.Spawn.QuietTimezone)
+	e.int("GUESS_LETTERS_PER_TYPO", &c.Guess.LettersPerTypo)
+	e.int("GUESS_MAX_TYPOS", &c.Guess.MaxTypos)
+	e.str("IMAGE_STYLE", &c.Images.Style)
+	e.str("IMAGE_CACHE_DIR", &c.Images.CacheDir)
+	e.int64("IMAGE_CACHE_MAX_BYTES", &c.Images.CacheMaxBytes)
+	e.int64("IMAGE_MAX_BYTES", &c.Images.MaxBytes)
+	e.duration("IMAGE_TIMEOUT", &c.Images.Timeout)
+
+	e.str("UPDATE_MODE", &c.UpdateMode)
+	e.str("WEBHOOK_URL", &c.WebhookURL)
+	e.str("WEBHOOK_PATH", &c.WebhookPath)
+	if port := os.Getenv("PORT"); port != "" {
+		c.WebhookListen = ":" + port
+	}
+	e.str("WEBHOOK_LISTEN", &c.WebhookListen)
+	e.str("WEBHOOK_SECRET", &c.WebhookSecret)
+
+	e.str("METRICS_LISTEN", &c.MetricsListen)
+	e.str("HEALTH_LISTEN", &c.HealthListen)
+	e.duration("SHUTDOWN_TIMEOUT", &c.ShutdownTimeout)
+	e.str("LOG_LEVEL", &c.LogLevel)
+	e.str("LOG_FORMAT", &c.LogFormat)
+}
+
+// envLoader sets fields from environment variables that are set and non-empty
+type envLoader struct {
+	problems *[]string
+}
+
+func (e envLoader) fail(key, want string) {
+	*e.problems = append(*e.problems, fmt.Sprintf("%s must be %s", key, want))
+}
+
+func (e envLoader) str(key string, dst *string) {
+	if v := os.Getenv(key); v != "" {
+		*dst = v
+	}
+}
+
+func (e envLoader) int64(key string, dst *int64) {
+	v := os.Getenv(key)
+	if v == "" {
+		return
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil {
+		e.fail(key, "an integer")
+		return
+	}
+	*dst = n
+}
+
+func (e envLoader) int(key string, dst *int) {
+	n := int64(*dst)
+	e.int64(key, &n)
+	*dst = int(n)
+}
+
+func (e envLoader) bool(key string, dst *bool) {
+	v := os.Getenv(key)
+	if v == "" {
+		return
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(v))
+	if err != nil {
+		e.fail(key, "true or false")
+		return
+	}
+	*dst = b
+}
+
+func (e envLoader) duration(key string, dst *time.Duration) {
+	v := os.Getenv(key)
+	if v == "" {
+		return
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(v))
+	if err != nil {
+		e.fail(key, "a duration such as 30s")
+		return
+	}
+	*dst = d
+}
+
+// int64s parses a comma-separated list of integers
+func (e envLoader) int64s(key string, dst *[]int64) {
+	v := os.Getenv(key)
+	if v == "" {
+		return
+	}
+	var ids []int64
+	for _, field := range strings.Split(v, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			e.fail(key, "a comma-separated list of integers")
+			return
+		}
+		ids = append(ids, id)
+	}
+	*dst = ids
+}
+
+// strs parses a comma-separated list
+func (e envLoader) strs(key string, dst *[]string) {
+	v := os.Getenv(key)
+	if v == "" {
+		return
+	}
+	var items []string
+	for _, field := range strings.Split(v, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			items = append(items, field)
+		}
+	}
+	*dst = items
+}
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/imaging"
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/utils"
+)
+
+// ValidationError lists every problem found in the configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks the configuration, returning a *ValidationError with every problem found
+func (c *Config) Validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// problems lists what is wrong with the configuration
+func (c *Config) problems() []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.BotToken == "" {
+		add("BOT_TOKEN is required")
+	}
+	if c.OwnerID == 0 {
+		add("OWNER_ID is required")
+	}
+	if c.MongoURL == "" {
+		add("MONGO_URL is required")
+	}
+	if c.GroupID == 0 {
+		add("GROUP_ID is required")
+	}
+	if c.CharaChannelID == 0 {
+		add("CHARA_CHANNEL_ID is required")
+	}
+
+	if c.Database.Name == "" {
+		add("database.name must not be empty")
+	}
+	for key, name := range c.Database.Collections.byKey() {
+		if name == "" {
+			add("database.collections.%s must not be empty", key)
+		}
+	}
+
+	e := c.Economy
+	if e.GuessReward < 0 {
+		add("economy.guess_reward must not be negative")
+	}
+	if e.ClaimMin <= 0 || e.ClaimMax < e.ClaimMin {
+		add("economy.claim_min must be positive and at most economy.claim_max")
+	}
+	if e.ShopRefreshCost < 0 {
+		add("economy.shop_refresh_cost must not be negative")
+	}
+	if len(e.ShopPrices) == 0 {
+		add("economy.shop_prices must list at least one rarity")
+	}
+	for rarity, prices := range e.ShopPrices {
+		if prices[0] <= 0 || prices[1] <= prices[0] {
+			add("economy.shop_prices[%d] must be [min, max] with 0 < min < max", rarity)
+		}
+	}
+	if e.ShopDiscountMin < 0 || e.ShopDiscountMax < e.ShopDiscountMin || e.ShopDiscountMax >= 100 {
+		add("economy.shop_discount_min and shop_discount_max must satisfy 0 <= min <= max < 100")
+	}
+	if e.PayCooldown < 0 || e.TradeCooldown < 0 || e.GiftCooldown < 0 {
+		add("economy cooldowns must not be negative")
+	}
+	if len(e.HintCosts) == 0 || len(e.HintCosts) > models.HintTiers {
+		add("economy.hint_costs must list 1 to %d costs", models.HintTiers)
+	}
+	for i, cost := range e.HintCosts {
+		if cost <= 0 || (i > 0 && cost <= e.HintCosts[i-1]) {
+			add("economy.hint_costs must be positive and increasing")
+			break
+		}
+	}
+	if e.HintsPerSpawn < 0 || e.HintsPerSpawn > len(e.HintCosts) {
+		add("economy.hints_per_spawn must be between 0 and the number of economy.hint_costs")
+	}
+	if e.HintRewardCut < 0 || e.HintRewardCut > 100 {
+		add("economy.hint_reward_cut must be between 0 and 100")
+	}
+	s := c.Spawn
+	if s.DefaultFrequency <= 0 {
+		add("spawn.default_frequency must be positive")
+	}
+	switch s.DefaultMode {
+	case models.SpawnModeMessages, models.SpawnModeInterval:
+	case models.SpawnModeAdaptive:
+		if s.MaxGap <= 0 {
+			add("spawn.max_gap is required when spawn.default_mode is adaptive")
+		}
+	default:
+		add("spawn.default_mode must be messages, interval or adaptive")
+	}
+	if s.DefaultInterval < time.Minute {
+		add("spawn.default_interval must be at least 1m")
+	}
+	if s.MinGap < 0 || s.MaxGap < 0 || (s.MaxGap > 0 && s.MaxGap < s.MinGap) {
+		add("spawn.min_gap and spawn.max_gap must satisfy 0 <= min_gap <= max_gap, or max_gap 0 for no maximum")
+	}
+	if s.Expiry != 0 && s.Expiry < time.Minute {
+		add("spawn.expiry must be 0 or at least 1m")
+	}
+	if _, err := utils.LoadLocation(s.QuietTimezone); err != nil {
+		add("spawn.quiet_timezone must be an IANA time zone such as Asia/Kolkata")
+	}
+	totalWeight := 0
+	for rarity, weight := range s.RarityWeights {
+		if rarity < 1 || rarity > 15 || weight < 0 {
+			add("spawn.rarity_weights[%d] must be a rarity from 1 to 15 with a weight of at least 0", rarity)
+		}
+		totalWeight += weight
+	}
+	if totalWeight <= 0 {
+		add("spawn.rarity_weights must give at least one rarity a positive weight")
+	}
+	if c.Guess.LettersPerTypo < 0 || c.Guess.MaxTypos < 0 {
+		add("guess.letters_per_typo and guess.max_typos must not be negative")
+	}
+	switch c.Images.Style {
+	case imaging.StyleSilhouette, imaging.StyleBlur, imaging.StyleOff:
+	default:
+		add("images.style must be silhouette, blur or off")
+	}
+	if c.Images.MaxBytes <= 0 || c.Images.Timeout <= 0 {
+		add("images.max_bytes and images.timeout must be positive")
+	}
+	if c.Images.CacheMaxBytes < 0 {
+		add("images.cache_max_bytes must not be negative")
+	}
+
+	if !i18n.Default().Has(c.Language) {
+		add("language must be one of %s", strings.Join(i18n.Default().Languages(), ", "))
+	}
+
+	switch c.UpdateMode {
+	case UpdateModePolling:
+	case UpdateModeWebhook:
+		if !validWebhookSecret(c.WebhookSecret) {
+			add("WEBHOOK_SECRET is required in webhook mode (1-256 characters of A-Z, a-z, 0-9, _ and -)")
+		}
+		if !strings.HasPrefix(c.WebhookPath, "/") {
+			add("WEBHOOK_PATH must start with /")
+		}
+	default:
+		add("UPDATE_MODE must be polling or webhook")
+	}
+	if _, err := zapcore.ParseLevel(c.LogLevel); err != nil {
+		add("LOG_LEVEL must be one of debug, info, warn, error")
+	}
+	if c.LogFormat != "json" && c.LogFormat != "console" {
+		add("LOG_FORMAT must be json or console")
+	}
+	if c.CacheTTL < 0 {
+		add("CACHE_TTL must not be negative")
+	}
+	if c.ShutdownTimeout <= 0 {
+		add("SHUTDOWN_TIMEOUT must be a positive duration such as 25s")
+	}
+
+	sort.Strings(problems)
+	return problems
+}
+
+// byKey maps each collection's config key to its name
+func (c Collections) byKey() map[string]string {
+	return map[string]string{
+		"characters":          c.Characters,
+		"users":               c.Users,
+		"user_totals":         c.UserTotals,
+		"group_user_totals":   c.GroupUserTotals,
+		"top_global_groups":   c.TopGlobalGroups,
+		"pm_users":            c.PMUsers,
+		"daily_user_guesses":  c.DailyUserGuesses,
+		"daily_group_guesses": c.DailyGroupGuesses,
+		"redeem_codes":        c.RedeemCodes,
+		"claim_codes":         c.ClaimCodes,
+		"rarity_settings":     c.RaritySettings,
+		"locked_characters":   c.LockedCharacters,
+		"sort_preferences":    c.SortPreferences,
+		"ledger":              c.Ledger,
+		"active_spawns":       c.ActiveSpawns,
+		"message_counters":    c.MessageCounters,
+		"pending_payments":    c.PendingPayments,
+		"pending_trades":      c.PendingTrades,
+		"pending_gifts":       c.PendingGifts,
+		"chat_locales":        c.ChatLocales,
+		"roles":               c.Roles,
+		"audit_log":           c.AuditLog,
+		"counters":            c.Counters,
+		"migrations":          c.Migrations,
+	}
+}
+
+// validWebhookSecret reports whether s is a secret token Telegram accepts
+func validWebhookSecret(s string) bool {
+	if len(s) == 0 || len(s) > 256 {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -2,12 +2,15 @@ package database
 
 import (
 	"context"
-	"log"
+	"errors"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
 	"senpai-waifu-bot/internal/config"
+	"senpai-waifu-bot/internal/metrics"
 )
 
 var (
@@ -30,51 +33,91 @@ var (
 	RaritySettingsCollection   *mongo.Collection
 	LockedCharactersCollection *mongo.Collection
 	SortPreferencesCollection  *mongo.Collection
+	LedgerCollection           *mongo.Collection
+	ActiveSpawnsCollection     *mongo.Collection
+	MessageCountersCollection  *mongo.Collection
+	PendingPaymentsCollection  *mongo.Collection
+	PendingTradesCollection    *mongo.Collection
+	PendingGiftsCollection     *mongo.Collection
+	ChatLocalesCollection      *mongo.Collection
+	RolesCollection            *mongo.Collection
+	AuditLogCollection         *mongo.Collection
+	CountersCollection         *mongo.Collection
 )
 
-// Connect establishes connection to MongoDB
-func Connect(cfg *config.Config) error {
+// Open connects to MongoDB and pings it without touching any collection, for tools such as
+// the migration runner that must work before the bot creates its indexes
+func Open(cfg *config.Config) (*mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	clientOptions := options.Client().ApplyURI(cfg.MongoURL)
+	clientOptions := options.Client().ApplyURI(cfg.MongoURL).SetMonitor(metrics.MongoMonitor())
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Ping the database
+	// Ping the database; a client that cannot reach it is dropped so Open can be retried
 	err = client.Ping(ctx, nil)
+	if err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+	return client, nil
+}
+
+// Connect establishes connection to MongoDB
+func Connect(cfg *config.Config, log *zap.Logger) error {
+	client, err := Open(cfg)
 	if err != nil {
 		return err
 	}
 
-	log.Println("✅ Connected to MongoDB!")
+	log.Info("connected to MongoDB")
 
 	Client = client
-	DB = client.Database("Character_catcher")
+	DB = client.Database(cfg.Database.Name)
 
 	// Initialize collections
-	CharacterCollection = DB.Collection("anime_characters_lol")
-	UserCollection = DB.Collection("user_collection_lmaoooo")
-	UserTotalsCollection = DB.Collection("user_totals_lmaoooo")
-	GroupUserTotalsCollection = DB.Collection("group_user_totalsssssss")
-	TopGlobalGroupsCollection = DB.Collection("top_global_groups")
-	PMUsersCollection = DB.Collection("total_pm_users")
-	DailyUserGuessesCollection = DB.Collection("daily_user_guesses")
-	DailyGroupGuessesCollection = DB.Collection("daily_group_guesses")
-	RedeemCodesCollection = DB.Collection("redeem_codes")
-	ClaimCodesCollection = DB.Collection("claim_codes")
-	RaritySettingsCollection = DB.Collection("rarity_settings")
-	LockedCharactersCollection = DB.Collection("locked_characters")
-	SortPreferencesCollection = DB.Collection("sort_preferences")
+	names := cfg.Database.Collections
+	CharacterCollection = DB.Collection(names.Characters)
+	UserCollection = DB.Collection(names.Users)
+	UserTotalsCollection = DB.Collection(names.UserTotals)
+	GroupUserTotalsCollection = DB.Collection(names.GroupUserTotals)
+	TopGlobalGroupsCollection = DB.Collection(names.TopGlobalGroups)
+	PMUsersCollection = DB.Collection(names.PMUsers)
+	DailyUserGuessesCollection = DB.Collection(names.DailyUserGuesses)
+	DailyGroupGuessesCollection = DB.Collection(names.DailyGroupGuesses)
+	RedeemCodesCollection = DB.Collection(names.RedeemCodes)
+	ClaimCodesCollection = DB.Collection(names.ClaimCodes)
+	RaritySettingsCollection = DB.Collection(names.RaritySettings)
+	LockedCharactersCollection = DB.Collection(names.LockedCharacters)
+	SortPreferencesCollection = DB.Collection(names.SortPreferences)
+	LedgerCollection = DB.Collection(names.Ledger)
+	ActiveSpawnsCollection = DB.Collection(names.ActiveSpawns)
+	MessageCountersCollection = DB.Collection(names.MessageCounters)
+	PendingPaymentsCollection = DB.Collection(names.PendingPayments)
+	PendingTradesCollection = DB.Collection(names.PendingTrades)
+	PendingGiftsCollection = DB.Collection(names.PendingGifts)
+	ChatLocalesCollection = DB.Collection(names.ChatLocales)
+	RolesCollection = DB.Collection(names.Roles)
+	AuditLogCollection = DB.Collection(names.AuditLog)
+	CountersCollection = DB.Collection(names.Counters)
 
 	// Create indexes
-	createIndexes()
+	createIndexes(log)
 
 	return nil
 }
 
+// Ping checks that MongoDB is reachable
+func Ping(ctx context.Context) error {
+	if Client == nil {
+		return errors.New("not connected")
+	}
+	return Client.Ping(ctx, nil)
+}
+
 // Disconnect closes the MongoDB connection
 func Disconnect() error {
 	if Client != nil {
@@ -85,7 +128,7 @@ func Disconnect() error {
 	return nil
 }
 
-func createIndexes() {
+func createIndexes(log *zap.Logger) {
 	ctx := context.Background()
 
 	// User collection indexes
@@ -100,7 +143,7 @@ func createIndexes() {
 	}
 	_, err := UserCollection.Indexes().CreateMany(ctx, userIndexes)
 	if err != nil {
-		log.Printf("Error creating user indexes: %v", err)
+		log.Error("failed to create user indexes", zap.Error(err))
 	}
 
 	// Character collection indexes
@@ -115,7 +158,7 @@ func createIndexes() {
 	}
 	_, err = CharacterCollection.Indexes().CreateMany(ctx, charIndexes)
 	if err != nil {
-		log.Printf("Error creating character indexes: %v", err)
+		log.Error("failed to create character indexes", zap.Error(err))
 	}
 
 	// Redeem codes index
@@ -124,7 +167,7 @@ func createIndexes() {
 		Options: options.Index().SetUnique(true),
 	})
 	if err != nil {
-		log.Printf("Error creating redeem code index: %v", err)
+		log.Error("failed to create redeem code index", zap.Error(err))
 	}
 
 	// Claim codes index
@@ -133,7 +176,7 @@ func createIndexes() {
 		Options: options.Index().SetUnique(true),
 	})
 	if err != nil {
-		log.Printf("Error creating claim code index: %v", err)
+		log.Error("failed to create claim code index", zap.Error(err))
 	}
 
 	// Locked characters index
@@ -142,8 +185,67 @@ func createIndexes() {
 		Options: options.Index().SetUnique(true),
 	})
 	if err != nil {
-		log.Printf("Error creating locked characters index: %v", err)
+		log.Error("failed to create locked characters index", zap.Error(err))
+	}
+
+	// Ledger index for per-user history, newest first
+	_, err = LedgerCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	if err != nil {
+		log.Error("failed to create ledger index", zap.Error(err))
+	}
+
+	// Bot state and chat locales are keyed by chat
+	for _, coll := range []*mongo.Collection{ActiveSpawnsCollection, MessageCountersCollection, ChatLocalesCollection} {
+		_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    map[string]interface{}{"chat_id": 1},
+			Options: options.Index().SetUnique(true),
+		})
+		if err != nil {
+			log.Error("failed to create index", zap.String("collection", coll.Name()), zap.Error(err))
+		}
+	}
+
+	// The spawn scheduler looks up the spawns that are due to flee
+	_, err = ActiveSpawnsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "flees_at", Value: 1}},
+	})
+	if err != nil {
+		log.Error("failed to create spawn expiry index", zap.Error(err))
+	}
+
+	// A user holds each role at most once
+	_, err = RolesCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "role", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Error("failed to create roles index", zap.Error(err))
+	}
+
+	// Audit log queries list the newest actions, optionally by actor, target user or character
+	auditIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "target_user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "character_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	}
+	_, err = AuditLogCollection.Indexes().CreateMany(ctx, auditIndexes)
+	if err != nil {
+		log.Error("failed to create audit log indexes", zap.Error(err))
+	}
+
+	// Pending confirmations are removed by MongoDB once they expire
+	for _, coll := range []*mongo.Collection{PendingPaymentsCollection, PendingTradesCollection, PendingGiftsCollection} {
+		_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    map[string]interface{}{"expires_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		})
+		if err != nil {
+			log.Error("failed to create TTL index", zap.String("collection", coll.Name()), zap.Error(err))
+		}
 	}
 
-	log.Println("✅ Database indexes created")
+	log.Info("database indexes created")
 }
@@ -0,0 +1,177 @@
+// Package guess decides whether a /guess names the spawned character. Guesses and names are
+// compared after normalization: case, diacritics, punctuation, honorifics, long vowel
+// spellings and name order do not matter, and a few typos are forgiven in longer names.
+package guess
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// honorifics are dropped from guesses and names, whether written as a word or joined with a
+// hyphen as in "Shinobu-san"
+var honorifics = map[string]bool{
+	"san": true, "chan": true, "kun": true, "sama": true, "tan": true, "dono": true,
+	"senpai": true, "sempai": true, "sensei": true, "hime": true,
+}
+
+// longVowels folds the long vowel spellings of romanized Japanese, so that "Kochou",
+// "Kocho" and "Kochoo" match. Macrons are folded by the diacritic removal before this.
+var longVowels = strings.NewReplacer("ou", "o", "oo", "o", "oh", "o", "uu", "u", "aa", "a", "ii", "i", "ee", "e")
+
+// letters maps the letters that do not decompose into a base letter and a mark
+var letters = strings.NewReplacer("ß", "ss", "æ", "ae", "œ", "oe", "ø", "o", "đ", "d", "ł", "l", "þ", "th")
+
+// Matcher compares guesses with character names
+type Matcher struct {
+	lettersPerTypo int
+	maxTypos       int
+}
+
+// NewMatcher creates a Matcher that forgives one typo for every lettersPerTypo letters of a
+// name part, up to maxTypos. A lettersPerTypo of 0 only accepts exact matches after
+// normalization.
+func NewMatcher(lettersPerTypo, maxTypos int) *Matcher {
+	return &Matcher{lettersPerTypo: lettersPerTypo, maxTypos: maxTypos}
+}
+
+// Match reports whether guess names the character with the given names, its name followed by
+// its aliases. A guess matches a name when each of its words matches a different word of the
+// name in any order, or when it matches the whole name written without spaces, as in "hutao"
+// for "Hu Tao".
+func (m *Matcher) Match(guess string, names ...string) bool {
+	words := Words(guess)
+	if len(words) == 0 {
+		return false
+	}
+	joined := strings.Join(words, "")
+
+	for _, name := range names {
+		nameWords := Words(name)
+		if len(nameWords) == 0 {
+			continue
+		}
+		if m.matchWords(words, nameWords, make([]bool, len(nameWords))) {
+			return true
+		}
+		if len(nameWords) > 1 && m.close(joined, strings.Join(nameWords, "")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWords reports whether every word in words matches a different word in names that is
+// not yet used
+func (m *Matcher) matchWords(words, names []string, used []bool) bool {
+	if len(words) == 0 {
+		return true
+	}
+	for i, name := range names {
+		if used[i] || !m.close(words[0], name) {
+			continue
+		}
+		used[i] = true
+		if m.matchWords(words[1:], names, used) {
+			return true
+		}
+		used[i] = false
+	}
+	return false
+}
+
+// close reports whether guess is within the typo tolerance of name
+func (m *Matcher) close(guess, name string) bool {
+	if guess == name {
+		return true
+	}
+	g, n := []rune(guess), []rune(name)
+	typos := m.Typos(len(n))
+	if typos == 0 || abs(len(g)-len(n)) > typos {
+		return false
+	}
+	return Distance(g, n) <= typos
+}
+
+// Typos returns the number of typos forgiven in a name part of length letters
+func (m *Matcher) Typos(length int) int {
+	if m.lettersPerTypo <= 0 {
+		return 0
+	}
+	return min(length/m.lettersPerTypo, m.maxTypos)
+}
+
+// Key returns the normalized form of a name, which is equal for names that match without typos
+// in any word order
+func Key(name string) string {
+	words := Words(name)
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// Words splits s into normalized words: lower case letters and digits without diacritics,
+// with long vowels folded and honorifics dropped. A name made of honorifics alone is kept.
+func Words(s string) []string {
+	folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), strings.ToLower(s))
+	if err != nil {
+		folded = strings.ToLower(s)
+	}
+	folded = letters.Replace(folded)
+
+	fields := strings.FieldsFunc(folded, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if !honorifics[field] {
+			words = append(words, longVowels.Replace(field))
+		}
+	}
+	if len(words) == 0 && len(fields) > 0 {
+		for _, field := range fields {
+			words = append(words, longVowels.Replace(field))
+		}
+	}
+	return words
+}
+
+// Distance returns the number of single letter insertions, deletions, substitutions and
+// swaps of adjacent letters that turn a into b
+func Distance(a, b []rune) int {
+	// Three rows of the optimal string alignment table: two rows back, the previous row and
+	// the current one
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
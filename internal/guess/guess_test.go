@@ -0,0 +1,128 @@
+package guess
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	m := NewMatcher(5, 2)
+	tests := []struct {
+		name  string
+		guess string
+		names []string
+		want  bool
+	}{
+		{"exact", "Shinobu Kochou", []string{"Shinobu Kochou"}, true},
+		{"case and spaces", "  shinobu   KOCHOU ", []string{"Shinobu Kochou"}, true},
+		{"one part", "shinobu", []string{"Shinobu Kochou"}, true},
+		{"reversed order", "kochou shinobu", []string{"Shinobu Kochou"}, true},
+		{"long vowel", "kocho", []string{"Shinobu Kochou"}, true},
+		{"macron", "Kōchō", []string{"Shinobu Kochou"}, true},
+		{"diacritic in name", "zoe", []string{"Zoë"}, true},
+		{"diacritic in guess", "Zoë", []string{"Zoe"}, true},
+		{"hyphenated honorific", "Shinobu-san", []string{"Shinobu Kochou"}, true},
+		{"honorific word", "shinobu sama", []string{"Shinobu Kochou"}, true},
+		{"joined name", "hutao", []string{"Hu Tao"}, true},
+		{"joined name with typo", "hutaoo", []string{"Hu Tao"}, true},
+		{"alias", "shinobu", []string{"Kanao Tsuyuri", "Shinobu"}, true},
+		{"one typo in 7 letters", "shinbu", []string{"Shinobu Kochou"}, true},
+		{"swapped letters", "shniobu", []string{"Shinobu Kochou"}, true},
+		{"two typos in 7 letters", "shnbu", []string{"Shinobu Kochou"}, false},
+		{"typo in short word", "ram", []string{"Rem"}, false},
+		{"repeated word", "shinobu shinobu", []string{"Shinobu Kochou"}, false},
+		{"extra word", "shinobu kochou kanao", []string{"Shinobu Kochou"}, false},
+		{"bare honorific", "san", []string{"Shinobu Kochou"}, false},
+		{"other character", "kanao", []string{"Shinobu Kochou"}, false},
+		{"empty guess", "", []string{"Shinobu Kochou"}, false},
+		{"punctuation only", "!!", []string{"Shinobu Kochou"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Match(tt.guess, tt.names...); got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.guess, tt.names, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchExact(t *testing.T) {
+	m := NewMatcher(0, 2)
+	if !m.Match("kochou shinobu", "Shinobu Kochou") {
+		t.Error("exact matcher rejected a reordered name")
+	}
+	if m.Match("shinbu", "Shinobu Kochou") {
+		t.Error("exact matcher forgave a typo")
+	}
+}
+
+func TestTypos(t *testing.T) {
+	m := NewMatcher(5, 2)
+	tests := []struct {
+		length int
+		want   int
+	}{
+		{0, 0},
+		{4, 0},
+		{5, 1},
+		{9, 1},
+		{10, 2},
+		{30, 2},
+	}
+	for _, tt := range tests {
+		if got := m.Typos(tt.length); got != tt.want {
+			t.Errorf("Typos(%d) = %d, want %d", tt.length, got, tt.want)
+		}
+	}
+	if got := NewMatcher(0, 2).Typos(30); got != 0 {
+		t.Errorf("Typos(30) = %d without typo tolerance, want 0", got)
+	}
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []string
+	}{
+		{"Shinobu Kochou", []string{"shinobu", "kocho"}},
+		{"Shinobu-san", []string{"shinobu"}},
+		{"Zoë", []string{"zoe"}},
+		{"Straße", []string{"strasse"}},
+		{"C.C.", []string{"c", "c"}},
+		{"Hime-sama", []string{"hime", "sama"}},
+		{"San", []string{"san"}},
+		{"", []string{}},
+	}
+	for _, tt := range tests {
+		if got := Words(tt.s); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Words(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	if a, b := Key("Shinobu Kochou"), Key("kocho SHINOBU-chan"); a != b {
+		t.Errorf("Key() = %q and %q, want equal keys", a, b)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"shinobu", "shinobu", 0},
+		{"ab", "ba", 1},
+		{"shinobu", "shniobu", 1},
+		{"shinobu", "shinbu", 1},
+		{"kitten", "sitting", 3},
+		{"ca", "abc", 3},
+	}
+	for _, tt := range tests {
+		if got := Distance([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/store"
+	"senpai-waifu-bot/internal/utils"
+)
+
+// auditPageSize is the number of entries /audit shows
+const auditPageSize = 10
+
+// audit records that the command in c changed state and, when configured, posts the entry to
+// the log group. Failures are logged and never undo the change.
+func (b *Bot) audit(c *CommandContext, entry models.AuditEntry) {
+	entry.ActorID = c.Msg.From.ID
+	entry.ActorName = c.Msg.From.FirstName
+	entry.Command = c.Command.Name
+	entry.Args = c.Args
+	entry.ChatID = c.Msg.Chat.ID
+	if err := b.AuditService.Record(&entry); err != nil {
+		logError(c.Log, err, "record audit entry")
+	}
+
+	if b.Config.AuditToLogGroup {
+		p := b.printer(b.Config.GroupID, nil)
+		if _, err := b.reply(p, b.Config.GroupID, "audit.posted", i18n.Args{"Entry": i18n.HTML(formatAuditEntry(p, entry))}); err != nil {
+			logError(c.Log, err, "post audit entry to log group")
+		}
+	}
+}
+
+// formatAuditEntry renders one audit entry as HTML
+func formatAuditEntry(p *i18n.Printer, entry models.AuditEntry) string {
+	return p.Text("audit.entry", i18n.Args{
+		"When":        entry.CreatedAt.In(utils.GetISTNow().Location()).Format("02 Jan 15:04"),
+		"ActorID":     entry.ActorID,
+		"Actor":       entry.ActorName,
+		"Command":     strings.TrimSpace("/" + entry.Command + " " + strings.Join(entry.Args, " ")),
+		"TargetID":    entry.TargetUserID,
+		"CharacterID": entry.CharacterID,
+		"Changes":     i18n.HTML(formatAuditChanges(p, entry.Before, entry.After)),
+	})
+}
+
+// formatAuditChanges renders one line per field in before or after, in field order
+func formatAuditChanges(p *i18n.Printer, before, after map[string]interface{}) string {
+	var fields []string
+	for field := range before {
+		fields = append(fields, field)
+	}
+	for field := range after {
+		if _, ok := before[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	lines := make([]string, len(fields))
+	for i, field := range fields {
+		args := i18n.Args{"Field": field, "Before": "", "After": ""}
+		if v, ok := before[field]; ok {
+			args["Before"] = fmt.Sprint(v)
+		}
+		if v, ok := after[field]; ok {
+			args["After"] = fmt.Sprint(v)
+		}
+		lines[i] = p.Text("audit.change", args)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cmdAudit handles /audit: it lists the newest audit entries, optionally only those by an
+// actor, about a target user or about a character
+func (b *Bot) cmdAudit(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+
+	var filter store.AuditFilter
+	if len(c.Args) > 0 {
+		id, value := c.Arg(1), int64(0)
+		if id == "" {
+			b.reply(p, msg.Chat.ID, "audit.usage", nil)
+			return
+		}
+		kind := strings.ToLower(c.Arg(0))
+		if kind == "actor" || kind == "user" {
+			var err error
+			if value, err = strconv.ParseInt(id, 10, 64); err != nil {
+				b.reply(p, msg.Chat.ID, "audit.usage", nil)
+				return
+			}
+		}
+		switch kind {
+		case "actor":
+			filter.ActorID = value
+		case "user":
+			filter.TargetUserID = value
+		case "char", "character":
+			filter.CharacterID = id
+		default:
+			b.reply(p, msg.Chat.ID, "audit.usage", nil)
+			return
+		}
+	}
+
+	entries, err := b.AuditService.Recent(filter, auditPageSize)
+	if err != nil {
+		logError(c.Log, err, "list audit entries", zap.Strings("args", c.Args))
+		b.reply(p, msg.Chat.ID, "audit.failed", nil)
+		return
+	}
+	if len(entries) == 0 {
+		b.reply(p, msg.Chat.ID, "audit.empty", nil)
+		return
+	}
+
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		lines[i] = formatAuditEntry(p, entry)
+	}
+	b.reply(p, msg.Chat.ID, "audit.list", i18n.Args{
+		"Filter":  strings.Join(c.Args, " "),
+		"Entries": i18n.HTML(strings.Join(lines, "\n\n")),
+	})
+}
@@ -1,19 +1,49 @@
 package handlers
 
 import (
-	"log"
+	"context"
+	"errors"
 	"sync"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
 	"senpai-waifu-bot/internal/config"
+	"senpai-waifu-bot/internal/guess"
+	"senpai-waifu-bot/internal/health"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/imaging"
+	"senpai-waifu-bot/internal/logger"
+	"senpai-waifu-bot/internal/metrics"
 	"senpai-waifu-bot/internal/services"
+	"senpai-waifu-bot/internal/state"
+	"senpai-waifu-bot/internal/store"
 )
 
+// HealthTelegram names the Telegram subsystem in health reports
+const HealthTelegram = "telegram"
+
+// TelegramCheckInterval is how often getMe is called to check that Telegram is reachable
+const TelegramCheckInterval = time.Minute
+
+// heartbeatInterval is how often the update loop beats while no updates arrive
+const heartbeatInterval = 15 * time.Second
+
 // Bot represents the Telegram bot
 type Bot struct {
-	API                *tgbotapi.BotAPI
+	API                Messenger
+	// Me is the bot's own account
+	Me                 tgbotapi.User
 	Config             *config.Config
+	Log                *zap.Logger
+	// Catalog renders every reply
+	Catalog            *i18n.Catalog
+	// Guesses decides which guesses name the spawned character
+	Guesses            *guess.Matcher
+	// Images disguises spawn images
+	Images             *imaging.Disguiser
+	// counterLog samples the per-message spawn counter logs
+	counterLog         *zap.Logger
 	UserService        *services.UserService
 	CharacterService   *services.CharacterService
 	GroupService       *services.GroupService
@@ -21,151 +51,295 @@ type Bot struct {
 	RedeemService      *services.RedeemService
 	ClaimCodeService   *services.ClaimCodeService
 	RarityService      *services.RarityService
+	DropService        *services.DropService
 	SortPrefService    *services.SortPreferenceService
+	TransferService    *services.TransferService
+	HintService        *services.HintService
+	LedgerService      *services.LedgerService
+	StateService       *services.StateService
+	RoleService        *services.RoleService
+	AuditService       *services.AuditService
+	
+	// Command registry and middleware chain
+	Router             *Router
+	
+	// In-memory state; active spawns, message counters and pending
+	// payments, trades and gifts live in StateService
+	Chats              *state.Chats
+	ChatLocks          *state.ChatLocks
+	
+	// Cooldowns
+	PaymentCooldowns   *state.Cooldowns
+	TradeCooldowns     *state.Cooldowns
+	GiftCooldowns      *state.Cooldowns
 	
-	// In-memory state
-	MessageCounters    map[int64]int
-	LastCharacters     map[int64]*LastCharInfo
-	SentCharacters     map[int64][]string
-	FirstCorrectGuesses map[int64]int64
-	LastUser           map[int64]*LastUserInfo
-	WarnedUsers        map[int64]time.Time
-	ChatLocks          sync.Map
-	
-	// Payment state
-	PendingPayments    map[string]*PendingPaymentInfo
-	PaymentCooldowns   map[int64]time.Time
-	
-	// Trade/Gift state
-	PendingTrades      map[string]*PendingTradeInfo
-	PendingGifts       map[string]*PendingGiftInfo
-	TradeCooldowns     map[int64]time.Time
-	GiftCooldowns      map[int64]time.Time
-}
-
-// LastCharInfo stores info about the last spawned character in a chat
-type LastCharInfo struct {
-	CharacterID string
-	Name        string
-	Anime       string
-	Rarity      int
-	ImgURL      string
-}
-
-// LastUserInfo stores info about the last user who sent a message in a chat
-type LastUserInfo struct {
-	UserID int64
-	Count  int
-}
-
-// PendingPaymentInfo stores pending payment info
-type PendingPaymentInfo struct {
-	Token     string
-	SenderID  int64
-	TargetID  int64
-	Amount    int64
-	CreatedAt time.Time
-	ChatID    int64
-	MessageID int
-}
-
-// PendingTradeInfo stores pending trade info
-type PendingTradeInfo struct {
-	SenderID       int64
-	ReceiverID     int64
-	SenderCharID   string
-	ReceiverCharID string
-	Timestamp      time.Time
-}
-
-// PendingGiftInfo stores pending gift info
-type PendingGiftInfo struct {
-	SenderID          int64
-	ReceiverID        int64
-	CharacterID       string
-	CharacterName     string
-	ReceiverUsername  string
-	ReceiverFirstName string
-	Timestamp         time.Time
-}
-
-// NewBot creates a new Bot instance
-func NewBot(cfg *config.Config) (*Bot, error) {
-	api, err := tgbotapi.NewBotAPI(cfg.BotToken)
-	if err != nil {
-		return nil, err
-	}
-	
-	api.Debug = false
-	log.Printf("✅ Authorized on account %s", api.Self.UserName)
+	// Health of the update loop, Telegram and background routines
+	Health             *health.Checker
 	
+	// Lifecycle: in-flight updates and background routines are waited for on shutdown
+	startedAt          time.Time
+	inflight           sync.WaitGroup
+	background         sync.WaitGroup
+	stopBackground     context.CancelFunc
+}
+
+// NewBot creates a new Bot instance that talks to Telegram through api as the account me,
+// backed by the given store
+func NewBot(cfg *config.Config, api Messenger, me tgbotapi.User, st *store.Store, log *zap.Logger, checker *health.Checker) *Bot {
 	bot := &Bot{
 		API:                 api,
+		Me:                  me,
 		Config:              cfg,
-		UserService:         services.NewUserService(),
-		CharacterService:    services.NewCharacterService(),
-		GroupService:        services.NewGroupService(),
-		DailyService:        services.NewDailyService(),
-		RedeemService:       services.NewRedeemService(),
-		ClaimCodeService:    services.NewClaimCodeService(),
-		RarityService:       services.NewRarityService(),
-		SortPrefService:     services.NewSortPreferenceService(),
-		MessageCounters:     make(map[int64]int),
-		LastCharacters:      make(map[int64]*LastCharInfo),
-		SentCharacters:      make(map[int64][]string),
-		FirstCorrectGuesses: make(map[int64]int64),
-		LastUser:            make(map[int64]*LastUserInfo),
-		WarnedUsers:         make(map[int64]time.Time),
-		PendingPayments:     make(map[string]*PendingPaymentInfo),
-		PaymentCooldowns:    make(map[int64]time.Time),
-		PendingTrades:       make(map[string]*PendingTradeInfo),
-		PendingGifts:        make(map[string]*PendingGiftInfo),
-		TradeCooldowns:      make(map[int64]time.Time),
-		GiftCooldowns:       make(map[int64]time.Time),
-	}
-	
-	// Start cleanup goroutine
-	go bot.cleanupRoutine()
-	
-	return bot, nil
-}
-
-// Start starts the bot
-func (b *Bot) Start() {
+		Log:                 log,
+		Catalog:             i18n.Default(),
+		Guesses:             guess.NewMatcher(cfg.Guess.LettersPerTypo, cfg.Guess.MaxTypos),
+		Images:              imaging.NewDisguiser(cfg.Images.Style, cfg.Images.CacheDir, cfg.Images.CacheMaxBytes, cfg.Images.MaxBytes, cfg.Images.Timeout),
+		counterLog:          logger.Sampled(log),
+		UserService:         services.NewUserService(st.Users, st.Ledger, st.Tx, log),
+		CharacterService:    services.NewCharacterService(st.Characters, st.Users, st.Counters, services.ShopPricing{
+			Prices:      cfg.Economy.ShopPrices,
+			DiscountMin: cfg.Economy.ShopDiscountMin,
+			DiscountMax: cfg.Economy.ShopDiscountMax,
+		}),
+		GroupService:        services.NewGroupService(st.Groups),
+		DailyService:        services.NewDailyService(st.Groups),
+		RedeemService:       services.NewRedeemService(st.Codes, log),
+		ClaimCodeService:    services.NewClaimCodeService(st.Codes),
+		RarityService:       services.NewRarityService(st.Rarity, log),
+		DropService:         services.NewDropService(st.Characters, st.Rarity, nil),
+		SortPrefService:     services.NewSortPreferenceService(st.Users),
+		TransferService:     services.NewTransferService(st.Users, st.Ledger, st.Tx, log),
+		HintService:         services.NewHintService(st.Users, st.State, st.Ledger, st.Tx, services.HintPricing{
+			Costs:     cfg.Economy.HintCosts,
+			PerSpawn:  cfg.Economy.HintsPerSpawn,
+			RewardCut: cfg.Economy.HintRewardCut,
+		}, log),
+		LedgerService:       services.NewLedgerService(st.Ledger),
+		StateService:        services.NewStateService(st.State),
+		RoleService:         services.NewRoleService(st.Roles),
+		AuditService:        services.NewAuditService(st.Audit),
+		Chats:               state.NewChats(),
+		ChatLocks:           state.NewChatLocks(),
+		PaymentCooldowns:    state.NewCooldowns(),
+		TradeCooldowns:      state.NewCooldowns(),
+		GiftCooldowns:       state.NewCooldowns(),
+		Health:              checker,
+		startedAt:           time.Now(),
+	}
+	
+	bot.Router = bot.newRouter()
+	bot.registerBotCommands()
+	
+	// Start background routines
+	bgCtx, cancel := context.WithCancel(context.Background())
+	bot.stopBackground = cancel
+	bot.runBackground("cleanup", func() { bot.cleanupRoutine(bgCtx) })
+	bot.runBackground("telegram_check", func() { bot.telegramCheckRoutine(bgCtx) })
+	bot.runBackground("spawn_scheduler", func() { bot.spawnSchedulerRoutine(bgCtx) })
+	
+	return bot
+}
+
+// registerBotCommands publishes the command menus in every catalog language: user commands
+// for everyone, group admin commands as well for chat administrators, and the commands their
+// roles allow in the private chat of every staff member. Clients in other languages see the
+// menu of the default language.
+func (b *Bot) registerBotCommands() {
+	for _, lang := range b.Catalog.Languages() {
+		code := b.menuLanguageCode(lang)
+		log := b.Log.With(zap.String("language", lang))
+		
+		userCommands := b.menu(lang, func(role Role) bool { return role == RoleUser })
+		if _, err := b.API.Request(tgbotapi.NewSetMyCommandsWithScopeAndLanguage(tgbotapi.NewBotCommandScopeDefault(), code, userCommands...)); err != nil {
+			log.Warn("failed to register bot commands", zap.Error(err))
+		}
+		adminCommands := b.menu(lang, func(role Role) bool { return role == RoleUser || role == RoleGroupAdmin })
+		if _, err := b.API.Request(tgbotapi.NewSetMyCommandsWithScopeAndLanguage(tgbotapi.NewBotCommandScopeAllChatAdministrators(), code, adminCommands...)); err != nil {
+			log.Warn("failed to register group admin commands", zap.Error(err))
+		}
+	}
+	for _, id := range b.staffIDs() {
+		b.publishStaffMenu(id)
+	}
+}
+
+// menu returns the visible commands whose role passes allow, described in lang
+func (b *Bot) menu(lang string, allow func(Role) bool) []tgbotapi.BotCommand {
+	p := b.Catalog.Printer(i18n.Locale{Language: lang})
+	var commands []tgbotapi.BotCommand
+	for _, cmd := range b.Router.Commands() {
+		if cmd.Hidden || !allow(cmd.Role) {
+			continue
+		}
+		commands = append(commands, tgbotapi.BotCommand{Command: cmd.Name, Description: p.Plain(cmd.DescriptionID(), nil)})
+	}
+	return commands
+}
+
+// menuLanguageCode is the language_code the command menu in lang is registered for; the
+// default language's menu is registered without one
+func (b *Bot) menuLanguageCode(lang string) string {
+	if lang == b.Config.Language {
+		return ""
+	}
+	return lang
+}
+
+// ApplyConfig switches to the reloadable settings of next, which must be validated, and
+// republishes the staff command menus for the new sudo list
+func (b *Bot) ApplyConfig(next *config.Config) {
+	previous := b.Config.Live().SudoUsers
+	b.Config.Apply(next)
+	current := b.Config.Live()
+	
+	// Sudo users that were removed keep only the commands of their granted roles
+	for _, id := range previous {
+		if !b.Config.IsSudo(id) {
+			b.publishStaffMenu(id)
+		}
+	}
+	b.registerBotCommands()
+	
+	b.Log.Info("configuration reloaded",
+		zap.Int("sudo_users", len(current.SudoUsers)),
+		zap.Int("video_urls", len(current.VideoURLs)),
+		zap.Int("spawn_frequency", current.Spawn.DefaultFrequency),
+		zap.String("spawn_mode", current.Spawn.DefaultMode))
+}
+
+// Start receives updates in the configured update mode until ctx is cancelled.
+// Updates that are still being handled when it returns are waited for by Shutdown.
+func (b *Bot) Start(ctx context.Context) error {
+	if b.Config.UpdateMode == config.UpdateModeWebhook {
+		return b.startWebhook(ctx)
+	}
+	return b.startPolling(ctx)
+}
+
+// Shutdown waits for in-flight updates to finish, then stops the background routines.
+// It gives up once ctx is done. State is written through to the store as it changes,
+// so there is nothing left to flush once the handlers have returned.
+func (b *Bot) Shutdown(ctx context.Context) error {
+	var err error
+	if !waitContext(ctx, &b.inflight) {
+		err = errors.New("timed out waiting for in-flight updates")
+	}
+	
+	b.stopBackground()
+	if !waitContext(ctx, &b.background) && err == nil {
+		err = errors.New("timed out waiting for background routines")
+	}
+	return err
+}
+
+// waitContext waits for wg, reporting false if ctx is done first
+func waitContext(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// dispatch handles an update in its own goroutine, tracked for shutdown
+func (b *Bot) dispatch(update tgbotapi.Update) {
+	b.inflight.Add(1)
+	go func() {
+		defer b.inflight.Done()
+		b.HandleUpdate(update)
+	}()
+}
+
+// runBackground starts a long-running routine, tracked for shutdown and readiness
+func (b *Bot) runBackground(name string, fn func()) {
+	b.background.Add(1)
+	b.Health.JobStarted(name)
+	go func() {
+		defer b.background.Done()
+		defer b.Health.JobStopped(name)
+		fn()
+	}()
+}
+
+// startPolling receives updates with long polling until ctx is cancelled
+func (b *Bot) startPolling(ctx context.Context) error {
+	// getUpdates is refused while a webhook is set
+	if _, err := b.API.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		b.Log.Warn("failed to delete webhook", zap.Error(err))
+	}
+	
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 	
 	updates := b.API.GetUpdatesChan(u)
 	
-	log.Println("🤖 Bot is running...")
+	b.Log.Info("receiving updates with long polling")
+	
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	b.Health.Beat()
 	
-	for update := range updates {
-		go b.handleUpdate(update)
+	for {
+		select {
+		case update := <-updates:
+			b.Health.Beat()
+			b.dispatch(update)
+		case <-heartbeat.C:
+			b.Health.Beat()
+		case <-ctx.Done():
+			b.API.StopReceivingUpdates()
+			// Updates already fetched have been confirmed to Telegram, so handle them too
+			for {
+				select {
+				case update, ok := <-updates:
+					if !ok {
+						return nil
+					}
+					b.dispatch(update)
+				default:
+					return nil
+				}
+			}
+		}
 	}
 }
 
-// handleUpdate handles a single update
-func (b *Bot) handleUpdate(update tgbotapi.Update) {
+// HandleUpdate handles a single update synchronously. Start and the webhook run it in
+// the background for every update; tests call it directly.
+func (b *Bot) HandleUpdate(update tgbotapi.Update) {
+	kind := updateType(update)
+	metrics.UpdatesTotal.WithLabelValues(kind).Inc()
+	defer metrics.Since(metrics.HandlerDuration.WithLabelValues(kind), time.Now())
+	
+	log := b.updateLogger(update)
 	defer func() {
 		if r := recover(); r != nil {
-			log.Printf("Recovered from panic: %v", r)
+			log.Error("recovered from panic", zap.Any("panic", r), zap.Stack("stack"))
 		}
 	}()
+	log.Debug("update received")
 	
 	// Handle callback queries
 	if update.CallbackQuery != nil {
-		b.handleCallback(update.CallbackQuery)
+		b.handleCallback(update.CallbackQuery, log)
 		return
 	}
 	
-	// Handle messages
-	if update.Message == nil {
+	// Handle chat member updates (bot added/removed from groups)
+	if update.MyChatMember != nil {
+		b.handleChatMemberUpdate(update.MyChatMember, log)
 		return
 	}
 	
-	// Handle chat member updates (bot added/removed from groups)
-	if update.MyChatMember != nil {
-		b.handleChatMemberUpdate(update.MyChatMember)
+	// Handle messages
+	if update.Message == nil {
 		return
 	}
 	
@@ -176,52 +350,68 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 	
 	// Handle commands
 	if update.Message.IsCommand() {
-		b.handleCommand(update.Message)
+		b.handleCommand(update.Message, log)
 	}
 }
 
-// cleanupRoutine periodically cleans up expired data
-func (b *Bot) cleanupRoutine() {
-	ticker := time.NewTicker(1 * time.Minute)
+// updateType names the kind of update for metrics
+func updateType(update tgbotapi.Update) string {
+	switch {
+	case update.CallbackQuery != nil:
+		return "callback_query"
+	case update.MyChatMember != nil:
+		return "my_chat_member"
+	case update.Message != nil && update.Message.IsCommand():
+		return "command"
+	case update.Message != nil:
+		return "message"
+	default:
+		return "other"
+	}
+}
+
+// telegramCheckRoutine calls getMe periodically so readiness reflects whether Telegram is reachable
+func (b *Bot) telegramCheckRoutine(ctx context.Context) {
+	ticker := time.NewTicker(TelegramCheckInterval)
 	defer ticker.Stop()
 	
-	for range ticker.C {
-		now := time.Now()
-		
-		// Clean up warned users (after 10 minutes)
-		for userID, warnedAt := range b.WarnedUsers {
-			if now.Sub(warnedAt) > 10*time.Minute {
-				delete(b.WarnedUsers, userID)
-			}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
 		
-		// Clean up pending payments (after 5 minutes)
-		for token, payment := range b.PendingPayments {
-			if now.Sub(payment.CreatedAt) > 5*time.Minute {
-				delete(b.PendingPayments, token)
-			}
+		_, err := b.API.GetMe()
+		if err != nil {
+			b.Log.Warn("telegram health check failed", zap.Error(err))
 		}
-		
-		// Clean up pending trades (after 5 minutes)
-		for key, trade := range b.PendingTrades {
-			if now.Sub(trade.Timestamp) > 5*time.Minute {
-				delete(b.PendingTrades, key)
-				delete(b.TradeCooldowns, trade.SenderID)
-			}
+		b.Health.Report(HealthTelegram, err)
+	}
+}
+
+// cleanupRoutine periodically cleans up expired data until ctx is cancelled
+func (b *Bot) cleanupRoutine(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+	
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
 		}
 		
-		// Clean up pending gifts (after 30 seconds)
-		for key, gift := range b.PendingGifts {
-			if now.Sub(gift.Timestamp) > 30*time.Second {
-				delete(b.PendingGifts, key)
-				delete(b.GiftCooldowns, gift.SenderID)
-			}
+		// Clean up expired cooldowns; pending payments, trades and gifts expire in the state store
+		b.PaymentCooldowns.Prune(now)
+		b.TradeCooldowns.Prune(now)
+		b.GiftCooldowns.Prune(now)
+		b.Router.PruneCooldowns(now)
+		
+		// Keep the disguised image cache within its size limit
+		if _, err := b.Images.PruneCache(); err != nil {
+			logError(b.Log, err, "prune image cache")
 		}
 	}
 }
-
-// getChatLock gets or creates a lock for a chat
-func (b *Bot) getChatLock(chatID int64) *sync.Mutex {
-	lock, _ := b.ChatLocks.LoadOrStore(chatID, &sync.Mutex{})
-	return lock.(*sync.Mutex)
-}
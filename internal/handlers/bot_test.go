@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/config"
+	"senpai-waifu-bot/internal/health"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/imaging"
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/store"
+	"senpai-waifu-bot/internal/telegramtest"
+)
+
+// ownerID is the bot owner in tests
+const ownerID = 1
+
+// newTestBot returns a bot that talks to a fake Bot API and is backed by a memory store.
+// Every message spawns a character once a user has sent five in a row, and spawn images are
+// posted as they are. The bot is shut down and the server closed when the test ends.
+func newTestBot(t *testing.T, configure func(cfg *config.Config)) (*Bot, *telegramtest.Server, *store.Store) {
+	t.Helper()
+	srv := telegramtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	cfg := config.Defaults()
+	cfg.OwnerID = ownerID
+	cfg.Spawn.DefaultFrequency = 1
+	cfg.Images.Style = imaging.StyleOff
+	if configure != nil {
+		configure(cfg)
+	}
+
+	st := store.NewMemoryStore()
+	api := srv.Client()
+	bot := NewBot(cfg, api, api.Self, st, zap.NewNop(), health.New(time.Minute))
+	t.Cleanup(func() {
+		if err := bot.Shutdown(context.Background()); err != nil {
+			t.Errorf("Shutdown() error = %v", err)
+		}
+	})
+	srv.Reset()
+	return bot, srv, st
+}
+
+// insertCharacters adds chars to the catalog
+func insertCharacters(t *testing.T, st *store.Store, chars ...models.Character) {
+	t.Helper()
+	for i := range chars {
+		if err := st.Characters.InsertCharacter(context.Background(), &chars[i]); err != nil {
+			t.Fatalf("InsertCharacter(%s) error = %v", chars[i].ID, err)
+		}
+	}
+}
+
+// texts returns the text of every call
+func texts(calls []telegramtest.Call) []string {
+	var texts []string
+	for _, call := range calls {
+		texts = append(texts, call.Text())
+	}
+	return texts
+}
+
+func TestHandleUpdateBotAdded(t *testing.T) {
+	bot, srv, _ := newTestBot(t, nil)
+	group := srv.Group(-100, "Waifu Club")
+	alice := srv.User(2, "alice")
+
+	bot.HandleUpdate(srv.BotAdded(group, alice))
+	welcome := bot.printer(group.ID, alice).Text("group.welcome", i18n.Args{"Frequency": bot.Config.Spawn.DefaultFrequency})
+	expectTexts(t, "bot added", srv.Sent(), welcome)
+}
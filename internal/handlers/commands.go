@@ -8,105 +8,235 @@ import (
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/metrics"
 	"senpai-waifu-bot/internal/models"
-	"senpai-waifu-bot/internal/utils"
+	"senpai-waifu-bot/internal/services"
 )
 
 // handleCommand handles bot commands
-func (b *Bot) handleCommand(msg *tgbotapi.Message) {
-	command := msg.Command()
-	
-	switch command {
-	case "start":
-		b.cmdStart(msg)
-	case "ping":
-		b.cmdPing(msg)
-	case "guess", "protecc", "collect", "grab", "hunt":
-		b.cmdGuess(msg)
-	case "harem", "collection":
-		b.cmdHarem(msg, 0)
-	case "bal", "balance":
-		b.cmdBalance(msg)
-	case "pay":
-		b.cmdPay(msg)
-	case "fav":
-		b.cmdFav(msg)
-	case "shop":
-		b.cmdShop(msg)
-	case "leaderboard":
-		b.cmdLeaderboard(msg)
-	case "gift":
-		b.cmdGift(msg)
-	case "trade":
-		b.cmdTrade(msg)
-	case "sfind", "find":
-		b.cmdSFind(msg)
-	case "scheck", "s", "check":
-		b.cmdSCheck(msg)
-	case "smode":
-		b.cmdSMode(msg)
-	case "sclaim":
-		b.cmdSClaim(msg)
-	case "claim":
-		b.cmdClaim(msg)
-	case "credeem":
-		b.cmdCRedeem(msg)
-	case "redeem":
-		b.cmdRedeem(msg)
-	case "gen":
-		b.cmdGen(msg)
-	case "sgen":
-		b.cmdSGen(msg)
-	case "addbal":
-		b.cmdAddBal(msg)
-	case "set_on":
-		b.cmdSetOn(msg)
-	case "set_off":
-		b.cmdSetOff(msg)
-	case "lock":
-		b.cmdLock(msg)
-	case "unlock":
-		b.cmdUnlock(msg)
-	case "locklist":
-		b.cmdLockList(msg)
-	case "resetshop":
-		b.cmdResetShop(msg)
-	case "upload":
-		b.cmdUpload(msg)
-	case "delete":
-		b.cmdDelete(msg)
-	case "update":
-		b.cmdUpdate(msg)
-	case "stats":
-		b.cmdStats(msg)
-	}
+func (b *Bot) handleCommand(msg *tgbotapi.Message, log *zap.Logger) {
+	b.Router.Dispatch(msg, log)
+}
+
+// newRouter builds the command router; registration order is the order shown in /help.
+// Each command's description is the catalog message "command.<name>".
+func (b *Bot) newRouter() *Router {
+	r := NewRouter()
+	// Metrics wrap recovery so a panicking command is still counted, with its outcome
+	r.Use(b.metricsMiddleware, b.recoverMiddleware, b.loggingMiddleware, b.authMiddleware, b.rateLimitMiddleware, b.argsMiddleware)
+	
+	// User commands
+	r.Register(&Command{Name: "start", Handler: withMessage(b.cmdStart)})
+	r.Register(&Command{Name: "help", Handler: b.cmdHelp})
+	r.Register(&Command{
+		Name: "guess", Aliases: []string{"protecc", "collect", "grab", "hunt"},
+		Scope: GroupOnly, Args: []Arg{{Name: "name", Kind: ArgRest}},
+		Handler: b.cmdGuess,
+	})
+	r.Register(&Command{
+		Name: "hint", Scope: GroupOnly, Cooldown: 3 * time.Second,
+		Handler: b.cmdHint,
+	})
+	r.Register(&Command{
+		Name: "harem", Aliases: []string{"collection"},
+		Cooldown: 2 * time.Second,
+		Handler: func(c *CommandContext) { b.cmdHarem(c.Msg, 0) },
+	})
+	r.Register(&Command{Name: "balance", Aliases: []string{"bal"}, Handler: withMessage(b.cmdBalance)})
+	r.Register(&Command{
+		Name: "pay",
+		Usage: "<amount> (reply) | <user_id> <amount>", Handler: withMessage(b.cmdPay),
+	})
+	r.Register(&Command{Name: "history", Handler: withMessage(b.cmdHistory)})
+	r.Register(&Command{Name: "shop", Handler: withMessage(b.cmdShop)})
+	r.Register(&Command{
+		Name: "gift",
+		Args: []Arg{{Name: "character_id"}}, Handler: b.cmdGift,
+	})
+	r.Register(&Command{
+		Name: "trade",
+		Args: []Arg{{Name: "your_id"}, {Name: "their_id"}}, Handler: b.cmdTrade,
+	})
+	r.Register(&Command{Name: "sclaim", Handler: withMessage(b.cmdSClaim)})
+	r.Register(&Command{Name: "claim", Handler: withMessage(b.cmdClaim)})
+	r.Register(&Command{
+		Name: "credeem",
+		Args: []Arg{{Name: "code"}}, Handler: b.cmdCRedeem,
+	})
+	r.Register(&Command{
+		Name: "redeem",
+		Args: []Arg{{Name: "code"}}, Handler: b.cmdRedeem,
+	})
+	r.Register(&Command{Name: "leaderboard", Cooldown: 5 * time.Second, Handler: withMessage(b.cmdLeaderboard)})
+	r.Register(&Command{
+		Name: "sfind", Aliases: []string{"find"},
+		Args: []Arg{{Name: "name", Kind: ArgRest}}, Cooldown: 3 * time.Second, Handler: b.cmdSFind,
+	})
+	r.Register(&Command{
+		Name: "scheck", Aliases: []string{"s", "check"},
+		Args: []Arg{{Name: "character_id"}}, Handler: b.cmdSCheck,
+	})
+	r.Register(&Command{Name: "smode", Handler: withMessage(b.cmdSMode)})
+	r.Register(&Command{
+		Name: "fav",
+		Args: []Arg{{Name: "character_id"}}, Handler: b.cmdFav,
+	})
+	r.Register(&Command{
+		Name: "language", Aliases: []string{"lang"},
+		Args: []Arg{{Name: "code", Optional: true}}, Handler: b.cmdLanguage,
+	})
+	r.Register(&Command{
+		Name: "smallcaps",
+		Args: []Arg{{Name: "on|off", Optional: true}}, Handler: b.cmdSmallCaps,
+	})
+	r.Register(&Command{
+		Name: "spawnmode", Scope: GroupOnly,
+		Args: []Arg{{Name: "mode", Optional: true}, {Name: "count|interval", Optional: true}}, Handler: b.cmdSpawnMode,
+	})
+	r.Register(&Command{
+		Name: "spawngap", Scope: GroupOnly,
+		Args: []Arg{{Name: "min|default"}, {Name: "max", Optional: true}}, Handler: b.cmdSpawnGap,
+	})
+	r.Register(&Command{
+		Name: "spawnexpiry", Scope: GroupOnly,
+		Args: []Arg{{Name: "duration|off|default"}}, Handler: b.cmdSpawnExpiry,
+	})
+	r.Register(&Command{
+		Name: "quiethours", Scope: GroupOnly,
+		Args: []Arg{{Name: "HH:MM-HH:MM|off"}, {Name: "timezone", Optional: true}}, Handler: b.cmdQuietHours,
+	})
+	r.Register(&Command{Name: "rates", Cooldown: 3 * time.Second, Handler: b.cmdRates})
+	
+	// Group admin commands
+	r.Register(&Command{
+		Name: "set_on", Role: RoleGroupAdmin,
+		Args: []Arg{{Name: "rarity", Kind: ArgInt}}, Handler: b.cmdSetOn,
+	})
+	r.Register(&Command{
+		Name: "set_off", Role: RoleGroupAdmin,
+		Args: []Arg{{Name: "rarity", Kind: ArgInt}}, Handler: b.cmdSetOff,
+	})
+	r.Register(&Command{
+		Name: "set_rate", Role: RoleGroupAdmin,
+		Args: []Arg{{Name: "rarity", Kind: ArgInt}, {Name: "weight|default"}}, Handler: b.cmdSetRate,
+	})
+	
+	// Uploader commands
+	r.Register(&Command{
+		Name: "upload", Role: RoleUploader,
+		Usage: "<name> <anime> <rarity>", Handler: b.cmdUpload,
+	})
+	r.Register(&Command{
+		Name: "delete", Role: RoleUploader,
+		Args: []Arg{{Name: "character_id"}}, Handler: b.cmdDelete,
+	})
+	r.Register(&Command{
+		Name: "update", Role: RoleUploader,
+		Args: []Arg{{Name: "character_id"}, {Name: "field"}, {Name: "value", Kind: ArgRest}}, Handler: b.cmdUpdate,
+	})
+	r.Register(&Command{Name: "reseed", Role: RoleUploader, Handler: b.cmdReseed})
+	r.Register(&Command{
+		Name: "addalias", Role: RoleUploader,
+		Args: []Arg{{Name: "character_id"}, {Name: "alias", Kind: ArgRest}}, Handler: b.cmdAddAlias,
+	})
+	r.Register(&Command{
+		Name: "rmalias", Role: RoleUploader,
+		Args: []Arg{{Name: "character_id"}, {Name: "alias", Kind: ArgRest}}, Handler: b.cmdRmAlias,
+	})
+	
+	// Economy commands
+	r.Register(&Command{
+		Name: "addbal", Role: RoleEconomy,
+		Args: []Arg{{Name: "user_id", Kind: ArgInt}, {Name: "amount", Kind: ArgInt}}, Handler: b.cmdAddBal,
+	})
+	r.Register(&Command{
+		Name: "ledger", Role: RoleEconomy,
+		Args: []Arg{{Name: "user_id", Kind: ArgInt}}, Handler: b.cmdLedger,
+	})
+	r.Register(&Command{
+		Name: "gen", Role: RoleEconomy,
+		Args: []Arg{{Name: "amount", Kind: ArgInt}, {Name: "max_uses", Kind: ArgInt, Optional: true}}, Handler: b.cmdGen,
+	})
+	r.Register(&Command{
+		Name: "sgen", Role: RoleEconomy,
+		Args: []Arg{{Name: "character_id"}, {Name: "max_uses", Kind: ArgInt, Optional: true}}, Handler: b.cmdSGen,
+	})
+	r.Register(&Command{
+		Name: "resetshop", Role: RoleEconomy,
+		Args: []Arg{{Name: "user_id", Kind: ArgInt}}, Handler: b.cmdResetShop,
+	})
+	
+	// Sudo commands
+	r.Register(&Command{Name: "ping", Role: RoleSudo, Handler: withMessage(b.cmdPing)})
+	r.Register(&Command{Name: "stats", Role: RoleSudo, Handler: withMessage(b.cmdStats)})
+	r.Register(&Command{
+		Name: "lock", Role: RoleSudo,
+		Args: []Arg{{Name: "character_id"}, {Name: "reason", Kind: ArgRest, Optional: true}}, Handler: b.cmdLock,
+	})
+	r.Register(&Command{
+		Name: "unlock", Role: RoleSudo,
+		Args: []Arg{{Name: "character_id"}}, Handler: b.cmdUnlock,
+	})
+	r.Register(&Command{Name: "locklist", Role: RoleSudo, Handler: withMessage(b.cmdLockList)})
+	r.Register(&Command{
+		Name: "audit", Role: RoleSudo,
+		Args: []Arg{{Name: "actor|user|char", Optional: true}, {Name: "id", Optional: true}}, Handler: b.cmdAudit,
+	})
+	
+	// Owner commands
+	r.Register(&Command{
+		Name: "addsudo", Role: RoleOwner,
+		Args: []Arg{{Name: "user_id", Kind: ArgInt}, {Name: "role", Optional: true}}, Handler: b.cmdAddSudo,
+	})
+	r.Register(&Command{
+		Name: "rmsudo", Role: RoleOwner,
+		Args: []Arg{{Name: "user_id", Kind: ArgInt}, {Name: "role", Optional: true}}, Handler: b.cmdRmSudo,
+	})
+	r.Register(&Command{Name: "roles", Role: RoleOwner, Handler: b.cmdRoles})
+	
+	return r
+}
+
+// withMessage adapts a handler that only needs the message
+func withMessage(fn func(msg *tgbotapi.Message)) HandlerFunc {
+	return func(c *CommandContext) { fn(c.Msg) }
+}
+
+// cmdHelp handles /help command
+func (b *Bot) cmdHelp(c *CommandContext) {
+	b.showHelp(c.Msg.Chat.ID, c.Msg.From)
 }
 
 // cmdStart handles /start command
 func (b *Bot) cmdStart(msg *tgbotapi.Message) {
 	user := msg.From
+	p := b.msgPrinter(msg)
+	
+	log := b.msgLogger(msg)
 	
 	// Add to PM users
-	_ = b.GroupService.AddPMUser(user.ID, user.UserName, user.FirstName)
+	logError(log, b.GroupService.AddPMUser(user.ID, user.UserName, user.FirstName), "add PM user")
 	
 	// Get random video URL
 	var videoURL string
-	if len(b.Config.VideoURLs) > 0 {
-		videoURL = b.Config.VideoURLs[rand.Intn(len(b.Config.VideoURLs))]
+	if videoURLs := b.Config.Live().VideoURLs; len(videoURLs) > 0 {
+		videoURL = videoURLs[rand.Intn(len(videoURLs))]
 	}
 	
-	caption := "✨ ᴡᴇʟᴄᴏᴍᴇ ᴛᴏ Sᴇɴᴘᴀɪ Wᴀɪғᴜ Bᴏᴛ ✨\n\nɪ'ᴍ ᴀɴ Sᴇɴᴘᴀɪ ᴄʜᴀʀᴀᴄᴛᴇʀ ᴄᴀᴛᴄʜᴇʀ ʙᴏᴛ ᴅᴇsɪɢɴᴇᴅ ғᴏʀ ᴜʟᴛɪᴍᴀᴛᴇ ᴄᴏʟʟᴇᴄᴛᴏʀs! 🎴"
+	caption := p.Text("start.caption", nil)
 	
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL("✦ ᴀᴅᴅ ᴍᴇ ʙᴀʙʏ", fmt.Sprintf("http://t.me/%s?startgroup=new", b.Config.BotUsername)),
+			tgbotapi.NewInlineKeyboardButtonURL(p.Plain("start.add_me", nil), fmt.Sprintf("http://t.me/%s?startgroup=new", b.Config.BotUsername)),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL("✧ sᴜᴘᴘᴏʀᴛ", fmt.Sprintf("https://t.me/%s", b.Config.SupportChat)),
-			tgbotapi.NewInlineKeyboardButtonURL("✧ ᴜᴘᴅᴀᴛᴇs", fmt.Sprintf("https://t.me/%s", b.Config.UpdateChat)),
+			tgbotapi.NewInlineKeyboardButtonURL(p.Plain("start.support", nil), fmt.Sprintf("https://t.me/%s", b.Config.SupportChat)),
+			tgbotapi.NewInlineKeyboardButtonURL(p.Plain("start.updates", nil), fmt.Sprintf("https://t.me/%s", b.Config.UpdateChat)),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("✦ ɢᴜɪᴅᴀɴᴄᴇ", "help"),
+			tgbotapi.NewInlineKeyboardButtonData(p.Plain("start.guidance", nil), "help"),
 		),
 	)
 	
@@ -115,181 +245,153 @@ func (b *Bot) cmdStart(msg *tgbotapi.Message) {
 		video.Caption = caption
 		video.ReplyMarkup = keyboard
 		video.ParseMode = "HTML"
-		b.API.Send(video)
+		b.send(video)
 	} else {
 		reply := tgbotapi.NewMessage(msg.Chat.ID, caption)
 		reply.ReplyMarkup = keyboard
 		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+		b.send(reply)
 	}
 	
 	// Send notification to group if new user
 	if msg.Chat.Type == "private" {
-		count, _ := b.GroupService.GetPMUsersCount()
-		notifText := fmt.Sprintf(
-			"#ʙᴏᴛsᴛᴀʀᴛ\n\nʙᴏᴛ sᴛᴀʀᴛᴇᴅ\n\nɴᴀᴍᴇ : <a href='tg://user?id=%d'>%s</a>\nɪᴅ : <code>%d</code>\nᴜsᴇʀɴᴀᴍᴇ : %s\n\nᴛᴏᴛᴀʟ ᴜsᴇʀs : %d",
-			user.ID, user.FirstName, user.ID, func() string {
-				if user.UserName != "" {
-					return "@" + user.UserName
-				}
-				return "ɴᴏ ᴜsᴇʀɴᴀᴍᴇ"
-			}(), count,
-		)
-		notif := tgbotapi.NewMessage(b.Config.GroupID, notifText)
-		notif.ParseMode = "HTML"
-		b.API.Send(notif)
+		count, err := b.GroupService.GetPMUsersCount()
+		logError(log, err, "count PM users")
+		b.reply(b.printer(b.Config.GroupID, nil), b.Config.GroupID, "start.notify", i18n.Args{
+			"UserID":   user.ID,
+			"Name":     user.FirstName,
+			"Username": user.UserName,
+			"Total":    count,
+		})
 	}
 }
 
 // cmdPing handles /ping command
 func (b *Bot) cmdPing(msg *tgbotapi.Message) {
-	// Check if user is sudo
-	if !b.Config.IsSudo(msg.From.ID) {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "⚠️ ᴛʜɪs ᴄᴏᴍᴍᴀɴᴅ ɪs ʀᴇsᴛʀɪᴄᴛᴇᴅ ᴛᴏ sᴜᴅᴏ ᴜsᴇʀs ᴏɴʟʏ.")
-		b.API.Send(reply)
-		return
-	}
-	
+	p := b.msgPrinter(msg)
 	start := time.Now()
-	sentMsg, _ := b.API.Send(tgbotapi.NewMessage(msg.Chat.ID, "🏓 ᴘᴏɴɢ!"))
+	sentMsg, _ := b.reply(p, msg.Chat.ID, "ping.pong", nil)
 	latency := time.Since(start).Milliseconds()
 	
-	status := "ғᴀɪʀ"
-	if latency < 100 {
-		status = "ᴇxᴄᴇʟʟᴇɴᴛ"
-	} else if latency < 300 {
-		status = "ɢᴏᴏᴅ"
-	}
-	
-	edit := tgbotapi.NewEditMessageText(
-		msg.Chat.ID,
-		sentMsg.MessageID,
-		fmt.Sprintf("🏓 **ᴘᴏɴɢ!**\n📊 ʟᴀᴛᴇɴᴄʏ: `%dᴍs`\n⚡ sᴛᴀᴛᴜs: %s", latency, status),
-	)
-	edit.ParseMode = "Markdown"
-	b.API.Send(edit)
+	edit := tgbotapi.NewEditMessageText(msg.Chat.ID, sentMsg.MessageID, p.Text("ping.result", i18n.Args{"Latency": latency}))
+	edit.ParseMode = "HTML"
+	b.send(edit)
 }
 
 // cmdGuess handles /guess command
-func (b *Bot) cmdGuess(msg *tgbotapi.Message) {
+func (b *Bot) cmdGuess(c *CommandContext) {
+	msg := c.Msg
 	chatID := msg.Chat.ID
 	userID := msg.From.ID
+	p := b.msgPrinter(msg)
+	
+	// Serialize with spawns and other guesses in the same chat
+	unlock := b.ChatLocks.Lock(chatID)
+	defer unlock()
 	
 	// Check if there's a character to guess
-	lastChar, exists := b.LastCharacters[chatID]
-	if !exists {
+	spawn, err := b.StateService.GetActiveSpawn(chatID)
+	if err != nil {
+		logError(c.Log, ignoreNotFound(err), "get active spawn")
 		return
 	}
+	lastChar := spawn.Character
 	
 	// Check if already guessed
-	if _, guessed := b.FirstCorrectGuesses[chatID]; guessed {
-		reply := tgbotapi.NewMessage(chatID, utils.ToSmallCaps("❌ Already guessed by someone. Try next time."))
-		b.API.Send(reply)
+	if spawn.GuessedBy != 0 {
+		b.reply(p, chatID, "guess.already_guessed", nil)
 		return
 	}
 	
-	// Get guess text
-	args := strings.Fields(msg.Text)
-	if len(args) < 2 {
-		reply := tgbotapi.NewMessage(chatID, "Please provide a guess, e.g. /guess Alice")
-		b.API.Send(reply)
+	// Check if it fled, or is about to
+	if spawn.FledAt != nil || (spawn.FleesAt != nil && !time.Now().Before(*spawn.FleesAt)) {
+		b.reply(p, chatID, "guess.fled", nil)
 		return
 	}
 	
-	guessText := strings.ToLower(strings.Join(args[1:], " "))
+	// Get guess text
+	guessText := c.Rest(0)
 	
 	// Check for invalid characters
 	if strings.Contains(guessText, "()") || strings.Contains(guessText, "&") {
-		reply := tgbotapi.NewMessage(chatID, utils.ToSmallCaps("You can't use these characters in your guess."))
-		b.API.Send(reply)
+		b.reply(p, chatID, "guess.invalid", nil)
 		return
 	}
 	
-	// Check guess
-	nameParts := strings.Fields(strings.ToLower(lastChar.Name))
-	guessParts := strings.Fields(guessText)
-	
-	correct := false
-	if strings.EqualFold(strings.Join(nameParts, " "), strings.Join(guessParts, " ")) {
-		correct = true
-	} else {
-		for _, part := range nameParts {
-			if part == guessText {
-				correct = true
-				break
-			}
+	// Check guess against the name and every alias
+	if b.Guesses.Match(guessText, b.guessNames(lastChar, c.Log)...) {
+		// Mark as guessed; only the first correct guess wins
+		claimed, err := b.StateService.ClaimActiveSpawn(chatID, userID)
+		if err != nil {
+			logError(c.Log, err, "claim spawn")
+			return
 		}
-	}
-	
-	if correct {
-		// Mark as guessed
-		b.FirstCorrectGuesses[chatID] = userID
+		if !claimed {
+			b.reply(p, chatID, "guess.already_guessed", nil)
+			return
+		}
+		metrics.GuessesTotal.Inc()
 		
 		// Update user info
-		user, _ := b.UserService.GetOrCreateUser(userID, msg.From.UserName, msg.From.FirstName)
-		_ = user
+		_, err = b.UserService.GetOrCreateUser(userID, msg.From.UserName, msg.From.FirstName)
+		logError(c.Log, err, "get or create user")
 		
 		// Add balance
-		_, _ = b.UserService.UpdateUserBalance(userID, 100)
+		guessSource := services.LedgerSource{Source: services.SourceGuess, Reference: lastChar.ID}
+		reward := b.HintService.Reward(b.Config.Economy.GuessReward, spawn.Hints)
+		_, err = b.UserService.UpdateUserBalance(userID, reward, guessSource)
+		logError(c.Log, err, "add guess reward", zap.String("character_id", lastChar.ID))
 		
 		// Add character to user
-		userChar := models.UserCharacter{
-			ID:     lastChar.CharacterID,
-			Name:   lastChar.Name,
-			Anime:  lastChar.Anime,
-			Rarity: lastChar.Rarity,
-			ImgURL: lastChar.ImgURL,
-		}
-		_ = b.UserService.AddCharacterToUser(userID, userChar)
-		
+		logError(c.Log, b.UserService.AddCharacterToUser(userID, lastChar, guessSource),
+			"add guessed character", zap.String("character_id", lastChar.ID))
+			
 		// Update group stats
-		_ = b.GroupService.UpdateGroupUserTotal(userID, chatID, msg.From.UserName, msg.From.FirstName)
-		_ = b.GroupService.UpdateTopGlobalGroup(chatID, msg.Chat.Title)
+		logError(c.Log, b.GroupService.UpdateGroupUserTotal(userID, chatID, msg.From.UserName, msg.From.FirstName), "update group user total")
+		logError(c.Log, b.GroupService.UpdateTopGlobalGroup(chatID, msg.Chat.Title), "update top global group")
 		
 		// Update daily stats
-		_ = b.DailyService.UpdateDailyUserGuess(userID, msg.From.UserName, msg.From.FirstName)
+		logError(c.Log, b.DailyService.UpdateDailyUserGuess(userID, msg.From.UserName, msg.From.FirstName), "update daily user guess")
 		if msg.Chat.Type == "group" || msg.Chat.Type == "supergroup" {
-			_ = b.DailyService.UpdateDailyGroupGuess(chatID, msg.Chat.Title)
+			logError(c.Log, b.DailyService.UpdateDailyGroupGuess(chatID, msg.Chat.Title), "update daily group guess")
 		}
 		
 		// Send congratulations
-		coinMsg := tgbotapi.NewMessage(chatID, utils.ToSmallCaps("✨ ᴄᴏɴɢʀᴀᴛᴜʟᴀᴛɪᴏɴꜱ 🎉  ʏᴏᴜ ɢᴜᴇꜱꜱᴇᴅ ɪᴛ ʀɪɢʜᴛ! ᴀꜱ ᴀ ʀᴇᴡᴀʀᴅ, 100 ᴄᴏɪɴꜱ ʜᴀᴠᴇ ʙᴇᴇɴ ᴀᴅᴅᴇᴅ ᴛᴏ ʏᴏᴜʀ ʙᴀʟᴀɴᴄᴇ.."))
-		coinMsg.ParseMode = "HTML"
-		sentCoinMsg, _ := b.API.Send(coinMsg)
+		b.reply(p, chatID, "guess.reward", i18n.Args{"Reward": reward, "Hints": spawn.Hints})
 		
-		// Set reaction if possible (requires additional API call)
-		_ = sentCoinMsg
-		
-		// Send character details
-		rarityDisplay := utils.GetRarityDisplay(lastChar.Rarity)
-		detailsText := fmt.Sprintf(
-			"✨ ᴄᴏɴɢʀᴀᴛᴜʟᴀᴛɪᴏɴꜱ 🎊 %s ᴛʜɪꜱ ᴄʜᴀʀᴀᴄᴛᴇʀ ʜᴀꜱ ʙᴇᴇɴ ᴀᴅᴅᴇᴅ ᴛᴏ ʏᴏᴜʀ.\n\n"+
-				"👤 ɴᴀᴍᴇ: %s\n"+
-				"🎬 ᴀɴɪᴍᴇ: %s\n"+
-				"✨ ʀᴀʀɪᴛʏ: %s\n"+
-				"🆔 ɪᴅ: %s\n\n"+
-				"✅ ꜱᴜᴄᴄᴇꜱꜱ ꜰᴜʟʟ ᴀᴅᴅ ʜᴀʀᴇᴍ.",
-			msg.From.FirstName,
-			lastChar.Name,
-			lastChar.Anime,
-			rarityDisplay,
-			lastChar.CharacterID,
-		)
-		
-		detailsMsg := tgbotapi.NewMessage(chatID, utils.ToSmallCaps(detailsText))
-		detailsMsg.ParseMode = "HTML"
-		detailsMsg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
-			tgbotapi.NewInlineKeyboardRow(
-				tgbotapi.NewInlineKeyboardButtonSwitch("ꜱᴇᴇ ʜᴀʀᴇᴍ", fmt.Sprintf("collection.%d", userID)),
+		// Send character details with the original image, revealing the spawn
+		details := p.Text("guess.caught", i18n.Args{
+			"Name":      msg.From.FirstName,
+			"Character": lastChar.Name,
+			"Anime":     lastChar.Anime,
+			"Rarity":    lastChar.Rarity,
+			"ID":        lastChar.ID,
+		})
+		b.sendCharacter(tgbotapi.BaseChat{
+			ChatID: chatID,
+			ReplyMarkup: tgbotapi.NewInlineKeyboardMarkup(
+				tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonSwitch(p.Plain("guess.see_harem", nil), fmt.Sprintf("collection.%d", userID)),
+				),
 			),
-		)
-		b.API.Send(detailsMsg)
+		}, lastChar.ImgURL, details)
 	} else {
-		reply := tgbotapi.NewMessage(chatID, utils.ToSmallCaps("Please write the correct character name. ❌"))
-		b.API.Send(reply)
+		b.reply(p, chatID, "guess.wrong", nil)
 	}
 }
 
+// guessNames returns the names /guess accepts for char: its name and the aliases in the
+// catalog, which may have been added after it spawned
+func (b *Bot) guessNames(char models.UserCharacter, log *zap.Logger) []string {
+	names := []string{char.Name}
+	catalogChar, err := b.CharacterService.GetCharacterByID(char.ID)
+	if err != nil {
+		logError(log, ignoreNotFound(err), "get character aliases", zap.String("character_id", char.ID))
+		return names
+	}
+	return append(names, catalogChar.Aliases...)
+}
+
 // cmdBalance handles /balance command
 func (b *Bot) cmdBalance(msg *tgbotapi.Message) {
 	targetID := msg.From.ID
@@ -309,88 +411,65 @@ func (b *Bot) cmdBalance(msg *tgbotapi.Message) {
 				// For now, just show own balance
 			} else if id, err := strconv.ParseInt(arg, 10, 64); err == nil {
 				targetID = id
-				targetName = fmt.Sprintf("User %d", id)
+				targetName = ""
 			}
 		}
 	}
 	
-	balance, _ := b.UserService.GetUserBalance(targetID)
+	balance, err := b.UserService.GetUserBalance(targetID)
+	logError(b.msgLogger(msg), err, "get balance", zap.Int64("target_id", targetID))
 	
-	replyText := fmt.Sprintf("💰 <b>%s</b>'s %s: <b>%s</b> ᴄᴏɪɴs",
-		targetName,
-		utils.ToSmallCaps("Balance"),
-		utils.FormatNumber(balance),
-	)
-	
-	reply := tgbotapi.NewMessage(msg.Chat.ID, replyText)
-	reply.ParseMode = "HTML"
-	b.API.Send(reply)
+	b.reply(b.msgPrinter(msg), msg.Chat.ID, "balance.show", i18n.Args{
+		"UserID":  targetID,
+		"Name":    targetName,
+		"Balance": balance,
+	})
 }
 
 // cmdFav handles /fav command
-func (b *Bot) cmdFav(msg *tgbotapi.Message) {
-	args := strings.Fields(msg.Text)
-	if len(args) < 2 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("Please provide a character id: /fav <id>"))
-		b.API.Send(reply)
-		return
-	}
-	
-	charID := args[1]
+func (b *Bot) cmdFav(c *CommandContext) {
+	msg := c.Msg
+	charID := c.Arg(0)
 	userID := msg.From.ID
+	p := b.msgPrinter(msg)
 	
 	// Check if user has this character
-	hasChar, _ := b.UserService.HasCharacter(userID, charID)
+	hasChar, err := b.UserService.HasCharacter(userID, charID)
+	logError(c.Log, err, "check character ownership", zap.String("character_id", charID))
 	if !hasChar {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("That character is not in your collection."))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "fav.not_owned", nil)
 		return
 	}
 	
 	// Add to favorites
-	_ = b.UserService.AddToFavorites(userID, charID)
+	if err := b.UserService.AddToFavorites(userID, charID); err != nil {
+		logError(c.Log, err, "add favorite", zap.String("character_id", charID))
+		b.reply(p, msg.Chat.ID, "fav.failed", nil)
+		return
+	}
 	
-	reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps(fmt.Sprintf("Character has been added to your favorites.")))
-	b.API.Send(reply)
+	b.reply(p, msg.Chat.ID, "fav.added", nil)
 }
 
 // cmdAddBal handles /addbal command (admin only)
-func (b *Bot) cmdAddBal(msg *tgbotapi.Message) {
-	if !b.Config.IsSudo(msg.From.ID) {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("✘ ɴᴏᴛ ᴀᴜᴛʜᴏʀɪᴢᴇᴅ."))
-		b.API.Send(reply)
-		return
-	}
-	
-	args := strings.Fields(msg.Text)
-	if len(args) < 3 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("Usage: /addbal <user_id> <amount>"))
-		b.API.Send(reply)
-		return
-	}
-	
-	targetID, err := strconv.ParseInt(args[1], 10, 64)
+func (b *Bot) cmdAddBal(c *CommandContext) {
+	msg := c.Msg
+	targetID := c.Int(0)
+	amount := c.Int(1)
+	p := b.msgPrinter(msg)
+	
+	newBalance, err := b.UserService.UpdateUserBalance(targetID, amount,
+		services.LedgerSource{Source: services.SourceAddBalance, CounterpartyID: msg.From.ID})
 	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("✘ ɪɴᴠᴀʟɪᴅ ᴜsᴇʀ ɪᴅ."))
-		b.API.Send(reply)
+		logError(c.Log, err, "add balance", zap.Int64("target_id", targetID))
+		b.reply(p, msg.Chat.ID, "addbal.failed", nil)
 		return
 	}
+	b.audit(c, models.AuditEntry{
+		TargetUserID: targetID,
+		Before:       map[string]interface{}{"balance": newBalance - amount},
+		After:        map[string]interface{}{"balance": newBalance},
+	})
 	
-	amount, err := strconv.ParseInt(args[2], 10, 64)
-	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("✘ ɪɴᴠᴀʟɪᴅ ᴀᴍᴏᴜɴᴛ."))
-		b.API.Send(reply)
-		return
-	}
-	
-	newBalance, _ := b.UserService.UpdateUserBalance(targetID, amount)
-	
-	replyText := fmt.Sprintf("✓ ᴜᴘᴅᴀᴛᴇᴅ ʙᴀʟᴀɴᴄᴇ ғᴏʀ <a href='tg://user?id=%d'>ᴜsᴇʀ</a>: <b>%s</b>",
-		targetID,
-		utils.FormatNumber(newBalance),
-	)
-	
-	reply := tgbotapi.NewMessage(msg.Chat.ID, replyText)
-	reply.ParseMode = "HTML"
-	b.API.Send(reply)
+	b.reply(p, msg.Chat.ID, "addbal.done", i18n.Args{"UserID": targetID, "Balance": newBalance})
 }
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/telegramtest"
+	"senpai-waifu-bot/internal/utils"
+)
+
+// spawnBy sends the five messages in a row from user that spawn a character in chat and
+// returns the announcement
+func spawnBy(t *testing.T, bot *Bot, srv *telegramtest.Server, chat *tgbotapi.Chat, user *tgbotapi.User) telegramtest.Call {
+	t.Helper()
+	srv.Reset()
+	for i := 0; i < 5; i++ {
+		bot.HandleUpdate(srv.Message(chat, user, "hello"))
+	}
+	sent := srv.WaitSent(1, time.Second)
+	if len(sent) != 1 {
+		t.Fatalf("%d messages sent for a spawn, want 1", len(sent))
+	}
+	srv.Reset()
+	return sent[0]
+}
+
+// expectTexts fails the test unless calls carry exactly the texts in want, in order
+func expectTexts(t *testing.T, what string, calls []telegramtest.Call, want ...string) {
+	t.Helper()
+	got := texts(calls)
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d messages %q, want %d", what, len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s: message %d =\n%s\nwant\n%s", what, i, got[i], want[i])
+		}
+	}
+}
+
+func TestConversationSpawnGuessHaremTrade(t *testing.T) {
+	bot, srv, st := newTestBot(t, nil)
+	ctx := context.Background()
+	shinobu := models.Character{ID: "001", Name: "Shinobu Kochou", Anime: "Demon Slayer", Rarity: 3, ImgURL: "https://example.com/shinobu.jpg"}
+	hutao := models.Character{ID: "002", Name: "Hu Tao", Anime: "Genshin Impact", Rarity: 2}
+	insertCharacters(t, st, shinobu)
+
+	group := srv.Group(-100, "Waifu Club")
+	alice, bob := srv.User(1, "alice"), srv.User(2, "bob")
+	p := bot.printer(group.ID, nil)
+
+	// Shinobu is the only character, so she spawns with her image and without her name
+	announce := spawnBy(t, bot, srv, group, alice)
+	if announce.Method != "sendPhoto" || announce.Params.Get("photo") != shinobu.ImgURL {
+		t.Errorf("spawn sent with %s %q, want sendPhoto %q", announce.Method, announce.Params.Get("photo"), shinobu.ImgURL)
+	}
+	if strings.Contains(announce.Text(), "Shinobu") || !strings.Contains(announce.Text(), p.Text("rarity.3", nil)) {
+		t.Errorf("spawn announcement gives away the name or misses the rarity:\n%s", announce.Text())
+	}
+
+	// Telegram cannot fetch the image, so the catch is revealed as text
+	srv.Fail("sendPhoto", 400, "Bad Request: wrong file identifier/HTTP URL specified")
+	bot.HandleUpdate(srv.Message(group, alice, "/guess shinobu"))
+	caught := p.Text("guess.caught", i18n.Args{
+		"Name":      "alice",
+		"Character": shinobu.Name,
+		"Anime":     shinobu.Anime,
+		"Rarity":    shinobu.Rarity,
+		"ID":        shinobu.ID,
+	})
+	expectTexts(t, "guess", srv.Sent(),
+		p.Text("guess.reward", i18n.Args{"Reward": bot.Config.Economy.GuessReward, "Hints": 0}),
+		caught,
+		caught,
+	)
+	if methods := []string{srv.Sent()[1].Method, srv.Sent()[2].Method}; methods[0] != "sendPhoto" || methods[1] != "sendMessage" {
+		t.Errorf("catch sent with %v, want a failed sendPhoto then sendMessage", methods)
+	}
+
+	srv.Reset()
+	bot.HandleUpdate(srv.Message(group, alice, "/harem"))
+	harem := p.Text("harem.header", i18n.Args{"Name": "alice", "Page": 1, "Pages": 1}) + "\n\n" +
+		p.Text("harem.anime", i18n.Args{"Anime": shinobu.Anime, "Owned": 1, "Total": int64(1)}) + "\n" +
+		haremRule + "\n" +
+		p.Text("harem.character", i18n.Args{"ID": shinobu.ID, "Emoji": utils.RarityEmojis[shinobu.Rarity], "Name": shinobu.Name, "Count": 1}) + "\n" +
+		haremRule + "\n\n"
+	expectTexts(t, "harem", srv.Sent(), harem)
+
+	// Shinobu spawned recently, so Hu Tao spawns next
+	insertCharacters(t, st, hutao)
+	announce = spawnBy(t, bot, srv, group, bob)
+	if announce.Method != "sendMessage" {
+		t.Errorf("spawn without an image sent with %s, want sendMessage", announce.Method)
+	}
+	bot.HandleUpdate(srv.Message(group, bob, "/guess hu tao"))
+	if owned, _ := st.Users.HasCharacter(ctx, bob.ID, hutao.ID); !owned {
+		t.Fatal("bob did not catch Hu Tao")
+	}
+
+	// Alice replies to bob to offer Shinobu for Hu Tao, and bob accepts
+	said := srv.Message(group, bob, "hi")
+	bot.HandleUpdate(said)
+	srv.Reset()
+	bot.HandleUpdate(srv.Reply(group, alice, said.Message, "/trade 001 002"))
+	expectTexts(t, "trade", srv.Sent(), p.Text("trade.request", i18n.Args{
+		"Sender":         "alice",
+		"SenderChar":     shinobu.Name,
+		"SenderRarity":   shinobu.Rarity,
+		"SenderAnime":    shinobu.Anime,
+		"ReceiverChar":   hutao.Name,
+		"ReceiverRarity": hutao.Rarity,
+		"ReceiverAnime":  hutao.Anime,
+		"ReceiverID":     bob.ID,
+		"Receiver":       "bob",
+	}))
+	offer := srv.LastSent()
+	data, ok := offer.Button(p.Plain("trade.accept", nil))
+	if !ok {
+		t.Fatalf("trade offer has no accept button: %v", offer.Buttons())
+	}
+
+	// Only the receiver can accept
+	srv.Reset()
+	bot.HandleUpdate(srv.Callback(group, alice, offer.MessageID, data))
+	if len(srv.Answers()) != 1 || len(srv.Edits()) != 0 {
+		t.Errorf("sender tapping accept: %d answers and %d edits, want 1 and 0", len(srv.Answers()), len(srv.Edits()))
+	}
+
+	srv.Reset()
+	bot.HandleUpdate(srv.Callback(group, bob, offer.MessageID, data))
+	if len(srv.Answers()) != 1 {
+		t.Errorf("%d callback answers, want 1", len(srv.Answers()))
+	}
+	expectTexts(t, "accept", srv.Edits(), p.Text("trade.done", i18n.Args{
+		"Sender":         "alice",
+		"ReceiverChar":   hutao.Name,
+		"ReceiverRarity": hutao.Rarity,
+		"ReceiverAnime":  hutao.Anime,
+		"Receiver":       "bob",
+		"SenderChar":     shinobu.Name,
+		"SenderRarity":   shinobu.Rarity,
+		"SenderAnime":    shinobu.Anime,
+	}))
+	if edit := srv.Edits()[0]; edit.MessageID != offer.MessageID {
+		t.Errorf("edited message %d, want the offer %d", edit.MessageID, offer.MessageID)
+	}
+
+	for _, owns := range []struct {
+		user   *tgbotapi.User
+		charID string
+		want   bool
+	}{
+		{alice, hutao.ID, true},
+		{alice, shinobu.ID, false},
+		{bob, shinobu.ID, true},
+		{bob, hutao.ID, false},
+	} {
+		if got, _ := st.Users.HasCharacter(ctx, owns.user.ID, owns.charID); got != owns.want {
+			t.Errorf("%s owns %s = %v, want %v", owns.user.UserName, owns.charID, got, owns.want)
+		}
+	}
+
+	// A second tap finds the trade gone
+	srv.Reset()
+	bot.HandleUpdate(srv.Callback(group, bob, offer.MessageID, data))
+	expectTexts(t, "accept again", srv.Edits(), p.Text("trade.expired", nil))
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/models"
+)
+
+func TestGuessConcurrentSingleWinner(t *testing.T) {
+	bot, srv, st := newTestBot(t, nil)
+	char := models.Character{ID: "001", Name: "Shinobu Kochou", Anime: "Demon Slayer", Rarity: 3}
+	insertCharacters(t, st, char)
+
+	group := srv.Group(-100, "Waifu Club")
+	err := bot.StateService.SetActiveSpawn(group.ID, models.UserCharacter{
+		ID:     char.ID,
+		Name:   char.Name,
+		Anime:  char.Anime,
+		Rarity: char.Rarity,
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every guess races for the chat lock and the claim on the spawn
+	const guessers = 20
+	var updates []tgbotapi.Update
+	for i := 1; i <= guessers; i++ {
+		user := srv.User(int64(100+i), fmt.Sprintf("user%d", i))
+		updates = append(updates, srv.Message(group, user, "/guess kochou shinobu"))
+	}
+	var wg sync.WaitGroup
+	for _, update := range updates {
+		wg.Add(1)
+		go func(update tgbotapi.Update) {
+			defer wg.Done()
+			bot.HandleUpdate(update)
+		}(update)
+	}
+	wg.Wait()
+
+	ctx := context.Background()
+	spawn, err := st.State.GetSpawn(ctx, group.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spawn.GuessedBy == 0 {
+		t.Fatal("nobody caught the spawn")
+	}
+	owners, err := st.Users.CountOwners(ctx, char.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owners != 1 {
+		t.Errorf("%d users own the character, want 1", owners)
+	}
+	winner, err := st.Users.GetUser(ctx, spawn.GuessedBy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if winner.Balance != bot.Config.Economy.GuessReward || len(winner.Characters) != 1 {
+		t.Errorf("winner has balance %d and %d characters, want %d and 1", winner.Balance, len(winner.Characters), bot.Config.Economy.GuessReward)
+	}
+
+	p := bot.printer(group.ID, nil)
+	reward := p.Text("guess.reward", i18n.Args{"Reward": bot.Config.Economy.GuessReward, "Hints": 0})
+	late := p.Text("guess.already_guessed", nil)
+	counts := map[string]int{}
+	for _, text := range texts(srv.Sent()) {
+		counts[text]++
+	}
+	if counts[reward] != 1 || counts[late] != guessers-1 {
+		t.Errorf("got %d rewards and %d late guesses, want 1 and %d", counts[reward], counts[late], guessers-1)
+	}
+}
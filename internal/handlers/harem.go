@@ -4,25 +4,29 @@ import (
 	"fmt"
 	"math"
 	"sort"
-	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/i18n"
 	"senpai-waifu-bot/internal/models"
 	"senpai-waifu-bot/internal/utils"
 )
 
+// haremRule separates the anime sections of a harem page
+const haremRule = "--------------------"
+
 // cmdHarem handles /harem command
 func (b *Bot) cmdHarem(msg *tgbotapi.Message, page int) {
 	userID := msg.From.ID
+	p := b.msgPrinter(msg)
 	
 	// Get user's sort preference
-	rarityFilter, _ := b.SortPrefService.GetUserSortPreference(userID)
+	rarityFilter := b.sortPreference(userID, b.msgLogger(msg))
 	
 	// Get user data
 	user, err := b.UserService.GetUserByID(userID)
 	if err != nil || len(user.Characters) == 0 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("You Have Not Guessed any Characters Yet.."))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "harem.empty", nil)
 		return
 	}
 	
@@ -40,13 +44,9 @@ func (b *Bot) cmdHarem(msg *tgbotapi.Message, page int) {
 	
 	if len(characters) == 0 {
 		if rarityFilter != nil {
-			filterName := utils.RarityNames[*rarityFilter]
-			reply := tgbotapi.NewMessage(msg.Chat.ID, 
-				utils.ToSmallCaps(fmt.Sprintf("You Have No Characters of %s!\n\nUse /smode to change filter.", filterName)))
-			b.API.Send(reply)
+			b.reply(p, msg.Chat.ID, "harem.empty_filter", i18n.Args{"Rarity": i18n.HTML(p.Text(rarityID(*rarityFilter), nil))})
 		} else {
-			reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("You Have Not Guessed any Characters Yet.."))
-			b.API.Send(reply)
+			b.reply(p, msg.Chat.ID, "harem.empty", nil)
 		}
 		return
 	}
@@ -69,7 +69,7 @@ func (b *Bot) cmdHarem(msg *tgbotapi.Message, page int) {
 		if uniqueChars[i].Anime != uniqueChars[j].Anime {
 			return uniqueChars[i].Anime < uniqueChars[j].Anime
 		}
-		return uniqueChars[i].ID < uniqueChars[j].ID
+		return utils.LessCharacterID(uniqueChars[i].ID, uniqueChars[j].ID)
 	})
 	
 	// Pagination
@@ -100,15 +100,18 @@ func (b *Bot) cmdHarem(msg *tgbotapi.Message, page int) {
 		animes = append(animes, anime)
 	}
 	
-	animeCounts, _ := b.CharacterService.GetAnimeCounts(animes)
+	animeCounts, err := b.CharacterService.GetAnimeCounts(animes)
+	logError(b.msgLogger(msg), err, "count anime characters")
 	
 	// Build message
-	headerText := fmt.Sprintf("%s's HAREM - PAGE %d/%d", msg.From.FirstName, page+1, totalPages)
-	haremMsg := fmt.Sprintf("<b>%s</b>\n", utils.ToSmallCaps(headerText))
+	haremMsg := p.Text("harem.header", i18n.Args{"Name": msg.From.FirstName, "Page": page + 1, "Pages": totalPages}) + "\n"
 	
 	if rarityFilter != nil {
-		filterName := utils.RarityNames[*rarityFilter]
-		haremMsg += fmt.Sprintf("<b>🔍 %s</b>\n", utils.ToSmallCaps(fmt.Sprintf("Filter: %s (%d/%d)", filterName, len(characters), len(user.Characters))))
+		haremMsg += p.Text("harem.filter", i18n.Args{
+			"Rarity": i18n.HTML(p.Text(rarityID(*rarityFilter), nil)),
+			"Count":  len(characters),
+			"Total":  len(user.Characters),
+		}) + "\n"
 	}
 	
 	haremMsg += "\n"
@@ -121,20 +124,18 @@ func (b *Bot) cmdHarem(msg *tgbotapi.Message, page int) {
 	
 	for anime, chars := range animeGroups {
 		totalAnimeChars := animeCounts[anime]
-		haremMsg += fmt.Sprintf("<b>𖤍 %s {%d/%d}</b>\n", utils.ToSmallCaps(anime), len(chars), totalAnimeChars)
-		haremMsg += fmt.Sprintf("%s\n", utils.ToSmallCaps("--------------------"))
+		haremMsg += p.Text("harem.anime", i18n.Args{"Anime": anime, "Owned": len(chars), "Total": totalAnimeChars}) + "\n"
+		haremMsg += haremRule + "\n"
 		
 		for _, char := range chars {
-			rarityEmoji := utils.RarityEmojis[char.Rarity]
-			count := charCounts[char.ID]
-			haremMsg += fmt.Sprintf("✶ %s [ %s ] %s %s\n",
-				char.ID,
-				rarityEmoji,
-				utils.ToSmallCaps(char.Name),
-				utils.ToSmallCaps(fmt.Sprintf("x%d", count)),
-			)
+			haremMsg += p.Text("harem.character", i18n.Args{
+				"ID":    char.ID,
+				"Emoji": utils.RarityEmojis[char.Rarity],
+				"Name":  char.Name,
+				"Count": charCounts[char.ID],
+			}) + "\n"
 		}
-		haremMsg += fmt.Sprintf("%s\n\n", utils.ToSmallCaps("--------------------"))
+		haremMsg += haremRule + "\n\n"
 	}
 	
 	// Build keyboard
@@ -143,7 +144,7 @@ func (b *Bot) cmdHarem(msg *tgbotapi.Message, page int) {
 	// Collection button
 	keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonSwitch(
-			utils.ToSmallCaps(fmt.Sprintf("🔮 See Collection (%d)", len(characters))),
+			p.Plain("harem.collection", i18n.Args{"Count": len(characters)}),
 			fmt.Sprintf("collection.%d", userID),
 		),
 	))
@@ -151,7 +152,7 @@ func (b *Bot) cmdHarem(msg *tgbotapi.Message, page int) {
 	// Cancel/Smode button
 	keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData(
-			"❌ "+utils.ToSmallCaps("ᴄᴀɴᴄᴇʟ"),
+			p.Plain("smode.cancel", nil),
 			fmt.Sprintf("open_smode:%d", userID),
 		),
 	))
@@ -195,76 +196,32 @@ func (b *Bot) cmdHarem(msg *tgbotapi.Message, page int) {
 		photo.Caption = haremMsg
 		photo.ParseMode = "HTML"
 		photo.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboardRows...)
-		b.API.Send(photo)
+		b.send(photo)
 	} else {
 		reply := tgbotapi.NewMessage(msg.Chat.ID, haremMsg)
 		reply.ParseMode = "HTML"
 		reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboardRows...)
-		b.API.Send(reply)
+		b.send(reply)
 	}
 }
 
+// sortPreference returns the rarity userID sorts their harem by, or nil for every rarity,
+// which is also the fallback when the preference cannot be read
+func (b *Bot) sortPreference(userID int64, log *zap.Logger) *int {
+	rarityFilter, err := b.SortPrefService.GetUserSortPreference(userID)
+	if err != nil {
+		logError(log, err, "get sort preference")
+		return nil
+	}
+	return rarityFilter
+}
+
 // cmdSMode handles /smode command
 func (b *Bot) cmdSMode(msg *tgbotapi.Message) {
-	userID := msg.From.ID
-	
-	// Get current preference
-	rarityFilter, _ := b.SortPrefService.GetUserSortPreference(userID)
-	
-	var currentText string
-	if rarityFilter == nil {
-		currentText = "🍃 " + utils.ToSmallCaps("default")
-	} else {
-		currentText = utils.RarityNames[*rarityFilter]
-	}
-	
-	caption := fmt.Sprintf(
-		"<b>✨ %s</b>\n\n🎯 %s <b>%s</b>\n",
-		utils.ToSmallCaps("SMODE"),
-		utils.ToSmallCaps("Current Model:"),
-		currentText,
-	)
-	
-	// Build keyboard
-	var keyboardRows [][]tgbotapi.InlineKeyboardButton
-	
-	// All rarities button
-	allText := utils.ToSmallCaps("🍃 default")
-	if rarityFilter == nil {
-		allText += " ✓"
-	}
-	keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(
-		tgbotapi.NewInlineKeyboardButtonData(allText, "smode_all"),
-	))
-	
-	// Rarity buttons (3 per row)
-	var currentRow []tgbotapi.InlineKeyboardButton
-	for i := 1; i <= 15; i++ {
-		btnText := utils.RarityNames[i]
-		if rarityFilter != nil && *rarityFilter == i {
-			btnText += " ✓"
-		}
-		currentRow = append(currentRow, tgbotapi.NewInlineKeyboardButtonData(
-			btnText,
-			fmt.Sprintf("smode_%d", i),
-		))
-		
-		if len(currentRow) == 3 {
-			keyboardRows = append(keyboardRows, currentRow)
-			currentRow = nil
-		}
-	}
-	if len(currentRow) > 0 {
-		keyboardRows = append(keyboardRows, currentRow)
-	}
-	
-	// Cancel button
-	keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(
-		tgbotapi.NewInlineKeyboardButtonData("❌ "+utils.ToSmallCaps("Cancel"), "smode_cancel"),
-	))
+	caption, markup := b.smodeMenu(b.msgPrinter(msg), msg.From.ID, b.msgLogger(msg))
 	
 	reply := tgbotapi.NewMessage(msg.Chat.ID, caption)
 	reply.ParseMode = "HTML"
-	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboardRows...)
-	b.API.Send(reply)
+	reply.ReplyMarkup = markup
+	b.send(reply)
 }
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/metrics"
+	"senpai-waifu-bot/internal/services"
+)
+
+// cmdHint handles /hint: it sells the next hint about the active spawn of the chat. Each hint
+// costs more than the one before and lowers the reward of the guess that catches the spawn.
+func (b *Bot) cmdHint(c *CommandContext) {
+	msg := c.Msg
+	chatID := msg.Chat.ID
+	userID := msg.From.ID
+	p := b.msgPrinter(msg)
+
+	perSpawn := b.HintService.PerSpawn()
+	if perSpawn == 0 {
+		b.reply(p, chatID, "hint.disabled", nil)
+		return
+	}
+
+	// Serialize with guesses, spawns and other hints in the same chat
+	unlock := b.ChatLocks.Lock(chatID)
+	defer unlock()
+
+	spawn, err := b.StateService.GetActiveSpawn(chatID)
+	if err != nil {
+		logError(c.Log, ignoreNotFound(err), "get active spawn")
+		b.reply(p, chatID, "hint.none", nil)
+		return
+	}
+	if spawn.GuessedBy != 0 || spawn.FledAt != nil || (spawn.FleesAt != nil && !time.Now().Before(*spawn.FleesAt)) {
+		b.reply(p, chatID, "hint.none", nil)
+		return
+	}
+
+	hint, err := b.HintService.Buy(spawn, userID)
+	switch {
+	case errors.Is(err, services.ErrNoHintsLeft):
+		b.reply(p, chatID, "hint.used_up", i18n.Args{"Tiers": perSpawn})
+		return
+	case errors.Is(err, services.ErrSpawnGone):
+		b.reply(p, chatID, "hint.none", nil)
+		return
+	case errors.Is(err, services.ErrInsufficientFunds):
+		cost, _ := b.HintService.NextCost(spawn)
+		balance, err := b.UserService.GetUserBalance(userID)
+		logError(c.Log, ignoreNotFound(err), "get balance")
+		b.reply(p, chatID, "hint.insufficient", i18n.Args{"Cost": cost, "Balance": balance})
+		return
+	case err != nil:
+		logError(c.Log, err, "buy hint", zap.String("character_id", spawn.Character.ID))
+		b.reply(p, chatID, "hint.failed", nil)
+		return
+	}
+	metrics.HintsTotal.WithLabelValues(strconv.Itoa(hint.Tier)).Inc()
+
+	b.reply(p, chatID, "hint.revealed", i18n.Args{
+		"Tier":    hint.Tier,
+		"Tiers":   perSpawn,
+		"Value":   hint.Value,
+		"Cost":    hint.Cost,
+		"Balance": hint.Balance,
+		"Reward":  b.HintService.Reward(b.Config.Economy.GuessReward, hint.Tier),
+	})
+}
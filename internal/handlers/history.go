@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/utils"
+)
+
+// historyPageSize is the number of ledger entries shown per /history page
+const historyPageSize = 10
+
+// cmdHistory handles /history command
+func (b *Bot) cmdHistory(msg *tgbotapi.Message) {
+	text, keyboard := b.buildHistoryPage(b.msgPrinter(msg), msg.From.ID, 0)
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ParseMode = "HTML"
+	if keyboard != nil {
+		reply.ReplyMarkup = *keyboard
+	}
+	b.send(reply)
+}
+
+// showHistoryPage edits a /history message to show another page
+func (b *Bot) showHistoryPage(chatID int64, messageID int, userID int64, page int) {
+	text, keyboard := b.buildHistoryPage(b.userPrinter(chatID, userID), userID, page)
+
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ParseMode = "HTML"
+	edit.ReplyMarkup = keyboard
+	b.send(edit)
+}
+
+// buildHistoryPage renders one page of a user's ledger and its navigation keyboard
+func (b *Bot) buildHistoryPage(p *i18n.Printer, userID int64, page int) (string, *tgbotapi.InlineKeyboardMarkup) {
+	if page < 0 {
+		page = 0
+	}
+
+	entries, total, err := b.LedgerService.GetHistory(userID, page, historyPageSize)
+	if err != nil {
+		logError(b.Log, err, "get history", zap.Int64("user_id", userID))
+		return p.Text("history.failed", nil), nil
+	}
+	if total == 0 {
+		return p.Text("history.empty", nil), nil
+	}
+
+	totalPages := int((total + historyPageSize - 1) / historyPageSize)
+	if page >= totalPages {
+		page = totalPages - 1
+		if entries, _, err = b.LedgerService.GetHistory(userID, page, historyPageSize); err != nil {
+			logError(b.Log, err, "get history", zap.Int64("user_id", userID))
+			return p.Text("history.failed", nil), nil
+		}
+	}
+
+	text := p.Text("history.header", i18n.Args{"Page": page + 1, "Pages": totalPages}) + "\n\n"
+	for _, entry := range entries {
+		text += formatLedgerEntry(p, entry) + "\n"
+	}
+
+	if totalPages == 1 {
+		return text, nil
+	}
+
+	var navButtons []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData(
+			"⬅️",
+			fmt.Sprintf("history:%d:%d", page-1, userID),
+		))
+	}
+	if page < totalPages-1 {
+		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData(
+			"➡️",
+			fmt.Sprintf("history:%d:%d", page+1, userID),
+		))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(navButtons)
+	return text, &keyboard
+}
+
+// formatLedgerEntry renders a single ledger entry as one line of HTML
+func formatLedgerEntry(p *i18n.Printer, entry models.LedgerEntry) string {
+	amount := entry.Amount
+	sign := "➕"
+	if amount < 0 {
+		sign = "➖"
+		amount = -amount
+	}
+	
+	// Sources the catalog does not name are shown as stored
+	source := entry.Source
+	if id := "history.source." + source; p.Has(id) {
+		source = p.Plain(id, nil)
+	}
+	return p.Text("history.entry", i18n.Args{
+		"Sign":         sign,
+		"Character":    entry.Type == models.LedgerCharacter,
+		"Name":         entry.CharacterName,
+		"ID":           entry.CharacterID,
+		"Amount":       amount,
+		"Source":       source,
+		"When":         entry.CreatedAt.In(utils.GetISTNow().Location()).Format("02 Jan 15:04"),
+		"Counterparty": entry.CounterpartyID,
+	})
+}
+
+// cmdLedger handles /ledger command (admin only): reconstructs a user's balance from the ledger
+func (b *Bot) cmdLedger(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+	targetID := c.Int(0)
+	summary, err := b.LedgerService.Reconstruct(targetID)
+	if err != nil {
+		b.reply(p, msg.Chat.ID, "ledger.failed", nil)
+		return
+	}
+
+	var storedBalance int64
+	var storedCharacters int
+	if user, err := b.UserService.GetUserByID(targetID); err == nil {
+		storedBalance = user.Balance
+		storedCharacters = len(user.Characters)
+	}
+
+	// Balances that predate the ledger show up as drift
+	b.reply(p, msg.Chat.ID, "ledger.summary", i18n.Args{
+		"UserID":           targetID,
+		"Entries":          summary.Entries,
+		"LedgerBalance":    summary.Coins,
+		"StoredBalance":    storedBalance,
+		"Drift":            storedBalance - summary.Coins,
+		"LedgerCharacters": summary.Characters,
+		"StoredCharacters": storedCharacters,
+		"CheckedAt":        time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
@@ -1,11 +1,10 @@
 package handlers
 
 import (
-	"fmt"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"senpai-waifu-bot/internal/utils"
+	"senpai-waifu-bot/internal/i18n"
 )
 
 // cmdLeaderboard handles /leaderboard command
@@ -29,42 +28,29 @@ func (b *Bot) cmdLeaderboard(msg *tgbotapi.Message) {
 		b.showBalanceLeaderboard(msg)
 	default:
 		// Show usage
-		usage := fmt.Sprintf(
-			"<b>📊 %s</b>\n\n"+
-				"🌐 <code>/leaderboard global</code> - %s\n"+
-				"📅 <code>/leaderboard daily</code> - %s\n"+
-				"👥 <code>/leaderboard group</code> - %s\n"+
-				"💰 <code>/leaderboard balance</code> - %s",
-			utils.ToSmallCaps("LEADERBOARD COMMANDS"),
-			utils.ToSmallCaps("Global character rankings"),
-			utils.ToSmallCaps("Daily character rankings"),
-			utils.ToSmallCaps("Group character rankings"),
-			utils.ToSmallCaps("Balance rankings"),
-		)
-		reply := tgbotapi.NewMessage(msg.Chat.ID, usage)
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+		b.reply(b.msgPrinter(msg), msg.Chat.ID, "leaderboard.usage", nil)
 	}
 }
 
 // showGlobalLeaderboard shows global leaderboard
 func (b *Bot) showGlobalLeaderboard(msg *tgbotapi.Message) {
+	p := b.msgPrinter(msg)
+	
 	// Get top users by character count
 	users, err := b.UserService.GetTopUsersByCharacters(10)
 	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("⚠️ Could not retrieve leaderboard data."))
-		b.API.Send(reply)
+		logError(b.msgLogger(msg), err, "get character leaderboard")
+		b.reply(p, msg.Chat.ID, "leaderboard.failed", nil)
 		return
 	}
 	
 	if len(users) == 0 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("📊 No data available yet!"))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "leaderboard.empty", nil)
 		return
 	}
 	
 	// Build message
-	message := fmt.Sprintf("<b>🏆 %s</b>\n\n", utils.ToSmallCaps("GLOBAL LEADERBOARD"))
+	message := p.Text("leaderboard.global", nil) + "\n\n"
 	
 	for i, user := range users {
 		charCount := len(user.Characters)
@@ -72,148 +58,117 @@ func (b *Bot) showGlobalLeaderboard(msg *tgbotapi.Message) {
 			continue
 		}
 		
-		name := user.FirstName
-		if user.Username != "" {
-			name = fmt.Sprintf("@%s", user.Username)
-		}
-		
-		emoji := "🥉"
-		if i == 0 {
-			emoji = "🥇"
-		} else if i == 1 {
-			emoji = "🥈"
-		}
-		
-		message += fmt.Sprintf("%s <b>%d.</b> %s - <b>%d</b> %s\n", 
-			emoji, i+1, name, charCount, utils.ToSmallCaps("characters"))
+		message += p.Text("leaderboard.characters", leaderboardArgs(i, user.FirstName, user.Username, charCount)) + "\n"
 	}
 	
 	reply := tgbotapi.NewMessage(msg.Chat.ID, message)
 	reply.ParseMode = "HTML"
-	b.API.Send(reply)
+	b.send(reply)
 }
 
 // showDailyLeaderboard shows daily leaderboard
 func (b *Bot) showDailyLeaderboard(msg *tgbotapi.Message) {
+	p := b.msgPrinter(msg)
+	
 	// Get top users by daily guesses
 	guesses, err := b.DailyService.GetTopDailyUsers(10)
 	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("⚠️ Could not retrieve daily leaderboard data."))
-		b.API.Send(reply)
+		logError(b.msgLogger(msg), err, "get daily leaderboard")
+		b.reply(p, msg.Chat.ID, "leaderboard.failed", nil)
 		return
 	}
 	
 	if len(guesses) == 0 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("📊 No daily data available yet!"))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "leaderboard.empty", nil)
 		return
 	}
 	
 	// Build message
-	message := fmt.Sprintf("<b>📅 %s</b>\n\n", utils.ToSmallCaps("DAILY LEADERBOARD"))
+	message := p.Text("leaderboard.daily", nil) + "\n\n"
 	
 	for i, guess := range guesses {
-		name := guess.FirstName
-		if guess.Username != "" {
-			name = fmt.Sprintf("@%s", guess.Username)
-		}
-		
-		emoji := "🥉"
-		if i == 0 {
-			emoji = "🥇"
-		} else if i == 1 {
-			emoji = "🥈"
-		}
-		
-		message += fmt.Sprintf("%s <b>%d.</b> %s - <b>%d</b> %s\n", 
-			emoji, i+1, name, guess.Count, utils.ToSmallCaps("guesses"))
+		message += p.Text("leaderboard.guesses", leaderboardArgs(i, guess.FirstName, guess.Username, guess.Count)) + "\n"
 	}
 	
 	reply := tgbotapi.NewMessage(msg.Chat.ID, message)
 	reply.ParseMode = "HTML"
-	b.API.Send(reply)
+	b.send(reply)
 }
 
 // showGroupLeaderboard shows group leaderboard
 func (b *Bot) showGroupLeaderboard(msg *tgbotapi.Message) {
+	p := b.msgPrinter(msg)
 	if msg.Chat.Type == "private" {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			utils.ToSmallCaps("⚠️ This command can only be used in groups!"))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "scope.group", nil)
 		return
 	}
 	
 	// Get top groups
 	groups, err := b.GroupService.GetTopGroups(10)
 	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("⚠️ Could not retrieve group leaderboard data."))
-		b.API.Send(reply)
+		logError(b.msgLogger(msg), err, "get group leaderboard")
+		b.reply(p, msg.Chat.ID, "leaderboard.failed", nil)
 		return
 	}
 	
 	if len(groups) == 0 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("📊 No group data available yet!"))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "leaderboard.empty", nil)
 		return
 	}
 	
 	// Build message
-	message := fmt.Sprintf("<b>👥 %s</b>\n\n", utils.ToSmallCaps("GROUP LEADERBOARD"))
+	message := p.Text("leaderboard.group", nil) + "\n\n"
 	
 	for i, group := range groups {
-		emoji := "🥉"
-		if i == 0 {
-			emoji = "🥇"
-		} else if i == 1 {
-			emoji = "🥈"
-		}
-		
-		message += fmt.Sprintf("%s <b>%d.</b> %s - <b>%d</b> %s\n", 
-			emoji, i+1, group.GroupName, group.Count, utils.ToSmallCaps("guesses"))
+		message += p.Text("leaderboard.guesses", leaderboardArgs(i, group.GroupName, "", group.Count)) + "\n"
 	}
 	
 	reply := tgbotapi.NewMessage(msg.Chat.ID, message)
 	reply.ParseMode = "HTML"
-	b.API.Send(reply)
+	b.send(reply)
 }
 
 // showBalanceLeaderboard shows balance leaderboard
 func (b *Bot) showBalanceLeaderboard(msg *tgbotapi.Message) {
+	p := b.msgPrinter(msg)
+	
 	// Get top users by balance
 	users, err := b.UserService.GetTopUsersByBalance(10)
 	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("⚠️ Could not retrieve balance leaderboard data."))
-		b.API.Send(reply)
+		logError(b.msgLogger(msg), err, "get balance leaderboard")
+		b.reply(p, msg.Chat.ID, "leaderboard.failed", nil)
 		return
 	}
 	
 	if len(users) == 0 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("📊 No balance data available yet!"))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "leaderboard.empty", nil)
 		return
 	}
 	
 	// Build message
-	message := fmt.Sprintf("<b>💰 %s</b>\n\n", utils.ToSmallCaps("BALANCE LEADERBOARD"))
+	message := p.Text("leaderboard.balance", nil) + "\n\n"
 	
 	for i, user := range users {
-		name := user.FirstName
-		if user.Username != "" {
-			name = fmt.Sprintf("@%s", user.Username)
-		}
-		
-		emoji := "🥉"
-		if i == 0 {
-			emoji = "🥇"
-		} else if i == 1 {
-			emoji = "🥈"
-		}
-		
-		message += fmt.Sprintf("%s <b>%d.</b> %s - <b>%s</b> %s\n", 
-			emoji, i+1, name, utils.FormatNumber(user.Balance), utils.ToSmallCaps("coins"))
+		message += p.Text("leaderboard.coins", leaderboardArgs(i, user.FirstName, user.Username, user.Balance)) + "\n"
 	}
 	
 	reply := tgbotapi.NewMessage(msg.Chat.ID, message)
 	reply.ParseMode = "HTML"
-	b.API.Send(reply)
+	b.send(reply)
+}
+
+// leaderboardArgs returns the arguments of the leaderboard line at index i, naming the
+// entry by @username when it has one
+func leaderboardArgs(i int, name, username string, count interface{}) i18n.Args {
+	if username != "" {
+		name = "@" + username
+	}
+	
+	medal := "🥉"
+	if i == 0 {
+		medal = "🥇"
+	} else if i == 1 {
+		medal = "🥈"
+	}
+	return i18n.Args{"Medal": medal, "Rank": i + 1, "Name": name, "Count": count}
 }
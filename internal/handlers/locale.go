@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"senpai-waifu-bot/internal/i18n"
+)
+
+// printer returns the printer for a reply in chatID to user, who may be nil. The chat's
+// own setting wins; otherwise the user's setting from their private chat, then the
+// language of their Telegram client, then the configured default.
+func (b *Bot) printer(chatID int64, user *tgbotapi.User) *i18n.Printer {
+	loc := i18n.Locale{Language: b.Config.Language, SmallCaps: b.Config.SmallCaps}
+
+	pref, err := b.GroupService.GetChatLocale(chatID)
+	logError(b.Log, err, "get chat locale")
+	if pref == nil && user != nil && user.ID != chatID {
+		pref, err = b.GroupService.GetChatLocale(user.ID)
+		logError(b.Log, err, "get user locale")
+	}
+
+	switch {
+	case pref != nil:
+		loc = i18n.Locale{Language: pref.Language, SmallCaps: pref.SmallCaps}
+	case user != nil:
+		if lang, ok := b.Catalog.Match(user.LanguageCode); ok {
+			loc.Language = lang
+		}
+	}
+	return b.Catalog.Printer(loc)
+}
+
+// msgPrinter returns the printer for a reply to msg
+func (b *Bot) msgPrinter(msg *tgbotapi.Message) *i18n.Printer {
+	return b.printer(msg.Chat.ID, msg.From)
+}
+
+// userPrinter returns the printer for a reply in chatID to userID, for callers that only
+// have the user's ID
+func (b *Bot) userPrinter(chatID, userID int64) *i18n.Printer {
+	return b.printer(chatID, &tgbotapi.User{ID: userID})
+}
+
+// reply sends message id to chatID as HTML
+func (b *Bot) reply(p *i18n.Printer, chatID int64, id string, args i18n.Args) (tgbotapi.Message, error) {
+	msg := tgbotapi.NewMessage(chatID, p.Text(id, args))
+	msg.ParseMode = "HTML"
+	return b.send(msg)
+}
+
+// editText replaces the text of messageID in chatID with message id as HTML
+func (b *Bot) editText(p *i18n.Printer, chatID int64, messageID int, id string, args i18n.Args) (tgbotapi.Message, error) {
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, p.Text(id, args))
+	edit.ParseMode = "HTML"
+	return b.send(edit)
+}
+
+// userName returns the first name of userID, or a placeholder when Telegram cannot tell
+func (b *Bot) userName(p *i18n.Printer, userID int64) string {
+	chat, err := b.API.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: userID}})
+	if err != nil {
+		return p.Plain("user.fallback", i18n.Args{"UserID": userID})
+	}
+	return chat.FirstName
+}
+
+// rarityID returns the message ID of the name of rarity
+func rarityID(rarity int) string {
+	return "rarity." + strconv.Itoa(rarity)
+}
+
+// cmdLanguage handles /language: without an argument it lists the languages, with one it
+// sets the language of the chat
+func (b *Bot) cmdLanguage(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+
+	if len(c.Args) == 0 {
+		var lines []string
+		for _, lang := range b.Catalog.Languages() {
+			lines = append(lines, "<code>"+lang+"</code> - "+b.Catalog.Name(lang))
+		}
+		b.reply(p, msg.Chat.ID, "language.list", i18n.Args{
+			"Current":   b.Catalog.Name(p.Locale().Language),
+			"Languages": i18n.HTML(strings.Join(lines, "\n")),
+		})
+		return
+	}
+
+	lang, ok := b.Catalog.Match(c.Arg(0))
+	if !ok {
+		b.reply(p, msg.Chat.ID, "language.unknown", i18n.Args{"Language": c.Arg(0)})
+		return
+	}
+	if !b.hasRole(msg.Chat.ID, msg.From.ID, RoleGroupAdmin) {
+		b.reply(p, msg.Chat.ID, "chat.admins_only", nil)
+		return
+	}
+
+	if err := b.GroupService.SetChatLocale(msg.Chat.ID, lang, p.Locale().SmallCaps, msg.From.ID); err != nil {
+		logError(c.Log, err, "set chat locale")
+		b.reply(p, msg.Chat.ID, "error.generic", nil)
+		return
+	}
+	p = b.Catalog.Printer(i18n.Locale{Language: lang, SmallCaps: p.Locale().SmallCaps})
+	b.reply(p, msg.Chat.ID, "language.set", i18n.Args{"Language": b.Catalog.Name(lang)})
+}
+
+// cmdSmallCaps handles /smallcaps: without an argument it shows the setting, with on or off
+// it changes it for the chat
+func (b *Bot) cmdSmallCaps(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+	loc := p.Locale()
+
+	var on bool
+	switch strings.ToLower(c.Arg(0)) {
+	case "":
+		b.reply(p, msg.Chat.ID, "smallcaps.status", i18n.Args{"On": loc.SmallCaps})
+		return
+	case "on":
+		on = true
+	case "off":
+		on = false
+	default:
+		b.reply(p, msg.Chat.ID, "smallcaps.usage", nil)
+		return
+	}
+	if !b.hasRole(msg.Chat.ID, msg.From.ID, RoleGroupAdmin) {
+		b.reply(p, msg.Chat.ID, "chat.admins_only", nil)
+		return
+	}
+
+	if err := b.GroupService.SetChatLocale(msg.Chat.ID, loc.Language, on, msg.From.ID); err != nil {
+		logError(c.Log, err, "set chat locale")
+		b.reply(p, msg.Chat.ID, "error.generic", nil)
+		return
+	}
+	p = b.Catalog.Printer(i18n.Locale{Language: loc.Language, SmallCaps: on})
+	b.reply(p, msg.Chat.ID, "smallcaps.set", i18n.Args{"On": on})
+}
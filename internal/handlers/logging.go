@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/metrics"
+	"senpai-waifu-bot/internal/store"
+)
+
+// updateLogger returns b.Log with the fields that identify an update
+func (b *Bot) updateLogger(update tgbotapi.Update) *zap.Logger {
+	fields := []zap.Field{zap.Int("update_id", update.UpdateID)}
+	if chat := update.FromChat(); chat != nil {
+		fields = append(fields, zap.Int64("chat_id", chat.ID))
+	}
+	if user := update.SentFrom(); user != nil {
+		fields = append(fields, zap.Int64("user_id", user.ID))
+	}
+	if update.Message != nil && update.Message.IsCommand() {
+		fields = append(fields, zap.String("command", strings.ToLower(update.Message.Command())))
+	}
+	return b.Log.With(fields...)
+}
+
+// send sends c through the Bot API, recording its latency and logging a failure
+func (b *Bot) send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	start := time.Now()
+	sent, err := b.API.Send(c)
+	b.observeAPICall(c, start, err)
+	return sent, err
+}
+
+// request makes a Bot API request whose result is not a message, recording its latency and
+// logging a failure
+func (b *Bot) request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	start := time.Now()
+	resp, err := b.API.Request(c)
+	b.observeAPICall(c, start, err)
+	return resp, err
+}
+
+// observeAPICall records a Bot API call in the metrics and logs it if it failed
+func (b *Bot) observeAPICall(c tgbotapi.Chattable, start time.Time, err error) {
+	method := apiMethod(c)
+	metrics.Since(metrics.TelegramRequestDuration.WithLabelValues(method), start)
+	if err == nil {
+		return
+	}
+
+	var apiErr *tgbotapi.Error
+	code := 0
+	if errors.As(err, &apiErr) {
+		code = apiErr.Code
+	}
+	metrics.RecordTelegramError(method, code)
+	b.logAPIError(c, err)
+}
+
+// apiMethod names a request by its config type, e.g. "MessageConfig"
+func apiMethod(c tgbotapi.Chattable) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", c), "tgbotapi.")
+}
+
+// logAPIError logs a failed Bot API call. Editing a message to the text it already has
+// fails harmlessly when a button is pressed twice, so that is only logged at debug level.
+func (b *Bot) logAPIError(c tgbotapi.Chattable, err error) {
+	fields := []zap.Field{zap.String("request", apiMethod(c)), zap.Error(err)}
+	if strings.Contains(err.Error(), "message is not modified") {
+		b.Log.Debug("telegram request failed", fields...)
+		return
+	}
+	b.Log.Warn("telegram request failed", fields...)
+}
+
+// msgLogger returns b.Log with the chat and sender of msg, for handlers outside the command router
+func (b *Bot) msgLogger(msg *tgbotapi.Message) *zap.Logger {
+	fields := []zap.Field{zap.Int64("chat_id", msg.Chat.ID)}
+	if msg.From != nil {
+		fields = append(fields, zap.Int64("user_id", msg.From.ID))
+	}
+	return b.Log.With(fields...)
+}
+
+// ignoreNotFound drops store.ErrNotFound, for lookups and removals where a missing document is expected
+func ignoreNotFound(err error) error {
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// logError logs err, if it is not nil, as a failure to do action
+func logError(log *zap.Logger, err error, action string, fields ...zap.Field) {
+	if err == nil {
+		return
+	}
+	log.Error("failed to "+action, append(fields, zap.Error(err))...)
+}
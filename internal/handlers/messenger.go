@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Messenger is the part of the Telegram Bot API the bot uses. *tgbotapi.BotAPI implements
+// it; tests point a real client at the fake server in package telegramtest.
+type Messenger interface {
+	// Send makes a request that returns a message
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	// Request makes a request whose result is not a message
+	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+	// MakeRequest calls a method the library has no config type for
+	MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error)
+
+	GetMe() (tgbotapi.User, error)
+	GetChat(config tgbotapi.ChatInfoConfig) (tgbotapi.Chat, error)
+	GetChatMember(config tgbotapi.GetChatMemberConfig) (tgbotapi.ChatMember, error)
+	GetFileDirectURL(fileID string) (string, error)
+
+	GetUpdatesChan(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel
+	StopReceivingUpdates()
+}
+
+var _ Messenger = (*tgbotapi.BotAPI)(nil)
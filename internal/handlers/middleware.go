@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/metrics"
+)
+
+// recoverMiddleware turns a panicking command into an error reply instead of a dead goroutine
+func (b *Bot) recoverMiddleware(next HandlerFunc) HandlerFunc {
+	return func(c *CommandContext) {
+		defer func() {
+			if r := recover(); r != nil {
+				c.outcome = metrics.OutcomePanic
+				c.Log.Error("recovered from panic in command", zap.Any("panic", r), zap.Stack("stack"))
+				b.reply(b.msgPrinter(c.Msg), c.Msg.Chat.ID, "error.generic", nil)
+			}
+		}()
+		next(c)
+	}
+}
+
+// loggingMiddleware logs every command with who ran it and how long it took
+func (b *Bot) loggingMiddleware(next HandlerFunc) HandlerFunc {
+	return func(c *CommandContext) {
+		start := time.Now()
+		next(c)
+		c.Log.Info("command handled", zap.String("invoked_as", c.Name), zap.Duration("took", time.Since(start)))
+	}
+}
+
+// metricsMiddleware counts command invocations by outcome and their duration
+func (b *Bot) metricsMiddleware(next HandlerFunc) HandlerFunc {
+	return func(c *CommandContext) {
+		start := time.Now()
+		next(c)
+
+		took := time.Since(start)
+		b.Router.observe(c.Command.Name, took)
+
+		outcome := c.outcome
+		if outcome == "" {
+			outcome = metrics.OutcomeOK
+		}
+		metrics.CommandsTotal.WithLabelValues(c.Command.Name, outcome).Inc()
+		metrics.CommandDuration.WithLabelValues(c.Command.Name).Observe(took.Seconds())
+	}
+}
+
+// authMiddleware enforces the command's role and chat scope
+func (b *Bot) authMiddleware(next HandlerFunc) HandlerFunc {
+	return func(c *CommandContext) {
+		if !b.hasRole(c.Msg.Chat.ID, c.Msg.From.ID, c.Command.Role) {
+			c.outcome = metrics.OutcomeDenied
+			b.reply(b.msgPrinter(c.Msg), c.Msg.Chat.ID, "auth.denied", nil)
+			return
+		}
+
+		switch c.Command.Scope {
+		case PrivateOnly:
+			if !c.Msg.Chat.IsPrivate() {
+				c.outcome = metrics.OutcomeDenied
+				b.reply(b.msgPrinter(c.Msg), c.Msg.Chat.ID, "scope.private", nil)
+				return
+			}
+		case GroupOnly:
+			if !c.Msg.Chat.IsGroup() && !c.Msg.Chat.IsSuperGroup() {
+				c.outcome = metrics.OutcomeDenied
+				b.reply(b.msgPrinter(c.Msg), c.Msg.Chat.ID, "scope.group", nil)
+				return
+			}
+		}
+		next(c)
+	}
+}
+
+// rateLimitMiddleware applies the command's per-user cooldown
+func (b *Bot) rateLimitMiddleware(next HandlerFunc) HandlerFunc {
+	return func(c *CommandContext) {
+		cooldowns := c.Command.cooldowns
+		if cooldowns == nil {
+			next(c)
+			return
+		}
+
+		if wait := cooldowns.Remaining(c.Msg.From.ID); wait > 0 {
+			c.outcome = metrics.OutcomeRateLimited
+			b.reply(b.msgPrinter(c.Msg), c.Msg.Chat.ID, "cooldown.wait", i18n.Args{
+				"Seconds": int(wait.Seconds()) + 1,
+				"Command": c.Command.Name,
+			})
+			return
+		}
+		cooldowns.Start(c.Msg.From.ID, c.Command.Cooldown)
+		next(c)
+	}
+}
+
+// argsMiddleware validates the arguments against the command's schema and replies with its usage
+func (b *Bot) argsMiddleware(next HandlerFunc) HandlerFunc {
+	return func(c *CommandContext) {
+		if c.Command.Args == nil {
+			next(c)
+			return
+		}
+
+		if problem := validateArgs(c.Command.Args, c.Args); problem != nil {
+			c.outcome = metrics.OutcomeBadArgs
+			p := b.msgPrinter(c.Msg)
+			b.reply(p, c.Msg.Chat.ID, "args.usage", i18n.Args{
+				"Problem":     i18n.HTML(p.Text(problem.ID, i18n.Args{"Arg": problem.Arg})),
+				"Usage":       c.Command.UsageLine(),
+				"Description": i18n.HTML(p.Text(c.Command.DescriptionID(), nil)),
+			})
+			return
+		}
+		next(c)
+	}
+}
@@ -3,25 +3,27 @@ package handlers
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
-	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"senpai-waifu-bot/internal/utils"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/services"
 )
 
 // cmdPay handles /pay command
 func (b *Bot) cmdPay(msg *tgbotapi.Message) {
+	log := b.msgLogger(msg)
+	p := b.msgPrinter(msg)
 	senderID := msg.From.ID
 	
 	// Check cooldown
-	if nextAllowed, ok := b.PaymentCooldowns[senderID]; ok && time.Now().Before(nextAllowed) {
-		remaining := int(time.Until(nextAllowed).Seconds())
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			utils.ToSmallCaps(fmt.Sprintf("⏱️ ʏᴏᴜ ᴍᴜsᴛ ᴡᴀɪᴛ %ds ʙᴇғᴏʀᴇ sᴛᴀʀᴛɪɴɢ ᴀɴᴏᴛʜᴇʀ ᴘᴀʏᴍᴇɴᴛ.", remaining)))
-		b.API.Send(reply)
+	if wait := b.PaymentCooldowns.Remaining(senderID); wait > 0 {
+		b.reply(p, msg.Chat.ID, "pay.cooldown", i18n.Args{"Seconds": int(wait.Seconds())})
 		return
 	}
 	
@@ -41,197 +43,161 @@ func (b *Bot) cmdPay(msg *tgbotapi.Message) {
 		if strings.HasPrefix(rawTarget, "@") {
 			// For now, we don't have username lookup implemented
 			// Would need to store username -> ID mapping
-			reply := tgbotapi.NewMessage(msg.Chat.ID, 
-				utils.ToSmallCaps("✘ ᴄᴏᴜʟᴅ ɴᴏᴛ ʀᴇsᴏʟᴠᴇ ᴛᴀʀɢᴇᴛ ᴜsᴇʀ. ᴜsᴇ ᴜsᴇʀ ɪᴅ ᴏʀ ʀᴇᴘʟʏ ᴛᴏ ᴛʜᴇɪʀ ᴍᴇssᴀɢᴇ."))
-			b.API.Send(reply)
+			b.reply(p, msg.Chat.ID, "pay.unresolved", nil)
 			return
 		}
 		targetID, _ = strconv.ParseInt(rawTarget, 10, 64)
 		amount, _ = strconv.ParseInt(args[2], 10, 64)
 	} else {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("Usage: /pay <amount> (reply to user) or /pay <user_id> <amount>"))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "pay.usage", nil)
 		return
 	}
 	
 	if targetID == 0 || amount <= 0 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("✘ ɪɴᴠᴀʟɪᴅ ᴀʀɢᴜᴍᴇɴᴛs."))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "pay.invalid", nil)
 		return
 	}
 	
 	if targetID == senderID {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("✓ ʏᴏᴜ ᴄᴀɴɴᴏᴛ ᴘᴀʏ ʏᴏᴜʀsᴇʟғ."))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "pay.self", nil)
 		return
 	}
 	
 	// Check sender balance
-	balance, _ := b.UserService.GetUserBalance(senderID)
+	balance, err := b.UserService.GetUserBalance(senderID)
+	logError(log, err, "get balance")
 	if balance < amount {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			utils.ToSmallCaps(fmt.Sprintf("✘ ʏᴏᴜ ᴅᴏɴ'ᴛ ʜᴀᴠᴇ ᴇɴᴏᴜɢʜ ᴄᴏɪɴs. ʏᴏᴜʀ ʙᴀʟᴀɴᴄᴇ: %s", utils.FormatNumber(balance))))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "pay.insufficient", i18n.Args{"Balance": balance})
 		return
 	}
 	
 	// Generate token
 	tokenBytes := make([]byte, 16)
-	rand.Read(tokenBytes)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		logError(log, err, "generate payment token")
+		b.reply(p, msg.Chat.ID, "pay.start_failed", nil)
+		return
+	}
 	token := hex.EncodeToString(tokenBytes)
 	
 	// Store pending payment
-	b.PendingPayments[token] = &PendingPaymentInfo{
-		Token:     token,
-		SenderID:  senderID,
-		TargetID:  targetID,
-		Amount:    amount,
-		CreatedAt: time.Now(),
-		ChatID:    msg.Chat.ID,
-	}
-	
-	// Get target info
-	targetChat, err := b.API.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: targetID}})
-	targetName := fmt.Sprintf("User %d", targetID)
-	if err == nil {
-		targetName = targetChat.FirstName
+	err = b.StateService.CreatePayment(&models.PendingPayment{
+		Token:    token,
+		SenderID: senderID,
+		TargetID: targetID,
+		Amount:   amount,
+		ChatID:   msg.Chat.ID,
+	})
+	if err != nil {
+		logError(log, err, "store payment", zap.String("token", token))
+		b.reply(p, msg.Chat.ID, "pay.start_failed", nil)
+		return
 	}
 	
 	// Send confirmation message
-	text := fmt.Sprintf(
-		"❗ <b>ᴘᴀʏᴍᴇɴᴛ ᴄᴏɴғɪʀᴍᴀᴛɪᴏɴ</b>\n\n"+
-			"sᴇɴᴅᴇʀ: <a href='tg://user?id=%d'>%s</a>\n"+
-			"ʀᴇᴄɪᴘɪᴇɴᴛ: <a href='tg://user?id=%d'>%s</a>\n"+
-			"ᴀᴍᴏᴜɴᴛ: <b>%s</b> ᴄᴏɪɴs\n\n"+
-			"ᴀʀᴇ ʏᴏᴜ sᴜʀᴇ ʏᴏᴜ ᴡᴀɴᴛ ᴛᴏ ᴘʀᴏᴄᴇᴇᴅ?",
-		senderID, msg.From.FirstName,
-		targetID, targetName,
-		utils.FormatNumber(amount),
-	)
+	text := p.Text("pay.confirm", i18n.Args{
+		"SenderID": senderID,
+		"Sender":   msg.From.FirstName,
+		"TargetID": targetID,
+		"Target":   b.userName(p, targetID),
+		"Amount":   amount,
+	})
 	
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("✓ ᴄᴏɴғɪʀᴍ", fmt.Sprintf("pay_confirm:%s", token)),
-			tgbotapi.NewInlineKeyboardButtonData("✘ ᴄᴀɴᴄᴇʟ", fmt.Sprintf("pay_cancel:%s", token)),
+			tgbotapi.NewInlineKeyboardButtonData(p.Plain("pay.confirm_button", nil), fmt.Sprintf("pay_confirm:%s", token)),
+			tgbotapi.NewInlineKeyboardButtonData(p.Plain("pay.cancel_button", nil), fmt.Sprintf("pay_cancel:%s", token)),
 		),
 	)
 	
 	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
 	reply.ParseMode = "HTML"
 	reply.ReplyMarkup = keyboard
-	sentMsg, _ := b.API.Send(reply)
+	sentMsg, _ := b.send(reply)
 	
 	// Store message ID for editing later
-	if payment, ok := b.PendingPayments[token]; ok {
-		payment.MessageID = sentMsg.MessageID
-	}
+	logError(log, b.StateService.SetPaymentMessage(token, sentMsg.MessageID), "store payment message", zap.String("token", token))
 }
 
 // confirmPayment confirms a payment
-func (b *Bot) confirmPayment(chatID int64, messageID int, token string, userID int64) {
-	payment, ok := b.PendingPayments[token]
-	if !ok {
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, 
-			utils.ToSmallCaps("✖️ ᴛʜɪs ᴘᴀʏᴍᴇɴᴛ ʀᴇǫᴜᴇsᴛ ʜᴀs ᴇxᴘɪʀᴇᴅ ᴏʀ ɪs ɪɴᴠᴀʟɪᴅ."))
-		b.API.Send(edit)
+func (b *Bot) confirmPayment(queryID string, chatID int64, messageID int, token string, userID int64) {
+	log := b.Log.With(zap.Int64("chat_id", chatID), zap.Int64("user_id", userID), zap.String("token", token))
+	p := b.printer(chatID, nil)
+	answer := tgbotapi.NewCallback(queryID, "")
+	defer func() { b.request(answer) }()
+	
+	payment, err := b.StateService.GetPayment(token)
+	if err != nil {
+		b.editText(p, chatID, messageID, "pay.invalid_request", nil)
 		return
 	}
 	
 	// Only sender can confirm
 	if userID != payment.SenderID {
-		b.API.Send(tgbotapi.NewCallback(tgbotapi.CallbackConfig{Text: "ᴏɴʟʏ ᴛʜᴇ ᴘᴀʏᴍᴇɴᴛ ɪɴɪᴛɪᴀᴛᴏʀ ᴄᴀɴ ᴄᴏɴғɪʀᴍ ᴏʀ ᴄᴀɴᴄᴇʟ ᴛʜɪs ᴘᴀʏᴍᴇɴᴛ.", ShowAlert: true}))
+		answer.Text = p.Plain("pay.not_sender", nil)
+		answer.ShowAlert = true
 		return
 	}
 	
-	// Check expiry
-	if time.Since(payment.CreatedAt) > 5*time.Minute {
-		delete(b.PendingPayments, token)
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, 
-			utils.ToSmallCaps("⏱️ ᴛʜɪs ᴘᴀʏᴍᴇɴᴛ ʀᴇǫᴜᴇsᴛ ʜᴀs ᴇxᴘɪʀᴇᴅ."))
-		b.API.Send(edit)
+	// Check cooldown again
+	if wait := b.PaymentCooldowns.Remaining(payment.SenderID); wait > 0 {
+		b.editText(p, chatID, messageID, "pay.cooldown", i18n.Args{"Seconds": int(wait.Seconds())})
+		_, err := b.StateService.TakePayment(token)
+		logError(log, ignoreNotFound(err), "discard payment")
 		return
 	}
 	
-	// Check cooldown again
-	if nextAllowed, ok := b.PaymentCooldowns[payment.SenderID]; ok && time.Now().Before(nextAllowed) {
-		remaining := int(time.Until(nextAllowed).Seconds())
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, 
-			utils.ToSmallCaps(fmt.Sprintf("⏱️ ʏᴏᴜ ᴍᴜsᴛ ᴡᴀɪᴛ %ds ʙᴇғᴏʀᴇ ᴍᴀᴋɪɴɢ ᴀɴᴏᴛʜᴇʀ ᴘᴀʏᴍᴇɴᴛ.", remaining)))
-		b.API.Send(edit)
-		delete(b.PendingPayments, token)
+	// Claim the payment before executing it so a repeated tap cannot run it twice
+	if _, err := b.StateService.TakePayment(token); err != nil {
+		b.editText(p, chatID, messageID, "pay.expired", nil)
 		return
 	}
 	
 	// Perform transfer
-	// First deduct from sender
-	senderBalance, err := b.UserService.UpdateUserBalance(payment.SenderID, -payment.Amount)
-	if err != nil || senderBalance < 0 {
-		// Rollback - add back to sender
-		_, _ = b.UserService.UpdateUserBalance(payment.SenderID, payment.Amount)
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, 
-			utils.ToSmallCaps("✘ ᴛʀᴀɴsᴀᴄᴛɪᴏɴ ғᴀɪʟᴇᴅ: ɪɴsᴜғғɪᴄɪᴇɴᴛ ғᴜɴᴅs ᴏʀ ɪɴᴛᴇʀɴᴀʟ ᴇʀʀᴏʀ."))
-		b.API.Send(edit)
-		delete(b.PendingPayments, token)
+	if _, err := b.TransferService.Pay(payment.SenderID, payment.TargetID, payment.Amount, token); err != nil {
+		if !errors.Is(err, services.ErrInsufficientFunds) {
+			logError(log, err, "transfer payment")
+		}
+		b.editText(p, chatID, messageID, "pay.failed", nil)
 		return
 	}
 	
-	// Add to receiver
-	_, _ = b.UserService.UpdateUserBalance(payment.TargetID, payment.Amount)
-	
 	// Set cooldown
-	b.PaymentCooldowns[payment.SenderID] = time.Now().Add(60 * time.Second)
-	
-	// Get names
-	senderChat, _ := b.API.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: payment.SenderID}})
-	targetChat, _ := b.API.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: payment.TargetID}})
-	
-	senderName := fmt.Sprintf("User %d", payment.SenderID)
-	targetName := fmt.Sprintf("User %d", payment.TargetID)
-	
-	if senderChat != nil {
-		senderName = senderChat.FirstName
-	}
-	if targetChat != nil {
-		targetName = targetChat.FirstName
-	}
+	b.PaymentCooldowns.Start(payment.SenderID, b.Config.Economy.PayCooldown)
 	
 	// Edit message to show success
-	text := fmt.Sprintf(
-		"✓ <b>ᴘᴀʏᴍᴇɴᴛ sᴜᴄᴄᴇssғᴜʟ</b>\n\n"+
-			"ꜱᴇɴᴅᴇʀ: <a href='tg://user?id=%d'>%s</a>\n"+
-			"ʀᴇᴄɪᴘɪᴇɴᴛ: <a href='tg://user?id=%d'>%s</a>\n"+
-			"ᴀᴍᴏᴜɴᴛ: <b>%s</b> ᴄᴏɪɴs\n\n"+
-			"ɴᴇxᴛ ᴘᴀʏᴍᴇɴᴛ ᴀʟʟᴏᴡᴇᴅ ᴀғᴛᴇʀ 60 ꜱᴇᴄᴏɴᴅꜱ.",
-		payment.SenderID, senderName,
-		payment.TargetID, targetName,
-		utils.FormatNumber(payment.Amount),
-	)
-	
-	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
-	edit.ParseMode = "HTML"
-	b.API.Send(edit)
-	
-	delete(b.PendingPayments, token)
+	b.editText(p, chatID, messageID, "pay.done", i18n.Args{
+		"SenderID": payment.SenderID,
+		"Sender":   b.userName(p, payment.SenderID),
+		"TargetID": payment.TargetID,
+		"Target":   b.userName(p, payment.TargetID),
+		"Amount":   payment.Amount,
+		"Cooldown": int(b.Config.Economy.PayCooldown.Seconds()),
+	})
 }
 
 // cancelPayment cancels a payment
-func (b *Bot) cancelPayment(chatID int64, messageID int, token string, userID int64) {
-	payment, ok := b.PendingPayments[token]
-	if !ok {
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, 
-			utils.ToSmallCaps("✖️ ᴛʜɪs ᴘᴀʏᴍᴇɴᴛ ʀᴇǫᴜᴇsᴛ ʜᴀs ᴇxᴘɪʀᴇᴅ ᴏʀ ɪs ɪɴᴠᴀʟɪᴅ."))
-		b.API.Send(edit)
+func (b *Bot) cancelPayment(queryID string, chatID int64, messageID int, token string, userID int64) {
+	log := b.Log.With(zap.Int64("chat_id", chatID), zap.Int64("user_id", userID), zap.String("token", token))
+	p := b.printer(chatID, nil)
+	answer := tgbotapi.NewCallback(queryID, "")
+	defer func() { b.request(answer) }()
+	
+	payment, err := b.StateService.GetPayment(token)
+	if err != nil {
+		b.editText(p, chatID, messageID, "pay.invalid_request", nil)
 		return
 	}
 	
 	// Only sender can cancel
 	if userID != payment.SenderID {
-		b.API.Send(tgbotapi.NewCallback(tgbotapi.CallbackConfig{Text: "ᴏɴʟʏ ᴛʜᴇ ᴘᴀʏᴍᴇɴᴛ ɪɴɪᴛɪᴀᴛᴏʀ ᴄᴀɴ ᴄᴏɴғɪʀᴍ ᴏʀ ᴄᴀɴᴄᴇʟ ᴛʜɪs ᴘᴀʏᴍᴇɴᴛ.", ShowAlert: true}))
+		answer.Text = p.Plain("pay.not_sender", nil)
+		answer.ShowAlert = true
 		return
 	}
 	
-	delete(b.PendingPayments, token)
+	_, err = b.StateService.TakePayment(token)
+	logError(log, ignoreNotFound(err), "cancel payment")
 	
-	edit := tgbotapi.NewEditMessageText(chatID, messageID, utils.ToSmallCaps("✘ ᴘᴀʏᴍᴇɴᴛ ᴄᴀɴᴄᴇʟʟᴇᴅ ʙʏ sᴇɴᴅᴇʀ."))
-	b.API.Send(edit)
+	b.editText(p, chatID, messageID, "pay.cancelled", nil)
 }
@@ -1,220 +1,226 @@
 package handlers
 
 import (
-	"fmt"
 	"strconv"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"senpai-waifu-bot/internal/utils"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/models"
 )
 
 // cmdSetOn handles /set_on command (enable rarity)
-func (b *Bot) cmdSetOn(msg *tgbotapi.Message) {
-	if !b.Config.IsSudo(msg.From.ID) {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("⚠️ You are not authorized!"))
-		b.API.Send(reply)
+func (b *Bot) cmdSetOn(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+	rarity := int(c.Int(0))
+	if rarity < 1 || rarity > 15 {
+		b.reply(p, msg.Chat.ID, "rarity.invalid", nil)
 		return
 	}
 	
-	args := strings.Fields(msg.Text)
-	if len(args) < 2 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			fmt.Sprintf("<b>✅ %s</b>\n\n%s <code>/set_on &lt;rarity_number&gt;</code>\n\n%s",
-				utils.ToSmallCaps("ENABLE RARITY"),
-				utils.ToSmallCaps("Usage:"),
-				utils.ToSmallCaps("Enable a rarity for spawning in this chat.")))
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
-		return
-	}
-	
-	rarity, err := strconv.Atoi(args[1])
-	if err != nil || rarity < 1 || rarity > 15 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			utils.ToSmallCaps("❌ Invalid rarity! Please use a number between 1 and 15."))
-		b.API.Send(reply)
+	// Enable rarity for this chat
+	if err := b.RarityService.EnableRarity(msg.Chat.ID, rarity); err != nil {
+		logError(c.Log, err, "enable rarity", zap.Int("rarity", rarity))
+		b.reply(p, msg.Chat.ID, "rarity.failed", nil)
 		return
 	}
 	
-	// Enable rarity for this chat
-	_ = b.RarityService.EnableRarity(msg.Chat.ID, rarity)
-	
-	rarityDisplay := utils.GetRarityDisplay(rarity)
-	reply := tgbotapi.NewMessage(msg.Chat.ID, 
-		fmt.Sprintf("✅ %s %s %s",
-			utils.ToSmallCaps("Rarity"),
-			rarityDisplay,
-			utils.ToSmallCaps("has been enabled for this chat!")))
-	b.API.Send(reply)
+	b.reply(p, msg.Chat.ID, "rarity.enabled", i18n.Args{"Rarity": rarity})
 }
 
 // cmdSetOff handles /set_off command (disable rarity)
-func (b *Bot) cmdSetOff(msg *tgbotapi.Message) {
-	if !b.Config.IsSudo(msg.From.ID) {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("⚠️ You are not authorized!"))
-		b.API.Send(reply)
+func (b *Bot) cmdSetOff(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+	rarity := int(c.Int(0))
+	if rarity < 1 || rarity > 15 {
+		b.reply(p, msg.Chat.ID, "rarity.invalid", nil)
 		return
 	}
 	
-	args := strings.Fields(msg.Text)
-	if len(args) < 2 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			fmt.Sprintf("<b>❌ %s</b>\n\n%s <code>/set_off &lt;rarity_number&gt;</code>\n\n%s",
-				utils.ToSmallCaps("DISABLE RARITY"),
-				utils.ToSmallCaps("Usage:"),
-				utils.ToSmallCaps("Disable a rarity from spawning in this chat.")))
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+	// Disable rarity for this chat
+	if err := b.RarityService.DisableRarity(msg.Chat.ID, rarity); err != nil {
+		logError(c.Log, err, "disable rarity", zap.Int("rarity", rarity))
+		b.reply(p, msg.Chat.ID, "rarity.failed", nil)
 		return
 	}
 	
-	rarity, err := strconv.Atoi(args[1])
-	if err != nil || rarity < 1 || rarity > 15 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			utils.ToSmallCaps("❌ Invalid rarity! Please use a number between 1 and 15."))
-		b.API.Send(reply)
+	b.reply(p, msg.Chat.ID, "rarity.disabled", i18n.Args{"Rarity": rarity})
+}
+
+// maxRarityWeight bounds the drop weight a chat can give a rarity
+const maxRarityWeight = 100000
+
+// cmdRates handles /rates command (show the chance of each rarity to spawn in this chat)
+func (b *Bot) cmdRates(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+	
+	// Locked characters never spawn, so they do not count towards their rarity
+	lockedIDs, err := b.RarityService.GetLockedCharacterIDs()
+	logError(c.Log, err, "get locked characters")
+	
+	table, err := b.DropService.Table(msg.Chat.ID, b.Config.Live().Spawn.RarityWeights, lockedIDs)
+	if err != nil {
+		logError(c.Log, err, "get drop table")
+		b.reply(p, msg.Chat.ID, "rates.failed", nil)
+		return
+	}
+	if table.Empty() {
+		b.reply(p, msg.Chat.ID, "rates.empty", nil)
 		return
 	}
 	
-	// Disable rarity for this chat
-	_ = b.RarityService.DisableRarity(msg.Chat.ID, rarity)
-	
-	rarityDisplay := utils.GetRarityDisplay(rarity)
-	reply := tgbotapi.NewMessage(msg.Chat.ID, 
-		fmt.Sprintf("❌ %s %s %s",
-			utils.ToSmallCaps("Rarity"),
-			rarityDisplay,
-			utils.ToSmallCaps("has been disabled for this chat!")))
-	b.API.Send(reply)
+	// Build message
+	message := p.Text("rates.header", i18n.Args{"Group": !msg.Chat.IsPrivate()}) + "\n"
+	custom := false
+	for _, odds := range table.Odds {
+		message += "\n" + p.Text("rates.entry", i18n.Args{
+			"Rarity":     odds.Rarity,
+			"Percent":    strconv.FormatFloat(odds.Percent, 'f', 2, 64),
+			"Characters": odds.Characters,
+			"Custom":     odds.Custom,
+		})
+		custom = custom || odds.Custom
+	}
+	if custom {
+		message += "\n\n" + p.Text("rates.custom", nil)
+	}
+	
+	reply := tgbotapi.NewMessage(msg.Chat.ID, message)
+	reply.ParseMode = "HTML"
+	b.send(reply)
 }
 
-// cmdLock handles /lock command (lock character from spawning)
-func (b *Bot) cmdLock(msg *tgbotapi.Message) {
-	if !b.Config.IsSudo(msg.From.ID) {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("⚠️ You are not authorized!"))
-		b.API.Send(reply)
+// cmdSetRate handles /set_rate command (override the drop weight of a rarity in this chat)
+func (b *Bot) cmdSetRate(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+	rarity := int(c.Int(0))
+	if rarity < 1 || rarity > 15 {
+		b.reply(p, msg.Chat.ID, "rarity.invalid", nil)
 		return
 	}
 	
-	args := strings.Fields(msg.Text)
-	if len(args) < 2 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			fmt.Sprintf("<b>🔒 %s</b>\n\n%s <code>/lock &lt;character_id&gt; [reason]</code>\n\n%s",
-				utils.ToSmallCaps("LOCK CHARACTER"),
-				utils.ToSmallCaps("Usage:"),
-				utils.ToSmallCaps("Lock a character from spawning.")))
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+	// Go back to the configured weight
+	if strings.ToLower(c.Arg(1)) == "default" {
+		if err := b.RarityService.ResetRarityWeight(msg.Chat.ID, rarity); err != nil {
+			logError(c.Log, err, "reset rarity weight", zap.Int("rarity", rarity))
+			b.reply(p, msg.Chat.ID, "rarity.failed", nil)
+			return
+		}
+		b.reply(p, msg.Chat.ID, "setrate.reset", i18n.Args{
+			"Rarity": rarity,
+			"Weight": b.Config.Live().Spawn.RarityWeights[rarity],
+		})
 		return
 	}
 	
-	charID := args[1]
-	reason := "No reason provided"
-	if len(args) >= 3 {
-		reason = strings.Join(args[2:], " ")
+	weight, err := strconv.Atoi(c.Arg(1))
+	if err != nil || weight < 0 || weight > maxRarityWeight {
+		b.reply(p, msg.Chat.ID, "setrate.usage", i18n.Args{"Max": maxRarityWeight})
+		return
 	}
-	
-	// Get character
-	char, err := b.CharacterService.GetCharacterByID(charID)
-	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			fmt.Sprintf("❌ %s <code>%s</code>",
-				utils.ToSmallCaps("Character not found with ID:"), charID))
-		b.API.Send(reply)
+	if err := b.RarityService.SetRarityWeight(msg.Chat.ID, rarity, weight); err != nil {
+		logError(c.Log, err, "set rarity weight", zap.Int("rarity", rarity))
+		b.reply(p, msg.Chat.ID, "rarity.failed", nil)
 		return
 	}
 	
-	// Lock character
-	_ = b.RarityService.LockCharacter(charID, char.Name, msg.From.ID, msg.From.FirstName, reason)
-	
-	reply := tgbotapi.NewMessage(msg.Chat.ID, 
-		fmt.Sprintf("🔒 <b>%s</b> %s\n\n%s <code>%s</code>\n%s %s",
-			utils.ToSmallCaps("Character Locked"),
-			utils.ToSmallCaps(char.Name),
-			utils.ToSmallCaps("ID:"),
-			charID,
-			utils.ToSmallCaps("Reason:"),
-			reason))
-	reply.ParseMode = "HTML"
-	b.API.Send(reply)
+	b.reply(p, msg.Chat.ID, "setrate.done", i18n.Args{"Rarity": rarity, "Weight": weight})
 }
 
-// cmdUnlock handles /unlock command (unlock character)
-func (b *Bot) cmdUnlock(msg *tgbotapi.Message) {
-	if !b.Config.IsSudo(msg.From.ID) {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("⚠️ You are not authorized!"))
-		b.API.Send(reply)
+// cmdLock handles /lock command (lock character from spawning)
+func (b *Bot) cmdLock(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+	charID := c.Arg(0)
+	reason := c.Rest(1)
+	if reason == "" {
+		reason = p.Plain("lock.no_reason", nil)
+	}
+	
+	// Get character
+	char, err := b.CharacterService.GetCharacterByID(charID)
+	if err != nil {
+		b.reply(p, msg.Chat.ID, "character.not_found", i18n.Args{"ID": charID})
 		return
 	}
 	
-	args := strings.Fields(msg.Text)
-	if len(args) < 2 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			fmt.Sprintf("<b>🔓 %s</b>\n\n%s <code>/unlock &lt;character_id&gt;</code>\n\n%s",
-				utils.ToSmallCaps("UNLOCK CHARACTER"),
-				utils.ToSmallCaps("Usage:"),
-				utils.ToSmallCaps("Unlock a character to allow spawning.")))
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+	// Lock character
+	if err := b.RarityService.LockCharacter(charID, char.Name, msg.From.ID, msg.From.FirstName, reason); err != nil {
+		logError(c.Log, err, "lock character", zap.String("character_id", charID))
+		b.reply(p, msg.Chat.ID, "lock.failed", nil)
 		return
 	}
+	b.audit(c, models.AuditEntry{
+		CharacterID: charID,
+		After:       map[string]interface{}{"locked": true, "reason": reason},
+	})
 	
-	charID := args[1]
+	b.reply(p, msg.Chat.ID, "lock.done", i18n.Args{"Name": char.Name, "ID": charID, "Reason": reason})
+}
+
+// cmdUnlock handles /unlock command (unlock character)
+func (b *Bot) cmdUnlock(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+	charID := c.Arg(0)
 	
 	// Check if locked
-	isLocked, _ := b.RarityService.IsCharacterLocked(charID)
+	isLocked, err := b.RarityService.IsCharacterLocked(charID)
+	logError(c.Log, err, "check character lock", zap.String("character_id", charID))
 	if !isLocked {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			fmt.Sprintf("❌ %s <code>%s</code> %s",
-				utils.ToSmallCaps("Character"), charID, utils.ToSmallCaps("is not locked!")))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "unlock.not_locked", i18n.Args{"ID": charID})
 		return
 	}
 	
 	// Unlock character
-	_ = b.RarityService.UnlockCharacter(charID)
+	if err := b.RarityService.UnlockCharacter(charID); err != nil {
+		logError(c.Log, err, "unlock character", zap.String("character_id", charID))
+		b.reply(p, msg.Chat.ID, "unlock.failed", nil)
+		return
+	}
+	b.audit(c, models.AuditEntry{
+		CharacterID: charID,
+		Before:      map[string]interface{}{"locked": true},
+		After:       map[string]interface{}{"locked": false},
+	})
 	
-	reply := tgbotapi.NewMessage(msg.Chat.ID, 
-		fmt.Sprintf("🔓 <b>%s</b> %s",
-			utils.ToSmallCaps("Character unlocked:"),
-			charID))
-	reply.ParseMode = "HTML"
-	b.API.Send(reply)
+	b.reply(p, msg.Chat.ID, "unlock.done", i18n.Args{"ID": charID})
 }
 
 // cmdLockList handles /locklist command (show locked characters)
 func (b *Bot) cmdLockList(msg *tgbotapi.Message) {
-	if !b.Config.IsSudo(msg.From.ID) {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("⚠️ You are not authorized!"))
-		b.API.Send(reply)
-		return
-	}
+	p := b.msgPrinter(msg)
 	
 	// Get locked characters
 	locked, err := b.RarityService.GetLockedCharacters()
 	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("⚠️ Could not retrieve locked characters!"))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "locklist.failed", nil)
 		return
 	}
 	
 	if len(locked) == 0 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("📋 No characters are currently locked."))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "locklist.empty", nil)
 		return
 	}
 	
 	// Build message
-	message := fmt.Sprintf("<b>🔒 %s</b>\n\n", utils.ToSmallCaps("LOCKED CHARACTERS"))
+	message := p.Text("locklist.header", nil) + "\n\n"
 	
 	for _, lock := range locked {
-		message += fmt.Sprintf("🆔 <code>%s</code> - %s\n", lock.CharacterID, utils.ToSmallCaps(lock.CharacterName))
-		message += fmt.Sprintf("   %s %s\n", utils.ToSmallCaps("Locked by:"), lock.LockedByName)
-		message += fmt.Sprintf("   %s %s\n\n", utils.ToSmallCaps("Reason:"), lock.Reason)
+		message += p.Text("locklist.entry", i18n.Args{
+			"ID":       lock.CharacterID,
+			"Name":     lock.CharacterName,
+			"LockedBy": lock.LockedByName,
+			"Reason":   lock.Reason,
+		}) + "\n\n"
 	}
 	
 	reply := tgbotapi.NewMessage(msg.Chat.ID, message)
 	reply.ParseMode = "HTML"
-	b.API.Send(reply)
+	b.send(reply)
 }
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/services"
+	"senpai-waifu-bot/internal/store"
+	"senpai-waifu-bot/internal/utils"
+)
+
+// roleNames maps the roles that can be granted to their stored names
+var roleNames = map[Role]string{
+	RoleUploader: models.RoleUploader,
+	RoleEconomy:  models.RoleEconomy,
+	RoleSudo:     models.RoleSudo,
+}
+
+// grants are the roles a user holds in every chat
+type grants struct {
+	owner bool
+	sudo  bool
+	roles []string
+}
+
+// holds reports whether the grants include role. Group admin is only held in every chat by
+// sudo users; everyone else needs to be an administrator of the chat.
+func (g grants) holds(role Role) bool {
+	switch {
+	case role == RoleUser, g.owner:
+		return true
+	case role == RoleOwner:
+		return false
+	case g.sudo:
+		return true
+	}
+	name, ok := roleNames[role]
+	return ok && utils.ContainsString(g.roles, name)
+}
+
+// staff reports whether the user holds any role
+func (g grants) staff() bool {
+	return g.owner || g.sudo || len(g.roles) > 0
+}
+
+// grantsOf returns the roles of userID: the owner and the sudo users in the configuration,
+// then the roles granted in the database. A failed lookup grants nothing.
+func (b *Bot) grantsOf(userID int64) grants {
+	g := grants{owner: userID == b.Config.OwnerID, sudo: b.Config.IsSudo(userID)}
+	if g.owner || g.sudo {
+		return g
+	}
+
+	roles, err := b.RoleService.Roles(userID)
+	if err != nil {
+		logError(b.Log, err, "get user roles", zap.Int64("user_id", userID))
+		return g
+	}
+	g.roles = roles
+	g.sudo = utils.ContainsString(roles, models.RoleSudo)
+	return g
+}
+
+// hasRole reports whether userID holds role in chatID
+func (b *Bot) hasRole(chatID, userID int64, role Role) bool {
+	if role == RoleUser {
+		return true
+	}
+	if b.grantsOf(userID).holds(role) {
+		return true
+	}
+	return role == RoleGroupAdmin && b.isChatAdmin(chatID, userID)
+}
+
+// isChatAdmin asks Telegram whether userID administers chatID; users administer their own
+// private chat
+func (b *Bot) isChatAdmin(chatID, userID int64) bool {
+	if chatID == userID {
+		return true
+	}
+	member, err := b.API.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chatID, UserID: userID},
+	})
+	if err != nil {
+		logError(b.Log, err, "get chat member", zap.Int64("chat_id", chatID), zap.Int64("user_id", userID))
+		return false
+	}
+	return member.IsCreator() || member.IsAdministrator()
+}
+
+// staffIDs returns the owner, the configured sudo users and every user with a granted role
+func (b *Bot) staffIDs() []int64 {
+	ids := []int64{b.Config.OwnerID}
+	for _, id := range b.Config.Live().SudoUsers {
+		if !utils.ContainsInt64(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	grants, err := b.RoleService.Grants()
+	if err != nil {
+		logError(b.Log, err, "list role grants")
+	}
+	for _, g := range grants {
+		if !utils.ContainsInt64(ids, g.UserID) {
+			ids = append(ids, g.UserID)
+		}
+	}
+	return ids
+}
+
+// publishStaffMenu gives userID a command menu in their private chat with every command their
+// roles allow, or removes it when they hold none
+func (b *Bot) publishStaffMenu(userID int64) {
+	if userID == 0 {
+		return
+	}
+	g := b.grantsOf(userID)
+	scope := tgbotapi.NewBotCommandScopeChat(userID)
+	for _, lang := range b.Catalog.Languages() {
+		code := b.menuLanguageCode(lang)
+		var req tgbotapi.Chattable = tgbotapi.NewDeleteMyCommandsWithScopeAndLanguage(scope, code)
+		if g.staff() {
+			req = tgbotapi.NewSetMyCommandsWithScopeAndLanguage(scope, code, b.menu(lang, g.holds)...)
+		}
+		if _, err := b.API.Request(req); err != nil {
+			b.Log.Warn("failed to publish staff commands", zap.Int64("user_id", userID), zap.String("language", lang), zap.Error(err))
+		}
+	}
+}
+
+// cmdAddSudo handles /addsudo: it grants a role, sudo by default, to a user
+func (b *Bot) cmdAddSudo(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+	userID := c.Int(0)
+	role := strings.ToLower(c.Arg(1))
+	if role == "" {
+		role = models.RoleSudo
+	}
+
+	if !services.IsGrantable(role) {
+		b.reply(p, msg.Chat.ID, "roles.unknown", i18n.Args{"Role": role, "Roles": strings.Join(services.GrantableRoles, ", ")})
+		return
+	}
+	if userID == b.Config.OwnerID {
+		b.reply(p, msg.Chat.ID, "roles.owner", nil)
+		return
+	}
+
+	args := i18n.Args{"UserID": userID, "Name": b.userName(p, userID), "Role": role}
+	err := b.RoleService.Grant(userID, role, msg.From.ID)
+	switch {
+	case errors.Is(err, store.ErrDuplicate):
+		b.reply(p, msg.Chat.ID, "roles.already", args)
+	case err != nil:
+		logError(c.Log, err, "grant role", zap.Int64("target_id", userID), zap.String("role", role))
+		b.reply(p, msg.Chat.ID, "roles.failed", nil)
+	default:
+		c.Log.Info("role granted", zap.Int64("target_id", userID), zap.String("role", role))
+		b.audit(c, models.AuditEntry{TargetUserID: userID, After: map[string]interface{}{"role": role}})
+		b.publishStaffMenu(userID)
+		b.reply(p, msg.Chat.ID, "roles.granted", args)
+	}
+}
+
+// cmdRmSudo handles /rmsudo: it revokes one role from a user, or all of them when no role is
+// given. Sudo users in the configuration keep their role until they are removed from it.
+func (b *Bot) cmdRmSudo(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+	userID := c.Int(0)
+	role := strings.ToLower(c.Arg(1))
+
+	if role != "" && !services.IsGrantable(role) {
+		b.reply(p, msg.Chat.ID, "roles.unknown", i18n.Args{"Role": role, "Roles": strings.Join(services.GrantableRoles, ", ")})
+		return
+	}
+	if userID == b.Config.OwnerID {
+		b.reply(p, msg.Chat.ID, "roles.owner", nil)
+		return
+	}
+
+	roles := []string{role}
+	if role == "" {
+		var err error
+		if roles, err = b.RoleService.Roles(userID); err != nil {
+			logError(c.Log, err, "get user roles", zap.Int64("target_id", userID))
+			b.reply(p, msg.Chat.ID, "roles.failed", nil)
+			return
+		}
+	}
+
+	var revoked []string
+	for _, r := range roles {
+		err := b.RoleService.Revoke(userID, r)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			logError(c.Log, err, "revoke role", zap.Int64("target_id", userID), zap.String("role", r))
+			b.reply(p, msg.Chat.ID, "roles.failed", nil)
+			return
+		}
+		revoked = append(revoked, r)
+	}
+
+	args := i18n.Args{
+		"UserID":     userID,
+		"Name":       b.userName(p, userID),
+		"Role":       role,
+		"Configured": b.Config.IsSudo(userID) && (role == "" || role == models.RoleSudo),
+	}
+	if len(revoked) == 0 {
+		b.reply(p, msg.Chat.ID, "roles.not_granted", args)
+		return
+	}
+	c.Log.Info("roles revoked", zap.Int64("target_id", userID), zap.Strings("roles", revoked))
+	b.audit(c, models.AuditEntry{TargetUserID: userID, Before: map[string]interface{}{"roles": strings.Join(revoked, ", ")}})
+	b.publishStaffMenu(userID)
+	args["Roles"] = strings.Join(revoked, ", ")
+	b.reply(p, msg.Chat.ID, "roles.revoked", args)
+}
+
+// cmdRoles handles /roles: it lists the owner and the users holding each role
+func (b *Bot) cmdRoles(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+
+	grants, err := b.RoleService.Grants()
+	if err != nil {
+		logError(c.Log, err, "list role grants")
+		b.reply(p, msg.Chat.ID, "roles.failed", nil)
+		return
+	}
+
+	userLine := func(userID int64, configured bool) string {
+		return p.Text("roles.user", i18n.Args{"UserID": userID, "Name": b.userName(p, userID), "Configured": configured})
+	}
+	holders := make(map[string][]string)
+	for _, id := range b.Config.Live().SudoUsers {
+		if id != b.Config.OwnerID {
+			holders[models.RoleSudo] = append(holders[models.RoleSudo], userLine(id, true))
+		}
+	}
+	for _, g := range grants {
+		holders[g.Role] = append(holders[g.Role], userLine(g.UserID, false))
+	}
+
+	var sections []string
+	for _, role := range services.GrantableRoles {
+		users := holders[role]
+		if len(users) == 0 {
+			users = []string{p.Text("roles.nobody", nil)}
+		}
+		sections = append(sections, p.Text("roles.section", i18n.Args{
+			"Role":  role,
+			"Users": i18n.HTML(strings.Join(users, "\n")),
+		}))
+	}
+	b.reply(p, msg.Chat.ID, "roles.list", i18n.Args{
+		"OwnerID": b.Config.OwnerID,
+		"Owner":   b.userName(p, b.Config.OwnerID),
+		"Roles":   i18n.HTML(strings.Join(sections, "\n\n")),
+	})
+}
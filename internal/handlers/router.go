@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/state"
+)
+
+// Role is the privilege needed to run a command. Sudo users hold every role but RoleOwner,
+// and the owner holds them all.
+type Role int
+
+const (
+	RoleUser Role = iota
+	// RoleGroupAdmin is held by the administrators of the chat the command is used in
+	RoleGroupAdmin
+	// RoleUploader manages the character catalog
+	RoleUploader
+	// RoleEconomy manages coins, codes and shops
+	RoleEconomy
+	RoleSudo
+	RoleOwner
+)
+
+// ChatScope restricts the chats a command can be used in
+type ChatScope int
+
+const (
+	AnyChat ChatScope = iota
+	PrivateOnly
+	GroupOnly
+)
+
+// ArgKind describes how a command argument is parsed
+type ArgKind int
+
+const (
+	// ArgWord is a single whitespace-separated word
+	ArgWord ArgKind = iota
+	// ArgInt is a single word that must parse as an integer
+	ArgInt
+	// ArgRest takes every remaining word; it must be the last argument
+	ArgRest
+)
+
+// Arg declares one positional command argument
+type Arg struct {
+	Name     string
+	Kind     ArgKind
+	Optional bool
+}
+
+// HandlerFunc runs a command
+type HandlerFunc func(c *CommandContext)
+
+// Middleware wraps a HandlerFunc with cross-cutting behaviour
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Command declares a bot command. A nil Args skips argument validation; Usage overrides the
+// usage line generated from Args. Hidden commands are left out of /help and setMyCommands.
+// The description shown for a command is the catalog message DescriptionID.
+type Command struct {
+	Name     string
+	Aliases  []string
+	Usage    string
+	Role     Role
+	Scope    ChatScope
+	Args     []Arg
+	Cooldown time.Duration
+	Hidden   bool
+	Handler  HandlerFunc
+
+	cooldowns *state.Cooldowns
+}
+
+// DescriptionID is the catalog message describing the command
+func (cmd *Command) DescriptionID() string {
+	return "command." + cmd.Name
+}
+
+// UsageLine returns the command with its arguments, e.g. "/gen <amount> [max_uses]"
+func (cmd *Command) UsageLine() string {
+	if cmd.Usage != "" {
+		return "/" + cmd.Name + " " + cmd.Usage
+	}
+
+	parts := []string{"/" + cmd.Name}
+	for _, arg := range cmd.Args {
+		name := arg.Name
+		if arg.Kind == ArgRest {
+			name += "..."
+		}
+		if arg.Optional {
+			parts = append(parts, "["+name+"]")
+		} else {
+			parts = append(parts, "<"+name+">")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// CommandContext carries one command invocation through the middleware chain
+type CommandContext struct {
+	Msg     *tgbotapi.Message
+	Command *Command
+	// Log carries the update's fields
+	Log *zap.Logger
+	// Name is the name or alias the command was invoked with
+	Name string
+	// Args holds the words after the command
+	Args []string
+
+	// outcome is set by middleware that stops the command; empty means it ran
+	outcome string
+}
+
+// Arg returns the i-th argument, or "" if it was not given
+func (c *CommandContext) Arg(i int) string {
+	if i < len(c.Args) {
+		return c.Args[i]
+	}
+	return ""
+}
+
+// Int returns the i-th argument as an integer; ArgInt arguments are validated before the handler runs
+func (c *CommandContext) Int(i int) int64 {
+	n, _ := strconv.ParseInt(c.Arg(i), 10, 64)
+	return n
+}
+
+// Rest returns the arguments from the i-th on, joined by single spaces
+func (c *CommandContext) Rest(i int) string {
+	if i >= len(c.Args) {
+		return ""
+	}
+	return strings.Join(c.Args[i:], " ")
+}
+
+// CommandStats summarizes the invocations of one command
+type CommandStats struct {
+	Calls int64
+	Total time.Duration
+}
+
+// Router maps command names to their declarations and runs them through the middleware chain
+type Router struct {
+	commands   []*Command
+	byName     map[string]*Command
+	middleware []Middleware
+
+	statsMu sync.Mutex
+	stats   map[string]*CommandStats
+}
+
+// NewRouter creates an empty Router
+func NewRouter() *Router {
+	return &Router{
+		byName: make(map[string]*Command),
+		stats:  make(map[string]*CommandStats),
+	}
+}
+
+// Use appends middleware; the first one added is the outermost
+func (r *Router) Use(mw ...Middleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+// Register adds a command. Registering a name or alias twice is a programming error and panics.
+func (r *Router) Register(cmd *Command) {
+	for _, name := range append([]string{cmd.Name}, cmd.Aliases...) {
+		if _, exists := r.byName[name]; exists {
+			panic(fmt.Sprintf("handlers: command %q registered twice", name))
+		}
+		r.byName[name] = cmd
+	}
+	if cmd.Cooldown > 0 {
+		cmd.cooldowns = state.NewCooldowns()
+	}
+	r.commands = append(r.commands, cmd)
+}
+
+// Lookup finds a command by name or alias
+func (r *Router) Lookup(name string) (*Command, bool) {
+	cmd, ok := r.byName[strings.ToLower(name)]
+	return cmd, ok
+}
+
+// Commands returns every registered command in registration order
+func (r *Router) Commands() []*Command {
+	return append([]*Command{}, r.commands...)
+}
+
+// PruneCooldowns drops the per-user cooldowns of every command that have run out by now
+func (r *Router) PruneCooldowns(now time.Time) {
+	for _, cmd := range r.commands {
+		if cmd.cooldowns != nil {
+			cmd.cooldowns.Prune(now)
+		}
+	}
+}
+
+// Dispatch runs the command in msg, reporting whether one was found
+func (r *Router) Dispatch(msg *tgbotapi.Message, log *zap.Logger) bool {
+	name := strings.ToLower(msg.Command())
+	cmd, ok := r.byName[name]
+	if !ok {
+		return false
+	}
+
+	handler := cmd.Handler
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		handler = r.middleware[i](handler)
+	}
+	handler(&CommandContext{
+		Msg:     msg,
+		Command: cmd,
+		Log:     log,
+		Name:    name,
+		Args:    strings.Fields(msg.CommandArguments()),
+	})
+	return true
+}
+
+// observe records one invocation of a command
+func (r *Router) observe(name string, d time.Duration) {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+
+	stats, ok := r.stats[name]
+	if !ok {
+		stats = &CommandStats{}
+		r.stats[name] = stats
+	}
+	stats.Calls++
+	stats.Total += d
+}
+
+// TopCommands returns the names of the most used commands with their stats, busiest first
+func (r *Router) TopCommands(limit int) ([]string, []CommandStats) {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+
+	names := make([]string, 0, len(r.stats))
+	for name := range r.stats {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return r.stats[names[i]].Calls > r.stats[names[j]].Calls })
+	if len(names) > limit {
+		names = names[:limit]
+	}
+
+	stats := make([]CommandStats, len(names))
+	for i, name := range names {
+		stats[i] = *r.stats[name]
+	}
+	return names, stats
+}
+
+// argProblem is a user-facing argument error: the catalog message describing it and the
+// argument it is about
+type argProblem struct {
+	ID  string
+	Arg string
+}
+
+// validateArgs checks args against the declared schema, returning nil if they are valid
+func validateArgs(schema []Arg, args []string) *argProblem {
+	for i, arg := range schema {
+		if i >= len(args) {
+			if arg.Optional {
+				return nil
+			}
+			return &argProblem{ID: "args.missing", Arg: arg.Name}
+		}
+		switch arg.Kind {
+		case ArgInt:
+			if _, err := strconv.ParseInt(args[i], 10, 64); err != nil {
+				return &argProblem{ID: "args.not_number", Arg: arg.Name}
+			}
+		case ArgRest:
+			return nil
+		}
+	}
+	if len(args) > len(schema) {
+		return &argProblem{ID: "args.too_many"}
+	}
+	return nil
+}
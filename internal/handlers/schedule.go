@@ -0,0 +1,420 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/metrics"
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/services"
+	"senpai-waifu-bot/internal/state"
+	"senpai-waifu-bot/internal/utils"
+)
+
+// SpawnCheckInterval is how often chats are checked for spawns that are due by time, and
+// spawns for whether they fled
+const SpawnCheckInterval = 15 * time.Second
+
+// Limits on the spawn settings a chat can choose
+const (
+	maxSpawnFrequency = 10000
+	minSpawnInterval  = time.Minute
+	maxSpawnInterval  = 24 * time.Hour
+)
+
+// defaultSpawnSchedule returns the spawn schedule of chats without settings of their own
+func (b *Bot) defaultSpawnSchedule() services.SpawnSchedule {
+	defaults := b.Config.Live().Spawn
+	location, err := utils.LoadLocation(defaults.QuietTimezone)
+	logError(b.Log, err, "load quiet hours time zone", zap.String("timezone", defaults.QuietTimezone))
+	return services.SpawnSchedule{
+		Mode:      defaults.DefaultMode,
+		Frequency: defaults.DefaultFrequency,
+		Interval:  defaults.DefaultInterval,
+		MinGap:    defaults.MinGap,
+		MaxGap:    defaults.MaxGap,
+		Location:  location,
+		Expiry:    defaults.Expiry,
+	}
+}
+
+// spawnSchedule returns the spawn schedule of chatID
+func (b *Bot) spawnSchedule(chatID int64) services.SpawnSchedule {
+	schedule := b.defaultSpawnSchedule()
+	settings, err := b.GroupService.GetSpawnSettings(chatID)
+	if err != nil {
+		logError(b.Log, err, "get spawn settings", zap.Int64("chat_id", chatID))
+		return schedule
+	}
+	return schedule.With(settings)
+}
+
+// spawnActivity returns the activity of chatID at now. The first time a chat is seen after a
+// restart, its last spawn is taken from the stored active spawn, or the start of the bot when
+// it has none.
+func (b *Bot) spawnActivity(chatID int64, now time.Time) state.Activity {
+	activity := b.Chats.Activity(chatID, now, services.SpawnRateWindow)
+	if activity.LastSpawn.IsZero() {
+		activity.LastSpawn = b.startedAt
+		spawn, err := b.StateService.GetActiveSpawn(chatID)
+		if err == nil {
+			activity.LastSpawn = spawn.SpawnedAt
+		}
+		logError(b.Log, ignoreNotFound(err), "get active spawn", zap.Int64("chat_id", chatID))
+		b.Chats.SetLastSpawn(chatID, activity.LastSpawn)
+	}
+	return activity
+}
+
+// scheduledSpawn starts a new count and spawns a character in chatID. Caller must hold the
+// chat lock.
+func (b *Bot) scheduledSpawn(chatID int64, log *zap.Logger) {
+	logError(log, b.StateService.ResetMessageCounter(chatID), "reset message counter")
+	b.spawnCharacter(chatID)
+}
+
+// spawnSchedulerRoutine lets expired spawns flee and spawns characters that are due by time
+// rather than by a counted message until ctx is cancelled: those of interval and adaptive
+// mode, and those of messages mode once the maximum gap has passed
+func (b *Bot) spawnSchedulerRoutine(ctx context.Context) {
+	ticker := time.NewTicker(SpawnCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+
+		b.fleeExpiredSpawns(now)
+		for _, chatID := range b.Chats.AwaitingSpawn() {
+			b.checkScheduledSpawn(chatID, now)
+		}
+	}
+}
+
+// checkScheduledSpawn spawns a character in chatID if its schedule calls for one at now
+func (b *Bot) checkScheduledSpawn(chatID int64, now time.Time) {
+	unlock := b.ChatLocks.Lock(chatID)
+	defer unlock()
+
+	schedule := b.spawnSchedule(chatID)
+	if schedule.Due(b.spawnActivity(chatID, now), 0, now) {
+		log := b.Log.With(zap.Int64("chat_id", chatID), zap.String("spawn_mode", schedule.Mode))
+		log.Debug("spawn due by time")
+		b.scheduledSpawn(chatID, log)
+	}
+}
+
+// fleeExpiredSpawns lets every spawn that nobody guessed in time flee
+func (b *Bot) fleeExpiredSpawns(now time.Time) {
+	spawns, err := b.StateService.ExpiredSpawns(now)
+	if err != nil {
+		logError(b.Log, err, "list expired spawns")
+		return
+	}
+	for i := range spawns {
+		b.fleeSpawn(&spawns[i], now)
+	}
+}
+
+// fleeSpawn records spawn as uncaught and reveals the character and its original image in a
+// reply to the spawn message. A spawn guessed or replaced since it was listed is left alone.
+func (b *Bot) fleeSpawn(spawn *models.ActiveSpawn, now time.Time) {
+	unlock := b.ChatLocks.Lock(spawn.ChatID)
+	defer unlock()
+
+	char := spawn.Character
+	log := b.Log.With(zap.Int64("chat_id", spawn.ChatID), zap.String("character_id", char.ID))
+	fled, err := b.StateService.FleeActiveSpawn(spawn, now)
+	if err != nil {
+		logError(log, err, "record fled spawn")
+		return
+	}
+	if !fled {
+		return
+	}
+	log.Info("character fled", zap.Duration("after", now.Sub(spawn.SpawnedAt)))
+	metrics.SpawnsFledTotal.WithLabelValues(strconv.Itoa(char.Rarity)).Inc()
+
+	p := b.printer(spawn.ChatID, nil)
+	text := p.Text("spawn.fled", i18n.Args{
+		"Name":   char.Name,
+		"Anime":  char.Anime,
+		"Rarity": char.Rarity,
+		"ID":     char.ID,
+	})
+	b.sendCharacter(tgbotapi.BaseChat{
+		ChatID:                   spawn.ChatID,
+		ReplyToMessageID:         spawn.MessageID,
+		AllowSendingWithoutReply: true,
+	}, char.ImgURL, text)
+}
+
+// cmdSpawnMode handles /spawnmode: without an argument it shows the spawn schedule of the
+// chat, with one admins change its mode
+func (b *Bot) cmdSpawnMode(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+
+	mode := strings.ToLower(c.Arg(0))
+	if mode == "" {
+		b.showSpawnSchedule(p, msg.Chat.ID)
+		return
+	}
+	if !b.hasRole(msg.Chat.ID, msg.From.ID, RoleGroupAdmin) {
+		b.reply(p, msg.Chat.ID, "chat.admins_only", nil)
+		return
+	}
+
+	settings, err := b.GroupService.GetSpawnSettings(msg.Chat.ID)
+	if err != nil {
+		logError(c.Log, err, "get spawn settings")
+		b.reply(p, msg.Chat.ID, "error.generic", nil)
+		return
+	}
+
+	value := c.Arg(1)
+	switch mode {
+	case models.SpawnModeMessages, models.SpawnModeAdaptive:
+		if value != "" {
+			frequency, err := strconv.Atoi(value)
+			if err != nil || frequency < 1 || frequency > maxSpawnFrequency {
+				b.reply(p, msg.Chat.ID, "spawnmode.bad_count", i18n.Args{"Max": maxSpawnFrequency})
+				return
+			}
+			settings.MessageFrequency = frequency
+		}
+	case models.SpawnModeInterval:
+		if value != "" {
+			interval, err := time.ParseDuration(value)
+			if err != nil || interval < minSpawnInterval || interval > maxSpawnInterval {
+				b.reply(p, msg.Chat.ID, "spawnmode.bad_interval", nil)
+				return
+			}
+			settings.Interval = interval
+		}
+	case "reset":
+		settings = &models.SpawnSettings{ChatID: msg.Chat.ID}
+	default:
+		b.reply(p, msg.Chat.ID, "spawnmode.usage", nil)
+		return
+	}
+	if mode != "reset" {
+		settings.Mode = mode
+	}
+
+	b.saveSpawnSettings(c, p, settings)
+}
+
+// cmdSpawnGap handles /spawngap: admins set the shortest and longest time between spawns.
+// A missing maximum keeps the default, and default resets both.
+func (b *Bot) cmdSpawnGap(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+
+	var minGap, maxGap *time.Duration
+	if strings.ToLower(c.Arg(0)) != "default" {
+		var okMin, okMax bool
+		minGap, okMin = parseGap(c.Arg(0))
+		maxGap, okMax = parseGap(c.Arg(1))
+		if minGap == nil || !okMin || !okMax {
+			b.reply(p, msg.Chat.ID, "spawngap.usage", nil)
+			return
+		}
+	}
+	if !b.hasRole(msg.Chat.ID, msg.From.ID, RoleGroupAdmin) {
+		b.reply(p, msg.Chat.ID, "chat.admins_only", nil)
+		return
+	}
+
+	settings, err := b.GroupService.GetSpawnSettings(msg.Chat.ID)
+	if err != nil {
+		logError(c.Log, err, "get spawn settings")
+		b.reply(p, msg.Chat.ID, "error.generic", nil)
+		return
+	}
+	settings.MinGap, settings.MaxGap = minGap, maxGap
+
+	// A default side counts too, so that a new minimum can't pass the default maximum
+	if next := b.defaultSpawnSchedule().With(settings); next.MaxGap > 0 && next.MaxGap < next.MinGap {
+		b.reply(p, msg.Chat.ID, "spawngap.invalid", nil)
+		return
+	}
+	b.saveSpawnSettings(c, p, settings)
+}
+
+// cmdQuietHours handles /quiethours: admins set the daily hours without spawns, in the time
+// zone they name or else the configured one
+func (b *Bot) cmdQuietHours(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+
+	var quiet *models.QuietHours
+	if arg := strings.ToLower(c.Arg(0)); arg != "off" {
+		var ok bool
+		if quiet, ok = parseQuietHours(arg); !ok {
+			b.reply(p, msg.Chat.ID, "quiethours.usage", nil)
+			return
+		}
+		if timezone := c.Arg(1); timezone != "" {
+			if _, err := utils.LoadLocation(timezone); err != nil {
+				b.reply(p, msg.Chat.ID, "quiethours.bad_timezone", nil)
+				return
+			}
+			quiet.Timezone = timezone
+		}
+	}
+	if !b.hasRole(msg.Chat.ID, msg.From.ID, RoleGroupAdmin) {
+		b.reply(p, msg.Chat.ID, "chat.admins_only", nil)
+		return
+	}
+
+	settings, err := b.GroupService.GetSpawnSettings(msg.Chat.ID)
+	if err != nil {
+		logError(c.Log, err, "get spawn settings")
+		b.reply(p, msg.Chat.ID, "error.generic", nil)
+		return
+	}
+	settings.QuietHours = quiet
+	b.saveSpawnSettings(c, p, settings)
+}
+
+// cmdSpawnExpiry handles /spawnexpiry: admins set how long spawns can be guessed before
+// they flee
+func (b *Bot) cmdSpawnExpiry(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+
+	var expiry time.Duration
+	switch arg := strings.ToLower(c.Arg(0)); arg {
+	case "default":
+	case "off":
+		expiry = models.SpawnExpiryNever
+	default:
+		d, err := time.ParseDuration(arg)
+		if err != nil || d < minSpawnInterval || d > maxSpawnInterval {
+			b.reply(p, msg.Chat.ID, "spawnexpiry.usage", nil)
+			return
+		}
+		expiry = d
+	}
+	if !b.hasRole(msg.Chat.ID, msg.From.ID, RoleGroupAdmin) {
+		b.reply(p, msg.Chat.ID, "chat.admins_only", nil)
+		return
+	}
+
+	settings, err := b.GroupService.GetSpawnSettings(msg.Chat.ID)
+	if err != nil {
+		logError(c.Log, err, "get spawn settings")
+		b.reply(p, msg.Chat.ID, "error.generic", nil)
+		return
+	}
+	settings.Expiry = expiry
+	b.saveSpawnSettings(c, p, settings)
+}
+
+// saveSpawnSettings stores settings and shows the resulting schedule. Adaptive mode is refused
+// without a maximum gap, since a quiet chat would otherwise never get a spawn.
+func (b *Bot) saveSpawnSettings(c *CommandContext, p *i18n.Printer, settings *models.SpawnSettings) {
+	chatID := c.Msg.Chat.ID
+	if next := b.defaultSpawnSchedule().With(settings); next.Mode == models.SpawnModeAdaptive && next.MaxGap <= 0 {
+		b.reply(p, chatID, "spawnmode.needs_max_gap", nil)
+		return
+	}
+
+	if err := b.GroupService.SetSpawnSettings(settings); err != nil {
+		logError(c.Log, err, "set spawn settings")
+		b.reply(p, chatID, "error.generic", nil)
+		return
+	}
+	b.showSpawnSchedule(p, chatID)
+}
+
+// showSpawnSchedule describes the spawn schedule of chatID
+func (b *Bot) showSpawnSchedule(p *i18n.Printer, chatID int64) {
+	schedule := b.spawnSchedule(chatID)
+	args := i18n.Args{
+		"Mode":      schedule.Mode,
+		"Frequency": schedule.Frequency,
+		"Interval":  formatDuration(schedule.Interval),
+		"MinGap":    "",
+		"MaxGap":    "",
+		"Quiet":     "",
+		"Expiry":    "",
+	}
+	if schedule.MinGap > 0 {
+		args["MinGap"] = formatDuration(schedule.MinGap)
+	}
+	if schedule.MaxGap > 0 {
+		args["MaxGap"] = formatDuration(schedule.MaxGap)
+	}
+	if schedule.Quiet != nil {
+		args["Quiet"] = formatClock(schedule.Quiet.Start) + "-" + formatClock(schedule.Quiet.End) + " " + schedule.QuietLocation().String()
+	}
+	if schedule.Expiry > 0 {
+		args["Expiry"] = formatDuration(schedule.Expiry)
+	}
+	b.reply(p, chatID, "spawnmode.show", args)
+}
+
+// parseGap parses a gap of up to a day, where 0 leaves that side open. A missing gap is nil,
+// which leaves that side to the default.
+func parseGap(s string) (*time.Duration, bool) {
+	if s == "" {
+		return nil, true
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 || d > maxSpawnInterval {
+		return nil, false
+	}
+	return &d, true
+}
+
+// parseQuietHours parses a window such as 01:00-08:00
+func parseQuietHours(s string) (*models.QuietHours, bool) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, false
+	}
+	start, okStart := parseClock(from)
+	end, okEnd := parseClock(to)
+	if !okStart || !okEnd || start == end {
+		return nil, false
+	}
+	return &models.QuietHours{Start: start, End: end}, true
+}
+
+// parseClock parses a time of day such as 08:30 into minutes after midnight
+func parseClock(s string) (int, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// formatClock formats minutes after midnight as a time of day such as 08:30
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// formatDuration formats d the way it is typed, without trailing zero units: 1h30m, 45m, 1m30s
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
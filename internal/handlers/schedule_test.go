@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+// describeGap formats a stored gap, with nil as "default"
+func describeGap(gap *time.Duration) string {
+	if gap == nil {
+		return "default"
+	}
+	return gap.String()
+}
+
+func TestSpawnGap(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		wantMin      string
+		wantMax      string
+		wantRejected bool
+	}{
+		{name: "min and max", text: "/spawngap 5m 2h", wantMin: "5m0s", wantMax: "2h0m0s"},
+		{name: "min only", text: "/spawngap 5m", wantMin: "5m0s", wantMax: "default"},
+		{name: "no max", text: "/spawngap 5m 0", wantMin: "5m0s", wantMax: "0s"},
+		{name: "no min", text: "/spawngap 0 2h", wantMin: "0s", wantMax: "2h0m0s"},
+		{name: "default", text: "/spawngap default", wantMin: "default", wantMax: "default"},
+		{name: "max below min", text: "/spawngap 2h 5m", wantRejected: true},
+		{name: "bad min", text: "/spawngap soon", wantRejected: true},
+		{name: "missing min", text: "/spawngap", wantRejected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot, srv, _ := newTestBot(t, nil)
+			group := srv.Group(-100, "Waifu Club")
+			owner := srv.User(ownerID, "owner")
+
+			// Start from a gap that every accepted command replaces
+			bot.HandleUpdate(srv.Message(group, owner, "/spawngap 1m 3h"))
+			srv.Reset()
+
+			bot.HandleUpdate(srv.Message(group, owner, tt.text))
+			settings, err := bot.GroupService.GetSpawnSettings(group.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotMin, gotMax := describeGap(settings.MinGap), describeGap(settings.MaxGap)
+			if tt.wantRejected {
+				if gotMin != "1m0s" || gotMax != "3h0m0s" {
+					t.Errorf("gap changed to %s-%s by a rejected command", gotMin, gotMax)
+				}
+				return
+			}
+			if gotMin != tt.wantMin || gotMax != tt.wantMax {
+				t.Errorf("gap = %s-%s, want %s-%s", gotMin, gotMax, tt.wantMin, tt.wantMax)
+			}
+			usage := bot.printer(group.ID, nil).Text("spawngap.usage", nil)
+			for _, text := range texts(srv.Sent()) {
+				if text == usage {
+					t.Errorf("%s replied with the usage", tt.text)
+				}
+			}
+		})
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		wantTimezone string
+		wantRejected bool
+	}{
+		{name: "default zone", text: "/quiethours 01:00-08:00"},
+		{name: "own zone", text: "/quiethours 01:00-08:00 Europe/Madrid", wantTimezone: "Europe/Madrid"},
+		{name: "unknown zone", text: "/quiethours 01:00-08:00 Mars/Olympus", wantRejected: true},
+		{name: "host zone", text: "/quiethours 01:00-08:00 Local", wantRejected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot, srv, _ := newTestBot(t, nil)
+			group := srv.Group(-100, "Waifu Club")
+			owner := srv.User(ownerID, "owner")
+
+			bot.HandleUpdate(srv.Message(group, owner, tt.text))
+			settings, err := bot.GroupService.GetSpawnSettings(group.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantRejected {
+				if settings.QuietHours != nil {
+					t.Errorf("quiet hours set to %+v by a rejected command", settings.QuietHours)
+				}
+				return
+			}
+			if settings.QuietHours == nil || settings.QuietHours.Start != 60 || settings.QuietHours.End != 8*60 {
+				t.Fatalf("quiet hours = %+v, want 01:00-08:00", settings.QuietHours)
+			}
+			if settings.QuietHours.Timezone != tt.wantTimezone {
+				t.Errorf("time zone = %q, want %q", settings.QuietHours.Timezone, tt.wantTimezone)
+			}
+		})
+	}
+}
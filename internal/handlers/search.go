@@ -3,54 +3,39 @@ package handlers
 import (
 	"fmt"
 	"math"
-	"strconv"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/i18n"
 	"senpai-waifu-bot/internal/models"
 	"senpai-waifu-bot/internal/utils"
 )
 
 // cmdSFind handles /sfind command
-func (b *Bot) cmdSFind(msg *tgbotapi.Message) {
-	args := strings.Fields(msg.Text)
-	if len(args) < 2 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			fmt.Sprintf("<b>🔍 %s</b>\n\n%s <code>/sfind &lt;character_name&gt;</code>\n\n%s",
-				utils.ToSmallCaps("SEARCH CHARACTERS"),
-				utils.ToSmallCaps("Usage:"),
-				utils.ToSmallCaps("Search for characters by name or anime.")))
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
-		return
-	}
-	
-	query := strings.Join(args[1:], " ")
+func (b *Bot) cmdSFind(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+	query := c.Rest(0)
 	
 	// Search characters
 	chars, err := b.CharacterService.SearchCharacters(query)
 	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("⚠️ Search failed. Please try again."))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "search.failed", nil)
 		return
 	}
 	
 	if len(chars) == 0 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			fmt.Sprintf("❌ %s <b>%s</b>\n\n%s",
-				utils.ToSmallCaps("No characters found for:"), query,
-				utils.ToSmallCaps("Try a different search term.")))
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "search.none", i18n.Args{"Query": query})
 		return
 	}
 	
 	// Pagination (first page)
-	b.showSearchResults(msg.Chat.ID, chars, query, 0, msg.MessageID)
+	b.showSearchResults(p, msg.Chat.ID, chars, query, 0, msg.MessageID)
 }
 
 // showSearchResults shows search results with pagination
-func (b *Bot) showSearchResults(chatID int64, chars []models.Character, query string, page int, replyTo int) {
+func (b *Bot) showSearchResults(p *i18n.Printer, chatID int64, chars []models.Character, query string, page int, replyTo int) {
 	pageSize := 10
 	totalPages := int(math.Ceil(float64(len(chars)) / float64(pageSize)))
 	
@@ -70,14 +55,14 @@ func (b *Bot) showSearchResults(chatID int64, chars []models.Character, query st
 	pageChars := chars[startIdx:endIdx]
 	
 	// Build message
-	message := fmt.Sprintf("<b>🔍 %s</b> <code>%s</code>\n<i>%s %d</i>\n\n",
-		utils.ToSmallCaps("Search results for:"), query,
-		utils.ToSmallCaps("Found"), len(chars))
+	message := p.Text("search.results", i18n.Args{"Query": query, "Found": len(chars)}) + "\n\n"
 	
 	for _, char := range pageChars {
-		rarityEmoji := utils.RarityEmojis[char.Rarity]
-		message += fmt.Sprintf("🆔 <code>%s</code> - %s <b>%s</b>\n", 
-			char.ID, rarityEmoji, utils.ToSmallCaps(char.Name))
+		message += p.Text("search.result", i18n.Args{
+			"ID":    char.ID,
+			"Emoji": utils.RarityEmojis[char.Rarity],
+			"Name":  char.Name,
+		}) + "\n"
 	}
 	
 	// Build keyboard
@@ -99,7 +84,7 @@ func (b *Bot) showSearchResults(chatID int64, chars []models.Character, query st
 	
 	// Close button
 	keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(
-		tgbotapi.NewInlineKeyboardButtonData("❌ "+utils.ToSmallCaps("Close"), "close_search"),
+		tgbotapi.NewInlineKeyboardButtonData(p.Plain("button.close", nil), "close_search"),
 	))
 	
 	reply := tgbotapi.NewMessage(chatID, message)
@@ -108,69 +93,49 @@ func (b *Bot) showSearchResults(chatID int64, chars []models.Character, query st
 	if replyTo > 0 {
 		reply.ReplyToMessageID = replyTo
 	}
-	b.API.Send(reply)
+	b.send(reply)
 }
 
 // cmdSCheck handles /scheck command
-func (b *Bot) cmdSCheck(msg *tgbotapi.Message) {
-	args := strings.Fields(msg.Text)
-	if len(args) < 2 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			fmt.Sprintf("<b>🔎 %s</b>\n\n%s <code>/scheck &lt;character_id&gt;</code>\n\n%s",
-				utils.ToSmallCaps("CHECK CHARACTER"),
-				utils.ToSmallCaps("Usage:"),
-				utils.ToSmallCaps("Get detailed information about a character.")))
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
-		return
-	}
-	
-	charID := args[1]
+func (b *Bot) cmdSCheck(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+	chatID := msg.Chat.ID
+	charID := c.Arg(0)
 	
 	// Get character
 	char, err := b.CharacterService.GetCharacterByID(charID)
 	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			fmt.Sprintf("❌ %s <code>%s</code>\n\n%s",
-				utils.ToSmallCaps("Character not found with ID:"), charID,
-				utils.ToSmallCaps("Please check the ID and try again.")))
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "scheck.not_found", i18n.Args{"ID": charID})
 		return
 	}
 	
 	// Get owner count
-	ownerCount, _ := b.CharacterService.GetCharacterOwnerCount(charID)
+	ownerCount, err := b.CharacterService.GetCharacterOwnerCount(charID)
+	logError(c.Log, err, "count character owners", zap.String("character_id", charID))
 	
 	// Get top grabbers
-	topGrabbers, _ := b.CharacterService.GetTopGrabbers(charID, 3)
+	topGrabbers, err := b.CharacterService.GetTopGrabbers(charID, 3)
+	logError(c.Log, err, "get top grabbers", zap.String("character_id", charID))
 	
 	// Build message
-	rarityDisplay := utils.GetRarityDisplay(char.Rarity)
-	message := fmt.Sprintf(
-		"<b>📋 %s</b>\n\n"+
-			"🎭 <b>%s</b> %s\n"+
-			"📺 <b>%s</b> %s\n"+
-			"🆔 <b>%s</b> <code>%s</code>\n"+
-			"⭐ <b>%s</b> %s\n"+
-			"👥 <b>%s</b> %d\n\n",
-		utils.ToSmallCaps("CHARACTER INFO"),
-		utils.ToSmallCaps("Name:"), utils.ToSmallCaps(char.Name),
-		utils.ToSmallCaps("Anime:"), utils.ToSmallCaps(char.Anime),
-		utils.ToSmallCaps("ID:"), char.ID,
-		utils.ToSmallCaps("Rarity:"), rarityDisplay,
-		utils.ToSmallCaps("Owners:"), ownerCount,
-	)
+	message := p.Text("scheck.info", i18n.Args{
+		"Name":    char.Name,
+		"Aliases": strings.Join(char.Aliases, ", "),
+		"Anime":   char.Anime,
+		"ID":      char.ID,
+		"Rarity":  char.Rarity,
+		"Owners":  ownerCount,
+	}) + "\n\n"
 	
 	if len(topGrabbers) > 0 {
-		message += fmt.Sprintf("<b>🏆 %s</b>\n", utils.ToSmallCaps("Top Grabbers:"))
+		message += p.Text("scheck.grabbers", nil) + "\n"
 		for i, grabber := range topGrabbers {
-			name := grabber["first_name"].(string)
-			if username, ok := grabber["username"].(string); ok && username != "" {
-				name = fmt.Sprintf("@%s", username)
+			name := grabber.FirstName
+			if grabber.Username != "" {
+				name = "@" + grabber.Username
 			}
-			count := grabber["count"].(int32)
-			message += fmt.Sprintf("%d. %s - x%d\n", i+1, name, count)
+			message += p.Text("scheck.grabber", i18n.Args{"Rank": i + 1, "Name": name, "Count": grabber.Count}) + "\n"
 		}
 	}
 	
@@ -178,13 +143,13 @@ func (b *Bot) cmdSCheck(msg *tgbotapi.Message) {
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonSwitch(
-				utils.ToSmallCaps("📋 See Collection"),
+				p.Plain("scheck.collection", nil),
 				fmt.Sprintf("collection.%d", msg.From.ID),
 			),
 		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(
-				"❌ "+utils.ToSmallCaps("Close"),
+				p.Plain("button.close", nil),
 				"close_check",
 			),
 		),
@@ -195,11 +160,11 @@ func (b *Bot) cmdSCheck(msg *tgbotapi.Message) {
 		photo.Caption = message
 		photo.ParseMode = "HTML"
 		photo.ReplyMarkup = keyboard
-		b.API.Send(photo)
+		b.send(photo)
 	} else {
 		reply := tgbotapi.NewMessage(chatID, message)
 		reply.ParseMode = "HTML"
 		reply.ReplyMarkup = keyboard
-		b.API.Send(reply)
+		b.send(reply)
 	}
 }
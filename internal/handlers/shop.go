@@ -1,38 +1,45 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
-	"strconv"
 	"strings"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/i18n"
 	"senpai-waifu-bot/internal/models"
-	"senpai-waifu-bot/internal/utils"
+	"senpai-waifu-bot/internal/services"
 )
 
 // cmdShop handles /shop command
 func (b *Bot) cmdShop(msg *tgbotapi.Message) {
+	log := b.msgLogger(msg)
 	userID := msg.From.ID
 	
 	// Get or create shop data
 	shopData, err := b.UserService.GetShopData(userID)
+	logError(log, ignoreNotFound(err), "get shop")
 	if err != nil || shopData == nil || len(shopData.Characters) == 0 {
 		// Initialize new shop
 		shopData, err = b.CharacterService.InitializeShop()
 		if err != nil {
-			reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("⚠️ Shop is empty! Please try again later."))
-			b.API.Send(reply)
+			logError(log, err, "initialize shop")
+			b.reply(b.msgPrinter(msg), msg.Chat.ID, "shop.unavailable", nil)
 			return
 		}
-		_ = b.UserService.UpdateShopData(userID, shopData)
+		logError(log, b.UserService.UpdateShopData(userID, shopData), "store shop")
 	}
 	
 	// Check if shop needs reset (24 hours)
 	if time.Since(shopData.LastReset) > 24*time.Hour {
-		shopData, _ = b.CharacterService.InitializeShop()
-		_ = b.UserService.UpdateShopData(userID, shopData)
+		if shopData, err = b.CharacterService.InitializeShop(); err != nil {
+			logError(log, err, "reset shop")
+		} else {
+			logError(log, b.UserService.UpdateShopData(userID, shopData), "store shop")
+		}
 	}
 	
 	b.displayShopCharacter(msg.Chat.ID, userID, 0, msg.MessageID)
@@ -40,10 +47,12 @@ func (b *Bot) cmdShop(msg *tgbotapi.Message) {
 
 // displayShopCharacter displays a shop character
 func (b *Bot) displayShopCharacter(chatID int64, userID int64, index int, replyTo int) {
-	shopData, _ := b.UserService.GetShopData(userID)
+	log := b.Log.With(zap.Int64("chat_id", chatID), zap.Int64("user_id", userID))
+	p := b.userPrinter(chatID, userID)
+	shopData, err := b.UserService.GetShopData(userID)
+	logError(log, ignoreNotFound(err), "get shop")
 	if shopData == nil || len(shopData.Characters) == 0 {
-		reply := tgbotapi.NewMessage(chatID, utils.ToSmallCaps("⚠️ Shop is empty!"))
-		b.API.Send(reply)
+		b.reply(p, chatID, "shop.empty", nil)
 		return
 	}
 	
@@ -56,46 +65,32 @@ func (b *Bot) displayShopCharacter(chatID int64, userID int64, index int, replyT
 	
 	// Update current index
 	shopData.CurrentIndex = index
-	_ = b.UserService.UpdateShopData(userID, shopData)
+	logError(log, b.UserService.UpdateShopData(userID, shopData), "store shop")
 	
 	char := shopData.Characters[index]
 	
 	// Check if user already owns this character
-	owned, _ := b.UserService.HasCharacter(userID, char.ID)
-	status := "Available"
-	if owned {
-		status = "Sold"
-	}
+	owned, err := b.UserService.HasCharacter(userID, char.ID)
+	logError(log, err, "check character ownership", zap.String("character_id", char.ID))
 	
 	// Get owner count
-	ownerCount, _ := b.CharacterService.GetCharacterOwnerCount(char.ID)
+	ownerCount, err := b.CharacterService.GetCharacterOwnerCount(char.ID)
+	logError(log, err, "count character owners", zap.String("character_id", char.ID))
 	
 	// Build message
-	rarityEmoji := utils.RarityEmojis[char.Rarity]
-	rarityName := utils.RarityNames[char.Rarity]
-	
-	message := fmt.Sprintf(
-		"<b>🏪 %s</b>\n\n"+
-			"🎭 %s: %s\n"+
-			"📺 %s: %s\n"+
-			"🆔 %s: %s\n"+
-			"✨ %s: %s %s\n"+
-			"💸 %s: %s\n"+
-			"🛒 %s: %d%%\n"+
-			"🏷️ %s: %s\n"+
-			"🎴 %s: %d\n"+
-			"📋 %s: %s",
-		utils.ToSmallCaps(fmt.Sprintf("Character Shop (%d/%d)", index+1, len(shopData.Characters))),
-		utils.ToSmallCaps("Name"), utils.ToSmallCaps(char.Name),
-		utils.ToSmallCaps("Anime"), utils.ToSmallCaps(char.Anime),
-		utils.ToSmallCaps("Id"), char.ID,
-		utils.ToSmallCaps("Rarity"), rarityEmoji, rarityName,
-		utils.ToSmallCaps("Price"), utils.FormatNumber(char.BasePrice),
-		utils.ToSmallCaps("Discount"), char.DiscountPercent,
-		utils.ToSmallCaps("Discount Price"), utils.FormatNumber(char.FinalPrice),
-		utils.ToSmallCaps("Owner"), ownerCount,
-		utils.ToSmallCaps("Stats"), utils.ToSmallCaps(status),
-	)
+	message := p.Text("shop.character", i18n.Args{
+		"Index":         index + 1,
+		"Count":         len(shopData.Characters),
+		"Name":          char.Name,
+		"Anime":         char.Anime,
+		"ID":            char.ID,
+		"Rarity":        char.Rarity,
+		"Price":         char.BasePrice,
+		"Discount":      char.DiscountPercent,
+		"DiscountPrice": char.FinalPrice,
+		"Owners":        ownerCount,
+		"Sold":          owned,
+	})
 	
 	// Build keyboard
 	var keyboardRows [][]tgbotapi.InlineKeyboardButton
@@ -104,14 +99,14 @@ func (b *Bot) displayShopCharacter(chatID int64, userID int64, index int, replyT
 	if !owned {
 		keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(
-				utils.ToSmallCaps("💰 Purchase"),
+				p.Plain("shop.purchase", nil),
 				fmt.Sprintf("shop_purchase:%d:%d", userID, index),
 			),
 		))
 	} else {
 		keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(
-				utils.ToSmallCaps("❌ Already Owned"),
+				p.Plain("shop.owned", nil),
 				"shop_noop",
 			),
 		))
@@ -127,7 +122,7 @@ func (b *Bot) displayShopCharacter(chatID int64, userID int64, index int, replyT
 	}
 	
 	navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData(
-		fmt.Sprintf("🍃 %s", utils.ToSmallCaps("Refresh")),
+		p.Plain("shop.refresh", nil),
 		fmt.Sprintf("shop_refresh:%d", userID),
 	))
 	
@@ -143,7 +138,7 @@ func (b *Bot) displayShopCharacter(chatID int64, userID int64, index int, replyT
 	// Premium shop button
 	keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData(
-			fmt.Sprintf("💸 %s", utils.ToSmallCaps("Premium Shop")),
+			p.Plain("shop.premium", nil),
 			fmt.Sprintf("shop_premium:%d", userID),
 		),
 	))
@@ -151,7 +146,7 @@ func (b *Bot) displayShopCharacter(chatID int64, userID int64, index int, replyT
 	// Close button
 	keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData(
-			utils.ToSmallCaps("❌ Close"),
+			p.Plain("button.close", nil),
 			fmt.Sprintf("shop_close:%d", userID),
 		),
 	))
@@ -164,7 +159,7 @@ func (b *Bot) displayShopCharacter(chatID int64, userID int64, index int, replyT
 		if replyTo > 0 {
 			photo.ReplyToMessageID = replyTo
 		}
-		b.API.Send(photo)
+		b.send(photo)
 	} else {
 		reply := tgbotapi.NewMessage(chatID, message)
 		reply.ParseMode = "HTML"
@@ -172,98 +167,77 @@ func (b *Bot) displayShopCharacter(chatID int64, userID int64, index int, replyT
 		if replyTo > 0 {
 			reply.ReplyToMessageID = replyTo
 		}
-		b.API.Send(reply)
+		b.send(reply)
 	}
 }
 
 // cmdResetShop handles /resetshop command (admin only)
-func (b *Bot) cmdResetShop(msg *tgbotapi.Message) {
-	if !b.Config.IsSudo(msg.From.ID) {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("⚠️ You are not authorized!"))
-		b.API.Send(reply)
-		return
-	}
-	
-	args := strings.Fields(msg.Text)
-	if len(args) < 2 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("Usage: /resetshop <user_id>"))
-		b.API.Send(reply)
-		return
+func (b *Bot) cmdResetShop(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+	targetID := c.Int(0)
+	shopData, err := b.CharacterService.InitializeShop()
+	if err == nil {
+		err = b.UserService.UpdateShopData(targetID, shopData)
 	}
-	
-	targetID, err := strconv.ParseInt(args[1], 10, 64)
 	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("⚠️ Invalid user ID!"))
-		b.API.Send(reply)
+		logError(c.Log, err, "reset shop", zap.Int64("target_id", targetID))
+		b.reply(p, msg.Chat.ID, "resetshop.failed", nil)
 		return
 	}
+	shopIDs := make([]string, len(shopData.Characters))
+	for i, char := range shopData.Characters {
+		shopIDs[i] = char.ID
+	}
+	b.audit(c, models.AuditEntry{
+		TargetUserID: targetID,
+		After:        map[string]interface{}{"shop": strings.Join(shopIDs, ", ")},
+	})
 	
-	shopData, _ := b.CharacterService.InitializeShop()
-	_ = b.UserService.UpdateShopData(targetID, shopData)
-	
-	reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps(fmt.Sprintf("✅ Shop reset successfully for user %d!", targetID)))
-	b.API.Send(reply)
+	b.reply(p, msg.Chat.ID, "resetshop.done", i18n.Args{"UserID": targetID})
 }
 
 // processShopPurchase processes a shop purchase
 func (b *Bot) processShopPurchase(chatID int64, userID int64, index int) {
-	shopData, _ := b.UserService.GetShopData(userID)
+	log := b.Log.With(zap.Int64("chat_id", chatID), zap.Int64("user_id", userID))
+	p := b.userPrinter(chatID, userID)
+	shopData, err := b.UserService.GetShopData(userID)
+	logError(log, ignoreNotFound(err), "get shop")
 	if shopData == nil || index >= len(shopData.Characters) {
 		return
 	}
 	
 	char := shopData.Characters[index]
 	
-	// Check if already owned
-	owned, _ := b.UserService.HasCharacter(userID, char.ID)
-	if owned {
-		b.API.Send(tgbotapi.NewMessage(chatID, utils.ToSmallCaps("⚠️ You already own this character!")))
-		return
-	}
-	
-	// Check balance
-	balance, _ := b.UserService.GetUserBalance(userID)
-	if balance < char.FinalPrice {
-		b.API.Send(tgbotapi.NewMessage(chatID, utils.ToSmallCaps(fmt.Sprintf("⚠️ Insufficient balance! Need %s coins", utils.FormatNumber(char.FinalPrice)))))
-		return
-	}
-	
 	// Get full character data
 	fullChar, err := b.CharacterService.GetCharacterByID(char.ID)
 	if err != nil {
-		b.API.Send(tgbotapi.NewMessage(chatID, utils.ToSmallCaps("⚠️ Character not found in database!")))
+		b.reply(p, chatID, "shop.character_missing", nil)
 		return
 	}
 	
-	// Deduct balance
-	newBalance, _ := b.UserService.UpdateUserBalance(userID, -char.FinalPrice)
-	
-	// Add character
-	userChar := models.UserCharacter{
-		ID:     fullChar.ID,
-		Name:   fullChar.Name,
-		Anime:  fullChar.Anime,
-		Rarity: fullChar.Rarity,
-		ImgURL: fullChar.ImgURL,
+	// Deduct balance and add character in one step
+	newBalance, err := b.TransferService.Purchase(userID, b.CharacterService.ToUserCharacter(fullChar), char.FinalPrice)
+	switch {
+	case errors.Is(err, services.ErrAlreadyOwned):
+		b.reply(p, chatID, "shop.already_owned", nil)
+		return
+	case errors.Is(err, services.ErrInsufficientFunds):
+		b.reply(p, chatID, "shop.insufficient", i18n.Args{"Price": char.FinalPrice})
+		return
+	case err != nil:
+		logError(log, err, "purchase", zap.String("character_id", char.ID))
+		b.reply(p, chatID, "shop.purchase_failed", nil)
+		return
 	}
-	_ = b.UserService.AddCharacterToUser(userID, userChar)
 	
 	// Send success message
-	successMsg := fmt.Sprintf(
-		"<b>✅ %s</b>\n\n"+
-			"🎉 %s: %s\n"+
-			"💸 %s: %s\n"+
-			"💰 %s: %s",
-		utils.ToSmallCaps("Purchase Successful!"),
-		utils.ToSmallCaps("You got"), utils.ToSmallCaps(char.Name),
-		utils.ToSmallCaps("Price"), utils.FormatNumber(char.FinalPrice),
-		utils.ToSmallCaps("New Balance"), utils.FormatNumber(newBalance),
-	)
+	successMsg := p.Text("shop.purchased", i18n.Args{"Name": char.Name, "Price": char.FinalPrice, "Balance": newBalance})
 	
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(
-				fmt.Sprintf("⬅️ %s", utils.ToSmallCaps("Back to Shop")),
+				p.Plain("shop.back", nil),
 				fmt.Sprintf("shop_nav:%d:%d", userID, index),
 			),
 		),
@@ -272,64 +246,63 @@ func (b *Bot) processShopPurchase(chatID int64, userID int64, index int) {
 	reply := tgbotapi.NewMessage(chatID, successMsg)
 	reply.ParseMode = "HTML"
 	reply.ReplyMarkup = keyboard
-	b.API.Send(reply)
+	b.send(reply)
 }
 
 // refreshShop refreshes the shop for a user
 func (b *Bot) refreshShop(chatID int64, userID int64) {
-	shopData, _ := b.UserService.GetShopData(userID)
+	log := b.Log.With(zap.Int64("chat_id", chatID), zap.Int64("user_id", userID))
+	p := b.userPrinter(chatID, userID)
+	shopData, err := b.UserService.GetShopData(userID)
+	logError(log, ignoreNotFound(err), "get shop")
 	if shopData != nil && shopData.RefreshUsed {
-		b.API.Send(tgbotapi.NewMessage(chatID, utils.ToSmallCaps("⚠️ You have reached daily limit of 1 refresh!")))
+		b.reply(p, chatID, "shop.refresh_limit", nil)
 		return
 	}
 	
-	// Check balance
-	balance, _ := b.UserService.GetUserBalance(userID)
-	refreshCost := int64(20000)
-	if balance < refreshCost {
-		b.API.Send(tgbotapi.NewMessage(chatID, utils.ToSmallCaps(fmt.Sprintf("⚠️ Insufficient balance! Need %s coins", utils.FormatNumber(refreshCost)))))
+	// Deduct refresh cost
+	refreshCost := b.Config.Economy.ShopRefreshCost
+	if _, err := b.TransferService.Charge(userID, refreshCost, services.LedgerSource{Source: services.SourceShopRefresh}); err != nil {
+		if !errors.Is(err, services.ErrInsufficientFunds) {
+			logError(log, err, "charge shop refresh")
+		}
+		b.reply(p, chatID, "shop.insufficient", i18n.Args{"Price": refreshCost})
 		return
 	}
 	
-	// Deduct refresh cost
-	_, _ = b.UserService.UpdateUserBalance(userID, -refreshCost)
-	
 	// Generate new shop
-	newShopData, _ := b.CharacterService.RefreshShop()
+	newShopData, err := b.CharacterService.RefreshShop()
+	if err != nil {
+		logError(log, err, "refresh shop")
+		b.reply(p, chatID, "shop.unavailable", nil)
+		return
+	}
 	newShopData.RefreshUsed = true
-	_ = b.UserService.UpdateShopData(userID, newShopData)
+	logError(log, b.UserService.UpdateShopData(userID, newShopData), "store shop")
 	
-	b.API.Send(tgbotapi.NewMessage(chatID, utils.ToSmallCaps(fmt.Sprintf("✅ Shop refreshed! Cost: %s coins", utils.FormatNumber(refreshCost)))))
+	b.reply(p, chatID, "shop.refreshed", i18n.Args{"Cost": refreshCost})
 	b.displayShopCharacter(chatID, userID, 0, 0)
 }
 
 // cmdSClaim handles /sclaim command
 func (b *Bot) cmdSClaim(msg *tgbotapi.Message) {
+	log := b.msgLogger(msg)
+	p := b.msgPrinter(msg)
 	userID := msg.From.ID
 	
 	// Check cooldown
 	canClaim, remaining, _ := b.UserService.CanSClaim(userID)
 	if !canClaim {
-		hours := int(remaining.Hours())
-		minutes := int(remaining.Minutes()) % 60
-		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf(
-			"<b>⏰ %s</b>\n\n⏳ %s <b>%dh %dm</b>\n\n💡 %s",
-			utils.ToSmallCaps("COOLDOWN ACTIVE"),
-			utils.ToSmallCaps("You can use /sclaim again in:"),
-			hours, minutes,
-			utils.ToSmallCaps("Come back later!"),
-		))
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "claim.cooldown", claimCooldownArgs("sclaim", remaining))
 		return
 	}
 	
 	// Get random character from allowed rarities (2, 3, 4)
 	allowedRarities := []int{2, 3, 4}
-	chars, _ := b.CharacterService.GetRandomCharactersByRarities(allowedRarities, 1)
+	chars, err := b.CharacterService.GetRandomCharactersByRarities(allowedRarities, 1)
+	logError(log, err, "pick sclaim character")
 	if len(chars) == 0 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, utils.ToSmallCaps("❌ No characters available at the moment!"))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "sclaim.none", nil)
 		return
 	}
 	
@@ -343,169 +316,107 @@ func (b *Bot) cmdSClaim(msg *tgbotapi.Message) {
 		Rarity: char.Rarity,
 		ImgURL: char.ImgURL,
 	}
-	_ = b.UserService.AddCharacterToUser(userID, userChar)
+	if err := b.UserService.AddCharacterToUser(userID, userChar, services.LedgerSource{Source: services.SourceSClaim}); err != nil {
+		logError(log, err, "add sclaim character", zap.String("character_id", char.ID))
+		b.reply(p, msg.Chat.ID, "sclaim.failed", nil)
+		return
+	}
 	
 	// Update last claim time
-	_ = b.UserService.UpdateLastSClaim(userID)
+	logError(log, b.UserService.UpdateLastSClaim(userID), "update last sclaim")
 	
 	// Send message
-	rarityDisplay := utils.GetRarityDisplay(char.Rarity)
-	message := fmt.Sprintf(
-		"<b>🎉 %s</b>\n\n"+
-			"🎴 <b>%s</b> %s\n"+
-			"📺 <b>%s</b> %s\n"+
-			"⭐ <b>%s</b> %s\n"+
-			"🆔 <b>%s</b> %s\n\n"+
-			"✅ %s",
-		utils.ToSmallCaps("CONGRATULATIONS!"),
-		utils.ToSmallCaps("Character:"), char.Name,
-		utils.ToSmallCaps("Anime:"), char.Anime,
-		utils.ToSmallCaps("Rarity:"), rarityDisplay,
-		utils.ToSmallCaps("ID:"), char.ID,
-		utils.ToSmallCaps("Character has been added to your collection!"),
-	)
+	message := p.Text("sclaim.done", i18n.Args{"Name": char.Name, "Anime": char.Anime, "Rarity": char.Rarity, "ID": char.ID})
 	
 	if char.ImgURL != "" {
 		photo := tgbotapi.NewPhoto(msg.Chat.ID, tgbotapi.FileURL(char.ImgURL))
 		photo.Caption = message
 		photo.ParseMode = "HTML"
-		b.API.Send(photo)
+		b.send(photo)
 	} else {
 		reply := tgbotapi.NewMessage(msg.Chat.ID, message)
 		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+		b.send(reply)
 	}
 }
 
 // cmdClaim handles /claim command (daily coin code)
 func (b *Bot) cmdClaim(msg *tgbotapi.Message) {
+	log := b.msgLogger(msg)
+	p := b.msgPrinter(msg)
 	userID := msg.From.ID
 	
 	// Check cooldown
 	canClaim, remaining, _ := b.UserService.CanClaim(userID)
 	if !canClaim {
-		hours := int(remaining.Hours())
-		minutes := int(remaining.Minutes()) % 60
-		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf(
-			"<b>⏰ %s</b>\n\n⏳ %s <b>%dh %dm</b>\n\n💡 %s",
-			utils.ToSmallCaps("COOLDOWN ACTIVE"),
-			utils.ToSmallCaps("You can use /claim again in:"),
-			hours, minutes,
-			utils.ToSmallCaps("Come back later!"),
-		))
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "claim.cooldown", claimCooldownArgs("claim", remaining))
 		return
 	}
 	
 	// Generate coin amount and code
-	coinAmount := rand.Int63n(2001) + 1000 // 1000-3000
-	code, _ := b.ClaimCodeService.CreateClaimCode(userID, coinAmount)
+	economy := b.Config.Economy
+	coinAmount := rand.Int63n(economy.ClaimMax-economy.ClaimMin+1) + economy.ClaimMin
+	code, err := b.ClaimCodeService.CreateClaimCode(userID, coinAmount)
+	if err != nil {
+		logError(log, err, "create claim code")
+		b.reply(p, msg.Chat.ID, "claim.failed", nil)
+		return
+	}
 	
 	// Update last claim time
-	_ = b.UserService.UpdateLastClaim(userID)
+	logError(log, b.UserService.UpdateLastClaim(userID), "update last claim")
 	
 	// Send message
-	message := fmt.Sprintf(
-		"<b>💰 %s</b>\n\n"+
-			"🎟️ <b>%s</b> <code>%s</code>\n"+
-			"💎 <b>%s</b> %s %s\n\n"+
-			"📌 %s <code>/credeem %s</code> %s\n"+
-			"⏰ %s",
-		utils.ToSmallCaps("COIN CODE GENERATED!"),
-		utils.ToSmallCaps("Your Code:"), code,
-		utils.ToSmallCaps("Amount:"), utils.FormatNumber(coinAmount), utils.ToSmallCaps("coins"),
-		utils.ToSmallCaps("Use"), code, utils.ToSmallCaps("to claim your coins!"),
-		utils.ToSmallCaps("Valid for 24 hours"),
-	)
-	
-	reply := tgbotapi.NewMessage(msg.Chat.ID, message)
-	reply.ParseMode = "HTML"
-	b.API.Send(reply)
+	b.reply(p, msg.Chat.ID, "claim.done", i18n.Args{"Code": code, "Amount": coinAmount})
 }
 
 // cmdCRedeem handles /credeem command
-func (b *Bot) cmdCRedeem(msg *tgbotapi.Message) {
+func (b *Bot) cmdCRedeem(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
 	userID := msg.From.ID
-	args := strings.Fields(msg.Text)
-	
-	if len(args) < 2 {
-		message := fmt.Sprintf(
-			"<b>🎁 %s</b>\n\n📝 %s <code>/credeem &lt;CODE&gt;</code>\n\n💡 %s",
-			utils.ToSmallCaps("REDEEM CODE"),
-			utils.ToSmallCaps("Usage:"),
-			utils.ToSmallCaps("Redeem your coin codes to add coins to your balance!"),
-		)
-		reply := tgbotapi.NewMessage(msg.Chat.ID, message)
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
-		return
-	}
-	
-	code := strings.ToUpper(args[1])
+	code := strings.ToUpper(c.Arg(0))
 	
 	// Get claim code
 	claimCode, err := b.ClaimCodeService.GetClaimCode(code)
 	if err != nil || claimCode.UserID != userID {
-		message := fmt.Sprintf(
-			"<b>❌ %s</b>\n\n⚠️ %s\n\n💡 %s",
-			utils.ToSmallCaps("INVALID CODE"),
-			utils.ToSmallCaps("This code does not exist or does not belong to you."),
-			utils.ToSmallCaps("Use /claim to generate a new code!"),
-		)
-		reply := tgbotapi.NewMessage(msg.Chat.ID, message)
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "credeem.invalid", nil)
 		return
 	}
 	
 	// Check if already redeemed
 	if claimCode.IsRedeemed {
-		message := fmt.Sprintf(
-			"<b>❌ %s</b>\n\n⚠️ %s\n\n💡 %s",
-			utils.ToSmallCaps("CODE ALREADY REDEEMED"),
-			utils.ToSmallCaps("This code has already been used."),
-			utils.ToSmallCaps("Use /claim to generate a new code!"),
-		)
-		reply := tgbotapi.NewMessage(msg.Chat.ID, message)
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "credeem.already_redeemed", nil)
 		return
 	}
 	
 	// Check if expired
 	if b.ClaimCodeService.IsClaimCodeExpired(claimCode) {
-		message := fmt.Sprintf(
-			"<b>❌ %s</b>\n\n⚠️ %s\n\n💡 %s",
-			utils.ToSmallCaps("CODE EXPIRED"),
-			utils.ToSmallCaps("This code has expired (24 hours limit)."),
-			utils.ToSmallCaps("Use /claim to generate a new code!"),
-		)
-		reply := tgbotapi.NewMessage(msg.Chat.ID, message)
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "credeem.expired", nil)
 		return
 	}
 	
-	// Redeem code
-	_, _ = b.ClaimCodeService.RedeemClaimCode(code, userID)
+	// Redeem code; this fails if the code was redeemed in the meantime
+	if _, err := b.ClaimCodeService.RedeemClaimCode(code, userID); err != nil {
+		logError(c.Log, ignoreNotFound(err), "redeem claim code", zap.String("code", code))
+		b.reply(p, msg.Chat.ID, "credeem.used", nil)
+		return
+	}
 	
 	// Add coins
-	newBalance, _ := b.UserService.UpdateUserBalance(userID, claimCode.Amount)
+	newBalance, err := b.UserService.UpdateUserBalance(userID, claimCode.Amount,
+		services.LedgerSource{Source: services.SourceClaimCode, Reference: code})
+	logError(c.Log, err, "add claim code coins", zap.String("code", code))
 	
 	// Send success message
-	message := fmt.Sprintf(
-		"<b>✅ %s</b>\n\n"+
-			"💰 <b>%s</b> %s\n"+
-			"💎 <b>%s</b> %s %s\n\n"+
-			"🎉 %s",
-		utils.ToSmallCaps("CODE REDEEMED SUCCESSFULLY!"),
-		utils.ToSmallCaps("Coins Added:"), utils.FormatNumber(claimCode.Amount),
-		utils.ToSmallCaps("New Balance:"), utils.FormatNumber(newBalance), utils.ToSmallCaps("coins"),
-		utils.ToSmallCaps("Enjoy your coins!"),
-	)
-	
-	reply := tgbotapi.NewMessage(msg.Chat.ID, message)
-	reply.ParseMode = "HTML"
-	b.API.Send(reply)
+	b.reply(p, msg.Chat.ID, "credeem.done", i18n.Args{"Amount": claimCode.Amount, "Balance": newBalance})
+}
+
+// claimCooldownArgs returns the arguments of claim.cooldown for command with remaining left
+func claimCooldownArgs(command string, remaining time.Duration) i18n.Args {
+	return i18n.Args{
+		"Command": command,
+		"Hours":   int(remaining.Hours()),
+		"Minutes": int(remaining.Minutes()) % 60,
+	}
 }
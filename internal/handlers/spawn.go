@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"strconv"
@@ -8,10 +9,16 @@ import (
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/imaging"
+	"senpai-waifu-bot/internal/metrics"
 	"senpai-waifu-bot/internal/models"
-	"senpai-waifu-bot/internal/utils"
 )
 
+// spawnIntros is the number of spawn.intro.N messages in the catalog
+const spawnIntros = 5
+
 // handleMessageCounter handles message counting for character spawning
 func (b *Bot) handleMessageCounter(msg *tgbotapi.Message) {
 	chatID := msg.Chat.ID
@@ -22,59 +29,62 @@ func (b *Bot) handleMessageCounter(msg *tgbotapi.Message) {
 		return
 	}
 	
-	// Check if user is the same as last
-	if lastUser, ok := b.LastUser[chatID]; ok && lastUser.UserID == userID {
-		lastUser.Count++
-	} else {
-		b.LastUser[chatID] = &LastUserInfo{UserID: userID, Count: 1}
-	}
+	// Serialize counting and spawning with guesses in the same chat
+	unlock := b.ChatLocks.Lock(chatID)
+	defer unlock()
 	
-	// Check if user has sent enough messages
-	if lastUser, ok := b.LastUser[chatID]; ok && lastUser.Count >= 5 {
-		// Reset count
-		lastUser.Count = 0
-		
+	// Every message passes through here, so its logs are sampled
+	log := b.counterLog.With(zap.Int64("chat_id", chatID), zap.Int64("user_id", userID))
+	
+	// Every message feeds the message rate; spawns that are due by time are left to the scheduler
+	now := time.Now()
+	b.Chats.RecordActivity(chatID, now)
+	
+	// Check if user has sent enough consecutive messages
+	if b.Chats.RecordMessage(chatID, userID, 5) {
 		// Increment message counter
-		b.MessageCounters[chatID]++
-		
-		// Get message frequency for this chat
-		freq, _ := b.GroupService.GetMessageFrequency(chatID)
-		if freq == 0 {
-			freq = 100
+		count, err := b.StateService.IncrementMessageCounter(chatID)
+		if err != nil {
+			logError(log, err, "increment message counter")
+			return
 		}
 		
+		schedule := b.spawnSchedule(chatID)
+		log.Debug("message counted", zap.Int("count", count), zap.Int("frequency", schedule.Frequency), zap.String("spawn_mode", schedule.Mode))
+		
 		// Check if it's time to spawn
-		if b.MessageCounters[chatID] >= freq {
-			// Reset counter
-			b.MessageCounters[chatID] = 0
-			
-			// Spawn character
-			b.spawnCharacter(chatID)
+		if schedule.Mode == models.SpawnModeMessages && schedule.Due(b.spawnActivity(chatID, now), count, now) {
+			b.scheduledSpawn(chatID, log)
 		}
 	}
 }
 
-// spawnCharacter spawns a character in the chat
+// spawnCharacter picks a character to spawn in the chat and posts it in the background, so
+// that preparing its image holds up neither the chat nor the spawn scheduler. Caller must
+// hold the chat lock.
 func (b *Bot) spawnCharacter(chatID int64) {
-	// Get disabled rarities for this chat
-	disabledRarities, _ := b.RarityService.GetDisabledRarities(chatID)
+	log := b.Log.With(zap.Int64("chat_id", chatID))
 	
 	// Get locked character IDs
-	lockedIDs, _ := b.RarityService.GetLockedCharacterIDs()
+	lockedIDs, err := b.RarityService.GetLockedCharacterIDs()
+	logError(log, err, "get locked characters")
 	
-	// Get random character
-	char, err := b.CharacterService.GetRandomCharacter(disabledRarities, lockedIDs)
+	// Pick a rarity from the chat's drop table, then one of its characters
+	weights := b.Config.Live().Spawn.RarityWeights
+	char, err := b.DropService.Pick(chatID, weights, lockedIDs)
 	if err != nil || char == nil {
+		logError(log, err, "pick a character to spawn")
 		return
 	}
 	
 	// Check if this character was recently sent to this chat
-	if sent, ok := b.SentCharacters[chatID]; ok {
+	if sent := b.Chats.RecentSpawns(chatID); len(sent) > 0 {
 		for _, id := range sent {
 			if id == char.ID {
 				// Try again with a different character
-				char, _ = b.CharacterService.GetRandomCharacter(disabledRarities, append(lockedIDs, char.ID))
-				if char == nil {
+				char, err = b.DropService.Pick(chatID, weights, append(lockedIDs, char.ID))
+				if err != nil || char == nil {
+					logError(log, err, "pick a character to spawn")
 					return
 				}
 				break
@@ -82,99 +92,147 @@ func (b *Bot) spawnCharacter(chatID int64) {
 		}
 	}
 	
-	// Track sent character
-	b.SentCharacters[chatID] = append(b.SentCharacters[chatID], char.ID)
-	if len(b.SentCharacters[chatID]) > 10 {
-		b.SentCharacters[chatID] = b.SentCharacters[chatID][1:]
-	}
+	// Track sent character; the spawn counts from now, so that it is not due again while
+	// its image is prepared
+	b.Chats.RememberSpawn(chatID, char.ID, 10)
+	b.Chats.SetLastSpawn(chatID, time.Now())
+	
+	// Tracked with the background routines, so that shutdown waits for the spawn to be posted
+	b.background.Add(1)
+	go func() {
+		defer b.background.Done()
+		b.postSpawn(chatID, char, log)
+	}()
+}
+
+// postSpawn stores char as the active spawn of chatID and announces it with its disguised
+// image
+func (b *Bot) postSpawn(chatID int64, char *models.Character, log *zap.Logger) {
+	// Disguise the image before taking the chat lock, as downloading it may take a while
+	image, hasImage := b.spawnImage(char, log)
 	
-	// Store as last character
-	b.LastCharacters[chatID] = &LastCharInfo{
-		CharacterID: char.ID,
-		Name:        char.Name,
-		Anime:       char.Anime,
-		Rarity:      char.Rarity,
-		ImgURL:      char.ImgURL,
+	unlock := b.ChatLocks.Lock(chatID)
+	defer unlock()
+	
+	// Store as the active spawn, which also clears the first correct guess
+	err := b.StateService.SetActiveSpawn(chatID, models.UserCharacter{
+		ID:     char.ID,
+		Name:   char.Name,
+		Anime:  char.Anime,
+		Rarity: char.Rarity,
+		ImgURL: char.ImgURL,
+	}, b.spawnSchedule(chatID).Expiry)
+	if err != nil {
+		logError(log, err, "store spawn", zap.String("character_id", char.ID))
+		return
 	}
 	
-	// Clear first correct guess
-	delete(b.FirstCorrectGuesses, chatID)
+	log.Info("character spawned", zap.String("character_id", char.ID))
+	metrics.SpawnsTotal.WithLabelValues(strconv.Itoa(char.Rarity)).Inc()
 	
-	// Build spawn message
-	rarityDisplay := utils.GetRarityDisplay(char.Rarity)
+	// Build spawn message with one of the intros at random; the name is left for /guess
+	p := b.printer(chatID, nil)
+	intro := p.Text(fmt.Sprintf("spawn.intro.%d", rand.Intn(spawnIntros)+1), nil)
+	message := p.Text("spawn.announce", i18n.Args{
+		"Intro":  i18n.HTML(intro),
+		"Rarity": char.Rarity,
+	})
 	
-	// Get random message
-	spawnMessages := []string{
-		"A new character appeared!",
-		"Look who just showed up!",
-		"A wild character appeared!",
-		"Guess who's here!",
-		"A character has spawned!",
-	}
-	spawnMsg := spawnMessages[rand.Intn(len(spawnMessages))]
-	
-	message := fmt.Sprintf(
-		"<b>%s</b>\n\n"+
-			"🎭 <b>%s</b> %s\n"+
-			"📺 <b>%s</b> %s\n"+
-			"⭐ <b>%s</b> %s\n"+
-			"🆔 <b>%s</b> <code>%s</code>\n\n"+
-			"📝 %s",
-		utils.ToSmallCaps(spawnMsg),
-		utils.ToSmallCaps("Name:"), utils.ToSmallCaps(char.Name),
-		utils.ToSmallCaps("Anime:"), utils.ToSmallCaps(char.Anime),
-		utils.ToSmallCaps("Rarity:"), rarityDisplay,
-		utils.ToSmallCaps("ID:"), char.ID,
-		utils.ToSmallCaps("Use /guess <name> to catch this character!"),
-	)
-	
-	// Send character image
-	if char.ImgURL != "" {
-		photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileURL(char.ImgURL))
+	// Send the disguised character image
+	var sent tgbotapi.Message
+	if hasImage {
+		photo := tgbotapi.NewPhoto(chatID, image)
 		photo.Caption = message
 		photo.ParseMode = "HTML"
-		b.API.Send(photo)
+		sent, err = b.send(photo)
 	} else {
 		reply := tgbotapi.NewMessage(chatID, message)
 		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+		sent, err = b.send(reply)
 	}
+	
+	// Remember the announcement, so that the character fleeing can reply to it
+	if err == nil {
+		logError(log, b.StateService.SetSpawnMessage(chatID, sent.MessageID), "store spawn message")
+	}
+}
+
+// spawnImage returns the image to post for a spawn of char: its image disguised in the
+// configured style, or the original when disguising is off. It returns false when the
+// character has no image or it could not be disguised, so that the spawn goes without one
+// rather than giving the character away.
+func (b *Bot) spawnImage(char *models.Character, log *zap.Logger) (tgbotapi.RequestFileData, bool) {
+	if char.ImgURL == "" {
+		return nil, false
+	}
+	if b.Images.Style() == imaging.StyleOff {
+		return tgbotapi.FileURL(char.ImgURL), true
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), b.Config.Images.Timeout)
+	defer cancel()
+	data, cached, err := b.Images.Disguise(ctx, char.ImgURL)
+	switch {
+	case data == nil:
+		metrics.SpawnImagesTotal.WithLabelValues("failed").Inc()
+		log.Warn("failed to disguise spawn image", zap.String("character_id", char.ID), zap.String("img_url", char.ImgURL), zap.Error(err))
+		return nil, false
+	case cached:
+		metrics.SpawnImagesTotal.WithLabelValues("cached").Inc()
+	default:
+		metrics.SpawnImagesTotal.WithLabelValues("generated").Inc()
+		logError(log, err, "cache spawn image", zap.String("character_id", char.ID))
+	}
+	return tgbotapi.FileBytes{Name: "spawn.jpg", Bytes: data}, true
+}
+
+// sendCharacter sends text as the caption of the original character image at imgURL,
+// revealing the character, or as a message when there is no image or Telegram cannot fetch
+// it. base addresses the message.
+func (b *Bot) sendCharacter(base tgbotapi.BaseChat, imgURL string, text string) (tgbotapi.Message, error) {
+	if imgURL != "" {
+		photo := tgbotapi.NewPhoto(base.ChatID, tgbotapi.FileURL(imgURL))
+		photo.BaseChat = base
+		photo.Caption = text
+		photo.ParseMode = "HTML"
+		if sent, err := b.send(photo); err == nil {
+			return sent, nil
+		}
+	}
+	
+	msg := tgbotapi.NewMessage(base.ChatID, text)
+	msg.BaseChat = base
+	msg.ParseMode = "HTML"
+	return b.send(msg)
 }
 
 // handleChatMemberUpdate handles bot being added/removed from groups
-func (b *Bot) handleChatMemberUpdate(update *tgbotapi.ChatMemberUpdated) {
-	if update.NewChatMember.User.ID == b.API.Self.ID {
+func (b *Bot) handleChatMemberUpdate(update *tgbotapi.ChatMemberUpdated, log *zap.Logger) {
+	if update.NewChatMember.User.ID == b.Me.ID {
 		if update.NewChatMember.Status == "member" || update.NewChatMember.Status == "administrator" {
 			// Bot was added to group
-			welcomeMsg := fmt.Sprintf(
-				"<b>🎉 %s</b>\n\n"+
-					"%s\n\n"+
-					"📌 %s <code>/guess &lt;name&gt;</code>\n"+
-					"📌 %s <code>/collection</code>\n"+
-					"📌 %s <code>/help</code>",
-				utils.ToSmallCaps("Thanks for adding me!"),
-				utils.ToSmallCaps("I'll spawn characters after every 100 messages."),
-				utils.ToSmallCaps("Use"),
-				utils.ToSmallCaps("View your"),
-				utils.ToSmallCaps("For more commands, use"),
-			)
-			reply := tgbotapi.NewMessage(update.Chat.ID, welcomeMsg)
-			reply.ParseMode = "HTML"
-			b.API.Send(reply)
+			freq, err := b.GroupService.GetMessageFrequency(update.Chat.ID)
+			logError(log, err, "get message frequency")
+			if err != nil || freq == 0 {
+				freq = b.Config.Live().Spawn.DefaultFrequency
+			}
+			b.reply(b.printer(update.Chat.ID, &update.From), update.Chat.ID, "group.welcome", i18n.Args{"Frequency": freq})
 		}
 	}
 }
 
 // handleCallback handles inline keyboard callbacks
-func (b *Bot) handleCallback(query *tgbotapi.CallbackQuery) {
+func (b *Bot) handleCallback(query *tgbotapi.CallbackQuery, log *zap.Logger) {
 	data := query.Data
 	userID := query.From.ID
 	chatID := query.Message.Chat.ID
 	messageID := query.Message.MessageID
 	
-	// Answer the callback
-	callback := tgbotapi.NewCallback(query.ID, "")
-	b.API.Request(callback)
+	// Answer the callback; payment callbacks answer it themselves so they can raise alerts
+	if !strings.HasPrefix(data, "pay_") {
+		callback := tgbotapi.NewCallback(query.ID, "")
+		b.request(callback)
+	}
 	
 	// Handle different callback types
 	switch {
@@ -185,7 +243,18 @@ func (b *Bot) handleCallback(query *tgbotapi.CallbackQuery) {
 			page, _ := strconv.Atoi(parts[1])
 			ownerID, _ := strconv.ParseInt(parts[2], 10, 64)
 			if userID == ownerID {
-				b.showHaremPage(chatID, messageID, ownerID, page)
+				b.showHaremPage(chatID, messageID, ownerID, page, log)
+			}
+		}
+		
+	case strings.HasPrefix(data, "history:"):
+		// History pagination
+		parts := strings.Split(data, ":")
+		if len(parts) == 3 {
+			page, _ := strconv.Atoi(parts[1])
+			ownerID, _ := strconv.ParseInt(parts[2], 10, 64)
+			if userID == ownerID {
+				b.showHistoryPage(chatID, messageID, ownerID, page)
 			}
 		}
 		
@@ -195,23 +264,23 @@ func (b *Bot) handleCallback(query *tgbotapi.CallbackQuery) {
 		if len(parts) == 2 {
 			ownerID, _ := strconv.ParseInt(parts[1], 10, 64)
 			if userID == ownerID {
-				b.showSMode(chatID, messageID, userID)
+				b.showSMode(chatID, messageID, query.From, log)
 			}
 		}
 		
 	case strings.HasPrefix(data, "smode_"):
 		// Smode selection
 		if data == "smode_all" {
-			_ = b.SortPrefService.SetUserSortPreference(userID, nil)
-			b.showSMode(chatID, messageID, userID)
+			logError(log, b.SortPrefService.SetUserSortPreference(userID, nil), "reset sort preference")
+			b.showSMode(chatID, messageID, query.From, log)
 		} else if data == "smode_cancel" {
 			b.deleteMessage(chatID, messageID)
 		} else {
 			parts := strings.Split(data, "_")
 			if len(parts) == 2 {
 				rarity, _ := strconv.Atoi(parts[1])
-				_ = b.SortPrefService.SetUserSortPreference(userID, &rarity)
-				b.showSMode(chatID, messageID, userID)
+				logError(log, b.SortPrefService.SetUserSortPreference(userID, &rarity), "set sort preference")
+				b.showSMode(chatID, messageID, query.From, log)
 			}
 		}
 		
@@ -264,7 +333,7 @@ func (b *Bot) handleCallback(query *tgbotapi.CallbackQuery) {
 		parts := strings.Split(data, ":")
 		if len(parts) == 2 {
 			token := parts[1]
-			b.confirmPayment(chatID, messageID, token, userID)
+			b.confirmPayment(query.ID, chatID, messageID, token, userID)
 		}
 		
 	case strings.HasPrefix(data, "pay_cancel:"):
@@ -272,7 +341,7 @@ func (b *Bot) handleCallback(query *tgbotapi.CallbackQuery) {
 		parts := strings.Split(data, ":")
 		if len(parts) == 2 {
 			token := parts[1]
-			b.cancelPayment(chatID, messageID, token, userID)
+			b.cancelPayment(query.ID, chatID, messageID, token, userID)
 		}
 		
 	case strings.HasPrefix(data, "accept_trade:"):
@@ -325,7 +394,8 @@ func (b *Bot) handleCallback(query *tgbotapi.CallbackQuery) {
 		if len(parts) == 3 {
 			query := parts[1]
 			page, _ := strconv.Atoi(parts[2])
-			chars, _ := b.CharacterService.SearchCharacters(query)
+			chars, err := b.CharacterService.SearchCharacters(query)
+			logError(b.Log, err, "search characters", zap.String("query", query))
 			b.updateSearchResults(chatID, messageID, chars, query, page)
 		}
 		
@@ -335,14 +405,14 @@ func (b *Bot) handleCallback(query *tgbotapi.CallbackQuery) {
 		
 	case data == "help":
 		// Show help
-		b.showHelp(chatID)
+		b.showHelp(chatID, query.From)
 	}
 }
 
 // showHaremPage shows a specific harem page
-func (b *Bot) showHaremPage(chatID int64, messageID int, userID int64, page int) {
+func (b *Bot) showHaremPage(chatID int64, messageID int, userID int64, page int, log *zap.Logger) {
 	// Get user's sort preference
-	rarityFilter, _ := b.SortPrefService.GetUserSortPreference(userID)
+	rarityFilter := b.sortPreference(userID, log)
 	
 	// Get user data
 	user, err := b.UserService.GetUserByID(userID)
@@ -371,29 +441,31 @@ func (b *Bot) showHaremPage(chatID int64, messageID int, userID int64, page int)
 }
 
 // showSMode shows the smode selection
-func (b *Bot) showSMode(chatID int64, messageID int, userID int64) {
+func (b *Bot) showSMode(chatID int64, messageID int, user *tgbotapi.User, log *zap.Logger) {
+	caption, markup := b.smodeMenu(b.printer(chatID, user), user.ID, log)
+	
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, caption)
+	edit.ParseMode = "HTML"
+	edit.ReplyMarkup = &markup
+	b.send(edit)
+}
+
+// smodeMenu builds the smode caption and keyboard for userID's current preference
+func (b *Bot) smodeMenu(p *i18n.Printer, userID int64, log *zap.Logger) (string, tgbotapi.InlineKeyboardMarkup) {
 	// Get current preference
-	rarityFilter, _ := b.SortPrefService.GetUserSortPreference(userID)
+	rarityFilter := b.sortPreference(userID, log)
 	
-	var currentText string
-	if rarityFilter == nil {
-		currentText = "🍃 " + utils.ToSmallCaps("default")
-	} else {
-		currentText = utils.RarityNames[*rarityFilter]
+	current := p.Text("smode.default", nil)
+	if rarityFilter != nil {
+		current = p.Text(rarityID(*rarityFilter), nil)
 	}
-	
-	caption := fmt.Sprintf(
-		"<b>✨ %s</b>\n\n🎯 %s <b>%s</b>\n",
-		utils.ToSmallCaps("SMODE"),
-		utils.ToSmallCaps("Current Model:"),
-		currentText,
-	)
+	caption := p.Text("smode.menu", i18n.Args{"Current": i18n.HTML(current)})
 	
 	// Build keyboard
 	var keyboardRows [][]tgbotapi.InlineKeyboardButton
 	
 	// All rarities button
-	allText := utils.ToSmallCaps("🍃 default")
+	allText := p.Plain("smode.default", nil)
 	if rarityFilter == nil {
 		allText += " ✓"
 	}
@@ -404,7 +476,7 @@ func (b *Bot) showSMode(chatID int64, messageID int, userID int64) {
 	// Rarity buttons (3 per row)
 	var currentRow []tgbotapi.InlineKeyboardButton
 	for i := 1; i <= 15; i++ {
-		btnText := utils.RarityNames[i]
+		btnText := p.Plain(rarityID(i), nil)
 		if rarityFilter != nil && *rarityFilter == i {
 			btnText += " ✓"
 		}
@@ -424,13 +496,10 @@ func (b *Bot) showSMode(chatID int64, messageID int, userID int64) {
 	
 	// Cancel button
 	keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(
-		tgbotapi.NewInlineKeyboardButtonData("❌ "+utils.ToSmallCaps("Cancel"), "smode_cancel"),
+		tgbotapi.NewInlineKeyboardButtonData(p.Plain("smode.cancel", nil), "smode_cancel"),
 	))
 	
-	edit := tgbotapi.NewEditMessageText(chatID, messageID, caption)
-	edit.ParseMode = "HTML"
-	edit.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboardRows...)
-	b.API.Send(edit)
+	return caption, tgbotapi.NewInlineKeyboardMarkup(keyboardRows...)
 }
 
 // updateShopMessage updates the shop message
@@ -447,49 +516,39 @@ func (b *Bot) updateSearchResults(chatID int64, messageID int, chars []models.Ch
 // deleteMessage deletes a message
 func (b *Bot) deleteMessage(chatID int64, messageID int) {
 	deleteMsg := tgbotapi.NewDeleteMessage(chatID, messageID)
-	b.API.Request(deleteMsg)
+	b.request(deleteMsg)
 }
 
-// showHelp shows help message
-func (b *Bot) showHelp(chatID int64) {
-	helpText := fmt.Sprintf(
-		"<b>📚 %s</b>\n\n"+
-			"<b>%s</b>\n"+
-			"• <code>/guess &lt;name&gt;</code> - %s\n"+
-			"• <code>/collection</code> - %s\n"+
-			"• <code>/balance</code> - %s\n"+
-			"• <code>/pay &lt;amount&gt;</code> - %s\n"+
-			"• <code>/shop</code> - %s\n"+
-			"• <code>/gift &lt;id&gt;</code> - %s\n"+
-			"• <code>/trade &lt;your_id&gt; &lt;their_id&gt;</code> - %s\n"+
-			"• <code>/sclaim</code> - %s\n"+
-			"• <code>/claim</code> - %s\n"+
-			"• <code>/redeem &lt;code&gt;</code> - %s\n"+
-			"• <code>/leaderboard</code> - %s\n"+
-			"• <code>/sfind &lt;name&gt;</code> - %s\n"+
-			"• <code>/scheck &lt;id&gt;</code> - %s\n"+
-			"• <code>/smode</code> - %s\n"+
-			"• <code>/fav &lt;id&gt;</code> - %s",
-		utils.ToSmallCaps("HELP MENU"),
-		utils.ToSmallCaps("Commands:"),
-		utils.ToSmallCaps("Guess the character name"),
-		utils.ToSmallCaps("View your collection"),
-		utils.ToSmallCaps("Check your balance"),
-		utils.ToSmallCaps("Send coins to another user"),
-		utils.ToSmallCaps("Browse the character shop"),
-		utils.ToSmallCaps("Gift a character to someone"),
-		utils.ToSmallCaps("Trade characters with someone"),
-		utils.ToSmallCaps("Claim a free character (24h cooldown)"),
-		utils.ToSmallCaps("Generate a coin code (24h cooldown)"),
-		utils.ToSmallCaps("Redeem a code for rewards"),
-		utils.ToSmallCaps("View leaderboards"),
-		utils.ToSmallCaps("Search for characters"),
-		utils.ToSmallCaps("Check character details"),
-		utils.ToSmallCaps("Change collection filter"),
-		utils.ToSmallCaps("Add character to favorites"),
-	)
-	
-	reply := tgbotapi.NewMessage(chatID, helpText)
-	reply.ParseMode = "HTML"
-	b.API.Send(reply)
+// showHelp renders the help menu from the command registry; staff also see the admin commands
+// their roles allow
+func (b *Bot) showHelp(chatID int64, user *tgbotapi.User) {
+	p := b.printer(chatID, user)
+	held := make(map[Role]bool)
+	var userLines, adminLines []string
+	for _, cmd := range b.Router.Commands() {
+		if cmd.Hidden {
+			continue
+		}
+		if _, ok := held[cmd.Role]; !ok {
+			held[cmd.Role] = b.hasRole(chatID, user.ID, cmd.Role)
+		}
+		if !held[cmd.Role] {
+			continue
+		}
+		line := p.Text("help.line", i18n.Args{
+			"Usage":       cmd.UsageLine(),
+			"Description": i18n.HTML(p.Text(cmd.DescriptionID(), nil)),
+		})
+		if cmd.Role == RoleUser {
+			userLines = append(userLines, line)
+		} else {
+			adminLines = append(adminLines, line)
+		}
+	}
+	
+	args := i18n.Args{
+		"Commands": i18n.HTML(strings.Join(userLines, "\n")),
+		"Admin":    i18n.HTML(strings.Join(adminLines, "\n")),
+	}
+	b.reply(p, chatID, "help.menu", args)
 }
@@ -1,63 +1,50 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
-	"strconv"
-	"strings"
-	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/i18n"
 	"senpai-waifu-bot/internal/models"
-	"senpai-waifu-bot/internal/utils"
+	"senpai-waifu-bot/internal/services"
+	"senpai-waifu-bot/internal/store"
 )
 
 // cmdTrade handles /trade command
-func (b *Bot) cmdTrade(msg *tgbotapi.Message) {
+func (b *Bot) cmdTrade(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
 	senderID := msg.From.ID
 	
 	// Check cooldown
-	if nextAllowed, ok := b.TradeCooldowns[senderID]; ok && time.Now().Before(nextAllowed) {
-		remaining := int(time.Until(nextAllowed).Seconds())
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			fmt.Sprintf("⏰ Please wait %d seconds before initiating another trade!", remaining))
-		b.API.Send(reply)
+	if wait := b.TradeCooldowns.Remaining(senderID); wait > 0 {
+		b.reply(p, msg.Chat.ID, "trade.cooldown", i18n.Args{"Seconds": int(wait.Seconds())})
 		return
 	}
 	
 	// Must reply to a message
 	if msg.ReplyToMessage == nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			"❌ You need to reply to a user's message to trade a character!\n\nUsage: /trade <your_character_id> <their_character_id>")
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "trade.no_reply", nil)
 		return
 	}
 	
 	receiverID := msg.ReplyToMessage.From.ID
-	receiverMention := fmt.Sprintf("<a href='tg://user?id=%d'>%s</a>", receiverID, msg.ReplyToMessage.From.FirstName)
+	receiverName := msg.ReplyToMessage.From.FirstName
 	
 	if senderID == receiverID {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ You can't trade a character with yourself!")
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "trade.self", nil)
 		return
 	}
 	
-	// Parse arguments
-	args := strings.Fields(msg.Text)
-	if len(args) < 3 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			"❌ Invalid format! Usage:\n/trade <your_character_id> <their_character_id>\n\nReply to the user's message you want to trade with.")
-		b.API.Send(reply)
-		return
-	}
-	
-	senderCharID := args[1]
-	receiverCharID := args[2]
+	senderCharID := c.Arg(0)
+	receiverCharID := c.Arg(1)
 	
 	// Check if sender has the character
 	sender, err := b.UserService.GetUserByID(senderID)
 	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ You don't have any characters yet!")
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "collection.empty", nil)
 		return
 	}
 	
@@ -72,16 +59,14 @@ func (b *Bot) cmdTrade(msg *tgbotapi.Message) {
 	}
 	
 	if !senderHasChar {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("❌ You don't have a character with ID %s!", senderCharID))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "collection.not_owned", i18n.Args{"ID": senderCharID})
 		return
 	}
 	
 	// Check if receiver has the character
 	receiver, err := b.UserService.GetUserByID(receiverID)
 	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("❌ %s doesn't have any characters yet!", receiverMention))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "trade.receiver_empty", i18n.Args{"UserID": receiverID, "Name": receiverName})
 		return
 	}
 	
@@ -96,107 +81,91 @@ func (b *Bot) cmdTrade(msg *tgbotapi.Message) {
 	}
 	
 	if !receiverHasChar {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			fmt.Sprintf("❌ %s doesn't have a character with ID %s!", receiverMention, receiverCharID))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "trade.receiver_not_owned", i18n.Args{"UserID": receiverID, "Name": receiverName, "ID": receiverCharID})
 		return
 	}
 	
 	// Check for existing pending trade
 	tradeKey := fmt.Sprintf("%d:%d", senderID, receiverID)
-	if _, exists := b.PendingTrades[tradeKey]; exists {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ You already have a pending trade with this user!")
-		b.API.Send(reply)
-		return
-	}
-	
-	// Store pending trade
-	b.PendingTrades[tradeKey] = &PendingTradeInfo{
+	err = b.StateService.CreateTrade(&models.PendingTrade{
+		Key:            tradeKey,
 		SenderID:       senderID,
 		ReceiverID:     receiverID,
 		SenderCharID:   senderCharID,
 		ReceiverCharID: receiverCharID,
-		Timestamp:      time.Now(),
+	})
+	if errors.Is(err, store.ErrDuplicate) {
+		b.reply(p, msg.Chat.ID, "trade.pending", nil)
+		return
+	}
+	if err != nil {
+		logError(c.Log, err, "store trade", zap.String("trade", tradeKey))
+		b.reply(p, msg.Chat.ID, "trade.create_failed", nil)
+		return
 	}
 	
 	// Send trade request
-	tradeMsg := fmt.Sprintf(
-		"🔄 **Trade Request**\n\n"+
-			"**%s** wants to trade:\n"+
-			"**%s**\n⭐ Rarity: %s\n📺 Anime: %s\n\n"+
-			"For your:\n"+
-			"**%s**\n⭐ Rarity: %s\n📺 Anime: %s\n\n"+
-			"%s, please confirm or decline this trade.",
-		msg.From.FirstName,
-		senderChar.Name, utils.GetRarityDisplay(senderChar.Rarity), senderChar.Anime,
-		receiverChar.Name, utils.GetRarityDisplay(receiverChar.Rarity), receiverChar.Anime,
-		receiverMention,
-	)
+	tradeMsg := p.Text("trade.request", i18n.Args{
+		"Sender":         msg.From.FirstName,
+		"SenderChar":     senderChar.Name,
+		"SenderRarity":   senderChar.Rarity,
+		"SenderAnime":    senderChar.Anime,
+		"ReceiverChar":   receiverChar.Name,
+		"ReceiverRarity": receiverChar.Rarity,
+		"ReceiverAnime":  receiverChar.Anime,
+		"ReceiverID":     receiverID,
+		"Receiver":       receiverName,
+	})
 	
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("✅ Accept Trade", fmt.Sprintf("accept_trade:%d:%d", senderID, receiverID)),
+			tgbotapi.NewInlineKeyboardButtonData(p.Plain("trade.accept", nil), fmt.Sprintf("accept_trade:%d:%d", senderID, receiverID)),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("❌ Decline Trade", fmt.Sprintf("decline_trade:%d:%d", senderID, receiverID)),
+			tgbotapi.NewInlineKeyboardButtonData(p.Plain("trade.decline", nil), fmt.Sprintf("decline_trade:%d:%d", senderID, receiverID)),
 		),
 	)
 	
 	reply := tgbotapi.NewMessage(msg.Chat.ID, tradeMsg)
-	reply.ParseMode = "Markdown"
+	reply.ParseMode = "HTML"
 	reply.ReplyMarkup = keyboard
-	b.API.Send(reply)
+	b.send(reply)
 	
 	// Set cooldown
-	b.TradeCooldowns[senderID] = time.Now().Add(60 * time.Second)
+	b.TradeCooldowns.Start(senderID, b.Config.Economy.TradeCooldown)
 }
 
 // cmdGift handles /gift command
-func (b *Bot) cmdGift(msg *tgbotapi.Message) {
+func (b *Bot) cmdGift(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
 	senderID := msg.From.ID
 	
 	// Check cooldown
-	if nextAllowed, ok := b.GiftCooldowns[senderID]; ok && time.Now().Before(nextAllowed) {
-		remaining := int(time.Until(nextAllowed).Seconds())
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			fmt.Sprintf("⏰ Please wait %d seconds before gifting another character!", remaining))
-		b.API.Send(reply)
+	if wait := b.GiftCooldowns.Remaining(senderID); wait > 0 {
+		b.reply(p, msg.Chat.ID, "gift.cooldown", i18n.Args{"Seconds": int(wait.Seconds())})
 		return
 	}
 	
 	// Must reply to a message
 	if msg.ReplyToMessage == nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			"❌ You need to reply to a user's message to gift a character!\n\nUsage: /gift <character_id>")
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "gift.no_reply", nil)
 		return
 	}
 	
 	receiverID := msg.ReplyToMessage.From.ID
-	receiverMention := fmt.Sprintf("<a href='tg://user?id=%d'>%s</a>", receiverID, msg.ReplyToMessage.From.FirstName)
 	
 	if senderID == receiverID {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ You can't gift a character to yourself!")
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "gift.self", nil)
 		return
 	}
 	
-	// Parse arguments
-	args := strings.Fields(msg.Text)
-	if len(args) < 2 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, 
-			"❌ Invalid format! Usage:\n/gift <character_id>\n\nReply to the user's message you want to gift to.")
-		b.API.Send(reply)
-		return
-	}
-	
-	charID := args[1]
+	charID := c.Arg(0)
 	
 	// Check if sender has the character
 	sender, err := b.UserService.GetUserByID(senderID)
 	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ You don't have any characters yet!")
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "collection.empty", nil)
 		return
 	}
 	
@@ -211,280 +180,161 @@ func (b *Bot) cmdGift(msg *tgbotapi.Message) {
 	}
 	
 	if !charFound {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("❌ You don't have a character with ID %s!", charID))
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "collection.not_owned", i18n.Args{"ID": charID})
 		return
 	}
 	
 	// Check for existing pending gift
 	giftKey := fmt.Sprintf("%d:%d", senderID, receiverID)
-	if _, exists := b.PendingGifts[giftKey]; exists {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ You already have a pending gift for this user!")
-		b.API.Send(reply)
-		return
-	}
-	
-	// Store pending gift
-	b.PendingGifts[giftKey] = &PendingGiftInfo{
+	err = b.StateService.CreateGift(&models.PendingGift{
+		Key:               giftKey,
 		SenderID:          senderID,
 		ReceiverID:        receiverID,
-		CharacterID:       charID,
-		CharacterName:     giftChar.Name,
+		Character:         giftChar,
 		ReceiverUsername:  msg.ReplyToMessage.From.UserName,
 		ReceiverFirstName: msg.ReplyToMessage.From.FirstName,
-		Timestamp:         time.Now(),
+	})
+	if errors.Is(err, store.ErrDuplicate) {
+		b.reply(p, msg.Chat.ID, "gift.pending", nil)
+		return
+	}
+	if err != nil {
+		logError(c.Log, err, "store gift", zap.String("gift", giftKey))
+		b.reply(p, msg.Chat.ID, "gift.create_failed", nil)
+		return
 	}
 	
-	// Format gift card
-	rarityDisplay := utils.GetRarityDisplay(giftChar.Rarity)
-	giftCard := fmt.Sprintf(
-		"━━━━━━━━━━━━━━━━━━\n"+
-			"🎁 %s\n"+
-			"━━━━━━━━━━━━━━━━━━\n"+
-			"✨ %s   : **%s**\n"+
-			"🎬 %s  : **%s**\n"+
-			"🆔 %s     : `%s`\n"+
-			"⭐ %s : %s\n"+
-			"━━━━━━━━━━━━━━━━━━\n"+
-			"💎 %s **%s**",
-		utils.ToSmallCaps("gift card"),
-		utils.ToSmallCaps("name"), utils.ToSmallCaps(giftChar.Name),
-		utils.ToSmallCaps("anime"), utils.ToSmallCaps(giftChar.Anime),
-		utils.ToSmallCaps("id"), giftChar.ID,
-		utils.ToSmallCaps("rarity"), rarityDisplay,
-		utils.ToSmallCaps("premium gift from"), msg.From.FirstName,
-	)
-	
-	giftMsg := fmt.Sprintf(
-		"%s\n\n"+
-			"━━━━━━━━━━━━━━━━━━\n"+
-			"Are you sure you want to gift this to %s?\n\n"+
-			"⏰ %s",
-		giftCard,
-		receiverMention,
-		utils.ToSmallCaps("you have 30 seconds to confirm"),
-	)
+	// Send the gift card
+	giftMsg := p.Text("gift.request", i18n.Args{
+		"Name":       giftChar.Name,
+		"Anime":      giftChar.Anime,
+		"ID":         giftChar.ID,
+		"Rarity":     giftChar.Rarity,
+		"Sender":     msg.From.FirstName,
+		"ReceiverID": receiverID,
+		"Receiver":   msg.ReplyToMessage.From.FirstName,
+	})
 	
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("✅ Confirm Gift", fmt.Sprintf("confirm_gift:%d:%d", senderID, receiverID)),
+			tgbotapi.NewInlineKeyboardButtonData(p.Plain("gift.confirm", nil), fmt.Sprintf("confirm_gift:%d:%d", senderID, receiverID)),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("❌ Cancel Gift", fmt.Sprintf("cancel_gift:%d:%d", senderID, receiverID)),
+			tgbotapi.NewInlineKeyboardButtonData(p.Plain("gift.cancel", nil), fmt.Sprintf("cancel_gift:%d:%d", senderID, receiverID)),
 		),
 	)
 	
 	reply := tgbotapi.NewMessage(msg.Chat.ID, giftMsg)
-	reply.ParseMode = "Markdown"
+	reply.ParseMode = "HTML"
 	reply.ReplyMarkup = keyboard
-	b.API.Send(reply)
+	b.send(reply)
 	
 	// Set cooldown
-	b.GiftCooldowns[senderID] = time.Now().Add(30 * time.Second)
+	b.GiftCooldowns.Start(senderID, b.Config.Economy.GiftCooldown)
 }
 
 // acceptTrade accepts a trade
 func (b *Bot) acceptTrade(chatID int64, messageID int, senderID, receiverID int64) {
+	p := b.printer(chatID, nil)
 	tradeKey := fmt.Sprintf("%d:%d", senderID, receiverID)
-	trade, ok := b.PendingTrades[tradeKey]
-	if !ok {
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, "❌ This trade has expired or doesn't exist!")
-		b.API.Send(edit)
-		return
-	}
-	
-	// Check expiry (5 minutes)
-	if time.Since(trade.Timestamp) > 5*time.Minute {
-		delete(b.PendingTrades, tradeKey)
-		delete(b.TradeCooldowns, senderID)
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, "❌ This trade request has expired!")
-		b.API.Send(edit)
-		return
-	}
-	
-	// Get both users' data
-	sender, err1 := b.UserService.GetUserByID(senderID)
-	receiver, err2 := b.UserService.GetUserByID(receiverID)
-	
-	if err1 != nil || err2 != nil {
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, "❌ Trade failed! Could not retrieve user data.")
-		b.API.Send(edit)
+	// Claim the trade before executing it so a repeated tap cannot run it twice
+	trade, err := b.StateService.TakeTrade(tradeKey)
+	if err != nil {
+		b.editText(p, chatID, messageID, "trade.expired", nil)
 		return
 	}
 	
-	// Verify both still have their characters
-	var senderChar, receiverChar models.UserCharacter
-	senderHasChar := false
-	receiverHasChar := false
-	
-	for _, char := range sender.Characters {
-		if char.ID == trade.SenderCharID {
-			senderChar = char
-			senderHasChar = true
-			break
-		}
-	}
-	
-	for _, char := range receiver.Characters {
-		if char.ID == trade.ReceiverCharID {
-			receiverChar = char
-			receiverHasChar = true
-			break
+	// Perform the trade
+	result, err := b.TransferService.Trade(senderID, trade.SenderCharID, receiverID, trade.ReceiverCharID, tradeKey)
+	if err != nil {
+		var notOwned *services.NotOwnedError
+		id := "trade.failed"
+		if errors.As(err, &notOwned) {
+			if notOwned.UserID == senderID {
+				id = "trade.failed_sender"
+			} else {
+				id = "trade.failed_receiver"
+			}
+		} else {
+			logError(b.Log, err, "trade", zap.Int64("chat_id", chatID), zap.String("trade", tradeKey))
 		}
-	}
-	
-	if !senderHasChar {
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, "❌ Trade failed! The sender's character no longer exists.")
-		b.API.Send(edit)
-		delete(b.PendingTrades, tradeKey)
+		b.editText(p, chatID, messageID, id, nil)
 		return
 	}
-	
-	if !receiverHasChar {
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, "❌ Trade failed! Your character no longer exists.")
-		b.API.Send(edit)
-		delete(b.PendingTrades, tradeKey)
-		return
-	}
-	
-	// Perform the trade
-	// Remove sender's character
-	_ = b.UserService.RemoveCharacterFromUser(senderID, trade.SenderCharID)
-	// Remove receiver's character
-	_ = b.UserService.RemoveCharacterFromUser(receiverID, trade.ReceiverCharID)
-	// Add receiver's character to sender
-	_ = b.UserService.AddCharacterToUser(senderID, receiverChar)
-	// Add sender's character to receiver
-	_ = b.UserService.AddCharacterToUser(receiverID, senderChar)
-	
-	// Clean up
-	delete(b.PendingTrades, tradeKey)
+	senderChar, receiverChar := result.SenderChar, result.ReceiverChar
 	
 	// Send success message
-	successMsg := fmt.Sprintf(
-		"✅ **Trade Successful!**\n\n"+
-			"**%s** received:\n"+
-			"**%s**\n⭐ Rarity: %s\n📺 Anime: %s\n\n"+
-			"**%s** received:\n"+
-			"**%s**\n⭐ Rarity: %s\n📺 Anime: %s",
-		sender.FirstName,
-		receiverChar.Name, utils.GetRarityDisplay(receiverChar.Rarity), receiverChar.Anime,
-		receiver.FirstName,
-		senderChar.Name, utils.GetRarityDisplay(senderChar.Rarity), senderChar.Anime,
-	)
-	
-	edit := tgbotapi.NewEditMessageText(chatID, messageID, successMsg)
-	edit.ParseMode = "Markdown"
-	b.API.Send(edit)
+	b.editText(p, chatID, messageID, "trade.done", i18n.Args{
+		"Sender":         b.storedName(p, senderID),
+		"ReceiverChar":   receiverChar.Name,
+		"ReceiverRarity": receiverChar.Rarity,
+		"ReceiverAnime":  receiverChar.Anime,
+		"Receiver":       b.storedName(p, receiverID),
+		"SenderChar":     senderChar.Name,
+		"SenderRarity":   senderChar.Rarity,
+		"SenderAnime":    senderChar.Anime,
+	})
 }
 
 // declineTrade declines a trade
 func (b *Bot) declineTrade(chatID int64, messageID int, senderID, receiverID int64) {
+	p := b.printer(chatID, nil)
 	tradeKey := fmt.Sprintf("%d:%d", senderID, receiverID)
-	delete(b.PendingTrades, tradeKey)
-	delete(b.TradeCooldowns, senderID)
+	_, err := b.StateService.TakeTrade(tradeKey)
+	logError(b.Log, ignoreNotFound(err), "decline trade", zap.Int64("chat_id", chatID), zap.String("trade", tradeKey))
+	b.TradeCooldowns.Clear(senderID)
 	
-	receiver, _ := b.UserService.GetUserByID(receiverID)
-	receiverName := fmt.Sprintf("User %d", receiverID)
-	if receiver != nil {
-		receiverName = receiver.FirstName
-	}
-	
-	edit := tgbotapi.NewEditMessageText(chatID, messageID, 
-		fmt.Sprintf("❌ **Trade Declined**\n\n%s has declined the trade.", receiverName))
-	edit.ParseMode = "Markdown"
-	b.API.Send(edit)
+	b.editText(p, chatID, messageID, "trade.declined", i18n.Args{"Receiver": b.storedName(p, receiverID)})
 }
 
 // confirmGift confirms a gift
 func (b *Bot) confirmGift(chatID int64, messageID int, senderID, receiverID int64) {
+	p := b.printer(chatID, nil)
 	giftKey := fmt.Sprintf("%d:%d", senderID, receiverID)
-	gift, ok := b.PendingGifts[giftKey]
-	if !ok {
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, "❌ This gift has expired or doesn't exist!")
-		b.API.Send(edit)
-		return
-	}
-	
-	// Check expiry (30 seconds)
-	if time.Since(gift.Timestamp) > 30*time.Second {
-		delete(b.PendingGifts, giftKey)
-		delete(b.GiftCooldowns, senderID)
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, 
-			"❌ This gift request has expired!\n\nYou can now send a new gift.")
-		b.API.Send(edit)
-		return
-	}
-	
-	// Get sender's data
-	sender, err := b.UserService.GetUserByID(senderID)
+	// Claim the gift before executing it so a repeated tap cannot run it twice
+	gift, err := b.StateService.TakeGift(giftKey)
 	if err != nil {
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, "❌ Gift failed! Could not retrieve user data.")
-		b.API.Send(edit)
+		b.GiftCooldowns.Clear(senderID)
+		b.editText(p, chatID, messageID, "gift.expired", nil)
 		return
 	}
 	
-	// Verify sender still has the character
-	var giftChar models.UserCharacter
-	charFound := false
-	for _, char := range sender.Characters {
-		if char.ID == gift.CharacterID {
-			giftChar = char
-			charFound = true
-			break
+	// Perform the gift
+	giftChar, err := b.TransferService.Gift(senderID, receiverID, gift.Character.ID, giftKey)
+	if err != nil {
+		var notOwned *services.NotOwnedError
+		id := "gift.failed"
+		switch {
+		case errors.As(err, &notOwned):
+			id = "gift.failed_not_owned"
+		case errors.Is(err, services.ErrInventoryFull):
+			id = "gift.failed_full"
+		default:
+			logError(b.Log, err, "gift", zap.Int64("chat_id", chatID), zap.String("gift", giftKey))
 		}
-	}
-	
-	if !charFound {
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, 
-			"❌ Gift failed! The character no longer exists in your collection.")
-		b.API.Send(edit)
-		delete(b.PendingGifts, giftKey)
-		return
-	}
-	
-	// Check receiver inventory size
-	receiverCharCount, _ := b.UserService.GetUserCharactersCount(receiverID)
-	if receiverCharCount >= 5000 {
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, "❌ Gift failed! Receiver's inventory is full.")
-		b.API.Send(edit)
-		delete(b.PendingGifts, giftKey)
+		b.editText(p, chatID, messageID, id, nil)
 		return
 	}
 	
-	// Perform the gift
-	_ = b.UserService.RemoveCharacterFromUser(senderID, gift.CharacterID)
-	_ = b.UserService.AddCharacterToUser(receiverID, giftChar)
-	
-	// Clean up
-	delete(b.PendingGifts, giftKey)
-	
 	// Send success message
-	successMsg := fmt.Sprintf(
-		"🎉 **%s**\n"+
-			"━━━━━━━━━━━━━━━━━━\n"+
-			"💝 **%s** %s\n"+
-			"%s **%s**\n"+
-			"━━━━━━━━━━━━━━━━━━\n"+
-			"✨ %s",
-		utils.ToSmallCaps("gift successful"),
-		utils.ToSmallCaps(giftChar.Name), utils.ToSmallCaps("has been sent"),
-		utils.ToSmallCaps("to"), gift.ReceiverFirstName,
-		utils.ToSmallCaps("thank you for being generous"),
-	)
-	
-	edit := tgbotapi.NewEditMessageText(chatID, messageID, successMsg)
-	edit.ParseMode = "Markdown"
-	b.API.Send(edit)
+	b.editText(p, chatID, messageID, "gift.done", i18n.Args{"Name": giftChar.Name, "Receiver": gift.ReceiverFirstName})
 }
 
 // cancelGift cancels a gift
 func (b *Bot) cancelGift(chatID int64, messageID int, senderID, receiverID int64) {
 	giftKey := fmt.Sprintf("%d:%d", senderID, receiverID)
-	delete(b.PendingGifts, giftKey)
+	_, err := b.StateService.TakeGift(giftKey)
+	logError(b.Log, ignoreNotFound(err), "cancel gift", zap.Int64("chat_id", chatID), zap.String("gift", giftKey))
 	
-	edit := tgbotapi.NewEditMessageText(chatID, messageID, 
-		"❌ **Gift Cancelled**\n\nThe gift has been cancelled.")
-	edit.ParseMode = "Markdown"
-	b.API.Send(edit)
+	b.editText(b.printer(chatID, nil), chatID, messageID, "gift.cancelled", nil)
+}
+
+// storedName returns the first name stored for userID, or a placeholder for unknown users
+func (b *Bot) storedName(p *i18n.Printer, userID int64) string {
+	user, err := b.UserService.GetUserByID(userID)
+	logError(b.Log, ignoreNotFound(err), "get user", zap.Int64("user_id", userID))
+	if err != nil || user == nil {
+		return p.Plain("user.fallback", i18n.Args{"UserID": userID})
+	}
+	return user.FirstName
 }
@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -10,57 +11,18 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"senpai-waifu-bot/internal/database"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/guess"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/services"
+	"senpai-waifu-bot/internal/store"
 	"senpai-waifu-bot/internal/utils"
 )
 
-// Rarity levels for upload
-var uploadRarityNames = map[int]string{
-	1:  "⚪ ᴄᴏᴍᴍᴏɴ",
-	2:  "🔵 ʀᴀʀᴇ",
-	3:  "🟡 ʟᴇɢᴇɴᴅᴀʀʏ",
-	4:  "💮 ꜱᴘᴇᴄɪᴀʟ",
-	5:  "👹 ᴀɴᴄɪᴇɴᴛ",
-	6:  "🎐 ᴄᴇʟᴇꜱᴛɪᴀʟ",
-	7:  "🔮 ᴇᴘɪᴄ",
-	8:  "🪐 ᴄᴏꜱᴍɪᴄ",
-	9:  "⚰️ ɴɪɢʜᴛᴍᴀʀᴇ",
-	10: "🌬️ ꜰʀᴏꜱᴛʙᴏʀɴ",
-	11: "💝 ᴠᴀʟᴇɴᴛɪɴᴇ",
-	12: "🌸 ꜱᴘʀɪɴɢ",
-	13: "🏖️ ᴛʀᴏᴘɪᴄᴀʟ",
-	14: "🍭 ᴋᴀᴡᴀɪɪ",
-	15: "🧬 ʜʏʙʀɪᴅ",
-}
-
-// Upload format text
-var uploadFormatText = `❌ Wrong format!
-
-<b>Usage:</b> Reply to an image with:
-<code>/upload character-name anime-name rarity-number</code>
-
-<b>Example:</b>
-<code>/upload naruto-uzumaki naruto 3</code>
-
-<b>Available Rarities:</b>
-1 - ⚪ ᴄᴏᴍᴍᴏɴ
-2 - 🔵 ʀᴀʀᴇ
-3 - 🟡 ʟᴇɢᴇɴᴅᴀʀʏ
-4 - 💮 ꜱᴘᴇᴄɪᴀʟ
-5 - 👹 ᴀɴᴄɪᴇɴᴛ
-6 - 🎐 ᴄᴇʟᴇꜱᴛɪᴀʟ
-7 - 🔮 ᴇᴘɪᴄ
-8 - 🪐 ᴄᴏꜱᴍɪᴄ
-9 - ⚰️ ɴɪɢʜᴛᴍᴀʀᴇ
-10 - 🌬️ ꜰʀᴏꜱᴛʙᴏʀɴ
-11 - 💝 ᴠᴀʟᴇɴᴛɪɴᴇ
-12 - 🌸 ꜱᴘʀɪɴɢ
-13 - 🏖️ ᴛʀᴏᴘɪᴄᴀʟ
-14 - 🍭 ᴋᴀᴡᴀɪɪ
-15 - 🧬 ʜʏʙʀɪᴅ`
-
 // ImageUploader handles image uploads to various hosting services
 type ImageUploader struct {
 	ImgBBKey string
@@ -240,54 +202,38 @@ func (u *ImageUploader) UploadWithFailover(imageData []byte) (string, error) {
 	return "", lastErr
 }
 
-// GetNextSequenceNumber gets the next character ID
-func (b *Bot) GetNextSequenceNumber() (string, error) {
-	// Get total count and add 1
-	count, err := b.CharacterService.GetCharacterCount()
-	if err != nil {
-		return "", err
-	}
-	
-	// Format as 3-digit number (001, 010, 100)
-	return fmt.Sprintf("%03d", count+1), nil
-}
+// maxSaveAttempts bounds how often an upload retries with a new ID when its ID was taken
+const maxSaveAttempts = 3
 
 // cmdUpload handles /upload command
-func (b *Bot) cmdUpload(msg *tgbotapi.Message) {
-	// Check if user is admin
-	if !b.Config.IsSudo(msg.From.ID) {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "⛔ You do not have permission to use this command.")
-		b.API.Send(reply)
-		return
-	}
+func (b *Bot) cmdUpload(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
 	
 	// Check if replying to a message
 	if msg.ReplyToMessage == nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, uploadFormatText)
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+		b.sendUploadUsage(p, msg.Chat.ID)
 		return
 	}
 	
 	// Check if replied message has a photo
 	if len(msg.ReplyToMessage.Photo) == 0 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ The replied message must contain an image!")
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "upload.no_image", nil)
 		return
 	}
 	
 	// Parse arguments
 	args := strings.Fields(msg.Text)
 	if len(args) != 4 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, uploadFormatText)
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+		b.sendUploadUsage(p, msg.Chat.ID)
 		return
 	}
 	
 	// Progress message
-	progressMsg, _ := b.API.Send(tgbotapi.NewMessage(msg.Chat.ID, "⏳ <b>Starting upload process...</b>"))
-	progressMsg.ParseMode = "HTML"
+	progressMsg, _ := b.reply(p, msg.Chat.ID, "upload.starting", nil)
+	progress := func(id string) {
+		b.editText(p, msg.Chat.ID, progressMsg.MessageID, id, nil)
+	}
 	
 	// Parse arguments
 	characterName := strings.Title(strings.ReplaceAll(args[1], "-", " "))
@@ -295,120 +241,132 @@ func (b *Bot) cmdUpload(msg *tgbotapi.Message) {
 	
 	rarityNum, err := strconv.Atoi(args[3])
 	if err != nil || rarityNum < 1 || rarityNum > 15 {
-		b.API.Send(tgbotapi.NewEditMessageText(msg.Chat.ID, progressMsg.MessageID, "❌ Rarity must be a number between 1-15."))
+		progress("upload.bad_rarity")
 		return
 	}
 	
-	rarityName := uploadRarityNames[rarityNum]
-	
 	// Step 1: Download image
-	b.API.Send(tgbotapi.NewEditMessageText(msg.Chat.ID, progressMsg.MessageID, "📥 <b>Downloading image...</b>"))
+	progress("upload.downloading")
 	
 	// Get the largest photo
 	photo := msg.ReplyToMessage.Photo[len(msg.ReplyToMessage.Photo)-1]
-	file, err := b.API.GetFile(tgbotapi.FileConfig{FileID: photo.FileID})
+	imageURL, err := b.API.GetFileDirectURL(photo.FileID)
 	if err != nil {
-		b.API.Send(tgbotapi.NewEditMessageText(msg.Chat.ID, progressMsg.MessageID, "❌ Failed to download image!"))
+		progress("upload.download_failed")
 		return
 	}
 	
 	// Download image data
-	imageURL := file.Link(b.API.Token)
 	resp, err := http.Get(imageURL)
 	if err != nil {
-		b.API.Send(tgbotapi.NewEditMessageText(msg.Chat.ID, progressMsg.MessageID, "❌ Failed to download image!"))
+		progress("upload.download_failed")
 		return
 	}
 	defer resp.Body.Close()
 	
 	imageData, err := io.ReadAll(resp.Body)
 	if err != nil {
-		b.API.Send(tgbotapi.NewEditMessageText(msg.Chat.ID, progressMsg.MessageID, "❌ Failed to read image data!"))
+		progress("upload.read_failed")
 		return
 	}
 	
 	// Check image size (10MB limit)
 	if len(imageData) > 10*1024*1024 {
-		b.API.Send(tgbotapi.NewEditMessageText(msg.Chat.ID, progressMsg.MessageID, "❌ Image too large! Max size: 10MB"))
+		progress("upload.too_large")
 		return
 	}
 	
 	// Step 2: Upload to hosting
-	b.API.Send(tgbotapi.NewEditMessageText(msg.Chat.ID, progressMsg.MessageID, "☁️ <b>Uploading to cloud storage...</b>\n<i>This may take a few seconds...</i>"))
+	progress("upload.uploading")
 	
 	uploader := NewImageUploader()
 	imgURL, err := uploader.UploadWithFailover(imageData)
 	if err != nil {
-		b.API.Send(tgbotapi.NewEditMessageText(msg.Chat.ID, progressMsg.MessageID, "❌ Failed to upload image. All hosting services failed.\nPlease try again later."))
+		progress("upload.upload_failed")
 		return
 	}
 	
 	// Step 3: Generate ID and save to database
-	b.API.Send(tgbotapi.NewEditMessageText(msg.Chat.ID, progressMsg.MessageID, "💾 <b>Saving to database...</b>"))
+	progress("upload.saving")
 	
-	charID, err := b.GetNextSequenceNumber()
+	charID, err := b.CharacterService.NextCharacterID()
 	if err != nil {
-		b.API.Send(tgbotapi.NewEditMessageText(msg.Chat.ID, progressMsg.MessageID, "❌ Failed to generate character ID!"))
+		logError(c.Log, err, "allocate character id")
+		progress("upload.id_failed")
 		return
 	}
 	
 	// Create character document
-	character := map[string]interface{}{
-		"id":            charID,
-		"name":          characterName,
-		"anime":         animeName,
-		"rarity":        rarityNum,
-		"img_url":       imgURL,
-		"created_at":    time.Now(),
-		"added_by":      msg.From.ID,
-		"added_by_name": msg.From.FirstName,
+	createdAt := time.Now()
+	character := &models.Character{
+		ID:          charID,
+		Name:        characterName,
+		Anime:       animeName,
+		Rarity:      rarityNum,
+		ImgURL:      imgURL,
+		CreatedAt:   &createdAt,
+		AddedBy:     msg.From.ID,
+		AddedByName: msg.From.FirstName,
 	}
 	
 	// Step 4: Post to channel
-	caption := fmt.Sprintf(
-		"<b>🎴 Character:</b> %s\n"+
-			"<b>📺 Anime:</b> %s\n"+
-			"<b>⭐ Rarity:</b> %s\n"+
-			"<b>🆔 ID:</b> <code>%s</code>\n\n"+
-			"<b>👤 Added by:</b> <a href=\"tg://user?id=%d\">%s</a>\n"+
-			"<b>📅 Date:</b> %s",
-		characterName,
-		animeName,
-		rarityName,
-		charID,
-		msg.From.ID,
-		msg.From.FirstName,
-		time.Now().Format("2006-01-02 15:04"),
-	)
+	channelCaption := func(id string) string {
+		return b.printer(b.Config.CharaChannelID, nil).Text("upload.channel_caption", i18n.Args{
+			"Name":    characterName,
+			"Anime":   animeName,
+			"Rarity":  rarityNum,
+			"ID":      id,
+			"AdderID": msg.From.ID,
+			"Adder":   msg.From.FirstName,
+			"Date":    createdAt.Format("2006-01-02 15:04"),
+		})
+	}
+	caption := channelCaption(charID)
 	
 	channelMsg := tgbotapi.NewPhoto(b.Config.CharaChannelID, tgbotapi.FileURL(imgURL))
 	channelMsg.Caption = caption
 	channelMsg.ParseMode = "HTML"
 	
-	sentMsg, err := b.API.Send(channelMsg)
+	sentMsg, err := b.send(channelMsg)
 	if err != nil {
 		// Fallback: send image directly
-		b.API.Send(tgbotapi.NewEditMessageText(msg.Chat.ID, progressMsg.MessageID, "⚠️ <b>URL failed, sending image directly...</b>"))
+		progress("upload.url_failed")
 		
 		channelMsg := tgbotapi.NewPhoto(b.Config.CharaChannelID, tgbotapi.FileBytes{Bytes: imageData})
 		channelMsg.Caption = caption
 		channelMsg.ParseMode = "HTML"
 		
-		sentMsg, err = b.API.Send(channelMsg)
+		sentMsg, err = b.send(channelMsg)
 		if err != nil {
-			b.API.Send(tgbotapi.NewEditMessageText(msg.Chat.ID, progressMsg.MessageID, "❌ Failed to post to channel!"))
+			progress("upload.post_failed")
 			return
 		}
 	}
 	
-	character["message_id"] = sentMsg.MessageID
+	character.MessageID = sentMsg.MessageID
 	
-	// Step 5: Save to database
-	_, err = database.CharacterCollection.InsertOne(nil, character)
+	// Step 5: Save to database. The ID was free when it was allocated; if another character
+	// took it since, allocate a new one and correct the channel post.
+	for attempt := 1; ; attempt++ {
+		err = b.CharacterService.AddCharacter(character)
+		if !errors.Is(err, store.ErrDuplicate) || attempt == maxSaveAttempts {
+			break
+		}
+		if charID, err = b.CharacterService.NextCharacterID(); err != nil {
+			break
+		}
+		character.ID = charID
+		
+		edit := tgbotapi.NewEditMessageCaption(b.Config.CharaChannelID, sentMsg.MessageID, channelCaption(charID))
+		edit.ParseMode = "HTML"
+		b.send(edit)
+	}
 	if err != nil {
-		b.API.Send(tgbotapi.NewEditMessageText(msg.Chat.ID, progressMsg.MessageID, "❌ Failed to save to database!"))
+		logError(c.Log, err, "save character", zap.String("character_id", charID))
+		progress("upload.save_failed")
 		return
 	}
+	b.audit(c, models.AuditEntry{CharacterID: charID, After: characterFields(character)})
 	
 	// Delete progress message
 	b.deleteMessage(msg.Chat.ID, progressMsg.MessageID)
@@ -419,57 +377,40 @@ func (b *Bot) cmdUpload(msg *tgbotapi.Message) {
 		channelUsername, _ = strconv.ParseInt(strconv.FormatInt(channelUsername, 10)[4:], 10, 64)
 	}
 	
-	successMsg := fmt.Sprintf(
-		"✅ <b>Character Added Successfully!</b>\n\n"+
-			"🆔 ID: <code>%s</code>\n"+
-			"👤 Name: %s\n"+
-			"📺 Anime: %s\n"+
-			"⭐ Rarity: %s\n"+
-			"🔗 <a href=\"%s\">Image Link</a>\n\n"+
-			"<b>View in channel:</b> <a href=\"https://t.me/c/%d/%d\">Click here</a>",
-		charID,
-		characterName,
-		animeName,
-		rarityName,
-		imgURL,
-		channelUsername,
-		sentMsg.MessageID,
-	)
+	successMsg := p.Text("upload.done", i18n.Args{
+		"ID":      charID,
+		"Name":    characterName,
+		"Anime":   animeName,
+		"Rarity":  rarityNum,
+		"ImgURL":  imgURL,
+		"PostURL": fmt.Sprintf("https://t.me/c/%d/%d", channelUsername, sentMsg.MessageID),
+	})
 	
 	reply := tgbotapi.NewMessage(msg.Chat.ID, successMsg)
 	reply.ParseMode = "HTML"
 	reply.DisableWebPagePreview = true
-	b.API.Send(reply)
+	b.send(reply)
 }
 
-// cmdDelete handles /delete command
-func (b *Bot) cmdDelete(msg *tgbotapi.Message) {
-	// Check if user is admin
-	if !b.Config.IsSudo(msg.From.ID) {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "⛔ You do not have permission to use this command.")
-		b.API.Send(reply)
-		return
+// sendUploadUsage explains the /upload format with the list of rarities
+func (b *Bot) sendUploadUsage(p *i18n.Printer, chatID int64) {
+	var rarities []string
+	for i := 1; i <= 15; i++ {
+		rarities = append(rarities, p.Text("upload.rarity_line", i18n.Args{"Rarity": i}))
 	}
-	
-	args := strings.Fields(msg.Text)
-	if len(args) != 2 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID,
-			"❌ <b>Incorrect format!</b>\n\n"+
-				"<b>Usage:</b> <code>/delete ID</code>\n"+
-				"<b>Example:</b> <code>/delete 042</code>")
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
-		return
-	}
-	
-	charID := args[1]
+	b.reply(p, chatID, "upload.usage", i18n.Args{"Rarities": i18n.HTML(strings.Join(rarities, "\n"))})
+}
+
+// cmdDelete handles /delete command
+func (b *Bot) cmdDelete(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+	charID := c.Arg(0)
 	
 	// Find character
 	char, err := b.CharacterService.GetCharacterByID(charID)
 	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("❌ Character with ID <code>%s</code> not found.", charID))
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "character.not_found", i18n.Args{"ID": charID})
 		return
 	}
 	
@@ -477,133 +418,198 @@ func (b *Bot) cmdDelete(msg *tgbotapi.Message) {
 	// (Would need to store message_id in character document)
 	
 	// Delete from database
-	_, err = database.CharacterCollection.DeleteOne(nil, map[string]interface{}{"id": charID})
+	err = b.CharacterService.DeleteCharacter(charID)
 	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Failed to delete character!")
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "delete.failed", nil)
 		return
 	}
+	b.audit(c, models.AuditEntry{CharacterID: charID, Before: characterFields(char)})
 	
-	reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf(
-		"✅ <b>Character Deleted!</b>\n\n"+
-			"🆔 ID: <code>%s</code>\n"+
-			"👤 Was: %s\n"+
-			"📺 Anime: %s",
-		charID, char.Name, char.Anime))
-	reply.ParseMode = "HTML"
-	b.API.Send(reply)
+	b.reply(p, msg.Chat.ID, "delete.done", i18n.Args{"ID": charID, "Name": char.Name, "Anime": char.Anime})
 }
 
-// cmdUpdate handles /update command
-func (b *Bot) cmdUpdate(msg *tgbotapi.Message) {
-	// Check if user is admin
-	if !b.Config.IsSudo(msg.From.ID) {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "⛔ You do not have permission to use this command.")
-		b.API.Send(reply)
-		return
-	}
+// cmdReseed handles /reseed command (restart character IDs after the highest one in use)
+func (b *Bot) cmdReseed(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
 	
-	args := strings.Fields(msg.Text)
-	if len(args) < 4 {
-		reply := tgbotapi.NewMessage(msg.Chat.ID,
-			"❌ <b>Incorrect format!</b>\n\n"+
-				"<b>Usage:</b> <code>/update ID field new_value</code>\n\n"+
-				"<b>Fields:</b> name, anime, rarity, img_url\n\n"+
-				"<b>Examples:</b>\n"+
-				"<code>/update 042 name Naruto-Uzumaki</code>\n"+
-				"<code>/update 042 rarity 5</code>\n"+
-				"<code>/update 042 img_url https://example.com/image.jpg</code>")
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+	before, after, err := b.CharacterService.ReseedCharacterIDs()
+	if err != nil {
+		logError(c.Log, err, "reseed character ids")
+		b.reply(p, msg.Chat.ID, "reseed.failed", nil)
 		return
 	}
+	b.audit(c, models.AuditEntry{
+		Before: map[string]interface{}{"id_counter": before},
+		After:  map[string]interface{}{"id_counter": after},
+	})
 	
-	charID := args[1]
-	field := args[2]
-	newValue := strings.Join(args[3:], " ")
+	b.reply(p, msg.Chat.ID, "reseed.done", i18n.Args{
+		"Before": utils.FormatCharacterID(before),
+		"After":  utils.FormatCharacterID(after),
+		"Next":   utils.FormatCharacterID(after + 1),
+	})
+}
+
+// cmdUpdate handles /update command
+func (b *Bot) cmdUpdate(c *CommandContext) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+	charID := c.Arg(0)
+	field := c.Arg(1)
+	newValue := c.Rest(2)
 	
 	validFields := map[string]bool{"img_url": true, "name": true, "anime": true, "rarity": true}
 	if !validFields[field] {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Invalid field. Use one of: img_url, name, anime, rarity")
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "update.bad_field", nil)
 		return
 	}
 	
 	// Find character
 	char, err := b.CharacterService.GetCharacterByID(charID)
 	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("❌ Character with ID <code>%s</code> not found.", charID))
-		reply.ParseMode = "HTML"
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "character.not_found", i18n.Args{"ID": charID})
 		return
 	}
 	
 	// Process new value
+	update := store.CharacterUpdate{
+		UpdatedBy: msg.From.ID,
+		UpdatedAt: time.Now(),
+	}
 	var processedValue interface{}
-	if field == "name" || field == "anime" {
-		processedValue = strings.Title(strings.ReplaceAll(newValue, "-", " "))
-	} else if field == "rarity" {
+	switch field {
+	case "name", "anime":
+		title := strings.Title(strings.ReplaceAll(newValue, "-", " "))
+		if field == "name" {
+			update.Name = &title
+		} else {
+			update.Anime = &title
+		}
+		processedValue = title
+	case "rarity":
 		rarityNum, err := strconv.Atoi(newValue)
 		if err != nil || rarityNum < 1 || rarityNum > 15 {
-			reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Rarity must be a number between 1-15.")
-			b.API.Send(reply)
+			b.reply(p, msg.Chat.ID, "upload.bad_rarity", nil)
 			return
 		}
+		update.Rarity = &rarityNum
 		processedValue = rarityNum
-	} else {
+	default:
+		update.ImgURL = &newValue
 		processedValue = newValue
 	}
 	
 	// Update database
-	update := map[string]interface{}{
-		"$set": map[string]interface{}{
-			field:        processedValue,
-			"updated_at": time.Now(),
-			"updated_by": msg.From.ID,
-		},
+	err = b.CharacterService.UpdateCharacter(charID, update)
+	if err != nil {
+		b.reply(p, msg.Chat.ID, "update.failed", nil)
+		return
 	}
+	b.audit(c, models.AuditEntry{
+		CharacterID: charID,
+		Before:      map[string]interface{}{field: characterFields(char)[field]},
+		After:       map[string]interface{}{field: processedValue},
+	})
 	
-	_, err = database.CharacterCollection.UpdateOne(nil, map[string]interface{}{"id": charID}, update)
-	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Failed to update character!")
-		b.API.Send(reply)
+	b.reply(p, msg.Chat.ID, "update.done", i18n.Args{"ID": charID, "Field": field, "Value": processedValue})
+}
+
+// maxAliasLength bounds the length of an alias in letters
+const maxAliasLength = 64
+
+// cmdAddAlias handles /addalias: adds a name /guess accepts for a character
+func (b *Bot) cmdAddAlias(c *CommandContext) {
+	b.changeAlias(c, "alias.added", b.CharacterService.AddAlias)
+}
+
+// cmdRmAlias handles /rmalias: removes a name /guess accepts for a character
+func (b *Bot) cmdRmAlias(c *CommandContext) {
+	b.changeAlias(c, "alias.removed", b.CharacterService.RemoveAlias)
+}
+
+// changeAlias applies an alias change to the character named by the command and reports the
+// character's aliases afterwards
+func (b *Bot) changeAlias(c *CommandContext, doneID string, change func(*models.Character, string, int64) ([]string, error)) {
+	msg := c.Msg
+	p := b.msgPrinter(msg)
+	charID := c.Arg(0)
+	alias := strings.Join(strings.Fields(c.Rest(1)), " ")
+	
+	if len(guess.Words(alias)) == 0 || utf8.RuneCountInString(alias) > maxAliasLength {
+		b.reply(p, msg.Chat.ID, "alias.invalid", i18n.Args{"Max": maxAliasLength})
 		return
 	}
 	
-	reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf(
-		"✅ <b>Updated Successfully!</b>\n\n"+
-			"🆔 ID: <code>%s</code>\n"+
-			"🔄 Field: <code>%s</code>\n"+
-			"✨ New Value: <code>%v</code>",
-		charID, field, processedValue))
-	reply.ParseMode = "HTML"
-	b.API.Send(reply)
+	char, err := b.CharacterService.GetCharacterByID(charID)
+	if err != nil {
+		b.reply(p, msg.Chat.ID, "character.not_found", i18n.Args{"ID": charID})
+		return
+	}
 	
-	_ = char // Use char variable
+	aliases, err := change(char, alias, msg.From.ID)
+	switch {
+	case err == nil:
+	case errors.Is(err, services.ErrAliasExists):
+		b.reply(p, msg.Chat.ID, "alias.exists", i18n.Args{"Alias": alias, "Name": char.Name})
+		return
+	case errors.Is(err, services.ErrAliasNotFound):
+		b.reply(p, msg.Chat.ID, "alias.not_found", i18n.Args{"Alias": alias, "Name": char.Name})
+		return
+	case errors.Is(err, services.ErrTooManyAliases):
+		b.reply(p, msg.Chat.ID, "alias.too_many", i18n.Args{"Max": services.MaxAliases})
+		return
+	default:
+		logError(c.Log, err, "update character aliases", zap.String("character_id", charID))
+		b.reply(p, msg.Chat.ID, "update.failed", nil)
+		return
+	}
+	b.audit(c, models.AuditEntry{
+		CharacterID: charID,
+		Before:      map[string]interface{}{"aliases": char.Aliases},
+		After:       map[string]interface{}{"aliases": aliases},
+	})
+	
+	b.reply(p, msg.Chat.ID, doneID, i18n.Args{
+		"ID":      charID,
+		"Name":    char.Name,
+		"Alias":   alias,
+		"Aliases": strings.Join(aliases, ", "),
+	})
+}
+
+// characterFields returns the fields of char that /upload sets and /update can change
+func characterFields(char *models.Character) map[string]interface{} {
+	return map[string]interface{}{
+		"name":    char.Name,
+		"anime":   char.Anime,
+		"rarity":  char.Rarity,
+		"img_url": char.ImgURL,
+	}
 }
 
 // cmdStats handles /stats command
 func (b *Bot) cmdStats(msg *tgbotapi.Message) {
-	// Check if user is admin
-	if !b.Config.IsSudo(msg.From.ID) {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "⛔ You do not have permission to use this command.")
-		b.API.Send(reply)
-		return
-	}
+	p := b.msgPrinter(msg)
 	
 	// Get total count
 	total, err := b.CharacterService.GetCharacterCount()
 	if err != nil {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Error fetching stats!")
-		b.API.Send(reply)
+		b.reply(p, msg.Chat.ID, "stats.failed", nil)
 		return
 	}
 	
-	// Build message
-	text := fmt.Sprintf("📊 <b>Database Statistics</b>\n\n")
-	text += fmt.Sprintf("📦 <b>Total Characters:</b> <code>%d</code>\n", total)
+	// Command usage since startup
+	var lines []string
+	names, stats := b.Router.TopCommands(5)
+	for i, name := range names {
+		avg := stats[i].Total / time.Duration(stats[i].Calls)
+		lines = append(lines, p.Text("stats.command", i18n.Args{
+			"Command": name,
+			"Calls":   stats[i].Calls,
+			"Average": avg.Round(time.Millisecond).String(),
+		}))
+	}
 	
-	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
-	reply.ParseMode = "HTML"
-	b.API.Send(reply)
+	b.reply(p, msg.Chat.ID, "stats.show", i18n.Args{"Total": total, "Commands": i18n.HTML(strings.Join(lines, "\n"))})
 }
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"senpai-waifu-bot/internal/config"
+	"senpai-waifu-bot/internal/i18n"
+	"senpai-waifu-bot/internal/telegramtest"
+)
+
+// webhookSecret is the secret token the test bot expects
+const webhookSecret = "s3cret"
+
+// recordedUpdate is a /balance command as Telegram posts it
+const recordedUpdate = `{
+  "update_id": 900100,
+  "message": {
+    "message_id": 42,
+    "from": {"id": 2, "is_bot": false, "first_name": "bob", "username": "bob", "language_code": "en"},
+    "chat": {"id": 2, "first_name": "bob", "username": "bob", "type": "private"},
+    "date": 1760000000,
+    "text": "/balance",
+    "entities": [{"offset": 0, "length": 8, "type": "bot_command"}]
+  }
+}`
+
+// newWebhookBot returns a test bot that receives updates by webhook
+func newWebhookBot(t *testing.T) (*Bot, *telegramtest.Server) {
+	t.Helper()
+	bot, srv, _ := newTestBot(t, func(cfg *config.Config) {
+		cfg.UpdateMode = config.UpdateModeWebhook
+		cfg.WebhookSecret = webhookSecret
+	})
+	return bot, srv
+}
+
+func TestWebhookHandlerRejects(t *testing.T) {
+	bot, srv := newWebhookBot(t)
+	handler := bot.WebhookHandler()
+	tests := []struct {
+		name   string
+		method string
+		secret string
+		body   string
+		want   int
+	}{
+		{"missing secret", http.MethodPost, "", recordedUpdate, http.StatusForbidden},
+		{"wrong secret", http.MethodPost, "guess", recordedUpdate, http.StatusForbidden},
+		{"GET", http.MethodGet, webhookSecret, "", http.StatusMethodNotAllowed},
+		{"PUT", http.MethodPut, webhookSecret, recordedUpdate, http.StatusMethodNotAllowed},
+		{"invalid JSON", http.MethodPost, webhookSecret, `{"update_id": `, http.StatusBadRequest},
+		{"too large", http.MethodPost, webhookSecret, `{"update_id": 1, "padding": "` + strings.Repeat("x", maxUpdateSize) + `"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/telegram/webhook", strings.NewReader(tt.body))
+			if tt.secret != "" {
+				req.Header.Set(secretTokenHeader, tt.secret)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusMethodNotAllowed && rec.Header().Get("Allow") != http.MethodPost {
+				t.Errorf("Allow = %q, want POST", rec.Header().Get("Allow"))
+			}
+		})
+	}
+
+	// Nothing rejected reached the bot
+	if err := bot.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sent := srv.Sent(); len(sent) != 0 {
+		t.Errorf("rejected requests were handled: %q", texts(sent))
+	}
+}
+
+func TestWebhookHandlerDispatchesUpdate(t *testing.T) {
+	bot, srv := newWebhookBot(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/telegram/webhook", strings.NewReader(recordedUpdate))
+	req.Header.Set(secretTokenHeader, webhookSecret)
+	rec := httptest.NewRecorder()
+	bot.WebhookHandler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	// The update is handled in the background; shutting down waits for it
+	if err := bot.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	bob := &tgbotapi.User{ID: 2, FirstName: "bob", UserName: "bob", LanguageCode: "en"}
+	want := bot.printer(bob.ID, bob).Text("balance.show", i18n.Args{"UserID": bob.ID, "Name": "bob", "Balance": int64(0)})
+	sent := srv.Sent()
+	expectTexts(t, "webhook update", sent, want)
+	if sent[0].ChatID() != bob.ID {
+		t.Errorf("reply sent to chat %d, want %d", sent[0].ChatID(), bob.ID)
+	}
+}
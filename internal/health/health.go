@@ -0,0 +1,248 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// probeTimeout bounds each probe run by a readiness check
+const probeTimeout = 2 * time.Second
+
+// Probe checks a subsystem on demand, e.g. by pinging it
+type Probe func(ctx context.Context) error
+
+// CheckResult is the state of one subsystem in a health report
+type CheckResult struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Report is the body of /healthz and /readyz
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// result is the last outcome reported for a subsystem
+type result struct {
+	err error
+	at  time.Time
+}
+
+// Checker collects the health of the bot's subsystems. Subsystems either report their own
+// state (Report), are probed when readiness is checked (AddProbe), or are background jobs
+// that must be running (JobStarted/JobStopped). The update loop proves it is alive by
+// calling Beat.
+type Checker struct {
+	mu       sync.Mutex
+	results  map[string]result
+	maxAge   map[string]time.Duration
+	probes   map[string]Probe
+	jobs     map[string]bool
+	lastBeat time.Time
+
+	beatTimeout time.Duration
+}
+
+// New creates a Checker that considers the update loop stalled when it has not beaten
+// for beatTimeout
+func New(beatTimeout time.Duration) *Checker {
+	return &Checker{
+		results:     make(map[string]result),
+		maxAge:      make(map[string]time.Duration),
+		probes:      make(map[string]Probe),
+		jobs:        make(map[string]bool),
+		beatTimeout: beatTimeout,
+	}
+}
+
+// Report records the outcome of checking a subsystem; a nil err means it is healthy
+func (c *Checker) Report(name string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results[name] = result{err: err, at: time.Now()}
+}
+
+// RequireFresh makes the subsystem unready when its last report is older than maxAge
+func (c *Checker) RequireFresh(name string, maxAge time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxAge[name] = maxAge
+}
+
+// AddProbe checks a subsystem on every readiness check. A probe replaces any report
+// under the same name.
+func (c *Checker) AddProbe(name string, probe Probe) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.probes[name] = probe
+}
+
+// JobStarted marks a background job as running
+func (c *Checker) JobStarted(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.jobs[name] = true
+}
+
+// JobStopped marks a background job as no longer running
+func (c *Checker) JobStopped(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.jobs[name] = false
+}
+
+// Beat records that the update loop is still running
+func (c *Checker) Beat() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastBeat = time.Now()
+}
+
+// Degraded returns the subsystems whose last report was a failure, with their errors
+func (c *Checker) Degraded() map[string]error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	degraded := make(map[string]error)
+	for name, r := range c.results {
+		if r.err != nil {
+			degraded[name] = r.err
+		}
+	}
+	return degraded
+}
+
+// Live reports whether the process is alive: the update loop has not started yet, or it
+// has beaten recently
+func (c *Checker) Live() Report {
+	c.mu.Lock()
+	loop := c.loopResult(true)
+	c.mu.Unlock()
+
+	return newReport("alive", "stalled", map[string]CheckResult{"update_loop": loop})
+}
+
+// Ready reports whether the bot can serve users: every reported subsystem is healthy and
+// fresh, every probe passes, every background job is running and the update loop is beating
+func (c *Checker) Ready(ctx context.Context) Report {
+	checks := make(map[string]CheckResult)
+
+	c.mu.Lock()
+	now := time.Now()
+	for name, r := range c.results {
+		if _, probed := c.probes[name]; probed {
+			continue
+		}
+		switch maxAge := c.maxAge[name]; {
+		case r.err != nil:
+			checks[name] = CheckResult{Error: r.err.Error()}
+		case maxAge > 0 && now.Sub(r.at) > maxAge:
+			checks[name] = CheckResult{Error: "last successful check was " + now.Sub(r.at).Round(time.Second).String() + " ago"}
+		default:
+			checks[name] = CheckResult{OK: true}
+		}
+	}
+	for name, running := range c.jobs {
+		if running {
+			checks["job:"+name] = CheckResult{OK: true}
+		} else {
+			checks["job:"+name] = CheckResult{Error: "not running"}
+		}
+	}
+	checks["update_loop"] = c.loopResult(false)
+
+	probes := make(map[string]Probe, len(c.probes))
+	for name, probe := range c.probes {
+		probes[name] = probe
+	}
+	c.mu.Unlock()
+
+	// Probes do I/O, so they run outside the lock and in parallel
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for name, probe := range probes {
+		wg.Add(1)
+		go func(name string, probe Probe) {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+			defer cancel()
+
+			check := CheckResult{OK: true}
+			if err := probe(probeCtx); err != nil {
+				check = CheckResult{Error: err.Error()}
+			}
+			mu.Lock()
+			checks[name] = check
+			mu.Unlock()
+		}(name, probe)
+	}
+	wg.Wait()
+
+	return newReport("ready", "not_ready", checks)
+}
+
+// loopResult checks the update loop's heartbeat; c.mu must be held
+func (c *Checker) loopResult(okBeforeStart bool) CheckResult {
+	if c.lastBeat.IsZero() {
+		if okBeforeStart {
+			return CheckResult{OK: true}
+		}
+		return CheckResult{Error: "not started"}
+	}
+	if since := time.Since(c.lastBeat); since > c.beatTimeout {
+		return CheckResult{Error: "no heartbeat for " + since.Round(time.Second).String()}
+	}
+	return CheckResult{OK: true}
+}
+
+// Failing returns the names of the failed checks in r, sorted
+func (r Report) Failing() []string {
+	var names []string
+	for name, check := range r.Checks {
+		if !check.OK {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LiveHandler serves Live as JSON: 200 when alive, 503 otherwise
+func (c *Checker) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, c.Live())
+	})
+}
+
+// ReadyHandler serves Ready as JSON: 200 when ready, 503 otherwise
+func (c *Checker) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, c.Ready(r.Context()))
+	})
+}
+
+// newReport sets the report's status to okStatus when every check passed
+func newReport(okStatus, failStatus string, checks map[string]CheckResult) Report {
+	report := Report{Status: okStatus, Checks: checks}
+	for _, check := range checks {
+		if !check.OK {
+			report.Status = failStatus
+			break
+		}
+	}
+	return report
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if len(report.Failing()) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,103 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Group runs one HTTP server per listen address, so endpoints configured with the same
+// address (say /metrics and /healthz) share a server. Handlers can be added after Start;
+// a new address then gets its server started right away.
+type Group struct {
+	log *zap.Logger
+
+	mu      sync.Mutex
+	servers map[string]*http.Server
+	muxes   map[string]*http.ServeMux
+	started bool
+}
+
+// NewGroup creates an empty Group
+func NewGroup(log *zap.Logger) *Group {
+	return &Group{
+		log:     log,
+		servers: make(map[string]*http.Server),
+		muxes:   make(map[string]*http.ServeMux),
+	}
+}
+
+// Handle serves h for pattern on the server listening on addr. It fails only when the
+// group is started and addr cannot be listened on.
+func (g *Group) Handle(addr, pattern string, h http.Handler) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	mux, ok := g.muxes[addr]
+	if !ok {
+		mux = http.NewServeMux()
+		server := &http.Server{
+			Addr:     addr,
+			Handler:  mux,
+			ErrorLog: zap.NewStdLog(g.log),
+		}
+		if g.started {
+			if err := g.serve(server); err != nil {
+				return err
+			}
+		}
+		g.muxes[addr] = mux
+		g.servers[addr] = server
+	}
+	mux.Handle(pattern, h)
+	return nil
+}
+
+// Start listens on every address and serves in the background
+func (g *Group) Start() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.started = true
+	for _, server := range g.servers {
+		if err := g.serve(server); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown stops accepting requests and waits for the active ones until ctx is done
+func (g *Group) Shutdown(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var errs []error
+	for _, server := range g.servers {
+		if err := server.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// serve listens synchronously, so a busy port is reported to the caller, then serves in
+// the background
+func (g *Group) serve(server *http.Server) error {
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", server.Addr, err)
+	}
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			g.log.Error("http server stopped", zap.String("listen", server.Addr), zap.Error(err))
+		}
+	}()
+	g.log.Info("serving http", zap.String("listen", server.Addr))
+	return nil
+}
@@ -0,0 +1,290 @@
+// Package i18n renders the bot's replies from per-language message catalogs.
+//
+// Each bundle in locales/ maps message IDs to text/template sources written as Telegram
+// HTML. Every value interpolated by an action is HTML-escaped unless it is of type HTML, so
+// user-supplied names can be passed as plain strings. Literal template text is trusted
+// markup. When a locale asks for small caps, the literal text is converted once at load and
+// the caps function converts interpolated values; tags, entities, <code> and <pre> blocks,
+// /commands and @usernames are left alone.
+//
+// Templates can call:
+//
+//	caps X         X in small caps when the locale uses them, X otherwise
+//	num N          N with thousands separators
+//	rarity N       the emoji and localized name of rarity N
+//	mention ID X   a tg://user link to ID labelled X
+package i18n
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+
+	"gopkg.in/yaml.v3"
+	"senpai-waifu-bot/internal/utils"
+)
+
+// Fallback is the language every bundle falls back to for messages it does not translate
+const Fallback = "en"
+
+// nameID is the message holding a bundle's own name for its language
+const nameID = "language.name"
+
+// escapeFunc is appended to every action so interpolated values are escaped
+const escapeFunc = "_escape"
+
+//go:embed locales/*.yaml
+var locales embed.FS
+
+// Args are the values a message template refers to as .Name
+type Args map[string]interface{}
+
+// HTML is trusted markup that is interpolated without escaping
+type HTML string
+
+// Locale selects the language of a message and whether it is rendered in small caps
+type Locale struct {
+	Language  string
+	SmallCaps bool
+}
+
+// Catalog holds the parsed templates of every bundle
+type Catalog struct {
+	languages []string
+	names     map[string]string
+	sets      map[Locale]*template.Template
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultCatalog *Catalog
+)
+
+// Default returns the catalog built from the bundles compiled into the binary. The bundles
+// are part of the source, so a broken one panics like template.Must.
+func Default() *Catalog {
+	defaultOnce.Do(func() {
+		sub, err := fs.Sub(locales, "locales")
+		if err == nil {
+			defaultCatalog, err = Load(sub)
+		}
+		if err != nil {
+			panic("i18n: " + err.Error())
+		}
+	})
+	return defaultCatalog
+}
+
+// Load parses every <language>.yaml bundle in fsys. The Fallback bundle is required, and
+// other bundles may only contain message IDs it defines.
+func Load(fsys fs.FS) (*Catalog, error) {
+	files, err := fs.Glob(fsys, "*.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	bundles := make(map[string]map[string]string)
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var messages map[string]string
+		if err := yaml.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", file, err)
+		}
+		bundles[strings.TrimSuffix(path.Base(file), ".yaml")] = messages
+	}
+
+	fallback, ok := bundles[Fallback]
+	if !ok {
+		return nil, fmt.Errorf("missing %s.yaml", Fallback)
+	}
+
+	c := &Catalog{
+		names: make(map[string]string),
+		sets:  make(map[Locale]*template.Template),
+	}
+	for lang, messages := range bundles {
+		merged := make(map[string]string, len(fallback))
+		for id, src := range fallback {
+			merged[id] = src
+		}
+		for id, src := range messages {
+			if _, ok := fallback[id]; !ok {
+				return nil, fmt.Errorf("%s.yaml: message %q is not in %s.yaml", lang, id, Fallback)
+			}
+			merged[id] = src
+		}
+
+		for _, smallCaps := range []bool{false, true} {
+			set, err := parseSet(merged, smallCaps)
+			if err != nil {
+				return nil, fmt.Errorf("%s.yaml: %w", lang, err)
+			}
+			c.sets[Locale{Language: lang, SmallCaps: smallCaps}] = set
+		}
+		c.languages = append(c.languages, lang)
+		c.names[lang] = merged[nameID]
+	}
+	sort.Strings(c.languages)
+	return c, nil
+}
+
+// Languages returns the codes of the available languages, sorted
+func (c *Catalog) Languages() []string {
+	return append([]string{}, c.languages...)
+}
+
+// Has reports whether lang is an available language
+func (c *Catalog) Has(lang string) bool {
+	_, ok := c.names[lang]
+	return ok
+}
+
+// Name returns the name of lang in that language, e.g. "Español"
+func (c *Catalog) Name(lang string) string {
+	return c.names[lang]
+}
+
+// Match finds the available language for a Telegram language code such as "es" or "pt-br"
+func (c *Catalog) Match(code string) (string, bool) {
+	code = strings.ToLower(code)
+	if c.Has(code) {
+		return code, true
+	}
+	if i := strings.IndexAny(code, "-_"); i > 0 && c.Has(code[:i]) {
+		return code[:i], true
+	}
+	return "", false
+}
+
+// Printer returns a printer for loc, using the Fallback language if loc's is not available
+func (c *Catalog) Printer(loc Locale) *Printer {
+	if !c.Has(loc.Language) {
+		loc.Language = Fallback
+	}
+	return &Printer{locale: loc, set: c.sets[loc]}
+}
+
+// parseSet parses every message into one template set, so messages can include each other
+func parseSet(messages map[string]string, smallCaps bool) (*template.Template, error) {
+	set := template.New("").Option("missingkey=error")
+	set.Funcs(funcs(set, smallCaps))
+
+	for id, src := range messages {
+		if _, err := set.New(id).Parse(src); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range set.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		rewrite(t.Tree.Root, smallCaps)
+	}
+	return set, nil
+}
+
+// rewrite escapes the output of every action and, for small caps, converts the literal text
+func rewrite(node parse.Node, smallCaps bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			rewrite(child, smallCaps)
+		}
+	case *parse.TextNode:
+		if smallCaps {
+			n.Text = []byte(SmallCaps(string(n.Text)))
+		}
+	case *parse.ActionNode:
+		// Variable declarations print nothing
+		if len(n.Pipe.Decl) == 0 {
+			n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+				NodeType: parse.NodeCommand,
+				Pos:      n.Pos,
+				Args:     []parse.Node{parse.NewIdentifier(escapeFunc).SetPos(n.Pos)},
+			})
+		}
+	case *parse.IfNode:
+		rewrite(n.List, smallCaps)
+		rewrite(n.ElseList, smallCaps)
+	case *parse.RangeNode:
+		rewrite(n.List, smallCaps)
+		rewrite(n.ElseList, smallCaps)
+	case *parse.WithNode:
+		rewrite(n.List, smallCaps)
+		rewrite(n.ElseList, smallCaps)
+	}
+}
+
+// funcs returns the functions available to the templates of set
+func funcs(set *template.Template, smallCaps bool) template.FuncMap {
+	return template.FuncMap{
+		escapeFunc: escape,
+		"caps": func(v interface{}) interface{} {
+			if !smallCaps {
+				return v
+			}
+			switch v := v.(type) {
+			case HTML:
+				return HTML(SmallCaps(string(v)))
+			case nil:
+				return ""
+			default:
+				return utils.ToSmallCaps(fmt.Sprint(v))
+			}
+		},
+		"num": func(v interface{}) (string, error) {
+			switch n := v.(type) {
+			case int:
+				return utils.FormatNumber(int64(n)), nil
+			case int64:
+				return utils.FormatNumber(n), nil
+			case int32:
+				return utils.FormatNumber(int64(n)), nil
+			}
+			return "", fmt.Errorf("num: %T is not an integer", v)
+		},
+		"rarity": func(rarity int) (string, error) {
+			t := set.Lookup("rarity." + strconv.Itoa(rarity))
+			if t == nil {
+				return utils.GetRarityDisplay(rarity), nil
+			}
+			var buf bytes.Buffer
+			if err := t.Execute(&buf, nil); err != nil {
+				return "", err
+			}
+			return utils.RarityEmojis[rarity] + " " + buf.String(), nil
+		},
+		"mention": func(id int64, name interface{}) HTML {
+			return HTML(fmt.Sprintf("<a href='tg://user?id=%d'>%s</a>", id, escape(name)))
+		},
+	}
+}
+
+// escape renders v for interpolation into HTML
+func escape(v interface{}) string {
+	switch v := v.(type) {
+	case HTML:
+		return string(v)
+	case string:
+		return html.EscapeString(v)
+	case nil:
+		return ""
+	default:
+		return html.EscapeString(fmt.Sprint(v))
+	}
+}
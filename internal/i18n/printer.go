@@ -0,0 +1,46 @@
+package i18n
+
+import (
+	"html"
+	"strings"
+	"text/template"
+)
+
+// Printer renders messages for one locale. It is safe for concurrent use.
+type Printer struct {
+	locale Locale
+	set    *template.Template
+}
+
+// Locale returns the locale the printer renders in
+func (p *Printer) Locale() Locale {
+	return p.locale
+}
+
+// Has reports whether message id is in the catalog
+func (p *Printer) Has(id string) bool {
+	return p.set.Lookup(id) != nil
+}
+
+// Text renders message id as Telegram HTML. A message that is missing or fails to render,
+// e.g. because an argument was not passed, comes out as its ID so the mistake is visible.
+func (p *Printer) Text(id string, args Args) string {
+	t := p.set.Lookup(id)
+	if t == nil {
+		return id
+	}
+	if args == nil {
+		args = Args{}
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, args); err != nil {
+		return id
+	}
+	return b.String()
+}
+
+// Plain renders message id as plain text, for buttons, alerts and command descriptions
+// that Telegram does not parse as HTML
+func (p *Printer) Plain(id string, args Args) string {
+	return html.UnescapeString(p.Text(id, args))
+}
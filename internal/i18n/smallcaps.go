@@ -0,0 +1,76 @@
+package i18n
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"senpai-waifu-bot/internal/utils"
+)
+
+// verbatimTags hold text that must be shown as written, such as IDs and usage lines
+var verbatimTags = []string{"code", "pre"}
+
+// SmallCaps converts the letters of an HTML fragment to small caps, leaving tags, entities,
+// the contents of <code> and <pre>, /commands, @usernames and #hashtags untouched
+func SmallCaps(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) * 2)
+
+	wordStart := true
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == '<':
+			end := verbatimEnd(s, i)
+			b.WriteString(s[i:end])
+			i = end
+			wordStart = true
+			continue
+		case c == '&':
+			if end := strings.IndexByte(s[i:], ';'); end > 0 && end < 10 {
+				b.WriteString(s[i : i+end+1])
+				i += end + 1
+				wordStart = false
+				continue
+			}
+		case wordStart && (c == '/' || c == '@' || c == '#'):
+			end := i + strings.IndexFunc(s[i:], unicode.IsSpace)
+			if end < i {
+				end = len(s)
+			}
+			b.WriteString(s[i:end])
+			i = end
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if small, ok := utils.SmallCapsMap[r]; ok {
+			b.WriteRune(small)
+		} else {
+			b.WriteString(s[i : i+size])
+		}
+		wordStart = unicode.IsSpace(r) || r == '(' || r == '"'
+		i += size
+	}
+	return b.String()
+}
+
+// verbatimEnd returns the end of the tag starting at s[i], or of its closing tag when the
+// tag's contents are verbatim
+func verbatimEnd(s string, i int) int {
+	close := strings.IndexByte(s[i:], '>')
+	if close < 0 {
+		return len(s)
+	}
+	end := i + close + 1
+	for _, tag := range verbatimTags {
+		if !strings.HasPrefix(s[i:], "<"+tag) {
+			continue
+		}
+		if closing := strings.Index(s[end:], "</"+tag+">"); closing >= 0 {
+			return end + closing + len(tag) + 3
+		}
+		return len(s)
+	}
+	return end
+}
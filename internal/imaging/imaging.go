@@ -0,0 +1,437 @@
+// Package imaging disguises spawn images so that the picture alone does not give the
+// character away: it downloads an image, turns it into a silhouette or blurs it, and caches
+// the result on disk. Everything is done in pure Go with the standard image packages.
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Disguise styles
+const (
+	// StyleSilhouette fills the character with a flat color, falling back to StyleBlur when
+	// it cannot be told apart from the background
+	StyleSilhouette = "silhouette"
+	// StyleBlur blurs the whole image
+	StyleBlur = "blur"
+	// StyleOff posts images as they are
+	StyleOff = "off"
+)
+
+// maxSide bounds the width and height of disguised images; Telegram shows spawns smaller
+// than this anyway
+const maxSide = 640
+
+// maxPixels bounds the size of images that are decoded. A small file can declare a huge
+// image, and decoding allocates 4 bytes per pixel before Fit shrinks it.
+const maxPixels = 25_000_000
+
+// Silhouette tuning. Distances are between RGB colors, at most about 441.
+const (
+	// foregroundDistance is how far a pixel must be from the background color to be part of
+	// the character
+	foregroundDistance = 48
+	// maxBorderSpread is the largest average distance of the border pixels from their mean
+	// color for the border to count as a plain background
+	maxBorderSpread = 40
+	// minForeground and maxForeground bound the share of the image, in percent, the character
+	// may cover for the silhouette to be believable
+	minForeground = 3
+	maxForeground = 85
+)
+
+var (
+	silhouetteColor  = color.NRGBA{R: 24, G: 24, B: 36, A: 255}
+	backgroundColor  = color.NRGBA{R: 236, G: 236, B: 242, A: 255}
+	errImageTooLarge = errors.New("image too large")
+	errTooManyPixels = errors.New("image has too many pixels")
+)
+
+// Disguiser downloads images and disguises them in one style, caching the results
+type Disguiser struct {
+	style         string
+	cacheDir      string
+	cacheMaxBytes int64
+	maxBytes      int64
+	client        *http.Client
+}
+
+// NewDisguiser creates a Disguiser that downloads images of up to maxBytes within timeout.
+// Disguised images are cached in cacheDir, which is created when needed; an empty cacheDir
+// disables the cache. PruneCache keeps the cache within cacheMaxBytes; 0 leaves it unbounded.
+func NewDisguiser(style, cacheDir string, cacheMaxBytes, maxBytes int64, timeout time.Duration) *Disguiser {
+	return &Disguiser{
+		style:         style,
+		cacheDir:      cacheDir,
+		cacheMaxBytes: cacheMaxBytes,
+		maxBytes:      maxBytes,
+		client:        &http.Client{Timeout: timeout},
+	}
+}
+
+// Style returns the style images are disguised in
+func (d *Disguiser) Style() string {
+	return d.style
+}
+
+// Disguise returns the disguised image at url as a JPEG, and whether it came from the cache
+func (d *Disguiser) Disguise(ctx context.Context, url string) ([]byte, bool, error) {
+	path := d.cachePath(url)
+	if path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			// Mark the image as recently used, so PruneCache removes it last
+			now := time.Now()
+			os.Chtimes(path, now, now)
+			return data, true, nil
+		}
+	}
+
+	img, err := d.download(ctx, url)
+	if err != nil {
+		return nil, false, err
+	}
+	var disguised image.Image
+	if d.style == StyleSilhouette {
+		if silhouette, ok := Silhouette(img); ok {
+			disguised = silhouette
+		}
+	}
+	if disguised == nil {
+		disguised = Blur(Flatten(img, color.White), blurRadius(img))
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, disguised, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, false, fmt.Errorf("encode image: %w", err)
+	}
+	if path != "" {
+		if err := writeFile(path, buf.Bytes()); err != nil {
+			return buf.Bytes(), false, fmt.Errorf("cache image: %w", err)
+		}
+	}
+	return buf.Bytes(), false, nil
+}
+
+// cachePath returns the cache file of the disguised image at url, or "" without a cache
+func (d *Disguiser) cachePath(url string) string {
+	if d.cacheDir == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(d.style + "\x00" + url))
+	return filepath.Join(d.cacheDir, hex.EncodeToString(sum[:])+".jpg")
+}
+
+// PruneCache removes the least recently used disguised images until the cache holds no more
+// than its size limit, and returns how many it removed
+func (d *Disguiser) PruneCache() (int, error) {
+	if d.cacheDir == "" || d.cacheMaxBytes <= 0 {
+		return 0, nil
+	}
+	entries, err := os.ReadDir(d.cacheDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	type cachedFile struct {
+		path string
+		size int64
+		used time.Time
+	}
+	var files []cachedFile
+	var total int64
+	for _, entry := range entries {
+		// Images being written are still temporary files without the extension
+		if !entry.Type().IsRegular() || filepath.Ext(entry.Name()) != ".jpg" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cachedFile{filepath.Join(d.cacheDir, entry.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].used.Before(files[j].used) })
+
+	removed := 0
+	for _, file := range files {
+		if total <= d.cacheMaxBytes {
+			break
+		}
+		if err := os.Remove(file.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, err
+		}
+		total -= file.size
+		removed++
+	}
+	return removed, nil
+}
+
+// download fetches and decodes the image at url, scaled down to fit maxSide. Images larger
+// than maxPixels are refused without being decoded.
+func (d *Disguiser) download(ctx context.Context, url string) (*image.NRGBA, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download image: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, d.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("download image: %w", err)
+	}
+	if int64(len(data)) > d.maxBytes {
+		return nil, errImageTooLarge
+	}
+	// Check the dimensions in the header before allocating the bitmap
+	conf, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	if conf.Width <= 0 || conf.Height <= 0 || int64(conf.Width)*int64(conf.Height) > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", errTooManyPixels, conf.Width, conf.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	return Fit(img, maxSide), nil
+}
+
+// writeFile writes data to path through a temporary file, so that readers never see a
+// partly written image
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Fit returns img as NRGBA, scaled down by averaging so that neither side exceeds side
+func Fit(img image.Image, side int) *image.NRGBA {
+	src := image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if w <= side && h <= side {
+		return src
+	}
+	dw, dh := side, h*side/w
+	if h > w {
+		dw, dh = w*side/h, side
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					i := src.PixOffset(sx, sy)
+					for c := 0; c < 4; c++ {
+						sum[c] += int(src.Pix[i+c])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			i := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[i+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
+
+// Flatten draws img over a background of color bg, leaving no transparency
+func Flatten(img *image.NRGBA, bg color.Color) *image.NRGBA {
+	dst := image.NewNRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
+
+// Silhouette fills the character in img with a flat color on a plain background. The
+// character is what differs from the background, which is taken from the transparent
+// pixels or else from the color of the border. It returns false when the border is not a
+// plain background or the character would cover too little or too much of the image.
+func Silhouette(img *image.NRGBA) (*image.NRGBA, bool) {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	border := borderPixels(img)
+
+	transparent := 0
+	for _, px := range border {
+		if px.A < 128 {
+			transparent++
+		}
+	}
+	useAlpha := transparent*2 > len(border)
+	bg, spread := meanColor(border)
+	if !useAlpha && spread > maxBorderSpread {
+		return nil, false
+	}
+
+	mask := make([]bool, w*h)
+	foreground := 0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			px := img.NRGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+			fg := px.A >= 128
+			if !useAlpha {
+				fg = fg && distance(px, bg) > foregroundDistance
+			}
+			if fg {
+				mask[y*w+x] = true
+				foreground++
+			}
+		}
+	}
+	share := foreground * 100 / max(w*h, 1)
+	if share < minForeground || share > maxForeground {
+		return nil, false
+	}
+
+	out := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i, fg := range mask {
+		c := backgroundColor
+		if fg {
+			c = silhouetteColor
+		}
+		out.SetNRGBA(i%w, i/w, c)
+	}
+	// Soften the jagged edges of the mask
+	return Blur(out, 1), true
+}
+
+// borderPixels returns the pixels in a frame around img about 2% of its size wide
+func borderPixels(img *image.NRGBA) []color.NRGBA {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	frame := max(min(w, h)/50, 1)
+
+	var pixels []color.NRGBA
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < frame || y < frame || x >= w-frame || y >= h-frame {
+				pixels = append(pixels, img.NRGBAAt(bounds.Min.X+x, bounds.Min.Y+y))
+			}
+		}
+	}
+	return pixels
+}
+
+// meanColor returns the mean color of pixels and their average distance from it
+func meanColor(pixels []color.NRGBA) (color.NRGBA, float64) {
+	if len(pixels) == 0 {
+		return color.NRGBA{}, 0
+	}
+	var r, g, b int
+	for _, px := range pixels {
+		r, g, b = r+int(px.R), g+int(px.G), b+int(px.B)
+	}
+	n := len(pixels)
+	mean := color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 255}
+
+	var spread float64
+	for _, px := range pixels {
+		spread += distance(px, mean)
+	}
+	return mean, spread / float64(n)
+}
+
+// distance returns the Euclidean distance between the RGB values of a and b
+func distance(a, b color.NRGBA) float64 {
+	dr, dg, db := float64(a.R)-float64(b.R), float64(a.G)-float64(b.G), float64(a.B)-float64(b.B)
+	return math.Sqrt(dr*dr + dg*dg + db*db)
+}
+
+// blurRadius returns a blur radius that hides the details of img but keeps its colors
+func blurRadius(img image.Image) int {
+	return max(img.Bounds().Dx(), img.Bounds().Dy())/24 + 1
+}
+
+// Blur returns img blurred by three passes of a box blur of the given radius, which is close
+// to a Gaussian blur
+func Blur(img *image.NRGBA, radius int) *image.NRGBA {
+	out := image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Src)
+	if radius < 1 {
+		return out
+	}
+
+	w, h := out.Bounds().Dx(), out.Bounds().Dy()
+	tmp := make([]uint8, len(out.Pix))
+	for pass := 0; pass < 3; pass++ {
+		boxBlur(out.Pix, tmp, w, h, 4, out.Stride, radius)
+		boxBlur(tmp, out.Pix, h, w, out.Stride, 4, radius)
+	}
+	return out
+}
+
+// boxBlur averages every line of src over a window of 2*radius+1 pixels into dst. Lines are
+// n pixels long and step apart; pixels within a line are next apart. Pixels past the ends
+// repeat the edge pixel.
+func boxBlur(src, dst []uint8, n, lines, next, step, radius int) {
+	window := 2*radius + 1
+	for line := 0; line < lines; line++ {
+		base := line * step
+		at := func(i int) int {
+			return base + min(max(i, 0), n-1)*next
+		}
+		for c := 0; c < 4; c++ {
+			sum := 0
+			for i := -radius; i <= radius; i++ {
+				sum += int(src[at(i)+c])
+			}
+			for i := 0; i < n; i++ {
+				dst[base+i*next+c] = uint8(sum / window)
+				sum += int(src[at(i+radius+1)+c]) - int(src[at(i-radius)+c])
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// serveImage serves data as the only image and returns its URL
+func serveImage(t *testing.T, data []byte) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestDisguiseRefusesTooManyPixels(t *testing.T) {
+	// A 1x1 PNG whose header claims 20000x20000 pixels
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 20000)
+	binary.BigEndian.PutUint32(data[20:], 20000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	d := NewDisguiser(StyleBlur, "", 0, 10<<20, 5*time.Second)
+	_, _, err := d.Disguise(context.Background(), serveImage(t, data))
+	if !errors.Is(err, errTooManyPixels) {
+		t.Fatalf("Disguise() error = %v, want %v", err, errTooManyPixels)
+	}
+}
+
+func TestDisguiseFitsImage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1280, 320))); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDisguiser(StyleBlur, t.TempDir(), 0, 10<<20, 5*time.Second)
+	url := serveImage(t, buf.Bytes())
+	data, cached, err := d.Disguise(context.Background(), url)
+	if err != nil || cached {
+		t.Fatalf("Disguise() cached = %v, error = %v", cached, err)
+	}
+	conf, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Width != maxSide || conf.Height != maxSide/4 {
+		t.Errorf("disguised image is %dx%d, want %dx%d", conf.Width, conf.Height, maxSide, maxSide/4)
+	}
+
+	if _, cached, err := d.Disguise(context.Background(), url); err != nil || !cached {
+		t.Errorf("second Disguise() cached = %v, error = %v, want a cache hit", cached, err)
+	}
+}
+
+func TestPruneCacheRemovesLeastRecentlyUsed(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 64, 64))); err != nil {
+		t.Fatal(err)
+	}
+	d := NewDisguiser(StyleBlur, t.TempDir(), 0, 10<<20, 5*time.Second)
+	ctx := context.Background()
+	older, newer := serveImage(t, buf.Bytes()), serveImage(t, buf.Bytes())
+	for i, url := range []string{older, newer} {
+		if _, _, err := d.Disguise(ctx, url); err != nil {
+			t.Fatal(err)
+		}
+		used := time.Now().Add(time.Duration(i-2) * time.Hour)
+		if err := os.Chtimes(d.cachePath(url), used, used); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := os.Stat(d.cachePath(older))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Using the older image makes the newer one the least recently used
+	if _, cached, err := d.Disguise(ctx, older); err != nil || !cached {
+		t.Fatalf("Disguise() cached = %v, error = %v, want a cache hit", cached, err)
+	}
+	d.cacheMaxBytes = info.Size()
+	removed, err := d.PruneCache()
+	if err != nil || removed != 1 {
+		t.Fatalf("PruneCache() = %d, %v, want 1 image removed", removed, err)
+	}
+	if _, err := os.Stat(d.cachePath(older)); err != nil {
+		t.Errorf("recently used image was removed: %v", err)
+	}
+	if _, err := os.Stat(d.cachePath(newer)); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("least recently used image was kept: %v", err)
+	}
+
+	// A cache within its limit is left alone
+	if removed, err := d.PruneCache(); err != nil || removed != 0 {
+		t.Errorf("second PruneCache() = %d, %v, want nothing removed", removed, err)
+	}
+}
@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Log formats
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// New builds the application logger. format is FormatJSON for production or FormatConsole
+// for development; level is a zap level name such as "debug" or "info".
+func New(level, format string) (*zap.Logger, error) {
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg zap.Config
+	switch format {
+	case FormatJSON:
+		cfg = zap.NewProductionConfig()
+		cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	case FormatConsole:
+		cfg = zap.NewDevelopmentConfig()
+		cfg.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+	cfg.Level = zap.NewAtomicLevelAt(lvl)
+	// Noisy paths opt into sampling with Sampled instead
+	cfg.Sampling = nil
+
+	return cfg.Build()
+}
+
+// Sampled returns a logger for hot paths: per message and level, it logs the first 5 entries
+// each second and then every 100th
+func Sampled(l *zap.Logger) *zap.Logger {
+	return l.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewSamplerWithOptions(core, time.Second, 5, 100)
+	}))
+}
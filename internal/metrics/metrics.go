@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+const namespace = "senpai"
+
+// Command outcomes
+const (
+	OutcomeOK          = "ok"
+	OutcomeDenied      = "denied"
+	OutcomeRateLimited = "rate_limited"
+	OutcomeBadArgs     = "bad_args"
+	OutcomePanic       = "panic"
+)
+
+var (
+	// UpdatesTotal counts updates by type
+	UpdatesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "updates_total",
+		Help:      "Telegram updates processed, by update type.",
+	}, []string{"type"})
+
+	// HandlerDuration measures how long an update takes to handle
+	HandlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "handler_duration_seconds",
+		Help:      "Time spent handling an update, by update type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"type"})
+
+	// CommandsTotal counts commands by name and outcome
+	CommandsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commands_total",
+		Help:      "Commands run, by command name and outcome.",
+	}, []string{"command", "outcome"})
+
+	// CommandDuration measures how long a command takes, including its middleware
+	CommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "command_duration_seconds",
+		Help:      "Time spent running a command, by command name.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"command"})
+
+	// TelegramRequestDuration measures Bot API calls
+	TelegramRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "telegram_request_duration_seconds",
+		Help:      "Bot API request latency, by request type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	// TelegramErrorsTotal counts failed Bot API calls by the error code Telegram returned
+	TelegramErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_errors_total",
+		Help:      "Failed Bot API requests, by request type and error code (\"network\" when there was no response).",
+	}, []string{"method", "code"})
+
+	// SpawnsTotal counts spawned characters by rarity
+	SpawnsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "spawns_total",
+		Help:      "Characters spawned in groups, by rarity.",
+	}, []string{"rarity"})
+
+	// SpawnsFledTotal counts spawns nobody guessed before they expired, by rarity
+	SpawnsFledTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "spawns_fled_total",
+		Help:      "Spawned characters that fled because nobody guessed them in time, by rarity.",
+	}, []string{"rarity"})
+
+	// SpawnImagesTotal counts disguised spawn images by whether they were cached, generated or
+	// failed
+	SpawnImagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "spawn_images_total",
+		Help:      "Disguised spawn images, by result: cached, generated or failed.",
+	}, []string{"result"})
+
+	// GuessesTotal counts spawns claimed by a correct guess
+	GuessesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "guesses_total",
+		Help:      "Spawned characters claimed by a correct guess.",
+	})
+
+	// HintsTotal counts hints bought with /hint, by tier
+	HintsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "hints_total",
+		Help:      "Hints bought about spawned characters, by hint tier.",
+	}, []string{"tier"})
+
+	// CoinsMintedTotal counts coins created, by ledger source
+	CoinsMintedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "coins_minted_total",
+		Help:      "Coins added to the economy, by ledger source.",
+	}, []string{"source"})
+
+	// CoinsBurnedTotal counts coins removed, by ledger source
+	CoinsBurnedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "coins_burned_total",
+		Help:      "Coins removed from the economy, by ledger source.",
+	}, []string{"source"})
+
+	// MongoOpDuration measures MongoDB commands
+	MongoOpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongo_operation_duration_seconds",
+		Help:      "MongoDB command latency, by command name and outcome.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"command", "outcome"})
+)
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RecordCoins records a change to the coin supply: positive amounts are minted, negative ones burned.
+// Transfers between users move coins without changing the supply and are not recorded.
+func RecordCoins(source string, amount int64) {
+	switch {
+	case amount > 0:
+		CoinsMintedTotal.WithLabelValues(source).Add(float64(amount))
+	case amount < 0:
+		CoinsBurnedTotal.WithLabelValues(source).Add(float64(-amount))
+	}
+}
+
+// RecordTelegramError counts a failed Bot API request; code is 0 when Telegram did not answer
+func RecordTelegramError(method string, code int) {
+	label := "network"
+	if code != 0 {
+		label = strconv.Itoa(code)
+	}
+	TelegramErrorsTotal.WithLabelValues(method, label).Inc()
+}
+
+// Since observes the time elapsed from start on h
+func Since(h prometheus.Observer, start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// MongoMonitor times every command the driver sends
+func MongoMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			MongoOpDuration.WithLabelValues(e.CommandName, "ok").Observe(e.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			MongoOpDuration.WithLabelValues(e.CommandName, "error").Observe(e.Duration.Seconds())
+		},
+	}
+}
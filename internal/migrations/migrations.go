@@ -0,0 +1,150 @@
+// Package migrations applies versioned changes to the MongoDB schema: renamed collections,
+// backfilled fields and restructured documents. Applied versions are recorded in the
+// migrations collection so every deployment runs each migration once, in order.
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/config"
+)
+
+// Migration is one versioned schema change. Up must tolerate being run again after it failed
+// part way, since a failed migration is not recorded and is retried on the next run.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, env *Env) error
+}
+
+// All lists every migration in version order. Append new migrations with the next version;
+// never renumber or remove one that has been released.
+var All = []Migration{
+	{Version: 1, Name: "backfill character created_at", Up: backfillCharacterCreatedAt},
+	{Version: 2, Name: "seed character id counter", Up: seedCharacterIDCounter},
+}
+
+// Env is what a migration works with. Writes go through its helpers, which only count and
+// log what they would change during a dry run.
+type Env struct {
+	DB          *mongo.Database
+	Collections config.Collections
+	DryRun      bool
+	Log         *zap.Logger
+}
+
+// Collection returns the collection with the given name
+func (e *Env) Collection(name string) *mongo.Collection {
+	return e.DB.Collection(name)
+}
+
+// UpdateMany applies update to every document in coll matching filter. update may be an
+// update document or an aggregation pipeline.
+func (e *Env) UpdateMany(ctx context.Context, coll string, filter, update interface{}) error {
+	if e.DryRun {
+		count, err := e.Collection(coll).CountDocuments(ctx, filter)
+		if err != nil {
+			return err
+		}
+		e.Log.Info("would update documents", zap.String("collection", coll), zap.Int64("count", count))
+		return nil
+	}
+
+	result, err := e.Collection(coll).UpdateMany(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	e.Log.Info("updated documents", zap.String("collection", coll), zap.Int64("matched", result.MatchedCount), zap.Int64("modified", result.ModifiedCount))
+	return nil
+}
+
+// Upsert applies update to the document in coll matching filter, inserting it when there is
+// none
+func (e *Env) Upsert(ctx context.Context, coll string, filter, update interface{}) error {
+	if e.DryRun {
+		e.Log.Info("would upsert document", zap.String("collection", coll), zap.Any("filter", filter), zap.Any("update", update))
+		return nil
+	}
+
+	result, err := e.Collection(coll).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	e.Log.Info("upserted document", zap.String("collection", coll), zap.Int64("modified", result.ModifiedCount), zap.Int64("upserted", result.UpsertedCount))
+	return nil
+}
+
+// RenameCollection renames from to to within the database. It does nothing when from does
+// not exist and to does, so a rename that already happened is skipped.
+func (e *Env) RenameCollection(ctx context.Context, from, to string) error {
+	names, err := e.DB.ListCollectionNames(ctx, bson.M{"name": bson.M{"$in": []string{from, to}}})
+	if err != nil {
+		return err
+	}
+	exists := make(map[string]bool)
+	for _, name := range names {
+		exists[name] = true
+	}
+	if !exists[from] && exists[to] {
+		e.Log.Info("collection already renamed", zap.String("from", from), zap.String("to", to))
+		return nil
+	}
+
+	if e.DryRun {
+		e.Log.Info("would rename collection", zap.String("from", from), zap.String("to", to))
+		return nil
+	}
+	cmd := bson.D{
+		{Key: "renameCollection", Value: e.DB.Name() + "." + from},
+		{Key: "to", Value: e.DB.Name() + "." + to},
+	}
+	if err := e.DB.Client().Database("admin").RunCommand(ctx, cmd).Err(); err != nil {
+		return err
+	}
+	e.Log.Info("renamed collection", zap.String("from", from), zap.String("to", to))
+	return nil
+}
+
+// backfillCharacterCreatedAt sets created_at on characters uploaded by the Python bot, which
+// did not record it, to the creation time embedded in their ObjectId
+func backfillCharacterCreatedAt(ctx context.Context, env *Env) error {
+	return env.UpdateMany(ctx, env.Collections.Characters,
+		bson.M{"created_at": nil, "_id": bson.M{"$type": "objectId"}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"created_at": bson.M{"$toDate": "$_id"}}}}},
+	)
+}
+
+// seedCharacterIDCounter starts the character ID counter at the highest numeric ID in the
+// catalog. IDs used to be derived from the character count, which reused the IDs of deleted
+// characters.
+func seedCharacterIDCounter(ctx context.Context, env *Env) error {
+	cursor, err := env.Collection(env.Collections.Characters).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"id": bson.M{"$regex": "^[0-9]+$"}}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "max": bson.M{"$max": bson.M{"$toLong": "$id"}}}}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Max int64 `bson:"max"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return err
+	}
+	var maxID int64
+	if len(results) > 0 {
+		maxID = results[0].Max
+	}
+
+	// The name matches services.CharacterIDCounter at the time this migration was written
+	return env.Upsert(ctx, env.Collections.Counters,
+		bson.M{"_id": "character_id"},
+		bson.M{"$max": bson.M{"seq": maxID}},
+	)
+}
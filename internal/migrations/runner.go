@@ -0,0 +1,183 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/config"
+)
+
+// lockID is the _id of the document that marks a run in progress
+const lockID = "lock"
+
+// ErrLocked is returned by Up when another run holds the migration lock
+var ErrLocked = errors.New("migrations: another run is in progress")
+
+// record is the stored state of an applied migration
+type record struct {
+	Version   int           `bson:"_id"`
+	Name      string        `bson:"name"`
+	AppliedAt time.Time     `bson:"applied_at"`
+	Took      time.Duration `bson:"took"`
+}
+
+// lock marks a run in progress, so that deployments starting together do not migrate twice
+type lock struct {
+	ID       string    `bson:"_id"`
+	Host     string    `bson:"host"`
+	LockedAt time.Time `bson:"locked_at"`
+}
+
+// Status is a migration and when it was applied; AppliedAt is nil while it is pending
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Runner applies migrations to a database
+type Runner struct {
+	db          *mongo.Database
+	collections config.Collections
+	state       *mongo.Collection
+	migrations  []Migration
+	log         *zap.Logger
+}
+
+// NewRunner creates a Runner for every migration in All, recording its state in the
+// migrations collection of db
+func NewRunner(db *mongo.Database, collections config.Collections, log *zap.Logger) *Runner {
+	return &Runner{
+		db:          db,
+		collections: collections,
+		state:       db.Collection(collections.Migrations),
+		migrations:  All,
+		log:         log,
+	}
+}
+
+// Status lists every migration with the time it was applied
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(r.migrations))
+	for i, m := range r.migrations {
+		statuses[i].Migration = m
+		if rec, ok := applied[m.Version]; ok {
+			at := rec.AppliedAt
+			statuses[i].AppliedAt = &at
+		}
+	}
+	return statuses, nil
+}
+
+// Pending lists the migrations that have not been applied, in version order
+func (r *Runner) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range r.migrations {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies the pending migrations up to and including version target, or all of them when
+// target is 0, stopping at the first that fails. A dry run applies nothing and records
+// nothing; it logs what each migration would change. Up returns the migrations it ran.
+func (r *Runner) Up(ctx context.Context, target int, dryRun bool) ([]Migration, error) {
+	if !dryRun {
+		if err := r.lock(ctx); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err := r.Unlock(context.Background()); err != nil {
+				r.log.Error("failed to release migration lock", zap.Error(err))
+			}
+		}()
+	}
+
+	pending, err := r.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, m := range pending {
+		if target > 0 && m.Version > target {
+			break
+		}
+		log := r.log.With(zap.Int("version", m.Version), zap.String("name", m.Name), zap.Bool("dry_run", dryRun))
+		log.Info("running migration")
+
+		start := time.Now()
+		env := &Env{DB: r.db, Collections: r.collections, DryRun: dryRun, Log: log}
+		if err := m.Up(ctx, env); err != nil {
+			return ran, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		took := time.Since(start)
+
+		if !dryRun {
+			rec := record{Version: m.Version, Name: m.Name, AppliedAt: time.Now(), Took: took}
+			if _, err := r.state.InsertOne(ctx, rec); err != nil {
+				return ran, fmt.Errorf("record migration %d: %w", m.Version, err)
+			}
+		}
+		log.Info("migration done", zap.Duration("took", took))
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// Unlock removes the migration lock, for when a run was killed before releasing it
+func (r *Runner) Unlock(ctx context.Context) error {
+	_, err := r.state.DeleteOne(ctx, bson.M{"_id": lockID})
+	return err
+}
+
+// applied returns the stored state of the applied migrations by version
+func (r *Runner) applied(ctx context.Context) (map[int]record, error) {
+	cursor, err := r.state.Find(ctx, bson.M{"_id": bson.M{"$ne": lockID}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]record, len(records))
+	for _, rec := range records {
+		applied[rec.Version] = rec
+	}
+	return applied, nil
+}
+
+// lock takes the migration lock, failing with ErrLocked when another run holds it
+func (r *Runner) lock(ctx context.Context) error {
+	host, _ := os.Hostname()
+	_, err := r.state.InsertOne(ctx, lock{ID: lockID, Host: host, LockedAt: time.Now()})
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	var held lock
+	if err := r.state.FindOne(ctx, bson.M{"_id": lockID}).Decode(&held); err != nil {
+		return ErrLocked
+	}
+	return fmt.Errorf("%w: locked by %s since %s", ErrLocked, held.Host, held.LockedAt.Format(time.RFC3339))
+}
@@ -8,11 +8,19 @@ import (
 
 // Character represents an anime character in the database
 type Character struct {
-	ID      string `bson:"id" json:"id"`
-	Name    string `bson:"name" json:"name"`
-	Anime   string `bson:"anime" json:"anime"`
-	Rarity  int    `bson:"rarity" json:"rarity"`
-	ImgURL  string `bson:"img_url" json:"img_url"`
+	ID          string     `bson:"id" json:"id"`
+	Name        string     `bson:"name" json:"name"`
+	Anime       string     `bson:"anime" json:"anime"`
+	Rarity      int        `bson:"rarity" json:"rarity"`
+	ImgURL      string     `bson:"img_url" json:"img_url"`
+	// Aliases are other names accepted by /guess, such as nicknames and romanizations
+	Aliases     []string   `bson:"aliases,omitempty" json:"aliases,omitempty"`
+	MessageID   int        `bson:"message_id,omitempty" json:"message_id,omitempty"`
+	AddedBy     int64      `bson:"added_by,omitempty" json:"added_by,omitempty"`
+	AddedByName string     `bson:"added_by_name,omitempty" json:"added_by_name,omitempty"`
+	CreatedAt   *time.Time `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt   *time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	UpdatedBy   int64      `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
 }
 
 // UserCharacter represents a character in user's collection
@@ -66,6 +74,14 @@ type GroupUserTotal struct {
 	Count     int64  `bson:"count" json:"count"`
 }
 
+// Grabber represents a user holding copies of a specific character
+type Grabber struct {
+	UserID    int64  `bson:"id" json:"id"`
+	Username  string `bson:"username,omitempty" json:"username,omitempty"`
+	FirstName string `bson:"first_name" json:"first_name"`
+	Count     int    `bson:"count" json:"count"`
+}
+
 // TopGlobalGroup represents a group's global stats
 type TopGlobalGroup struct {
 	GroupID   int64  `bson:"group_id" json:"group_id"`
@@ -79,6 +95,40 @@ type UserTotal struct {
 	MessageFrequency  int   `bson:"message_frequency" json:"message_frequency"`
 }
 
+// Spawn modes: a spawn after a number of messages, at a fixed interval, or after a time
+// adapted to how busy the chat is
+const (
+	SpawnModeMessages = "messages"
+	SpawnModeInterval = "interval"
+	SpawnModeAdaptive = "adaptive"
+)
+
+// SpawnSettings is how a chat schedules its spawns. It is stored in the chat's UserTotal
+// document, whose message frequency it includes. Zero fields use the configured defaults, as
+// do nil gaps; a gap of 0 means that side is open.
+type SpawnSettings struct {
+	ChatID           int64         `bson:"chat_id" json:"chat_id"`
+	MessageFrequency int           `bson:"message_frequency,omitempty" json:"message_frequency,omitempty"`
+	Mode             string        `bson:"spawn_mode,omitempty" json:"spawn_mode,omitempty"`
+	Interval         time.Duration `bson:"spawn_interval,omitempty" json:"spawn_interval,omitempty"`
+	MinGap           *time.Duration `bson:"spawn_min_gap,omitempty" json:"spawn_min_gap,omitempty"`
+	MaxGap           *time.Duration `bson:"spawn_max_gap,omitempty" json:"spawn_max_gap,omitempty"`
+	QuietHours       *QuietHours    `bson:"quiet_hours,omitempty" json:"quiet_hours,omitempty"`
+	Expiry           time.Duration  `bson:"spawn_expiry,omitempty" json:"spawn_expiry,omitempty"`
+}
+
+// SpawnExpiryNever is the spawn expiry of chats whose spawns never flee
+const SpawnExpiryNever time.Duration = -1
+
+// QuietHours is a daily window without spawns, in minutes after midnight. A window whose end
+// is before its start runs past midnight.
+type QuietHours struct {
+	Start int `bson:"start" json:"start"`
+	End   int `bson:"end" json:"end"`
+	// Timezone is the IANA name of the window's time zone; empty uses the configured default
+	Timezone string `bson:"timezone,omitempty" json:"timezone,omitempty"`
+}
+
 // PMUser represents a user who started the bot
 type PMUser struct {
 	ID        int64      `bson:"_id" json:"id"`
@@ -132,10 +182,12 @@ type ClaimCode struct {
 	RedeemedAt  *time.Time         `bson:"redeemed_at,omitempty" json:"redeemed_at,omitempty"`
 }
 
-// RaritySettings represents rarity settings for a chat
+// RaritySettings represents rarity settings for a chat. Weights overrides the configured drop
+// weight of some rarities in the chat.
 type RaritySettings struct {
-	ChatID           int64 `bson:"chat_id" json:"chat_id"`
-	DisabledRarities []int `bson:"disabled_rarities" json:"disabled_rarities"`
+	ChatID           int64       `bson:"chat_id" json:"chat_id"`
+	DisabledRarities []int       `bson:"disabled_rarities" json:"disabled_rarities"`
+	Weights          map[int]int `bson:"weights,omitempty" json:"weights,omitempty"`
 }
 
 // LockedCharacter represents a locked character
@@ -155,32 +207,114 @@ type SortPreference struct {
 	RarityFilter *int  `bson:"rarity_filter" json:"rarity_filter"`
 }
 
+// ChatLocale is the language a chat, or a user in private, chose for the bot's replies
+type ChatLocale struct {
+	ChatID    int64     `bson:"chat_id" json:"chat_id"`
+	Language  string    `bson:"language" json:"language"`
+	SmallCaps bool      `bson:"small_caps" json:"small_caps"`
+	UpdatedBy int64     `bson:"updated_by" json:"updated_by"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// ActiveSpawn is the character currently waiting to be guessed in a chat. A spawn nobody
+// guesses by FleesAt flees: FledAt records that it went uncaught. Hints counts the /hint
+// tiers revealed so far.
+type ActiveSpawn struct {
+	ChatID    int64         `bson:"chat_id" json:"chat_id"`
+	Character UserCharacter `bson:"character" json:"character"`
+	MessageID int           `bson:"message_id,omitempty" json:"message_id,omitempty"`
+	SpawnedAt time.Time     `bson:"spawned_at" json:"spawned_at"`
+	FleesAt   *time.Time    `bson:"flees_at,omitempty" json:"flees_at,omitempty"`
+	FledAt    *time.Time    `bson:"fled_at,omitempty" json:"fled_at,omitempty"`
+	Hints     int           `bson:"hints,omitempty" json:"hints,omitempty"`
+	GuessedBy int64         `bson:"guessed_by" json:"guessed_by"`
+	GuessedAt *time.Time    `bson:"guessed_at,omitempty" json:"guessed_at,omitempty"`
+}
+
+// HintTiers is the number of hints /hint can reveal about a spawn
+const HintTiers = 4
+
 // PendingPayment represents a pending payment transaction
 type PendingPayment struct {
-	Token     string    `json:"token"`
-	SenderID  int64     `json:"sender_id"`
-	TargetID  int64     `json:"target_id"`
-	Amount    int64     `json:"amount"`
-	CreatedAt time.Time `json:"created_at"`
-	ChatID    int64     `json:"chat_id"`
-	MessageID int       `json:"message_id,omitempty"`
+	Token     string    `bson:"_id" json:"token"`
+	SenderID  int64     `bson:"sender_id" json:"sender_id"`
+	TargetID  int64     `bson:"target_id" json:"target_id"`
+	Amount    int64     `bson:"amount" json:"amount"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+	ChatID    int64     `bson:"chat_id" json:"chat_id"`
+	MessageID int       `bson:"message_id,omitempty" json:"message_id,omitempty"`
 }
 
 // PendingTrade represents a pending trade
 type PendingTrade struct {
-	SenderID         int64     `json:"sender_id"`
-	ReceiverID       int64     `json:"receiver_id"`
-	SenderCharID     string    `json:"sender_char_id"`
-	ReceiverCharID   string    `json:"receiver_char_id"`
-	Timestamp        time.Time `json:"timestamp"`
+	Key            string    `bson:"_id" json:"key"`
+	SenderID       int64     `bson:"sender_id" json:"sender_id"`
+	ReceiverID     int64     `bson:"receiver_id" json:"receiver_id"`
+	SenderCharID   string    `bson:"sender_char_id" json:"sender_char_id"`
+	ReceiverCharID string    `bson:"receiver_char_id" json:"receiver_char_id"`
+	Timestamp      time.Time `bson:"timestamp" json:"timestamp"`
+	ExpiresAt      time.Time `bson:"expires_at" json:"expires_at"`
 }
 
 // PendingGift represents a pending gift
 type PendingGift struct {
-	SenderID           int64     `json:"sender_id"`
-	ReceiverID         int64     `json:"receiver_id"`
-	Character          UserCharacter `json:"character"`
-	ReceiverUsername   string    `json:"receiver_username"`
-	ReceiverFirstName  string    `json:"receiver_first_name"`
-	Timestamp          time.Time `json:"timestamp"`
+	Key               string        `bson:"_id" json:"key"`
+	SenderID          int64         `bson:"sender_id" json:"sender_id"`
+	ReceiverID        int64         `bson:"receiver_id" json:"receiver_id"`
+	Character         UserCharacter `bson:"character" json:"character"`
+	ReceiverUsername  string        `bson:"receiver_username" json:"receiver_username"`
+	ReceiverFirstName string        `bson:"receiver_first_name" json:"receiver_first_name"`
+	Timestamp         time.Time     `bson:"timestamp" json:"timestamp"`
+	ExpiresAt         time.Time     `bson:"expires_at" json:"expires_at"`
+}
+
+// Ledger entry types
+const (
+	LedgerCoins     = "coins"
+	LedgerCharacter = "character"
+)
+
+// LedgerEntry records a single change to a user's balance or collection.
+// Amount is the signed coin delta for coin entries and +1/-1 for character entries.
+type LedgerEntry struct {
+	UserID         int64     `bson:"user_id" json:"user_id"`
+	Type           string    `bson:"type" json:"type"`
+	Amount         int64     `bson:"amount" json:"amount"`
+	CharacterID    string    `bson:"character_id,omitempty" json:"character_id,omitempty"`
+	CharacterName  string    `bson:"character_name,omitempty" json:"character_name,omitempty"`
+	CounterpartyID int64     `bson:"counterparty_id,omitempty" json:"counterparty_id,omitempty"`
+	Source         string    `bson:"source" json:"source"`
+	Reference      string    `bson:"reference,omitempty" json:"reference,omitempty"`
+	CreatedAt      time.Time `bson:"created_at" json:"created_at"`
+}
+
+// Roles that can be granted to users in the database
+const (
+	RoleSudo     = "sudo"
+	RoleUploader = "uploader"
+	RoleEconomy  = "economy"
+)
+
+// RoleGrant gives a user a role on top of the owner and the sudo users in the configuration
+type RoleGrant struct {
+	UserID    int64     `bson:"user_id" json:"user_id"`
+	Role      string    `bson:"role" json:"role"`
+	GrantedBy int64     `bson:"granted_by" json:"granted_by"`
+	GrantedAt time.Time `bson:"granted_at" json:"granted_at"`
+}
+
+// AuditEntry records a privileged command that changed state. Before and After hold the
+// changed values, keyed by field name.
+type AuditEntry struct {
+	ActorID      int64                  `bson:"actor_id" json:"actor_id"`
+	ActorName    string                 `bson:"actor_name" json:"actor_name"`
+	Command      string                 `bson:"command" json:"command"`
+	Args         []string               `bson:"args" json:"args"`
+	ChatID       int64                  `bson:"chat_id" json:"chat_id"`
+	TargetUserID int64                  `bson:"target_user_id,omitempty" json:"target_user_id,omitempty"`
+	CharacterID  string                 `bson:"character_id,omitempty" json:"character_id,omitempty"`
+	Before       map[string]interface{} `bson:"before,omitempty" json:"before,omitempty"`
+	After        map[string]interface{} `bson:"after,omitempty" json:"after,omitempty"`
+	CreatedAt    time.Time              `bson:"created_at" json:"created_at"`
 }
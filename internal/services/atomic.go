@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/store"
+)
+
+// runAtomic executes op in a transaction, or step by step with compensation when the store
+// does not support transactions. Inside a transaction undo is nil, since aborting already
+// rolls every write back.
+func runAtomic(tx store.Transactor, log *zap.Logger, op func(ctx context.Context, undo *compensator) error) error {
+	ctx := context.Background()
+
+	err := tx.RunInTransaction(ctx, func(txCtx context.Context) error {
+		return op(txCtx, nil)
+	})
+	if !errors.Is(err, store.ErrTransactionsUnsupported) {
+		return err
+	}
+
+	undo := &compensator{}
+	if err := op(ctx, undo); err != nil {
+		undo.rollback(ctx, log)
+		return err
+	}
+	return nil
+}
+
+// compensator records how to undo each applied step of a non-transactional operation.
+// A nil compensator is valid and records nothing.
+type compensator struct {
+	steps []func(ctx context.Context) error
+}
+
+func (c *compensator) add(step func(ctx context.Context) error) {
+	if c == nil {
+		return
+	}
+	c.steps = append(c.steps, step)
+}
+
+// rollback undoes the recorded steps in reverse order
+func (c *compensator) rollback(ctx context.Context, log *zap.Logger) {
+	for i := len(c.steps) - 1; i >= 0; i-- {
+		if err := c.steps[i](ctx); err != nil {
+			log.Error("failed to compensate step", zap.Int("step", i), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/store"
+)
+
+// AuditService records and queries the audit log of privileged commands
+type AuditService struct {
+	audit store.AuditStore
+}
+
+// NewAuditService creates a new AuditService
+func NewAuditService(audit store.AuditStore) *AuditService {
+	return &AuditService{audit: audit}
+}
+
+// Record appends entry to the audit log, stamping it with the current time
+func (s *AuditService) Record(entry *models.AuditEntry) error {
+	entry.CreatedAt = time.Now()
+	return s.audit.AppendAudit(context.Background(), entry)
+}
+
+// Recent returns up to limit entries matching filter, newest first
+func (s *AuditService) Recent(filter store.AuditFilter, limit int) ([]models.AuditEntry, error) {
+	return s.audit.ListAudit(context.Background(), filter, limit)
+}
@@ -2,264 +2,263 @@ package services
 
 import (
 	"context"
+	"errors"
 	"math/rand"
+	"sort"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"senpai-waifu-bot/internal/database"
+	"senpai-waifu-bot/internal/guess"
 	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/store"
+	"senpai-waifu-bot/internal/utils"
+)
+
+// CharacterIDCounter names the counter that allocates character IDs
+const CharacterIDCounter = "character_id"
+
+// maxIDAttempts bounds how often NextCharacterID skips IDs that are already taken
+const maxIDAttempts = 5
+
+// ErrNoCharacterID is returned when no unused character ID could be allocated
+var ErrNoCharacterID = errors.New("no unused character ID")
+
+// MaxAliases is the most aliases a character can have
+const MaxAliases = 10
+
+// maxAliasAttempts bounds how often an alias change is retried after losing to a concurrent one
+const maxAliasAttempts = 5
+
+// Alias errors
+var (
+	ErrAliasExists    = errors.New("alias already names the character")
+	ErrAliasNotFound  = errors.New("alias not found")
+	ErrTooManyAliases = errors.New("too many aliases")
+	ErrAliasConflict  = errors.New("aliases kept changing concurrently")
 )
 
 // CharacterService handles character-related database operations
-type CharacterService struct{}
+type CharacterService struct {
+	characters store.CharacterStore
+	users      store.UserStore
+	counters   store.CounterStore
+	pricing    ShopPricing
+}
+
+// ShopPricing sets the price range of each rarity sold in the shop and the discount range in percent
+type ShopPricing struct {
+	Prices      map[int][2]int64
+	DiscountMin int
+	DiscountMax int
+}
 
 // NewCharacterService creates a new CharacterService
-func NewCharacterService() *CharacterService {
-	return &CharacterService{}
+func NewCharacterService(characters store.CharacterStore, users store.UserStore, counters store.CounterStore, pricing ShopPricing) *CharacterService {
+	return &CharacterService{characters: characters, users: users, counters: counters, pricing: pricing}
 }
 
 // GetCharacterByID gets a character by ID
 func (s *CharacterService) GetCharacterByID(charID string) (*models.Character, error) {
-	var char models.Character
-	err := database.CharacterCollection.FindOne(context.Background(), bson.M{"id": charID}).Decode(&char)
-	if err != nil {
-		return nil, err
-	}
-	return &char, nil
+	return s.characters.GetCharacter(context.Background(), charID)
 }
 
 // GetRandomCharacter gets a random character, optionally filtering by excluded rarities and locked IDs
 func (s *CharacterService) GetRandomCharacter(excludedRarities []int, lockedIDs []string) (*models.Character, error) {
-	filter := bson.M{}
-	
-	// Exclude disabled rarities
-	if len(excludedRarities) > 0 {
-		filter["rarity"] = bson.M{"$nin": excludedRarities}
-	}
-	
-	// Exclude locked characters
-	if len(lockedIDs) > 0 {
-		if _, ok := filter["rarity"]; ok {
-			filter["$and"] = []bson.M{
-				{"rarity": bson.M{"$nin": excludedRarities}},
-				{"id": bson.M{"$nin": lockedIDs}},
-			}
-			delete(filter, "rarity")
-		} else {
-			filter["id"] = bson.M{"$nin": lockedIDs}
-		}
-	}
-	
-	// Use aggregation with $sample for random selection
-	pipeline := []bson.M{
-		{"$match": filter},
-		{"$sample": bson.M{"size": 1}},
-	}
-	
-	cursor, err := database.CharacterCollection.Aggregate(context.Background(), pipeline)
+	chars, err := s.GetRandomCharacters(1, excludedRarities, lockedIDs)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(context.Background())
-	
-	var chars []models.Character
-	if err = cursor.All(context.Background(), &chars); err != nil {
-		return nil, err
-	}
-	
+
 	if len(chars) == 0 {
 		return nil, nil
 	}
-	
+
 	return &chars[0], nil
 }
 
 // GetRandomCharacters gets multiple random characters
 func (s *CharacterService) GetRandomCharacters(count int, excludedRarities []int, lockedIDs []string) ([]models.Character, error) {
-	filter := bson.M{}
-	
-	if len(excludedRarities) > 0 {
-		filter["rarity"] = bson.M{"$nin": excludedRarities}
-	}
-	
-	if len(lockedIDs) > 0 {
-		if _, ok := filter["rarity"]; ok {
-			filter["$and"] = []bson.M{
-				{"rarity": bson.M{"$nin": excludedRarities}},
-				{"id": bson.M{"$nin": lockedIDs}},
-			}
-			delete(filter, "rarity")
-		} else {
-			filter["id"] = bson.M{"$nin": lockedIDs}
-		}
+	filter := store.CharacterFilter{
+		ExcludedRarities: excludedRarities,
+		ExcludedIDs:      lockedIDs,
 	}
-	
-	pipeline := []bson.M{
-		{"$match": filter},
-		{"$sample": bson.M{"size": count}},
-	}
-	
-	cursor, err := database.CharacterCollection.Aggregate(context.Background(), pipeline)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(context.Background())
-	
-	var chars []models.Character
-	if err = cursor.All(context.Background(), &chars); err != nil {
-		return nil, err
-	}
-	
-	return chars, nil
+	return s.characters.SampleCharacters(context.Background(), filter, count)
 }
 
 // GetCharactersByRarity gets characters by rarity
 func (s *CharacterService) GetCharactersByRarity(rarity int) ([]models.Character, error) {
-	cursor, err := database.CharacterCollection.Find(context.Background(), bson.M{"rarity": rarity})
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(context.Background())
-	
-	var chars []models.Character
-	if err = cursor.All(context.Background(), &chars); err != nil {
-		return nil, err
-	}
-	return chars, nil
+	return s.GetCharactersByRarities([]int{rarity})
 }
 
 // SearchCharacters searches characters by name
 func (s *CharacterService) SearchCharacters(query string) ([]models.Character, error) {
-	filter := bson.M{
-		"$or": []bson.M{
-			{"name": bson.M{"$regex": query, "$options": "i"}},
-			{"anime": bson.M{"$regex": query, "$options": "i"}},
-		},
-	}
-	
-	cursor, err := database.CharacterCollection.Find(
-		context.Background(),
-		filter,
-		options.Find().SetProjection(bson.M{
-			"id":     1,
-			"name":   1,
-			"anime":  1,
-			"rarity": 1,
-			"img_url": 1,
-		}),
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(context.Background())
-	
-	var chars []models.Character
-	if err = cursor.All(context.Background(), &chars); err != nil {
-		return nil, err
-	}
-	return chars, nil
+	return s.characters.SearchCharacters(context.Background(), query)
 }
 
 // GetCharacterCount gets the total count of characters
 func (s *CharacterService) GetCharacterCount() (int64, error) {
-	return database.CharacterCollection.CountDocuments(context.Background(), bson.M{})
+	return s.characters.CountCharacters(context.Background())
 }
 
 // GetCharacterOwnerCount gets how many users own a specific character
 func (s *CharacterService) GetCharacterOwnerCount(charID string) (int64, error) {
-	return database.UserCollection.CountDocuments(
-		context.Background(),
-		bson.M{"characters.id": charID},
-	)
+	return s.users.CountOwners(context.Background(), charID)
 }
 
 // GetTopGrabbers gets top users who own a specific character
-func (s *CharacterService) GetTopGrabbers(charID string, limit int) ([]map[string]interface{}, error) {
-	pipeline := []bson.M{
-		{"$match": bson.M{"characters.id": charID}},
-		{"$project": bson.M{
-			"id":         1,
-			"username":   1,
-			"first_name": 1,
-			"count": bson.M{
-				"$size": bson.M{
-					"$filter": bson.M{
-						"input": "$characters",
-						"as":    "char",
-						"cond":  bson.M{"$eq": []interface{}{"$$char.id", charID}},
-					},
-				},
-			},
-		}},
-		{"$sort": bson.M{"count": -1}},
-		{"$limit": limit},
-	}
-	
-	cursor, err := database.UserCollection.Aggregate(context.Background(), pipeline)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(context.Background())
-	
-	var results []map[string]interface{}
-	if err = cursor.All(context.Background(), &results); err != nil {
-		return nil, err
-	}
-	return results, nil
+func (s *CharacterService) GetTopGrabbers(charID string, limit int) ([]models.Grabber, error) {
+	return s.users.TopGrabbers(context.Background(), charID, limit)
 }
 
 // GetAllCharacters gets all characters (use with caution on large datasets)
 func (s *CharacterService) GetAllCharacters() ([]models.Character, error) {
-	cursor, err := database.CharacterCollection.Find(context.Background(), bson.M{})
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(context.Background())
-	
-	var chars []models.Character
-	if err = cursor.All(context.Background(), &chars); err != nil {
-		return nil, err
-	}
-	return chars, nil
+	return s.characters.FindCharacters(context.Background(), store.CharacterFilter{})
 }
 
 // GetCharactersByRarities gets characters by multiple rarities
 func (s *CharacterService) GetCharactersByRarities(rarities []int) ([]models.Character, error) {
-	cursor, err := database.CharacterCollection.Find(
-		context.Background(),
-		bson.M{"rarity": bson.M{"$in": rarities}},
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(context.Background())
-	
-	var chars []models.Character
-	if err = cursor.All(context.Background(), &chars); err != nil {
-		return nil, err
-	}
-	return chars, nil
+	return s.characters.FindCharacters(context.Background(), store.CharacterFilter{Rarities: rarities})
 }
 
 // GetRandomCharactersByRarities gets random characters from specified rarities
 func (s *CharacterService) GetRandomCharactersByRarities(rarities []int, count int) ([]models.Character, error) {
-	pipeline := []bson.M{
-		{"$match": bson.M{"rarity": bson.M{"$in": rarities}}},
-		{"$sample": bson.M{"size": count}},
+	return s.characters.SampleCharacters(context.Background(), store.CharacterFilter{Rarities: rarities}, count)
+}
+
+// NextCharacterID allocates an unused character ID from the counter. IDs are never handed out
+// twice, even after the character holding one is deleted. When the counter lands on an ID
+// that is taken, for example because characters were imported directly, it is raised to the
+// highest ID in the catalog and the allocation retried.
+func (s *CharacterService) NextCharacterID() (string, error) {
+	ctx := context.Background()
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		seq, err := s.counters.NextSequence(ctx, CharacterIDCounter)
+		if err != nil {
+			return "", err
+		}
+		charID := utils.FormatCharacterID(seq)
+
+		_, err = s.characters.GetCharacter(ctx, charID)
+		if errors.Is(err, store.ErrNotFound) {
+			return charID, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		maxID, err := s.characters.MaxNumericID(ctx)
+		if err != nil {
+			return "", err
+		}
+		if err := s.counters.RaiseSequence(ctx, CharacterIDCounter, maxID); err != nil {
+			return "", err
+		}
+	}
+	return "", ErrNoCharacterID
+}
+
+// ReseedCharacterIDs sets the character ID counter to the highest ID in the catalog, so the
+// next upload gets the ID after it. It returns the counter before and after.
+func (s *CharacterService) ReseedCharacterIDs() (before, after int64, err error) {
+	ctx := context.Background()
+	before, err = s.counters.GetSequence(ctx, CharacterIDCounter)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return 0, 0, err
 	}
-	
-	cursor, err := database.CharacterCollection.Aggregate(context.Background(), pipeline)
+
+	after, err = s.characters.MaxNumericID(ctx)
 	if err != nil {
-		return nil, err
+		return 0, 0, err
 	}
-	defer cursor.Close(context.Background())
-	
-	var chars []models.Character
-	if err = cursor.All(context.Background(), &chars); err != nil {
-		return nil, err
+	if err := s.counters.SetSequence(ctx, CharacterIDCounter, after); err != nil {
+		return 0, 0, err
 	}
-	return chars, nil
+	return before, after, nil
+}
+
+// AddCharacter saves a new character to the catalog. It returns store.ErrDuplicate when the ID
+// is taken.
+func (s *CharacterService) AddCharacter(char *models.Character) error {
+	return s.characters.InsertCharacter(context.Background(), char)
+}
+
+// UpdateCharacter applies an update to a character
+func (s *CharacterService) UpdateCharacter(charID string, update store.CharacterUpdate) error {
+	return s.characters.UpdateCharacter(context.Background(), charID, update)
+}
+
+// AddAlias adds alias to the names /guess accepts for char and returns its new aliases.
+// Aliases that only differ from the name or another alias in case, accents or spacing are
+// refused with ErrAliasExists.
+func (s *CharacterService) AddAlias(char *models.Character, alias string, updatedBy int64) ([]string, error) {
+	key := guess.Key(alias)
+	return s.changeAliases(char, updatedBy, func(char *models.Character) ([]string, error) {
+		if key == guess.Key(char.Name) {
+			return nil, ErrAliasExists
+		}
+		for _, existing := range char.Aliases {
+			if guess.Key(existing) == key {
+				return nil, ErrAliasExists
+			}
+		}
+		if len(char.Aliases) >= MaxAliases {
+			return nil, ErrTooManyAliases
+		}
+		return append(append([]string{}, char.Aliases...), alias), nil
+	})
+}
+
+// RemoveAlias removes the alias of char matching alias and returns its remaining aliases
+func (s *CharacterService) RemoveAlias(char *models.Character, alias string, updatedBy int64) ([]string, error) {
+	key := guess.Key(alias)
+	return s.changeAliases(char, updatedBy, func(char *models.Character) ([]string, error) {
+		aliases := []string{}
+		for _, existing := range char.Aliases {
+			if guess.Key(existing) != key {
+				aliases = append(aliases, existing)
+			}
+		}
+		if len(aliases) == len(char.Aliases) {
+			return nil, ErrAliasNotFound
+		}
+		return aliases, nil
+	})
+}
+
+// changeAliases replaces the aliases of char with those change derives from it. The write only
+// applies while the aliases are still the ones change saw; when another change got there
+// first, the character is loaded again and change reapplied, so neither change is lost.
+func (s *CharacterService) changeAliases(char *models.Character, updatedBy int64, change func(*models.Character) ([]string, error)) ([]string, error) {
+	ctx := context.Background()
+	for attempt := 0; attempt < maxAliasAttempts; attempt++ {
+		aliases, err := change(char)
+		if err != nil {
+			return nil, err
+		}
+		prev := char.Aliases
+		err = s.characters.UpdateCharacter(ctx, char.ID, store.CharacterUpdate{
+			Aliases:     &aliases,
+			PrevAliases: &prev,
+			UpdatedBy:   updatedBy,
+			UpdatedAt:   time.Now(),
+		})
+		if !errors.Is(err, store.ErrNotFound) {
+			if err != nil {
+				return nil, err
+			}
+			return aliases, nil
+		}
+
+		// The aliases changed since char was loaded, or the character is gone
+		if char, err = s.characters.GetCharacter(ctx, char.ID); err != nil {
+			return nil, err
+		}
+	}
+	return nil, ErrAliasConflict
+}
+
+// DeleteCharacter removes a character from the catalog
+func (s *CharacterService) DeleteCharacter(charID string) error {
+	return s.characters.DeleteCharacter(context.Background(), charID)
 }
 
 // ToUserCharacter converts a Character to UserCharacter
@@ -275,54 +274,27 @@ func (s *CharacterService) ToUserCharacter(char *models.Character) models.UserCh
 
 // GetAnimeCounts gets count of characters per anime
 func (s *CharacterService) GetAnimeCounts(animes []string) (map[string]int64, error) {
-	pipeline := []bson.M{
-		{"$match": bson.M{"anime": bson.M{"$in": animes}}},
-		{"$group": bson.M{
-			"_id":   "$anime",
-			"count": bson.M{"$sum": 1},
-		}},
-	}
-	
-	cursor, err := database.CharacterCollection.Aggregate(context.Background(), pipeline)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(context.Background())
-	
-	var results []struct {
-		ID    string `bson:"_id"`
-		Count int64  `bson:"count"`
-	}
-	if err = cursor.All(context.Background(), &results); err != nil {
-		return nil, err
-	}
-	
-	counts := make(map[string]int64)
-	for _, r := range results {
-		counts[r.ID] = r.Count
-	}
-	return counts, nil
+	return s.characters.AnimeCounts(context.Background(), animes)
 }
 
-// ShopRarities defines which rarities are available in the shop
-var ShopRarities = []int{4, 5, 6, 14} // Special, Ancient, Celestial, Kawaii
-
-// ShopPriceRanges defines price ranges for each rarity
-var ShopPriceRanges = map[int][2]int64{
-	4:  {400000, 500000}, // Special
-	5:  {600000, 700000}, // Ancient
-	6:  {650000, 750000}, // Celestial
-	14: {450000, 550000}, // Kawaii
+// ShopRarities returns the rarities sold in the shop
+func (s *CharacterService) ShopRarities() []int {
+	rarities := make([]int, 0, len(s.pricing.Prices))
+	for rarity := range s.pricing.Prices {
+		rarities = append(rarities, rarity)
+	}
+	sort.Ints(rarities)
+	return rarities
 }
 
 // GenerateShopCharacter generates a shop character with pricing
 func (s *CharacterService) GenerateShopCharacter(char models.Character) models.ShopCharacter {
-	priceRange := ShopPriceRanges[char.Rarity]
+	priceRange := s.pricing.Prices[char.Rarity]
 	basePrice := rand.Int63n(priceRange[1]-priceRange[0]) + priceRange[0]
-	discountPercent := rand.Intn(11) + 5 // 5-15%
+	discountPercent := rand.Intn(s.pricing.DiscountMax-s.pricing.DiscountMin+1) + s.pricing.DiscountMin
 	discountAmount := basePrice * int64(discountPercent) / 100
 	finalPrice := basePrice - discountAmount
-	
+
 	return models.ShopCharacter{
 		ID:              char.ID,
 		Name:            char.Name,
@@ -337,16 +309,16 @@ func (s *CharacterService) GenerateShopCharacter(char models.Character) models.S
 
 // InitializeShop generates initial shop data for a user
 func (s *CharacterService) InitializeShop() (*models.ShopData, error) {
-	chars, err := s.GetRandomCharactersByRarities(ShopRarities, 3)
+	chars, err := s.GetRandomCharactersByRarities(s.ShopRarities(), 3)
 	if err != nil {
 		return nil, err
 	}
-	
+
 	shopChars := make([]models.ShopCharacter, len(chars))
 	for i, char := range chars {
 		shopChars[i] = s.GenerateShopCharacter(char)
 	}
-	
+
 	return &models.ShopData{
 		Characters:   shopChars,
 		LastReset:    time.Now(),
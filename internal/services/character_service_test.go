@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/store"
+)
+
+// newAliasService returns a character service over a memory store holding Shinobu, and
+// Shinobu as loaded before any alias change
+func newAliasService(t *testing.T) (*CharacterService, *store.Store, *models.Character) {
+	t.Helper()
+	st := store.NewMemoryStore()
+	char := &models.Character{ID: "001", Name: "Shinobu Kochou", Anime: "Demon Slayer", Rarity: 3, Aliases: []string{"shinobu"}}
+	if err := st.Characters.InsertCharacter(context.Background(), char); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := st.Characters.GetCharacter(context.Background(), char.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewCharacterService(st.Characters, st.Users, st.Counters, ShopPricing{}), st, loaded
+}
+
+// aliasesOf returns the sorted aliases of charID in st
+func aliasesOf(t *testing.T, st *store.Store, charID string) []string {
+	t.Helper()
+	char, err := st.Characters.GetCharacter(context.Background(), charID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aliases := append([]string{}, char.Aliases...)
+	sort.Strings(aliases)
+	return aliases
+}
+
+func TestAliasChangesFromStaleCharacter(t *testing.T) {
+	s, st, stale := newAliasService(t)
+
+	// Each change starts from the character as it was before the others
+	if _, err := s.AddAlias(stale, "kocho", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddAlias(stale, "insect hashira", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RemoveAlias(stale, "shinobu", 1); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(aliasesOf(t, st, stale.ID)), "[insect hashira kocho]"; got != want {
+		t.Errorf("aliases = %s, want %s", got, want)
+	}
+
+	// Checks run against the aliases as they are now, not as stale saw them
+	if _, err := s.AddAlias(stale, "Kochō", 1); !errors.Is(err, ErrAliasExists) {
+		t.Errorf("AddAlias() of an alias added since: error = %v, want %v", err, ErrAliasExists)
+	}
+	if _, err := s.RemoveAlias(stale, "shinobu", 1); !errors.Is(err, ErrAliasNotFound) {
+		t.Errorf("RemoveAlias() of an alias removed since: error = %v, want %v", err, ErrAliasNotFound)
+	}
+}
+
+func TestAliasChangesConcurrent(t *testing.T) {
+	s, st, char := newAliasService(t)
+
+	// Every change loses at most once to each of the others
+	const adders = maxAliasAttempts
+	var wg sync.WaitGroup
+	for i := 0; i < adders; i++ {
+		wg.Add(1)
+		go func(alias string) {
+			defer wg.Done()
+			if _, err := s.AddAlias(char, alias, 1); err != nil {
+				t.Errorf("AddAlias(%q) error = %v", alias, err)
+			}
+		}(fmt.Sprintf("alias %c", 'a'+i))
+	}
+	wg.Wait()
+
+	if got := aliasesOf(t, st, char.ID); len(got) != adders+1 {
+		t.Errorf("aliases = %q, want shinobu and %d added", got, adders)
+	}
+}
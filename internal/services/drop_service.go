@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/store"
+)
+
+// RarityOdds is the chance of one rarity to spawn in a chat
+type RarityOdds struct {
+	Rarity int
+	Weight int
+	// Percent is the chance of the next spawn being of the rarity
+	Percent float64
+	// Characters is the number of characters of the rarity that can spawn
+	Characters int64
+	// Custom is set when the chat overrides the configured weight
+	Custom bool
+}
+
+// DropTable holds the rarities that can spawn in a chat with their weights, in rarity order
+type DropTable struct {
+	Odds  []RarityOdds
+	total int
+}
+
+// NewDropTable builds the drop table of a chat from the configured weights, the chat's
+// settings and the number of characters of each rarity that can spawn. Rarities that are
+// disabled, weigh nothing or have no characters are left out.
+func NewDropTable(weights map[int]int, settings *models.RaritySettings, counts map[int]int64) *DropTable {
+	effective := make(map[int]int, len(weights))
+	for rarity, weight := range weights {
+		effective[rarity] = weight
+	}
+	for rarity, weight := range settings.Weights {
+		effective[rarity] = weight
+	}
+	for _, rarity := range settings.DisabledRarities {
+		delete(effective, rarity)
+	}
+
+	table := &DropTable{}
+	for rarity, weight := range effective {
+		if weight <= 0 || counts[rarity] == 0 {
+			continue
+		}
+		_, custom := settings.Weights[rarity]
+		table.Odds = append(table.Odds, RarityOdds{Rarity: rarity, Weight: weight, Characters: counts[rarity], Custom: custom})
+		table.total += weight
+	}
+	sort.Slice(table.Odds, func(i, j int) bool { return table.Odds[i].Rarity < table.Odds[j].Rarity })
+	for i := range table.Odds {
+		table.Odds[i].Percent = float64(table.Odds[i].Weight) * 100 / float64(table.total)
+	}
+	return table
+}
+
+// Empty reports whether no rarity can spawn
+func (t *DropTable) Empty() bool {
+	return t.total == 0
+}
+
+// Pick draws a rarity by weight; the table must not be empty
+func (t *DropTable) Pick(rng *rand.Rand) int {
+	n := rng.Intn(t.total)
+	for _, odds := range t.Odds {
+		if n < odds.Weight {
+			return odds.Rarity
+		}
+		n -= odds.Weight
+	}
+	return t.Odds[len(t.Odds)-1].Rarity
+}
+
+// DropService picks the characters that spawn: first a rarity by the weights of the chat's
+// drop table, then one of the characters of that rarity
+type DropService struct {
+	characters store.CharacterStore
+	rarity     store.RarityStore
+
+	// rng is not safe for concurrent use
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewDropService creates a new DropService drawing rarities from src, or from a source
+// seeded with the current time when src is nil. Tests pass a fixed source to get the same
+// rarities on every run.
+func NewDropService(characters store.CharacterStore, rarity store.RarityStore, src rand.Source) *DropService {
+	if src == nil {
+		src = rand.NewSource(time.Now().UnixNano())
+	}
+	return &DropService{characters: characters, rarity: rarity, rng: rand.New(src)}
+}
+
+// Table returns the drop table of chatID for the configured weights, leaving out the
+// characters in excludedIDs
+func (s *DropService) Table(chatID int64, weights map[int]int, excludedIDs []string) (*DropTable, error) {
+	ctx := context.Background()
+	settings, err := s.rarity.GetRaritySettings(ctx, chatID)
+	if errors.Is(err, store.ErrNotFound) {
+		settings, err = &models.RaritySettings{ChatID: chatID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.characters.CountByRarity(ctx, store.CharacterFilter{
+		ExcludedRarities: settings.DisabledRarities,
+		ExcludedIDs:      excludedIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewDropTable(weights, settings, counts), nil
+}
+
+// Pick returns a character to spawn in chatID, or nil when no character can spawn there
+func (s *DropService) Pick(chatID int64, weights map[int]int, excludedIDs []string) (*models.Character, error) {
+	table, err := s.Table(chatID, weights, excludedIDs)
+	if err != nil || table.Empty() {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	rarity := table.Pick(s.rng)
+	s.mu.Unlock()
+
+	chars, err := s.characters.SampleCharacters(context.Background(), store.CharacterFilter{
+		Rarities:    []int{rarity},
+		ExcludedIDs: excludedIDs,
+	}, 1)
+	if err != nil || len(chars) == 0 {
+		return nil, err
+	}
+	return &chars[0], nil
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/store"
+)
+
+// dropWeights are the configured weights of the drop table tests
+var dropWeights = map[int]int{1: 60, 2: 25, 3: 10, 4: 5}
+
+// oddsOf returns the rarities of table mapped to their weights
+func oddsOf(table *DropTable) map[int]int {
+	weights := map[int]int{}
+	for _, odds := range table.Odds {
+		weights[odds.Rarity] = odds.Weight
+	}
+	return weights
+}
+
+func TestNewDropTable(t *testing.T) {
+	all := map[int]int64{1: 10, 2: 5, 3: 3, 4: 1}
+	tests := []struct {
+		name     string
+		settings *models.RaritySettings
+		counts   map[int]int64
+		want     map[int]int
+	}{
+		{"defaults", &models.RaritySettings{}, all, dropWeights},
+		{"override", &models.RaritySettings{Weights: map[int]int{4: 40}}, all, map[int]int{1: 60, 2: 25, 3: 10, 4: 40}},
+		{"override of an unconfigured rarity", &models.RaritySettings{Weights: map[int]int{5: 1}}, map[int]int64{1: 1, 5: 1}, map[int]int{1: 60, 5: 1}},
+		{"disabled", &models.RaritySettings{DisabledRarities: []int{1, 3}}, all, map[int]int{2: 25, 4: 5}},
+		{"disabled beats override", &models.RaritySettings{Weights: map[int]int{1: 90}, DisabledRarities: []int{1}}, all, map[int]int{2: 25, 3: 10, 4: 5}},
+		{"zero weight", &models.RaritySettings{Weights: map[int]int{2: 0}}, all, map[int]int{1: 60, 3: 10, 4: 5}},
+		{"no characters", &models.RaritySettings{}, map[int]int64{1: 10, 3: 0, 4: 1}, map[int]int{1: 60, 4: 5}},
+		{"nothing left", &models.RaritySettings{DisabledRarities: []int{1}}, map[int]int64{1: 10}, map[int]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := NewDropTable(dropWeights, tt.settings, tt.counts)
+			if got := oddsOf(table); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("weights = %v, want %v", got, tt.want)
+			}
+			if table.Empty() != (len(tt.want) == 0) {
+				t.Errorf("Empty() = %v with %d rarities", table.Empty(), len(tt.want))
+			}
+
+			var sum float64
+			for i, odds := range table.Odds {
+				if i > 0 && table.Odds[i-1].Rarity >= odds.Rarity {
+					t.Errorf("rarity %d listed after %d", odds.Rarity, table.Odds[i-1].Rarity)
+				}
+				if _, custom := tt.settings.Weights[odds.Rarity]; odds.Custom != custom {
+					t.Errorf("rarity %d: Custom = %v, want %v", odds.Rarity, odds.Custom, custom)
+				}
+				if odds.Characters != tt.counts[odds.Rarity] {
+					t.Errorf("rarity %d: %d characters, want %d", odds.Rarity, odds.Characters, tt.counts[odds.Rarity])
+				}
+				sum += odds.Percent
+			}
+			if len(table.Odds) > 0 && math.Abs(sum-100) > 1e-9 {
+				t.Errorf("percentages add up to %v, want 100", sum)
+			}
+		})
+	}
+}
+
+func TestDropTablePick(t *testing.T) {
+	table := NewDropTable(dropWeights, &models.RaritySettings{}, map[int]int64{1: 1, 2: 1, 3: 1, 4: 1})
+	rng := rand.New(rand.NewSource(1))
+
+	// Every rarity is drawn close to its share of the weights
+	const draws = 20000
+	picks := map[int]int{}
+	for i := 0; i < draws; i++ {
+		picks[table.Pick(rng)]++
+	}
+	for _, odds := range table.Odds {
+		if got := float64(picks[odds.Rarity]) * 100 / draws; math.Abs(got-odds.Percent) > 1 {
+			t.Errorf("rarity %d drawn %.1f%% of the time, want %.1f%%", odds.Rarity, got, odds.Percent)
+		}
+	}
+}
+
+// newDropStore returns a memory store seeded with seed holding four characters of each
+// rarity from 1 to 4
+func newDropStore(t *testing.T, seed int64) *store.Store {
+	t.Helper()
+	st := store.NewSeededMemoryStore(seed)
+	for rarity := 1; rarity <= 4; rarity++ {
+		for i := 0; i < 4; i++ {
+			char := &models.Character{ID: fmt.Sprintf("%d%02d", rarity, i), Name: fmt.Sprintf("Character %d-%d", rarity, i), Anime: "Test", Rarity: rarity}
+			if err := st.Characters.InsertCharacter(context.Background(), char); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return st
+}
+
+// picks returns the IDs of n characters picked in chatID by a drop service over st with a
+// source seeded with seed
+func picks(t *testing.T, st *store.Store, seed int64, chatID int64, excludedIDs []string, n int) []string {
+	t.Helper()
+	s := NewDropService(st.Characters, st.Rarity, rand.NewSource(seed))
+	var ids []string
+	for i := 0; i < n; i++ {
+		char, err := s.Pick(chatID, dropWeights, excludedIDs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if char == nil {
+			t.Fatal("Pick() found no character")
+		}
+		ids = append(ids, char.ID)
+	}
+	return ids
+}
+
+func TestDropServicePickSeeded(t *testing.T) {
+	first := picks(t, newDropStore(t, 7), 42, -100, nil, 50)
+	if again := picks(t, newDropStore(t, 7), 42, -100, nil, 50); !reflect.DeepEqual(first, again) {
+		t.Errorf("same seeds picked\n%q\nthen\n%q", first, again)
+	}
+}
+
+func TestDropServicePickRespectsSettings(t *testing.T) {
+	ctx := context.Background()
+	st := newDropStore(t, 7)
+	const chatID = -100
+	if err := st.Rarity.DisableRarity(ctx, chatID, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Rarity.SetRarityWeight(ctx, chatID, 2, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only rarities 3 and 4 can spawn, and 300 is excluded
+	for _, id := range picks(t, st, 42, chatID, []string{"300"}, 200) {
+		if (id[0] != '3' && id[0] != '4') || id == "300" {
+			t.Errorf("picked %s with rarities 1 and 2 and character 300 left out", id)
+		}
+	}
+
+	// Other chats keep the configured weights
+	s := NewDropService(st.Characters, st.Rarity, rand.NewSource(42))
+	table, err := s.Table(-200, dropWeights, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := oddsOf(table); !reflect.DeepEqual(got, dropWeights) {
+		t.Errorf("weights of another chat = %v, want %v", got, dropWeights)
+	}
+
+	// With everything excluded, nothing spawns
+	var all []string
+	for rarity := 3; rarity <= 4; rarity++ {
+		for i := 0; i < 4; i++ {
+			all = append(all, fmt.Sprintf("%d%02d", rarity, i))
+		}
+	}
+	char, err := s.Pick(chatID, dropWeights, all)
+	if err != nil || char != nil {
+		t.Errorf("Pick() with every character excluded = %v, %v, want nothing", char, err)
+	}
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/metrics"
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/store"
+)
+
+// Hint tiers, in the order /hint reveals them
+const (
+	HintAnimeInitials = iota + 1
+	HintNameLength
+	HintFirstLetter
+	HintMaskedName
+)
+
+// Hint errors
+var (
+	// ErrNoHintsLeft is returned when the spawn already got every hint it can
+	ErrNoHintsLeft = errors.New("no hints left")
+	// ErrSpawnGone is returned when the spawn was guessed, fled or replaced before the hint
+	ErrSpawnGone = errors.New("spawn can no longer be guessed")
+)
+
+// HintPricing sets the cost of each hint on a spawn, how many a spawn can get and the percent
+// of the guess reward lost per hint
+type HintPricing struct {
+	Costs     []int64
+	PerSpawn  int
+	RewardCut int
+}
+
+// Hint is a hint bought for a spawn
+type Hint struct {
+	// Tier is the hint tier, which is also the number of hints the spawn has had
+	Tier    int
+	Value   string
+	Cost    int64
+	Balance int64
+}
+
+// HintService sells hints about the active spawn of a chat. The charge and the hint count
+// change as one unit of work, so a hint is never paid for without being revealed.
+type HintService struct {
+	users   store.UserStore
+	state   store.StateStore
+	ledger  store.LedgerStore
+	tx      store.Transactor
+	pricing HintPricing
+	log     *zap.Logger
+}
+
+// NewHintService creates a new HintService
+func NewHintService(users store.UserStore, state store.StateStore, ledger store.LedgerStore, tx store.Transactor, pricing HintPricing, log *zap.Logger) *HintService {
+	return &HintService{users: users, state: state, ledger: ledger, tx: tx, pricing: pricing, log: log}
+}
+
+// PerSpawn returns the number of hints a spawn can get
+func (s *HintService) PerSpawn() int {
+	return s.pricing.PerSpawn
+}
+
+// NextCost returns the cost of the next hint on spawn, or false if it has no hints left
+func (s *HintService) NextCost(spawn *models.ActiveSpawn) (int64, bool) {
+	if spawn.Hints >= s.pricing.PerSpawn {
+		return 0, false
+	}
+	return s.pricing.Costs[spawn.Hints], true
+}
+
+// Buy charges userID for the next hint on spawn and reveals it
+func (s *HintService) Buy(spawn *models.ActiveSpawn, userID int64) (*Hint, error) {
+	cost, ok := s.NextCost(spawn)
+	if !ok {
+		return nil, ErrNoHintsLeft
+	}
+	src := LedgerSource{Source: SourceHint, Reference: spawn.Character.ID}
+
+	var hint *Hint
+	err := runAtomic(s.tx, s.log, func(ctx context.Context, undo *compensator) error {
+		balance, err := s.users.DebitBalance(ctx, userID, cost)
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrInsufficientFunds
+		}
+		if err != nil {
+			return err
+		}
+		undo.add(func(ctx context.Context) error {
+			_, err := s.users.IncBalance(ctx, userID, cost)
+			return err
+		})
+
+		updated, err := s.state.AddSpawnHint(ctx, spawn.ChatID, spawn.SpawnedAt, s.pricing.PerSpawn, time.Now())
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrSpawnGone
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.ledger.AppendEntries(ctx, coinEntry(userID, -cost, src)); err != nil {
+			return err
+		}
+		hint = &Hint{
+			Tier:    updated.Hints,
+			Value:   HintValue(updated.Hints, updated.Character),
+			Cost:    cost,
+			Balance: balance,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.RecordCoins(src.Source, -cost)
+	return hint, nil
+}
+
+// Reward returns the guess reward left of reward after hints hints
+func (s *HintService) Reward(reward int64, hints int) int64 {
+	percent := max(100-hints*s.pricing.RewardCut, 0)
+	return reward * int64(percent) / 100
+}
+
+// HintValue returns what the hint of the given tier reveals about char
+func HintValue(tier int, char models.UserCharacter) string {
+	switch tier {
+	case HintAnimeInitials:
+		return Initials(char.Anime)
+	case HintNameLength:
+		return NameLengths(char.Name)
+	case HintFirstLetter:
+		for _, r := range char.Name {
+			return string(unicode.ToUpper(r))
+		}
+		return ""
+	default:
+		return MaskName(char.Name)
+	}
+}
+
+// Initials returns the first letter of every word of s, as in "A. o. T." for
+// "Attack on Titan"
+func Initials(s string) string {
+	var initials []string
+	for _, word := range strings.Fields(s) {
+		for _, r := range word {
+			initials = append(initials, string(r)+".")
+			break
+		}
+	}
+	return strings.Join(initials, " ")
+}
+
+// NameLengths returns the number of letters of every word of name, as in "7 + 6" for
+// "Shinobu Kochou"
+func NameLengths(name string) string {
+	var lengths []string
+	for _, word := range strings.Fields(name) {
+		letters := 0
+		for _, r := range word {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				letters++
+			}
+		}
+		lengths = append(lengths, strconv.Itoa(letters))
+	}
+	return strings.Join(lengths, " + ")
+}
+
+// MaskName hides about two thirds of the letters of name, showing the first letter of every
+// word and every third letter after it, as in "S _ _ n _ _ u   K _ _ h _ _" for
+// "Shinobu Kochou". Punctuation is shown as is.
+func MaskName(name string) string {
+	var words []string
+	for _, word := range strings.Fields(name) {
+		var masked []string
+		letter := 0
+		for _, r := range word {
+			switch {
+			case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+				masked = append(masked, string(r))
+			case letter%3 == 0:
+				masked = append(masked, string(r))
+				letter++
+			default:
+				masked = append(masked, "_")
+				letter++
+			}
+		}
+		words = append(words, strings.Join(masked, " "))
+	}
+	return strings.Join(words, "   ")
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/store"
+)
+
+// Ledger sources name the command or event behind a balance or collection change
+const (
+	SourceGuess       = "guess"
+	SourcePay         = "pay"
+	SourceShop        = "shop"
+	SourceShopRefresh = "shop_refresh"
+	SourceSClaim      = "sclaim"
+	SourceClaimCode   = "credeem"
+	SourceRedeem      = "redeem"
+	SourceAddBalance  = "addbal"
+	SourceTrade       = "trade"
+	SourceGift        = "gift"
+	SourceHint        = "hint"
+)
+
+// LedgerSource describes why a balance or collection changed
+type LedgerSource struct {
+	Source         string
+	Reference      string
+	CounterpartyID int64
+}
+
+// coinEntry builds a ledger entry for a balance change
+func coinEntry(userID int64, amount int64, src LedgerSource) models.LedgerEntry {
+	return models.LedgerEntry{
+		UserID:         userID,
+		Type:           models.LedgerCoins,
+		Amount:         amount,
+		CounterpartyID: src.CounterpartyID,
+		Source:         src.Source,
+		Reference:      src.Reference,
+		CreatedAt:      time.Now(),
+	}
+}
+
+// characterEntry builds a ledger entry for a character gained (delta 1) or lost (delta -1)
+func characterEntry(userID int64, delta int64, char models.UserCharacter, src LedgerSource) models.LedgerEntry {
+	return models.LedgerEntry{
+		UserID:         userID,
+		Type:           models.LedgerCharacter,
+		Amount:         delta,
+		CharacterID:    char.ID,
+		CharacterName:  char.Name,
+		CounterpartyID: src.CounterpartyID,
+		Source:         src.Source,
+		Reference:      src.Reference,
+		CreatedAt:      time.Now(),
+	}
+}
+
+// LedgerService reads the balance and collection ledger
+type LedgerService struct {
+	ledger store.LedgerStore
+}
+
+// NewLedgerService creates a new LedgerService
+func NewLedgerService(ledger store.LedgerStore) *LedgerService {
+	return &LedgerService{ledger: ledger}
+}
+
+// GetHistory gets a page of a user's ledger entries, newest first, and the total entry count
+func (s *LedgerService) GetHistory(userID int64, page, pageSize int) ([]models.LedgerEntry, int64, error) {
+	total, err := s.ledger.CountEntries(context.Background(), userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	entries, err := s.ledger.ListEntries(context.Background(), userID, page*pageSize, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// Reconstruct replays a user's ledger into the balance and collection size it implies
+func (s *LedgerService) Reconstruct(userID int64) (*store.LedgerSummary, error) {
+	return s.ledger.Summarize(context.Background(), userID)
+}
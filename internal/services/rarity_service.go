@@ -2,62 +2,60 @@ package services
 
 import (
 	"context"
+	"errors"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"senpai-waifu-bot/internal/database"
+	"go.uber.org/zap"
 	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/store"
 )
 
 // RarityService handles rarity settings and locked characters
-type RarityService struct{}
+type RarityService struct {
+	rarity store.RarityStore
+	log    *zap.Logger
+}
 
 // NewRarityService creates a new RarityService
-func NewRarityService() *RarityService {
-	return &RarityService{}
+func NewRarityService(rarity store.RarityStore, log *zap.Logger) *RarityService {
+	return &RarityService{rarity: rarity, log: log}
 }
 
 // GetChatRaritySettings gets rarity settings for a chat
 func (s *RarityService) GetChatRaritySettings(chatID int64) (*models.RaritySettings, error) {
-	var settings models.RaritySettings
-	err := database.RaritySettingsCollection.FindOne(
-		context.Background(),
-		bson.M{"chat_id": chatID},
-	).Decode(&settings)
-	
+	settings, err := s.rarity.GetRaritySettings(context.Background(), chatID)
 	if err != nil {
 		// Create default settings
-		settings = models.RaritySettings{
+		settings = &models.RaritySettings{
 			ChatID:           chatID,
 			DisabledRarities: []int{},
 		}
-		_, _ = database.RaritySettingsCollection.InsertOne(context.Background(), settings)
+		if err := s.rarity.InsertRaritySettings(context.Background(), settings); err != nil {
+			s.log.Warn("failed to store default rarity settings", zap.Int64("chat_id", chatID), zap.Error(err))
+		}
 	}
-	
-	return &settings, nil
+
+	return settings, nil
 }
 
 // EnableRarity enables a rarity for a chat
 func (s *RarityService) EnableRarity(chatID int64, rarity int) error {
-	_, err := database.RaritySettingsCollection.UpdateOne(
-		context.Background(),
-		bson.M{"chat_id": chatID},
-		bson.M{"$pull": bson.M{"disabled_rarities": rarity}},
-		options.Update().SetUpsert(true),
-	)
-	return err
+	return s.rarity.EnableRarity(context.Background(), chatID, rarity)
 }
 
 // DisableRarity disables a rarity for a chat
 func (s *RarityService) DisableRarity(chatID int64, rarity int) error {
-	_, err := database.RaritySettingsCollection.UpdateOne(
-		context.Background(),
-		bson.M{"chat_id": chatID},
-		bson.M{"$addToSet": bson.M{"disabled_rarities": rarity}},
-		options.Update().SetUpsert(true),
-	)
-	return err
+	return s.rarity.DisableRarity(context.Background(), chatID, rarity)
+}
+
+// SetRarityWeight overrides the drop weight of a rarity in a chat
+func (s *RarityService) SetRarityWeight(chatID int64, rarity, weight int) error {
+	return s.rarity.SetRarityWeight(context.Background(), chatID, rarity, weight)
+}
+
+// ResetRarityWeight returns a rarity in a chat to the configured drop weight
+func (s *RarityService) ResetRarityWeight(chatID int64, rarity int) error {
+	return s.rarity.ResetRarityWeight(context.Background(), chatID, rarity)
 }
 
 // GetDisabledRarities gets list of disabled rarities for a chat
@@ -75,7 +73,7 @@ func (s *RarityService) IsRarityEnabled(chatID int64, rarity int) (bool, error)
 	if err != nil {
 		return true, err
 	}
-	
+
 	for _, r := range settings.DisabledRarities {
 		if r == rarity {
 			return false, nil
@@ -86,7 +84,7 @@ func (s *RarityService) IsRarityEnabled(chatID int64, rarity int) (bool, error)
 
 // LockCharacter locks a character from spawning
 func (s *RarityService) LockCharacter(charID, charName string, lockedByID int64, lockedByName, reason string) error {
-	lockData := models.LockedCharacter{
+	lockData := &models.LockedCharacter{
 		CharacterID:   charID,
 		CharacterName: charName,
 		LockedByID:    lockedByID,
@@ -94,47 +92,22 @@ func (s *RarityService) LockCharacter(charID, charName string, lockedByID int64,
 		Reason:        reason,
 		LockedAt:      time.Now(),
 	}
-	
-	_, err := database.LockedCharactersCollection.UpdateOne(
-		context.Background(),
-		bson.M{"character_id": charID},
-		bson.M{"$set": lockData},
-		options.Update().SetUpsert(true),
-	)
-	return err
+	return s.rarity.LockCharacter(context.Background(), lockData)
 }
 
 // UnlockCharacter unlocks a character
 func (s *RarityService) UnlockCharacter(charID string) error {
-	_, err := database.LockedCharactersCollection.DeleteOne(
-		context.Background(),
-		bson.M{"character_id": charID},
-	)
-	return err
+	return s.rarity.UnlockCharacter(context.Background(), charID)
 }
 
 // IsCharacterLocked checks if a character is locked
 func (s *RarityService) IsCharacterLocked(charID string) (bool, error) {
-	count, err := database.LockedCharactersCollection.CountDocuments(
-		context.Background(),
-		bson.M{"character_id": charID},
-	)
-	return count > 0, err
+	return s.rarity.IsCharacterLocked(context.Background(), charID)
 }
 
 // GetLockedCharacters gets all locked characters
 func (s *RarityService) GetLockedCharacters() ([]models.LockedCharacter, error) {
-	cursor, err := database.LockedCharactersCollection.Find(context.Background(), bson.M{})
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(context.Background())
-	
-	var locked []models.LockedCharacter
-	if err = cursor.All(context.Background(), &locked); err != nil {
-		return nil, err
-	}
-	return locked, nil
+	return s.rarity.LockedCharacters(context.Background())
 }
 
 // GetLockedCharacterIDs gets all locked character IDs
@@ -143,7 +116,7 @@ func (s *RarityService) GetLockedCharacterIDs() ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	
+
 	ids := make([]string, len(locked))
 	for i, l := range locked {
 		ids[i] = l.CharacterID
@@ -152,34 +125,28 @@ func (s *RarityService) GetLockedCharacterIDs() ([]string, error) {
 }
 
 // SortPreferenceService handles user sort preferences
-type SortPreferenceService struct{}
+type SortPreferenceService struct {
+	users store.UserStore
+}
 
 // NewSortPreferenceService creates a new SortPreferenceService
-func NewSortPreferenceService() *SortPreferenceService {
-	return &SortPreferenceService{}
+func NewSortPreferenceService(users store.UserStore) *SortPreferenceService {
+	return &SortPreferenceService{users: users}
 }
 
 // GetUserSortPreference gets user's sort preference
 func (s *SortPreferenceService) GetUserSortPreference(userID int64) (*int, error) {
-	var pref models.SortPreference
-	err := database.SortPreferencesCollection.FindOne(
-		context.Background(),
-		bson.M{"user_id": userID},
-	).Decode(&pref)
-	
-	if err != nil {
+	pref, err := s.users.GetSortPreference(context.Background(), userID)
+	if errors.Is(err, store.ErrNotFound) {
 		return nil, nil // No preference set
 	}
+	if err != nil {
+		return nil, err
+	}
 	return pref.RarityFilter, nil
 }
 
 // SetUserSortPreference sets user's sort preference
 func (s *SortPreferenceService) SetUserSortPreference(userID int64, rarityFilter *int) error {
-	_, err := database.SortPreferencesCollection.UpdateOne(
-		context.Background(),
-		bson.M{"user_id": userID},
-		bson.M{"$set": bson.M{"rarity_filter": rarityFilter}},
-		options.Update().SetUpsert(true),
-	)
-	return err
+	return s.users.SetSortPreference(context.Background(), userID, rarityFilter)
 }
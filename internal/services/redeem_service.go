@@ -5,27 +5,29 @@ import (
 	"strings"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"senpai-waifu-bot/internal/database"
+	"go.uber.org/zap"
 	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/store"
 	"senpai-waifu-bot/internal/utils"
 )
 
 // RedeemService handles redeem code operations
-type RedeemService struct{}
+type RedeemService struct {
+	codes store.CodeStore
+	log   *zap.Logger
+}
 
 // NewRedeemService creates a new RedeemService
-func NewRedeemService() *RedeemService {
-	return &RedeemService{}
+func NewRedeemService(codes store.CodeStore, log *zap.Logger) *RedeemService {
+	return &RedeemService{codes: codes, log: log}
 }
 
 // CreateCoinCode creates a coin redeem code
 func (s *RedeemService) CreateCoinCode(amount int64, maxUses int, createdBy int64) (string, error) {
 	for i := 0; i < 10; i++ {
 		code := utils.GenerateUniqueCode()
-		
-		doc := models.RedeemCode{
+
+		doc := &models.RedeemCode{
 			Code:      strings.ToLower(code),
 			Type:      "coin",
 			Amount:    amount,
@@ -34,8 +36,8 @@ func (s *RedeemService) CreateCoinCode(amount int64, maxUses int, createdBy int6
 			IsActive:  true,
 			CreatedBy: createdBy,
 		}
-		
-		_, err := database.RedeemCodesCollection.InsertOne(context.Background(), doc)
+
+		err := s.codes.InsertRedeemCode(context.Background(), doc)
 		if err == nil {
 			return code, nil
 		}
@@ -48,8 +50,8 @@ func (s *RedeemService) CreateCoinCode(amount int64, maxUses int, createdBy int6
 func (s *RedeemService) CreateCharacterCode(charID string, maxUses int, createdBy int64) (string, error) {
 	for i := 0; i < 10; i++ {
 		code := utils.GenerateUniqueCode()
-		
-		doc := models.RedeemCode{
+
+		doc := &models.RedeemCode{
 			Code:        strings.ToLower(code),
 			Type:        "character",
 			CharacterID: charID,
@@ -58,8 +60,8 @@ func (s *RedeemService) CreateCharacterCode(charID string, maxUses int, createdB
 			IsActive:    true,
 			CreatedBy:   createdBy,
 		}
-		
-		_, err := database.RedeemCodesCollection.InsertOne(context.Background(), doc)
+
+		err := s.codes.InsertRedeemCode(context.Background(), doc)
 		if err == nil {
 			return code, nil
 		}
@@ -70,86 +72,57 @@ func (s *RedeemService) CreateCharacterCode(charID string, maxUses int, createdB
 // RedeemCode redeems a code for a user
 func (s *RedeemService) RedeemCode(code string, userID int64) (*models.RedeemCode, error) {
 	code = strings.ToLower(code)
-	
+
 	// Find and update atomically
-	result := database.RedeemCodesCollection.FindOneAndUpdate(
-		context.Background(),
-		bson.M{
-			"code":      code,
-			"is_active": true,
-			"used_by":   bson.M{"$ne": userID},
-			"$expr":     bson.M{"$lt": []interface{}{bson.M{"$size": "$used_by"}, "$max_uses"}},
-		},
-		bson.M{"$push": bson.M{"used_by": userID}},
-		options.FindOneAndUpdate().SetReturnDocument(options.After),
-	)
-	
-	var redeemCode models.RedeemCode
-	if err := result.Decode(&redeemCode); err != nil {
+	redeemCode, err := s.codes.UseRedeemCode(context.Background(), code, userID)
+	if err != nil {
 		return nil, err
 	}
-	
+
 	// Check if max uses reached and deactivate
 	if len(redeemCode.UsedBy) >= redeemCode.MaxUses {
-		_, _ = database.RedeemCodesCollection.UpdateOne(
-			context.Background(),
-			bson.M{"code": code},
-			bson.M{"$set": bson.M{"is_active": false}},
-		)
+		if err := s.codes.DeactivateRedeemCode(context.Background(), code); err != nil {
+			s.log.Warn("failed to deactivate used up redeem code", zap.String("code", code), zap.Error(err))
+		}
 	}
-	
-	return &redeemCode, nil
+
+	return redeemCode, nil
 }
 
 // GetRedeemCode gets a redeem code by code string
 func (s *RedeemService) GetRedeemCode(code string) (*models.RedeemCode, error) {
-	code = strings.ToLower(code)
-	
-	var redeemCode models.RedeemCode
-	err := database.RedeemCodesCollection.FindOne(
-		context.Background(),
-		bson.M{"code": code},
-	).Decode(&redeemCode)
-	
-	if err != nil {
-		return nil, err
-	}
-	return &redeemCode, nil
+	return s.codes.GetRedeemCode(context.Background(), strings.ToLower(code))
 }
 
 // HasUserRedeemed checks if a user has already redeemed a code
 func (s *RedeemService) HasUserRedeemed(code string, userID int64) (bool, error) {
-	code = strings.ToLower(code)
-	
-	count, err := database.RedeemCodesCollection.CountDocuments(
-		context.Background(),
-		bson.M{"code": code, "used_by": userID},
-	)
-	return count > 0, err
+	return s.codes.HasRedeemed(context.Background(), strings.ToLower(code), userID)
 }
 
 // ClaimCodeService handles claim code operations
-type ClaimCodeService struct{}
+type ClaimCodeService struct {
+	codes store.CodeStore
+}
 
 // NewClaimCodeService creates a new ClaimCodeService
-func NewClaimCodeService() *ClaimCodeService {
-	return &ClaimCodeService{}
+func NewClaimCodeService(codes store.CodeStore) *ClaimCodeService {
+	return &ClaimCodeService{codes: codes}
 }
 
 // CreateClaimCode creates a claim code for daily coins
 func (s *ClaimCodeService) CreateClaimCode(userID int64, amount int64) (string, error) {
 	for i := 0; i < 10; i++ {
 		code := utils.GenerateCoinCode()
-		
-		doc := models.ClaimCode{
+
+		doc := &models.ClaimCode{
 			Code:       code,
 			UserID:     userID,
 			Amount:     amount,
 			CreatedAt:  time.Now(),
 			IsRedeemed: false,
 		}
-		
-		_, err := database.ClaimCodesCollection.InsertOne(context.Background(), doc)
+
+		err := s.codes.InsertClaimCode(context.Background(), doc)
 		if err == nil {
 			return code, nil
 		}
@@ -159,43 +132,12 @@ func (s *ClaimCodeService) CreateClaimCode(userID int64, amount int64) (string,
 
 // GetClaimCode gets a claim code
 func (s *ClaimCodeService) GetClaimCode(code string) (*models.ClaimCode, error) {
-	var claimCode models.ClaimCode
-	err := database.ClaimCodesCollection.FindOne(
-		context.Background(),
-		bson.M{"code": code},
-	).Decode(&claimCode)
-	
-	if err != nil {
-		return nil, err
-	}
-	return &claimCode, nil
+	return s.codes.GetClaimCode(context.Background(), code)
 }
 
 // RedeemClaimCode redeems a claim code
 func (s *ClaimCodeService) RedeemClaimCode(code string, userID int64) (*models.ClaimCode, error) {
-	now := time.Now()
-	
-	result := database.ClaimCodesCollection.FindOneAndUpdate(
-		context.Background(),
-		bson.M{
-			"code":        code,
-			"user_id":     userID,
-			"is_redeemed": false,
-		},
-		bson.M{
-			"$set": bson.M{
-				"is_redeemed": true,
-				"redeemed_at": now,
-			},
-		},
-		options.FindOneAndUpdate().SetReturnDocument(options.After),
-	)
-	
-	var claimCode models.ClaimCode
-	if err := result.Decode(&claimCode); err != nil {
-		return nil, err
-	}
-	return &claimCode, nil
+	return s.codes.RedeemClaimCode(context.Background(), code, userID, time.Now())
 }
 
 // IsClaimCodeExpired checks if a claim code is expired (24 hours)
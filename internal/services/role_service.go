@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/store"
+)
+
+// GrantableRoles are the roles that can be granted with /addsudo
+var GrantableRoles = []string{models.RoleSudo, models.RoleUploader, models.RoleEconomy}
+
+// IsGrantable reports whether role can be granted
+func IsGrantable(role string) bool {
+	for _, r := range GrantableRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleService handles the roles granted to users
+type RoleService struct {
+	roles store.RoleStore
+}
+
+// NewRoleService creates a new RoleService
+func NewRoleService(roles store.RoleStore) *RoleService {
+	return &RoleService{roles: roles}
+}
+
+// Grant gives userID the role. It returns store.ErrDuplicate when they already hold it.
+func (s *RoleService) Grant(userID int64, role string, grantedBy int64) error {
+	return s.roles.GrantRole(context.Background(), &models.RoleGrant{
+		UserID:    userID,
+		Role:      role,
+		GrantedBy: grantedBy,
+		GrantedAt: time.Now(),
+	})
+}
+
+// Revoke takes the role from userID. It returns store.ErrNotFound when they do not hold it.
+func (s *RoleService) Revoke(userID int64, role string) error {
+	return s.roles.RevokeRole(context.Background(), userID, role)
+}
+
+// Roles returns the names of the roles granted to userID
+func (s *RoleService) Roles(userID int64) ([]string, error) {
+	grants, err := s.roles.UserRoles(context.Background(), userID)
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]string, len(grants))
+	for i, g := range grants {
+		roles[i] = g.Role
+	}
+	return roles, nil
+}
+
+// Grants returns every role granted to any user
+func (s *RoleService) Grants() ([]models.RoleGrant, error) {
+	return s.roles.RoleGrants(context.Background())
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"time"
+
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/state"
+	"senpai-waifu-bot/internal/utils"
+)
+
+// SpawnRateWindow is how far back a chat's message rate is measured for adaptive spawns
+const SpawnRateWindow = 15 * time.Minute
+
+// SpawnSchedule is when a chat gets its spawns: its own settings over the configured defaults
+type SpawnSchedule struct {
+	Mode string
+	// Frequency is the number of counted messages between spawns in messages mode, and the
+	// number of messages a spawn should take on average in adaptive mode
+	Frequency int
+	// Interval is the time between spawns in interval mode
+	Interval time.Duration
+	// MinGap and MaxGap bound the time between spawns in messages and adaptive mode; zero
+	// leaves that side open
+	MinGap time.Duration
+	MaxGap time.Duration
+	// Quiet is the daily window without spawns, if any
+	Quiet *models.QuietHours
+	// Location is the time zone of quiet hours that do not name their own; nil is IST
+	Location *time.Location
+	// Expiry is how long a spawn can be guessed before it flees; spawns never flee when it is
+	// not positive
+	Expiry time.Duration
+}
+
+// With returns the schedule of a chat with settings, taking every field it does not set from s
+func (s SpawnSchedule) With(settings *models.SpawnSettings) SpawnSchedule {
+	if settings.Mode != "" {
+		s.Mode = settings.Mode
+	}
+	if settings.MessageFrequency > 0 {
+		s.Frequency = settings.MessageFrequency
+	}
+	if settings.Interval > 0 {
+		s.Interval = settings.Interval
+	}
+	if settings.MinGap != nil {
+		s.MinGap = *settings.MinGap
+	}
+	if settings.MaxGap != nil {
+		s.MaxGap = *settings.MaxGap
+	}
+	if settings.QuietHours != nil {
+		s.Quiet = settings.QuietHours
+	}
+	if settings.Expiry != 0 {
+		s.Expiry = settings.Expiry
+	}
+	return s
+}
+
+// QuietLocation returns the time zone of the quiet hours: their own, or else Location
+func (s SpawnSchedule) QuietLocation() *time.Location {
+	if s.Quiet != nil && s.Quiet.Timezone != "" {
+		if loc, err := utils.LoadLocation(s.Quiet.Timezone); err == nil {
+			return loc
+		}
+	}
+	if s.Location != nil {
+		return s.Location
+	}
+	return utils.IST
+}
+
+// InQuietHours reports whether t falls in the quiet hours
+func (s SpawnSchedule) InQuietHours(t time.Time) bool {
+	if s.Quiet == nil || s.Quiet.Start == s.Quiet.End {
+		return false
+	}
+	t = t.In(s.QuietLocation())
+	minute := t.Hour()*60 + t.Minute()
+	if s.Quiet.Start < s.Quiet.End {
+		return minute >= s.Quiet.Start && minute < s.Quiet.End
+	}
+	return minute >= s.Quiet.Start || minute < s.Quiet.End
+}
+
+// AdaptiveGap is the time between spawns in adaptive mode for a chat sending rate messages a
+// minute: the time it takes to send Frequency messages, within MinGap and MaxGap
+func (s SpawnSchedule) AdaptiveGap(rate float64) time.Duration {
+	gap := s.MaxGap
+	if rate > 0 {
+		gap = time.Duration(float64(s.Frequency) / rate * float64(time.Minute))
+	}
+	if s.MaxGap > 0 && gap > s.MaxGap {
+		gap = s.MaxGap
+	}
+	if gap < s.MinGap {
+		gap = s.MinGap
+	}
+	return gap
+}
+
+// Due reports whether a chat with activity a should get a spawn at now. counted is the
+// number of counted messages since the last spawn, which only messages mode uses. Chats
+// never get a spawn during quiet hours or when nobody wrote since the last one.
+func (s SpawnSchedule) Due(a state.Activity, counted int, now time.Time) bool {
+	if s.InQuietHours(now) || !a.LastMessage.After(a.LastSpawn) {
+		return false
+	}
+	since := now.Sub(a.LastSpawn)
+
+	switch s.Mode {
+	case models.SpawnModeInterval:
+		return since >= s.Interval
+	case models.SpawnModeAdaptive:
+		// Without a maximum a silent chat has no gap to wait for
+		if a.Rate <= 0 && s.MaxGap <= 0 {
+			return false
+		}
+		return since >= s.AdaptiveGap(a.Rate)
+	default:
+		if since < s.MinGap {
+			return false
+		}
+		if s.MaxGap > 0 && since >= s.MaxGap {
+			return true
+		}
+		return counted >= s.Frequency
+	}
+}
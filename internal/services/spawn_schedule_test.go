@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"senpai-waifu-bot/internal/models"
+)
+
+func TestSpawnScheduleWith(t *testing.T) {
+	defaults := SpawnSchedule{Mode: models.SpawnModeMessages, Frequency: 100, MinGap: time.Minute, MaxGap: time.Hour}
+	zero, tenMinutes := time.Duration(0), 10*time.Minute
+	tests := []struct {
+		name     string
+		settings models.SpawnSettings
+		wantMin  time.Duration
+		wantMax  time.Duration
+	}{
+		{"unset", models.SpawnSettings{}, time.Minute, time.Hour},
+		{"set", models.SpawnSettings{MinGap: &tenMinutes, MaxGap: &tenMinutes}, tenMinutes, tenMinutes},
+		{"zero", models.SpawnSettings{MinGap: &zero, MaxGap: &zero}, 0, 0},
+		{"max only", models.SpawnSettings{MaxGap: &zero}, time.Minute, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := defaults.With(&tt.settings)
+			if got.MinGap != tt.wantMin || got.MaxGap != tt.wantMax {
+				t.Errorf("gap = %v-%v, want %v-%v", got.MinGap, got.MaxGap, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestSpawnScheduleInQuietHours(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 02:30 UTC is 03:30 in Madrid in winter and 08:00 in India
+	at := time.Date(2026, 1, 15, 2, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		location *time.Location
+		quiet    models.QuietHours
+		want     bool
+	}{
+		{"default zone", madrid, models.QuietHours{Start: 3 * 60, End: 4 * 60}, true},
+		{"default zone outside", madrid, models.QuietHours{Start: 7 * 60, End: 9 * 60}, false},
+		{"own zone", madrid, models.QuietHours{Start: 7 * 60, End: 9 * 60, Timezone: "Asia/Kolkata"}, true},
+		{"IST without a default", nil, models.QuietHours{Start: 7 * 60, End: 9 * 60}, true},
+		{"past midnight", madrid, models.QuietHours{Start: 23 * 60, End: 4 * 60}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quiet := tt.quiet
+			s := SpawnSchedule{Quiet: &quiet, Location: tt.location}
+			if got := s.InQuietHours(at); got != tt.want {
+				t.Errorf("InQuietHours(%v) in %v = %v, want %v", at, s.QuietLocation(), got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/store"
+)
+
+// How long pending confirmations stay valid
+const (
+	PaymentTTL = 5 * time.Minute
+	TradeTTL   = 5 * time.Minute
+	GiftTTL    = 30 * time.Second
+)
+
+// StateService handles the spawn and confirmation state that has to survive restarts
+type StateService struct {
+	state store.StateStore
+}
+
+// NewStateService creates a new StateService
+func NewStateService(state store.StateStore) *StateService {
+	return &StateService{state: state}
+}
+
+// SetActiveSpawn makes char the character to guess in chatID, replacing any previous spawn.
+// The spawn flees after expiry unless expiry is not positive.
+func (s *StateService) SetActiveSpawn(chatID int64, char models.UserCharacter, expiry time.Duration) error {
+	spawn := &models.ActiveSpawn{
+		ChatID:    chatID,
+		Character: char,
+		SpawnedAt: time.Now(),
+	}
+	if expiry > 0 {
+		fleesAt := spawn.SpawnedAt.Add(expiry)
+		spawn.FleesAt = &fleesAt
+	}
+	return s.state.SetSpawn(context.Background(), spawn)
+}
+
+// SetSpawnMessage records the message that announced the spawn of chatID
+func (s *StateService) SetSpawnMessage(chatID int64, messageID int) error {
+	return s.state.SetSpawnMessage(context.Background(), chatID, messageID)
+}
+
+// ExpiredSpawns lists the spawns nobody guessed before they were due to flee at now
+func (s *StateService) ExpiredSpawns(now time.Time) ([]models.ActiveSpawn, error) {
+	return s.state.ExpiredSpawns(context.Background(), now)
+}
+
+// FleeActiveSpawn records spawn as uncaught; it returns false if the spawn was guessed or
+// replaced in the meantime
+func (s *StateService) FleeActiveSpawn(spawn *models.ActiveSpawn, now time.Time) (bool, error) {
+	_, err := s.state.FleeSpawn(context.Background(), spawn.ChatID, spawn.SpawnedAt, now)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetActiveSpawn gets the current spawn of a chat
+func (s *StateService) GetActiveSpawn(chatID int64) (*models.ActiveSpawn, error) {
+	return s.state.GetSpawn(context.Background(), chatID)
+}
+
+// ClaimActiveSpawn records userID as the first correct guesser; it returns false if someone was
+// first or the spawn fled
+func (s *StateService) ClaimActiveSpawn(chatID, userID int64) (bool, error) {
+	_, err := s.state.ClaimSpawn(context.Background(), chatID, userID, time.Now())
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// IncrementMessageCounter bumps a chat's spawn counter and returns the new value
+func (s *StateService) IncrementMessageCounter(chatID int64) (int, error) {
+	return s.state.IncMessageCounter(context.Background(), chatID)
+}
+
+// ResetMessageCounter sets a chat's spawn counter back to zero
+func (s *StateService) ResetMessageCounter(chatID int64) error {
+	return s.state.ResetMessageCounter(context.Background(), chatID)
+}
+
+// CreatePayment stores a pending payment that expires after PaymentTTL
+func (s *StateService) CreatePayment(payment *models.PendingPayment) error {
+	payment.CreatedAt = time.Now()
+	payment.ExpiresAt = payment.CreatedAt.Add(PaymentTTL)
+	return s.state.PutPayment(context.Background(), payment)
+}
+
+// GetPayment gets a pending payment by token
+func (s *StateService) GetPayment(token string) (*models.PendingPayment, error) {
+	return s.state.GetPayment(context.Background(), token)
+}
+
+// SetPaymentMessage records the confirmation message of a pending payment
+func (s *StateService) SetPaymentMessage(token string, messageID int) error {
+	return s.state.SetPaymentMessage(context.Background(), token, messageID)
+}
+
+// TakePayment removes a pending payment and returns it, so only one caller can act on it
+func (s *StateService) TakePayment(token string) (*models.PendingPayment, error) {
+	return s.state.TakePayment(context.Background(), token)
+}
+
+// CreateTrade stores a pending trade that expires after TradeTTL; it fails with
+// store.ErrDuplicate while another trade with the same key is pending
+func (s *StateService) CreateTrade(trade *models.PendingTrade) error {
+	trade.Timestamp = time.Now()
+	trade.ExpiresAt = trade.Timestamp.Add(TradeTTL)
+	return s.state.PutTrade(context.Background(), trade)
+}
+
+// GetTrade gets a pending trade by key
+func (s *StateService) GetTrade(key string) (*models.PendingTrade, error) {
+	return s.state.GetTrade(context.Background(), key)
+}
+
+// TakeTrade removes a pending trade and returns it, so only one caller can act on it
+func (s *StateService) TakeTrade(key string) (*models.PendingTrade, error) {
+	return s.state.TakeTrade(context.Background(), key)
+}
+
+// CreateGift stores a pending gift that expires after GiftTTL; it fails with
+// store.ErrDuplicate while another gift with the same key is pending
+func (s *StateService) CreateGift(gift *models.PendingGift) error {
+	gift.Timestamp = time.Now()
+	gift.ExpiresAt = gift.Timestamp.Add(GiftTTL)
+	return s.state.PutGift(context.Background(), gift)
+}
+
+// GetGift gets a pending gift by key
+func (s *StateService) GetGift(key string) (*models.PendingGift, error) {
+	return s.state.GetGift(context.Background(), key)
+}
+
+// TakeGift removes a pending gift and returns it, so only one caller can act on it
+func (s *StateService) TakeGift(key string) (*models.PendingGift, error) {
+	return s.state.TakeGift(context.Background(), key)
+}
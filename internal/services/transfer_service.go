@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/metrics"
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/store"
+)
+
+// MaxInventorySize is the maximum number of characters a user can hold
+const MaxInventorySize = 5000
+
+var (
+	// ErrInsufficientFunds is returned when the payer's balance does not cover the amount
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrInventoryFull is returned when the receiver already holds MaxInventorySize characters
+	ErrInventoryFull = errors.New("inventory full")
+	// ErrAlreadyOwned is returned when buying a character the user already has
+	ErrAlreadyOwned = errors.New("character already owned")
+)
+
+// NotOwnedError is returned when a user no longer has the character being transferred
+type NotOwnedError struct {
+	UserID      int64
+	CharacterID string
+}
+
+func (e *NotOwnedError) Error() string {
+	return fmt.Sprintf("user %d does not own character %s", e.UserID, e.CharacterID)
+}
+
+// TradeResult holds the characters that changed hands in a trade
+type TradeResult struct {
+	SenderChar   models.UserCharacter
+	ReceiverChar models.UserCharacter
+}
+
+// TransferService moves characters and coins between users. Every operation runs as a single
+// MongoDB transaction; when the deployment does not support transactions it falls back to
+// conditional single-document writes and compensates the steps already applied on failure.
+// Each operation appends its ledger entries as part of the same unit of work.
+type TransferService struct {
+	users  store.UserStore
+	ledger store.LedgerStore
+	tx     store.Transactor
+	log    *zap.Logger
+}
+
+// NewTransferService creates a new TransferService
+func NewTransferService(users store.UserStore, ledger store.LedgerStore, tx store.Transactor, log *zap.Logger) *TransferService {
+	return &TransferService{users: users, ledger: ledger, tx: tx, log: log}
+}
+
+// Trade swaps one copy of senderCharID for one copy of receiverCharID
+func (s *TransferService) Trade(senderID int64, senderCharID string, receiverID int64, receiverCharID string, ref string) (*TradeResult, error) {
+	var result *TradeResult
+	err := runAtomic(s.tx, s.log, func(ctx context.Context, undo *compensator) error {
+		senderChar, err := s.take(ctx, undo, senderID, senderCharID)
+		if err != nil {
+			return err
+		}
+		receiverChar, err := s.take(ctx, undo, receiverID, receiverCharID)
+		if err != nil {
+			return err
+		}
+		if err := s.give(ctx, undo, senderID, *receiverChar); err != nil {
+			return err
+		}
+		if err := s.give(ctx, undo, receiverID, *senderChar); err != nil {
+			return err
+		}
+
+		senderSrc := LedgerSource{Source: SourceTrade, Reference: ref, CounterpartyID: receiverID}
+		receiverSrc := LedgerSource{Source: SourceTrade, Reference: ref, CounterpartyID: senderID}
+		err = s.ledger.AppendEntries(ctx,
+			characterEntry(senderID, -1, *senderChar, senderSrc),
+			characterEntry(senderID, 1, *receiverChar, senderSrc),
+			characterEntry(receiverID, -1, *receiverChar, receiverSrc),
+			characterEntry(receiverID, 1, *senderChar, receiverSrc),
+		)
+		if err != nil {
+			return err
+		}
+
+		result = &TradeResult{SenderChar: *senderChar, ReceiverChar: *receiverChar}
+		return nil
+	})
+	return result, err
+}
+
+// Gift moves one copy of charID from sender to receiver
+func (s *TransferService) Gift(senderID, receiverID int64, charID string, ref string) (*models.UserCharacter, error) {
+	var gifted *models.UserCharacter
+	err := runAtomic(s.tx, s.log, func(ctx context.Context, undo *compensator) error {
+		if err := s.checkInventory(ctx, receiverID); err != nil {
+			return err
+		}
+		char, err := s.take(ctx, undo, senderID, charID)
+		if err != nil {
+			return err
+		}
+		if err := s.give(ctx, undo, receiverID, *char); err != nil {
+			return err
+		}
+
+		err = s.ledger.AppendEntries(ctx,
+			characterEntry(senderID, -1, *char, LedgerSource{Source: SourceGift, Reference: ref, CounterpartyID: receiverID}),
+			characterEntry(receiverID, 1, *char, LedgerSource{Source: SourceGift, Reference: ref, CounterpartyID: senderID}),
+		)
+		if err != nil {
+			return err
+		}
+
+		gifted = char
+		return nil
+	})
+	return gifted, err
+}
+
+// Pay moves amount coins from sender to target and returns the sender's new balance
+func (s *TransferService) Pay(senderID, targetID int64, amount int64, ref string) (int64, error) {
+	var senderBalance int64
+	err := runAtomic(s.tx, s.log, func(ctx context.Context, undo *compensator) error {
+		balance, err := s.debit(ctx, undo, senderID, amount)
+		if err != nil {
+			return err
+		}
+		if err := s.credit(ctx, undo, targetID, amount); err != nil {
+			return err
+		}
+
+		err = s.ledger.AppendEntries(ctx,
+			coinEntry(senderID, -amount, LedgerSource{Source: SourcePay, Reference: ref, CounterpartyID: targetID}),
+			coinEntry(targetID, amount, LedgerSource{Source: SourcePay, Reference: ref, CounterpartyID: senderID}),
+		)
+		if err != nil {
+			return err
+		}
+
+		senderBalance = balance
+		return nil
+	})
+	return senderBalance, err
+}
+
+// Purchase charges price to the user and adds char to their collection, returning the new balance
+func (s *TransferService) Purchase(userID int64, char models.UserCharacter, price int64) (int64, error) {
+	var newBalance int64
+	err := runAtomic(s.tx, s.log, func(ctx context.Context, undo *compensator) error {
+		owned, err := s.users.HasCharacter(ctx, userID, char.ID)
+		if err != nil {
+			return err
+		}
+		if owned {
+			return ErrAlreadyOwned
+		}
+		balance, err := s.debit(ctx, undo, userID, price)
+		if err != nil {
+			return err
+		}
+		if err := s.give(ctx, undo, userID, char); err != nil {
+			return err
+		}
+
+		src := LedgerSource{Source: SourceShop, Reference: char.ID}
+		err = s.ledger.AppendEntries(ctx,
+			coinEntry(userID, -price, src),
+			characterEntry(userID, 1, char, src),
+		)
+		if err != nil {
+			return err
+		}
+
+		newBalance = balance
+		return nil
+	})
+	if err == nil {
+		metrics.RecordCoins(SourceShop, -price)
+	}
+	return newBalance, err
+}
+
+// Charge deducts amount from the user's balance if it is covered, returning the new balance
+func (s *TransferService) Charge(userID int64, amount int64, src LedgerSource) (int64, error) {
+	var newBalance int64
+	err := runAtomic(s.tx, s.log, func(ctx context.Context, undo *compensator) error {
+		balance, err := s.debit(ctx, undo, userID, amount)
+		if err != nil {
+			return err
+		}
+		if err := s.ledger.AppendEntries(ctx, coinEntry(userID, -amount, src)); err != nil {
+			return err
+		}
+
+		newBalance = balance
+		return nil
+	})
+	if err == nil {
+		metrics.RecordCoins(src.Source, -amount)
+	}
+	return newBalance, err
+}
+
+func (s *TransferService) take(ctx context.Context, undo *compensator, userID int64, charID string) (*models.UserCharacter, error) {
+	char, err := s.users.TakeCharacter(ctx, userID, charID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, &NotOwnedError{UserID: userID, CharacterID: charID}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	undo.add(func(ctx context.Context) error {
+		return s.users.PushCharacter(ctx, userID, *char)
+	})
+	return char, nil
+}
+
+func (s *TransferService) give(ctx context.Context, undo *compensator, userID int64, char models.UserCharacter) error {
+	if err := s.users.PushCharacter(ctx, userID, char); err != nil {
+		return err
+	}
+
+	undo.add(func(ctx context.Context) error {
+		_, err := s.users.TakeCharacter(ctx, userID, char.ID)
+		return err
+	})
+	return nil
+}
+
+func (s *TransferService) debit(ctx context.Context, undo *compensator, userID int64, amount int64) (int64, error) {
+	balance, err := s.users.DebitBalance(ctx, userID, amount)
+	if errors.Is(err, store.ErrNotFound) {
+		return 0, ErrInsufficientFunds
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	undo.add(func(ctx context.Context) error {
+		_, err := s.users.IncBalance(ctx, userID, amount)
+		return err
+	})
+	return balance, nil
+}
+
+func (s *TransferService) credit(ctx context.Context, undo *compensator, userID int64, amount int64) error {
+	if _, err := s.users.IncBalance(ctx, userID, amount); err != nil {
+		return err
+	}
+
+	undo.add(func(ctx context.Context) error {
+		_, err := s.users.IncBalance(ctx, userID, -amount)
+		return err
+	})
+	return nil
+}
+
+func (s *TransferService) checkInventory(ctx context.Context, userID int64) error {
+	user, err := s.users.GetUser(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(user.Characters) >= MaxInventorySize {
+		return ErrInventoryFull
+	}
+	return nil
+}
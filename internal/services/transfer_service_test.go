@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/store"
+)
+
+var errLedgerDown = errors.New("ledger down")
+
+// failingLedger is a ledger whose appends fail, so that every transfer fails at its last step
+type failingLedger struct {
+	store.LedgerStore
+}
+
+func (failingLedger) AppendEntries(ctx context.Context, entries ...models.LedgerEntry) error {
+	return errLedgerDown
+}
+
+// Users of the transfer tests: alice has 100 coins, Shinobu and Kanao; bob has 50 coins and
+// Hu Tao
+const (
+	alice = 1
+	bob   = 2
+)
+
+var (
+	shinobu = models.UserCharacter{ID: "001", Name: "Shinobu Kochou", Anime: "Demon Slayer", Rarity: 3}
+	hutao   = models.UserCharacter{ID: "002", Name: "Hu Tao", Anime: "Genshin Impact", Rarity: 2}
+	kanao   = models.UserCharacter{ID: "003", Name: "Kanao Tsuyuri", Anime: "Demon Slayer", Rarity: 1}
+	rem     = models.UserCharacter{ID: "004", Name: "Rem", Anime: "Re:Zero", Rarity: 3}
+)
+
+// newTransferStore returns a memory store holding alice and bob. Memory stores do not support
+// transactions, so transfers run step by step with compensation.
+func newTransferStore(t *testing.T) *store.Store {
+	t.Helper()
+	st := store.NewMemoryStore()
+	for _, user := range []models.User{
+		{ID: alice, FirstName: "alice", Balance: 100, Characters: []models.UserCharacter{shinobu, kanao}},
+		{ID: bob, FirstName: "bob", Balance: 50, Characters: []models.UserCharacter{hutao}},
+	} {
+		if err := st.Users.InsertUser(context.Background(), &user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return st
+}
+
+// holdings is a user's balance and the IDs of their characters, in ID order
+type holdings struct {
+	Balance    int64
+	Characters []string
+}
+
+func holdingsOf(t *testing.T, st *store.Store, userID int64) holdings {
+	t.Helper()
+	user, err := st.Users.GetUser(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := holdings{Balance: user.Balance, Characters: []string{}}
+	for _, char := range user.Characters {
+		h.Characters = append(h.Characters, char.ID)
+	}
+	sort.Strings(h.Characters)
+	return h
+}
+
+func expectHoldings(t *testing.T, st *store.Store, userID int64, want holdings) {
+	t.Helper()
+	if got := holdingsOf(t, st, userID); !reflect.DeepEqual(got, want) {
+		t.Errorf("user %d holds %+v, want %+v", userID, got, want)
+	}
+}
+
+var (
+	aliceBefore = holdings{Balance: 100, Characters: []string{"001", "003"}}
+	bobBefore   = holdings{Balance: 50, Characters: []string{"002"}}
+)
+
+func TestTransferRollsBackWhenLedgerFails(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(s *TransferService) error
+	}{
+		{"trade", func(s *TransferService) error {
+			_, err := s.Trade(alice, shinobu.ID, bob, hutao.ID, "t1")
+			return err
+		}},
+		{"gift", func(s *TransferService) error {
+			_, err := s.Gift(alice, bob, shinobu.ID, "g1")
+			return err
+		}},
+		{"pay", func(s *TransferService) error {
+			_, err := s.Pay(alice, bob, 30, "p1")
+			return err
+		}},
+		{"purchase", func(s *TransferService) error {
+			_, err := s.Purchase(alice, rem, 40)
+			return err
+		}},
+		{"charge", func(s *TransferService) error {
+			_, err := s.Charge(alice, 40, LedgerSource{Source: SourceShopRefresh})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newTransferStore(t)
+			s := NewTransferService(st.Users, failingLedger{st.Ledger}, st.Tx, zap.NewNop())
+
+			if err := tt.run(s); !errors.Is(err, errLedgerDown) {
+				t.Fatalf("error = %v, want %v", err, errLedgerDown)
+			}
+			expectHoldings(t, st, alice, aliceBefore)
+			expectHoldings(t, st, bob, bobBefore)
+		})
+	}
+}
+
+func TestTransferErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		run      func(s *TransferService) error
+		want     error
+		notOwned *NotOwnedError
+	}{
+		{"pay more than the balance", func(s *TransferService) error {
+			_, err := s.Pay(alice, bob, 101, "p1")
+			return err
+		}, ErrInsufficientFunds, nil},
+		{"buy more than the balance", func(s *TransferService) error {
+			_, err := s.Purchase(bob, rem, 51)
+			return err
+		}, ErrInsufficientFunds, nil},
+		{"buy an owned character", func(s *TransferService) error {
+			_, err := s.Purchase(alice, shinobu, 10)
+			return err
+		}, ErrAlreadyOwned, nil},
+		{"trade a character the sender lacks", func(s *TransferService) error {
+			_, err := s.Trade(alice, hutao.ID, bob, hutao.ID, "t1")
+			return err
+		}, nil, &NotOwnedError{UserID: alice, CharacterID: hutao.ID}},
+		{"trade for a character the receiver lacks", func(s *TransferService) error {
+			_, err := s.Trade(alice, shinobu.ID, bob, kanao.ID, "t1")
+			return err
+		}, nil, &NotOwnedError{UserID: bob, CharacterID: kanao.ID}},
+		{"gift a character the sender lacks", func(s *TransferService) error {
+			_, err := s.Gift(bob, alice, shinobu.ID, "g1")
+			return err
+		}, nil, &NotOwnedError{UserID: bob, CharacterID: shinobu.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newTransferStore(t)
+			s := NewTransferService(st.Users, st.Ledger, st.Tx, zap.NewNop())
+
+			err := tt.run(s)
+			if tt.notOwned != nil {
+				var notOwned *NotOwnedError
+				if !errors.As(err, &notOwned) || *notOwned != *tt.notOwned {
+					t.Fatalf("error = %v, want %v", err, tt.notOwned)
+				}
+			} else if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+			expectHoldings(t, st, alice, aliceBefore)
+			expectHoldings(t, st, bob, bobBefore)
+		})
+	}
+}
+
+func TestGiftToFullInventory(t *testing.T) {
+	st := newTransferStore(t)
+	full := models.User{ID: 3, FirstName: "carol", Characters: make([]models.UserCharacter, MaxInventorySize)}
+	for i := range full.Characters {
+		full.Characters[i] = rem
+	}
+	if err := st.Users.InsertUser(context.Background(), &full); err != nil {
+		t.Fatal(err)
+	}
+	s := NewTransferService(st.Users, st.Ledger, st.Tx, zap.NewNop())
+
+	if _, err := s.Gift(alice, full.ID, shinobu.ID, "g1"); !errors.Is(err, ErrInventoryFull) {
+		t.Fatalf("error = %v, want %v", err, ErrInventoryFull)
+	}
+	expectHoldings(t, st, alice, aliceBefore)
+	if n := len(holdingsOf(t, st, full.ID).Characters); n != MaxInventorySize {
+		t.Errorf("carol holds %d characters, want %d", n, MaxInventorySize)
+	}
+}
+
+func TestTransfers(t *testing.T) {
+	st := newTransferStore(t)
+	s := NewTransferService(st.Users, st.Ledger, st.Tx, zap.NewNop())
+	ctx := context.Background()
+
+	result, err := s.Trade(alice, shinobu.ID, bob, hutao.ID, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SenderChar != shinobu || result.ReceiverChar != hutao {
+		t.Errorf("Trade() = %+v, want Shinobu for Hu Tao", result)
+	}
+	if _, err := s.Gift(alice, bob, kanao.ID, "g1"); err != nil {
+		t.Fatal(err)
+	}
+	if balance, err := s.Pay(bob, alice, 20, "p1"); err != nil || balance != 30 {
+		t.Fatalf("Pay() = %d, %v, want 30", balance, err)
+	}
+	if balance, err := s.Purchase(alice, rem, 40); err != nil || balance != 80 {
+		t.Fatalf("Purchase() = %d, %v, want 80", balance, err)
+	}
+
+	expectHoldings(t, st, alice, holdings{Balance: 80, Characters: []string{"002", "004"}})
+	expectHoldings(t, st, bob, holdings{Balance: 30, Characters: []string{"001", "003"}})
+
+	// The ledger records the change from the starting holdings
+	for _, user := range []struct {
+		id           int64
+		coins, chars int64
+	}{
+		{alice, 80 - 100, 2 - 2},
+		{bob, 30 - 50, 2 - 1},
+	} {
+		summary, err := st.Ledger.Summarize(ctx, user.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if summary.Coins != user.coins || summary.Characters != user.chars {
+			t.Errorf("ledger of user %d: %d coins and %d characters, want %d and %d", user.id, summary.Coins, summary.Characters, user.coins, user.chars)
+		}
+	}
+}
@@ -2,30 +2,31 @@ package services
 
 import (
 	"context"
+	"errors"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"senpai-waifu-bot/internal/database"
+	"go.uber.org/zap"
+	"senpai-waifu-bot/internal/metrics"
 	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/store"
 )
 
 // UserService handles user-related database operations
-type UserService struct{}
+type UserService struct {
+	users  store.UserStore
+	ledger store.LedgerStore
+	tx     store.Transactor
+	log    *zap.Logger
+}
 
 // NewUserService creates a new UserService
-func NewUserService() *UserService {
-	return &UserService{}
+func NewUserService(users store.UserStore, ledger store.LedgerStore, tx store.Transactor, log *zap.Logger) *UserService {
+	return &UserService{users: users, ledger: ledger, tx: tx, log: log}
 }
 
 // GetUserByID gets a user by their ID
 func (s *UserService) GetUserByID(userID int64) (*models.User, error) {
-	var user models.User
-	err := database.UserCollection.FindOne(context.Background(), bson.M{"id": userID}).Decode(&user)
-	if err != nil {
-		return nil, err
-	}
-	return &user, nil
+	return s.users.GetUser(context.Background(), userID)
 }
 
 // GetOrCreateUser gets a user or creates if not exists
@@ -41,99 +42,89 @@ func (s *UserService) GetOrCreateUser(userID int64, username, firstName string)
 			Favorites:  []string{},
 			Balance:    0,
 		}
-		_, err = database.UserCollection.InsertOne(context.Background(), newUser)
-		if err != nil {
+		if err := s.users.InsertUser(context.Background(), newUser); err != nil {
 			return nil, err
 		}
 		return newUser, nil
 	}
-	
+
 	// Update username/firstname if changed
-	update := bson.M{}
+	changedUsername := ""
 	if user.Username != username && username != "" {
-		update["username"] = username
+		changedUsername = username
 	}
+	changedFirstName := ""
 	if user.FirstName != firstName && firstName != "" {
-		update["first_name"] = firstName
+		changedFirstName = firstName
 	}
-	
-	if len(update) > 0 {
-		_, _ = database.UserCollection.UpdateOne(
-			context.Background(),
-			bson.M{"id": userID},
-			bson.M{"$set": update},
-		)
+
+	if changedUsername != "" || changedFirstName != "" {
+		if err := s.users.UpdateNames(context.Background(), userID, changedUsername, changedFirstName); err != nil {
+			s.log.Warn("failed to update user names", zap.Int64("user_id", userID), zap.Error(err))
+		}
 	}
-	
+
 	return user, nil
 }
 
-// UpdateUserBalance updates a user's balance
-func (s *UserService) UpdateUserBalance(userID int64, amount int64) (int64, error) {
-	result := database.UserCollection.FindOneAndUpdate(
-		context.Background(),
-		bson.M{"id": userID},
-		bson.M{"$inc": bson.M{"balance": amount}},
-		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
-	)
-	
-	var user models.User
-	if err := result.Decode(&user); err != nil {
-		return 0, err
+// UpdateUserBalance updates a user's balance and records the change in the ledger
+func (s *UserService) UpdateUserBalance(userID int64, amount int64, src LedgerSource) (int64, error) {
+	var newBalance int64
+	err := runAtomic(s.tx, s.log, func(ctx context.Context, undo *compensator) error {
+		balance, err := s.users.IncBalance(ctx, userID, amount)
+		if err != nil {
+			return err
+		}
+		undo.add(func(ctx context.Context) error {
+			_, err := s.users.IncBalance(ctx, userID, -amount)
+			return err
+		})
+
+		if err := s.ledger.AppendEntries(ctx, coinEntry(userID, amount, src)); err != nil {
+			return err
+		}
+		newBalance = balance
+		return nil
+	})
+	if err == nil {
+		metrics.RecordCoins(src.Source, amount)
 	}
-	return user.Balance, nil
+	return newBalance, err
 }
 
 // GetUserBalance gets a user's balance
 func (s *UserService) GetUserBalance(userID int64) (int64, error) {
 	user, err := s.GetUserByID(userID)
 	if err != nil {
+		s.logLookupError(err, userID)
 		return 0, nil // Return 0 if user doesn't exist
 	}
 	return user.Balance, nil
 }
 
-// AddCharacterToUser adds a character to user's collection
-func (s *UserService) AddCharacterToUser(userID int64, char models.UserCharacter) error {
-	_, err := database.UserCollection.UpdateOne(
-		context.Background(),
-		bson.M{"id": userID},
-		bson.M{
-			"$push": bson.M{"characters": char},
-			"$setOnInsert": bson.M{"id": userID, "balance": 0},
-		},
-		options.Update().SetUpsert(true),
-	)
-	return err
-}
+// AddCharacterToUser adds a character to user's collection and records it in the ledger
+func (s *UserService) AddCharacterToUser(userID int64, char models.UserCharacter, src LedgerSource) error {
+	return runAtomic(s.tx, s.log, func(ctx context.Context, undo *compensator) error {
+		if err := s.users.PushCharacter(ctx, userID, char); err != nil {
+			return err
+		}
+		undo.add(func(ctx context.Context) error {
+			_, err := s.users.TakeCharacter(ctx, userID, char.ID)
+			return err
+		})
 
-// RemoveCharacterFromUser removes a character from user's collection
-func (s *UserService) RemoveCharacterFromUser(userID int64, charID string) error {
-	_, err := database.UserCollection.UpdateOne(
-		context.Background(),
-		bson.M{"id": userID},
-		bson.M{"$pull": bson.M{"characters": bson.M{"id": charID}}},
-	)
-	return err
+		return s.ledger.AppendEntries(ctx, characterEntry(userID, 1, char, src))
+	})
 }
 
 // HasCharacter checks if user has a character
 func (s *UserService) HasCharacter(userID int64, charID string) (bool, error) {
-	count, err := database.UserCollection.CountDocuments(
-		context.Background(),
-		bson.M{"id": userID, "characters.id": charID},
-	)
-	return count > 0, err
+	return s.users.HasCharacter(context.Background(), userID, charID)
 }
 
 // AddToFavorites adds a character to user's favorites
 func (s *UserService) AddToFavorites(userID int64, charID string) error {
-	_, err := database.UserCollection.UpdateOne(
-		context.Background(),
-		bson.M{"id": userID},
-		bson.M{"$addToSet": bson.M{"favorites": charID}},
-	)
-	return err
+	return s.users.AddFavorite(context.Background(), userID, charID)
 }
 
 // GetUserCharactersCount gets the count of user's characters
@@ -147,90 +138,41 @@ func (s *UserService) GetUserCharactersCount(userID int64) (int, error) {
 
 // GetTopUsersByBalance gets top users by balance
 func (s *UserService) GetTopUsersByBalance(limit int) ([]models.User, error) {
-	cursor, err := database.UserCollection.Find(
-		context.Background(),
-		bson.M{},
-		options.Find().SetSort(bson.M{"balance": -1}).SetLimit(int64(limit)),
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(context.Background())
-	
-	var users []models.User
-	if err = cursor.All(context.Background(), &users); err != nil {
-		return nil, err
-	}
-	return users, nil
+	return s.users.TopByBalance(context.Background(), limit)
 }
 
 // GetTopUsersByCharacters gets top users by character count
 func (s *UserService) GetTopUsersByCharacters(limit int) ([]models.User, error) {
-	pipeline := []bson.M{
-		{"$project": bson.M{
-			"id":         1,
-			"username":   1,
-			"first_name": 1,
-			"characters": 1,
-			"charCount":  bson.M{"$size": bson.M{"$ifNull": []interface{}{"$characters", []interface{}{}}}},
-		}},
-		{"$sort": bson.M{"charCount": -1}},
-		{"$limit": limit},
-	}
-	
-	cursor, err := database.UserCollection.Aggregate(context.Background(), pipeline)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(context.Background())
-	
-	var users []models.User
-	if err = cursor.All(context.Background(), &users); err != nil {
-		return nil, err
-	}
-	return users, nil
+	return s.users.TopByCharacters(context.Background(), limit)
 }
 
 // UpdateLastSClaim updates user's last sclaim time
 func (s *UserService) UpdateLastSClaim(userID int64) error {
-	now := time.Now()
-	_, err := database.UserCollection.UpdateOne(
-		context.Background(),
-		bson.M{"id": userID},
-		bson.M{"$set": bson.M{"last_sclaim": now}},
-		options.Update().SetUpsert(true),
-	)
-	return err
+	return s.users.SetLastSClaim(context.Background(), userID, time.Now())
 }
 
 // UpdateLastClaim updates user's last claim time
 func (s *UserService) UpdateLastClaim(userID int64) error {
-	now := time.Now()
-	_, err := database.UserCollection.UpdateOne(
-		context.Background(),
-		bson.M{"id": userID},
-		bson.M{"$set": bson.M{"last_claim": now}},
-		options.Update().SetUpsert(true),
-	)
-	return err
+	return s.users.SetLastClaim(context.Background(), userID, time.Now())
 }
 
 // CanSClaim checks if user can use sclaim (24h cooldown)
 func (s *UserService) CanSClaim(userID int64) (bool, time.Duration, error) {
 	user, err := s.GetUserByID(userID)
 	if err != nil {
+		s.logLookupError(err, userID)
 		return true, 0, nil // New user can claim
 	}
-	
+
 	if user.LastSClaim == nil {
 		return true, 0, nil
 	}
-	
+
 	timeSince := time.Since(*user.LastSClaim)
 	if timeSince >= 24*time.Hour {
 		return true, 0, nil
 	}
-	
+
 	remaining := 24*time.Hour - timeSince
 	return false, remaining, nil
 }
@@ -239,22 +181,30 @@ func (s *UserService) CanSClaim(userID int64) (bool, time.Duration, error) {
 func (s *UserService) CanClaim(userID int64) (bool, time.Duration, error) {
 	user, err := s.GetUserByID(userID)
 	if err != nil {
+		s.logLookupError(err, userID)
 		return true, 0, nil // New user can claim
 	}
-	
+
 	if user.LastClaim == nil {
 		return true, 0, nil
 	}
-	
+
 	timeSince := time.Since(*user.LastClaim)
 	if timeSince >= 24*time.Hour {
 		return true, 0, nil
 	}
-	
+
 	remaining := 24*time.Hour - timeSince
 	return false, remaining, nil
 }
 
+// logLookupError logs a failed user lookup that is treated as a new user; a missing user is expected
+func (s *UserService) logLookupError(err error, userID int64) {
+	if !errors.Is(err, store.ErrNotFound) {
+		s.log.Error("failed to get user", zap.Int64("user_id", userID), zap.Error(err))
+	}
+}
+
 // GetShopData gets user's shop data
 func (s *UserService) GetShopData(userID int64) (*models.ShopData, error) {
 	user, err := s.GetUserByID(userID)
@@ -266,10 +216,5 @@ func (s *UserService) GetShopData(userID int64) (*models.ShopData, error) {
 
 // UpdateShopData updates user's shop data
 func (s *UserService) UpdateShopData(userID int64, shopData *models.ShopData) error {
-	_, err := database.UserCollection.UpdateOne(
-		context.Background(),
-		bson.M{"id": userID},
-		bson.M{"$set": bson.M{"shop_data": shopData}},
-	)
-	return err
+	return s.users.SetShopData(context.Background(), userID, shopData)
 }
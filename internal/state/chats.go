@@ -0,0 +1,169 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// maxMessageTimes bounds the message times kept per chat for measuring its message rate
+const maxMessageTimes = 200
+
+// Chats tracks per-chat message activity: who sent the last messages, how fast messages
+// arrive and when and which characters were spawned recently
+type Chats struct {
+	mu    sync.Mutex
+	chats map[int64]*chatActivity
+}
+
+type chatActivity struct {
+	lastUserID int64
+	streak     int
+	recent     []string
+
+	// messageTimes holds the most recent message times, oldest first
+	messageTimes []time.Time
+	lastSpawn    time.Time
+}
+
+// Activity is a snapshot of a chat's activity
+type Activity struct {
+	// LastMessage is when the last message arrived; zero if none did since the bot started
+	LastMessage time.Time
+	// LastSpawn is when a character was last spawned; zero if unknown
+	LastSpawn time.Time
+	// Rate is the number of messages per minute over the requested window
+	Rate float64
+}
+
+// NewChats creates an empty Chats
+func NewChats() *Chats {
+	return &Chats{chats: make(map[int64]*chatActivity)}
+}
+
+// activity returns the entry for chatID, creating it if needed. Caller must hold the lock.
+func (c *Chats) activity(chatID int64) *chatActivity {
+	activity, ok := c.chats[chatID]
+	if !ok {
+		activity = &chatActivity{}
+		c.chats[chatID] = activity
+	}
+	return activity
+}
+
+// RecordMessage counts a message from userID in chatID and reports whether it completed a
+// streak of every consecutive messages from the same user, starting a new streak if so
+func (c *Chats) RecordMessage(chatID, userID int64, every int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	activity := c.activity(chatID)
+	if activity.lastUserID == userID {
+		activity.streak++
+	} else {
+		activity.lastUserID = userID
+		activity.streak = 1
+	}
+
+	if activity.streak >= every {
+		activity.streak = 0
+		return true
+	}
+	return false
+}
+
+// RecordActivity notes that a message arrived in chatID at at
+func (c *Chats) RecordActivity(chatID int64, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	activity := c.activity(chatID)
+	activity.messageTimes = append(activity.messageTimes, at)
+	if len(activity.messageTimes) > maxMessageTimes {
+		activity.messageTimes = activity.messageTimes[len(activity.messageTimes)-maxMessageTimes:]
+	}
+}
+
+// Activity returns the activity of chatID at now, measuring its message rate over window.
+// Chats busy enough to fill the kept message times have their rate measured over the span
+// of those times instead.
+func (c *Chats) Activity(chatID int64, now time.Time, window time.Duration) Activity {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	activity, ok := c.chats[chatID]
+	if !ok {
+		return Activity{}
+	}
+
+	var a Activity
+	a.LastSpawn = activity.lastSpawn
+	times := activity.messageTimes
+	if len(times) == 0 {
+		return a
+	}
+	a.LastMessage = times[len(times)-1]
+
+	since := now.Add(-window)
+	span := window
+	if len(times) == maxMessageTimes && times[0].After(since) {
+		span = now.Sub(times[0])
+		if span < time.Minute {
+			span = time.Minute
+		}
+	}
+	count := 0
+	for _, t := range times {
+		if t.After(since) {
+			count++
+		}
+	}
+	a.Rate = float64(count) / span.Minutes()
+	return a
+}
+
+// SetLastSpawn records that a character was spawned in chatID at at
+func (c *Chats) SetLastSpawn(chatID int64, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.activity(chatID).lastSpawn = at
+}
+
+// AwaitingSpawn returns the chats that had a message since their last spawn
+func (c *Chats) AwaitingSpawn() []int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var chatIDs []int64
+	for chatID, activity := range c.chats {
+		times := activity.messageTimes
+		if len(times) > 0 && times[len(times)-1].After(activity.lastSpawn) {
+			chatIDs = append(chatIDs, chatID)
+		}
+	}
+	return chatIDs
+}
+
+// RecentSpawns returns the IDs of the characters spawned recently in chatID, oldest first
+func (c *Chats) RecentSpawns(chatID int64) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	activity, ok := c.chats[chatID]
+	if !ok {
+		return nil
+	}
+	return append([]string{}, activity.recent...)
+}
+
+// RememberSpawn records charID as spawned in chatID, keeping at most keep entries
+func (c *Chats) RememberSpawn(chatID int64, charID string, keep int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	activity := c.activity(chatID)
+	activity.recent = append(activity.recent, charID)
+	if len(activity.recent) > keep {
+		activity.recent = activity.recent[len(activity.recent)-keep:]
+	}
+}
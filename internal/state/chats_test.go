@@ -0,0 +1,78 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChatsRecordMessage(t *testing.T) {
+	c := NewChats()
+
+	// Every third message in a row from the same user completes a streak
+	var completed []bool
+	for _, userID := range []int64{1, 1, 1, 1, 1, 1} {
+		completed = append(completed, c.RecordMessage(10, userID, 3))
+	}
+	want := []bool{false, false, true, false, false, true}
+	if !reflect.DeepEqual(completed, want) {
+		t.Errorf("streaks = %v, want %v", completed, want)
+	}
+}
+
+func TestChatsRecordMessageStreakReset(t *testing.T) {
+	c := NewChats()
+
+	// Another user breaks the streak, which then starts over
+	c.RecordMessage(10, 1, 3)
+	c.RecordMessage(10, 1, 3)
+	if c.RecordMessage(10, 2, 3) {
+		t.Error("a message from another user completed the streak")
+	}
+	if c.RecordMessage(10, 1, 3) || c.RecordMessage(10, 1, 3) {
+		t.Error("the streak did not start over")
+	}
+	if !c.RecordMessage(10, 1, 3) {
+		t.Error("the third message of the new streak did not complete it")
+	}
+
+	// Chats keep their own streaks
+	c.RecordMessage(10, 1, 2)
+	if c.RecordMessage(20, 1, 2) {
+		t.Error("a message in another chat continued the streak")
+	}
+}
+
+func TestChatsActivity(t *testing.T) {
+	c := NewChats()
+	now := time.Now()
+	for i := 10; i > 0; i-- {
+		c.RecordActivity(1, now.Add(-time.Duration(i)*time.Minute))
+	}
+
+	a := c.Activity(1, now, 5*time.Minute)
+	if a.Rate != 4.0/5 {
+		t.Errorf("Rate = %v, want %v", a.Rate, 4.0/5)
+	}
+	if !a.LastMessage.Equal(now.Add(-time.Minute)) {
+		t.Errorf("LastMessage = %v, want %v", a.LastMessage, now.Add(-time.Minute))
+	}
+
+	if got := c.AwaitingSpawn(); !reflect.DeepEqual(got, []int64{1}) {
+		t.Errorf("AwaitingSpawn() = %v, want [1]", got)
+	}
+	c.SetLastSpawn(1, now)
+	if got := c.AwaitingSpawn(); len(got) != 0 {
+		t.Errorf("AwaitingSpawn() = %v after a spawn, want none", got)
+	}
+}
+
+func TestChatsRememberSpawn(t *testing.T) {
+	c := NewChats()
+	for _, id := range []string{"001", "002", "003"} {
+		c.RememberSpawn(1, id, 2)
+	}
+	if got := c.RecentSpawns(1); !reflect.DeepEqual(got, []string{"002", "003"}) {
+		t.Errorf("RecentSpawns() = %v, want [002 003]", got)
+	}
+}
@@ -0,0 +1,62 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// Cooldowns tracks, per user, the time until which an action is not allowed again
+type Cooldowns struct {
+	mu    sync.Mutex
+	until map[int64]time.Time
+}
+
+// NewCooldowns creates an empty Cooldowns
+func NewCooldowns() *Cooldowns {
+	return &Cooldowns{until: make(map[int64]time.Time)}
+}
+
+// Remaining returns how long userID still has to wait, or zero if the action is allowed
+func (c *Cooldowns) Remaining(userID int64) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	until, ok := c.until[userID]
+	if !ok {
+		return 0
+	}
+	remaining := time.Until(until)
+	if remaining <= 0 {
+		delete(c.until, userID)
+		return 0
+	}
+	return remaining
+}
+
+// Start puts userID on cooldown for d
+func (c *Cooldowns) Start(userID int64, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.until[userID] = time.Now().Add(d)
+}
+
+// Clear lifts userID's cooldown
+func (c *Cooldowns) Clear(userID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.until, userID)
+}
+
+// Prune drops every cooldown that has run out by now
+func (c *Cooldowns) Prune(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for userID, until := range c.until {
+		if !now.Before(until) {
+			delete(c.until, userID)
+		}
+	}
+}
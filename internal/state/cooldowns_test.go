@@ -0,0 +1,52 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCooldownsRemaining(t *testing.T) {
+	c := NewCooldowns()
+	if got := c.Remaining(1); got != 0 {
+		t.Errorf("Remaining() = %v without a cooldown, want 0", got)
+	}
+
+	c.Start(1, time.Minute)
+	if got := c.Remaining(1); got <= 59*time.Second || got > time.Minute {
+		t.Errorf("Remaining() = %v, want about 1m", got)
+	}
+	if got := c.Remaining(2); got != 0 {
+		t.Errorf("Remaining() = %v for another user, want 0", got)
+	}
+
+	c.Clear(1)
+	if got := c.Remaining(1); got != 0 {
+		t.Errorf("Remaining() = %v after Clear, want 0", got)
+	}
+}
+
+func TestCooldownsRemainingExpired(t *testing.T) {
+	c := NewCooldowns()
+	c.Start(1, -time.Second)
+	if got := c.Remaining(1); got != 0 {
+		t.Errorf("Remaining() = %v for a cooldown that ran out, want 0", got)
+	}
+	if _, ok := c.until[1]; ok {
+		t.Error("a cooldown that ran out was kept")
+	}
+}
+
+func TestCooldownsPrune(t *testing.T) {
+	c := NewCooldowns()
+	c.Start(1, time.Minute)
+	c.Start(2, time.Hour)
+	c.Start(3, -time.Second)
+
+	c.Prune(time.Now().Add(2 * time.Minute))
+	if len(c.until) != 1 {
+		t.Fatalf("%d cooldowns left, want 1", len(c.until))
+	}
+	if _, ok := c.until[2]; !ok {
+		t.Error("the cooldown still running was pruned")
+	}
+}
@@ -0,0 +1,46 @@
+// Package state holds the bot's in-process runtime state behind proper synchronization.
+// Update handlers run in their own goroutines, so nothing here may be a bare map.
+package state
+
+import "sync"
+
+// ChatLocks serializes work per chat. Locks are created on demand and dropped once nobody
+// holds or waits for them, so the set does not grow with every chat the bot has seen.
+type ChatLocks struct {
+	mu    sync.Mutex
+	locks map[int64]*chatLock
+}
+
+type chatLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewChatLocks creates an empty ChatLocks
+func NewChatLocks() *ChatLocks {
+	return &ChatLocks{locks: make(map[int64]*chatLock)}
+}
+
+// Lock blocks until the caller holds chatID's lock and returns the function that releases it
+func (l *ChatLocks) Lock(chatID int64) (unlock func()) {
+	l.mu.Lock()
+	lock, ok := l.locks[chatID]
+	if !ok {
+		lock = &chatLock{}
+		l.locks[chatID] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+
+		l.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, chatID)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,94 @@
+package state
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// held returns the number of chats with a lock entry
+func (l *ChatLocks) held() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.locks)
+}
+
+func TestChatLocksSerializeChat(t *testing.T) {
+	locks := NewChatLocks()
+
+	var wg sync.WaitGroup
+	counter := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := locks.Lock(1)
+			defer unlock()
+			// Unsynchronized apart from the chat lock, so the race detector catches overlap
+			n := counter
+			time.Sleep(time.Microsecond)
+			counter = n + 1
+		}()
+	}
+	wg.Wait()
+
+	if counter != 50 {
+		t.Errorf("counter = %d, want 50", counter)
+	}
+	if n := locks.held(); n != 0 {
+		t.Errorf("%d locks left after every holder released, want 0", n)
+	}
+}
+
+func TestChatLocksRefcount(t *testing.T) {
+	locks := NewChatLocks()
+
+	unlockA := locks.Lock(1)
+	unlockB := locks.Lock(2)
+	if n := locks.held(); n != 2 {
+		t.Fatalf("held() = %d, want 2", n)
+	}
+
+	// A waiter keeps the entry alive after the holder releases it
+	acquired := make(chan func())
+	go func() { acquired <- locks.Lock(1) }()
+	for {
+		locks.mu.Lock()
+		refs := locks.locks[1].refs
+		locks.mu.Unlock()
+		if refs == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	unlockA()
+	if n := locks.held(); n != 2 {
+		t.Errorf("held() = %d after release with a waiter, want 2", n)
+	}
+
+	(<-acquired)()
+	if n := locks.held(); n != 1 {
+		t.Errorf("held() = %d after the waiter released, want 1", n)
+	}
+	unlockB()
+	if n := locks.held(); n != 0 {
+		t.Errorf("held() = %d after every release, want 0", n)
+	}
+}
+
+func TestChatLocksIndependentChats(t *testing.T) {
+	locks := NewChatLocks()
+	unlock := locks.Lock(1)
+	defer unlock()
+
+	done := make(chan struct{})
+	go func() {
+		locks.Lock(2)()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("locking another chat blocked on a held lock")
+	}
+}
@@ -0,0 +1,256 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"senpai-waifu-bot/internal/models"
+)
+
+// NewCachedStore wraps st so that the lookups made for every spawn and message are served from
+// memory for up to ttl: rarity settings, locked characters, spawn settings, sort preferences
+// and characters that exist. Writes made through the returned Store invalidate the entries they change,
+// so only changes made by another process, such as a second bot instance or a migration, can
+// go unseen for up to ttl. A ttl of zero returns st unchanged.
+func NewCachedStore(st *Store, ttl time.Duration) *Store {
+	if ttl <= 0 {
+		return st
+	}
+
+	c := &storeCache{entries: cache.New(ttl, 2*ttl)}
+	cached := *st
+	cached.Users = &cachedUserStore{UserStore: st.Users, c: c}
+	cached.Characters = &cachedCharacterStore{CharacterStore: st.Characters, c: c}
+	cached.Groups = &cachedGroupStore{GroupStore: st.Groups, c: c}
+	cached.Rarity = &cachedRarityStore{RarityStore: st.Rarity, c: c}
+	return &cached
+}
+
+// storeCache holds the cached lookups of every wrapped store. Entries hold copies that callers
+// never see; a hit returns a fresh copy, so a caller modifying its result cannot corrupt the cache.
+type storeCache struct {
+	entries *cache.Cache
+	// generation counts invalidations, so that a lookup that raced with a write does not store
+	// the value it read from before the write
+	generation atomic.Uint64
+}
+
+// cacheEntry is a cached lookup result; notFound records that the lookup returned ErrNotFound
+type cacheEntry[T any] struct {
+	value    T
+	notFound bool
+}
+
+// invalidate drops the entries under keys
+func (c *storeCache) invalidate(keys ...string) {
+	c.generation.Add(1)
+	for _, key := range keys {
+		c.entries.Delete(key)
+	}
+}
+
+// cachedLookup returns the entry under key, calling load on a miss. Found values are cached,
+// and so is ErrNotFound when cacheNotFound is set; other errors are returned without caching.
+// clone copies a value so that the cache and its callers never share one.
+func cachedLookup[T any](c *storeCache, key string, cacheNotFound bool, clone func(T) T, load func() (T, error)) (T, error) {
+	if v, ok := c.entries.Get(key); ok {
+		entry := v.(cacheEntry[T])
+		if entry.notFound {
+			var zero T
+			return zero, ErrNotFound
+		}
+		return clone(entry.value), nil
+	}
+
+	generation := c.generation.Load()
+	value, err := load()
+	var entry cacheEntry[T]
+	switch {
+	case err == nil:
+		entry.value = clone(value)
+	case errors.Is(err, ErrNotFound) && cacheNotFound:
+		entry.notFound = true
+	default:
+		return value, err
+	}
+	if c.generation.Load() == generation {
+		c.entries.SetDefault(key, entry)
+	}
+	return value, err
+}
+
+func idKey(prefix string, id int64) string {
+	return prefix + ":" + strconv.FormatInt(id, 10)
+}
+
+// lockedKey is the cache key of the locked character list
+const lockedKey = "locked"
+
+type cachedRarityStore struct {
+	RarityStore
+	c *storeCache
+}
+
+func (s *cachedRarityStore) GetRaritySettings(ctx context.Context, chatID int64) (*models.RaritySettings, error) {
+	return cachedLookup(s.c, idKey("rarity", chatID), true, copyRaritySettings, func() (*models.RaritySettings, error) {
+		return s.RarityStore.GetRaritySettings(ctx, chatID)
+	})
+}
+
+func (s *cachedRarityStore) InsertRaritySettings(ctx context.Context, settings *models.RaritySettings) error {
+	defer s.c.invalidate(idKey("rarity", settings.ChatID))
+	return s.RarityStore.InsertRaritySettings(ctx, settings)
+}
+
+func (s *cachedRarityStore) EnableRarity(ctx context.Context, chatID int64, rarity int) error {
+	defer s.c.invalidate(idKey("rarity", chatID))
+	return s.RarityStore.EnableRarity(ctx, chatID, rarity)
+}
+
+func (s *cachedRarityStore) DisableRarity(ctx context.Context, chatID int64, rarity int) error {
+	defer s.c.invalidate(idKey("rarity", chatID))
+	return s.RarityStore.DisableRarity(ctx, chatID, rarity)
+}
+
+func (s *cachedRarityStore) SetRarityWeight(ctx context.Context, chatID int64, rarity, weight int) error {
+	defer s.c.invalidate(idKey("rarity", chatID))
+	return s.RarityStore.SetRarityWeight(ctx, chatID, rarity, weight)
+}
+
+func (s *cachedRarityStore) ResetRarityWeight(ctx context.Context, chatID int64, rarity int) error {
+	defer s.c.invalidate(idKey("rarity", chatID))
+	return s.RarityStore.ResetRarityWeight(ctx, chatID, rarity)
+}
+
+func (s *cachedRarityStore) LockCharacter(ctx context.Context, lock *models.LockedCharacter) error {
+	defer s.c.invalidate(lockedKey)
+	return s.RarityStore.LockCharacter(ctx, lock)
+}
+
+func (s *cachedRarityStore) UnlockCharacter(ctx context.Context, charID string) error {
+	defer s.c.invalidate(lockedKey)
+	return s.RarityStore.UnlockCharacter(ctx, charID)
+}
+
+func (s *cachedRarityStore) LockedCharacters(ctx context.Context) ([]models.LockedCharacter, error) {
+	return cachedLookup(s.c, lockedKey, true, copyLockedCharacters, func() ([]models.LockedCharacter, error) {
+		return s.RarityStore.LockedCharacters(ctx)
+	})
+}
+
+func (s *cachedRarityStore) IsCharacterLocked(ctx context.Context, charID string) (bool, error) {
+	locked, err := s.LockedCharacters(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, lock := range locked {
+		if lock.CharacterID == charID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type cachedGroupStore struct {
+	GroupStore
+	c *storeCache
+}
+
+func (s *cachedGroupStore) GetMessageFrequency(ctx context.Context, chatID int64) (int, error) {
+	return cachedLookup(s.c, idKey("frequency", chatID), true, copyValue[int], func() (int, error) {
+		return s.GroupStore.GetMessageFrequency(ctx, chatID)
+	})
+}
+
+func (s *cachedGroupStore) SetMessageFrequency(ctx context.Context, chatID int64, frequency int) error {
+	defer s.c.invalidate(idKey("frequency", chatID), idKey("spawn", chatID))
+	return s.GroupStore.SetMessageFrequency(ctx, chatID, frequency)
+}
+
+func (s *cachedGroupStore) GetSpawnSettings(ctx context.Context, chatID int64) (*models.SpawnSettings, error) {
+	return cachedLookup(s.c, idKey("spawn", chatID), true, copySpawnSettings, func() (*models.SpawnSettings, error) {
+		return s.GroupStore.GetSpawnSettings(ctx, chatID)
+	})
+}
+
+func (s *cachedGroupStore) SetSpawnSettings(ctx context.Context, settings *models.SpawnSettings) error {
+	defer s.c.invalidate(idKey("frequency", settings.ChatID), idKey("spawn", settings.ChatID))
+	return s.GroupStore.SetSpawnSettings(ctx, settings)
+}
+
+type cachedUserStore struct {
+	UserStore
+	c *storeCache
+}
+
+func (s *cachedUserStore) GetSortPreference(ctx context.Context, userID int64) (*models.SortPreference, error) {
+	return cachedLookup(s.c, idKey("sort", userID), true, copySortPreference, func() (*models.SortPreference, error) {
+		return s.UserStore.GetSortPreference(ctx, userID)
+	})
+}
+
+func (s *cachedUserStore) SetSortPreference(ctx context.Context, userID int64, rarityFilter *int) error {
+	defer s.c.invalidate(idKey("sort", userID))
+	return s.UserStore.SetSortPreference(ctx, userID, rarityFilter)
+}
+
+type cachedCharacterStore struct {
+	CharacterStore
+	c *storeCache
+}
+
+// GetCharacter does not cache misses, so a character inserted by another process is seen at
+// once and NextCharacterID never hands out an ID that was taken since it was last probed
+func (s *cachedCharacterStore) GetCharacter(ctx context.Context, charID string) (*models.Character, error) {
+	return cachedLookup(s.c, "character:"+charID, false, copyCharacter, func() (*models.Character, error) {
+		return s.CharacterStore.GetCharacter(ctx, charID)
+	})
+}
+
+func (s *cachedCharacterStore) InsertCharacter(ctx context.Context, char *models.Character) error {
+	defer s.c.invalidate("character:" + char.ID)
+	return s.CharacterStore.InsertCharacter(ctx, char)
+}
+
+func (s *cachedCharacterStore) UpdateCharacter(ctx context.Context, charID string, update CharacterUpdate) error {
+	defer s.c.invalidate("character:" + charID)
+	return s.CharacterStore.UpdateCharacter(ctx, charID, update)
+}
+
+func (s *cachedCharacterStore) DeleteCharacter(ctx context.Context, charID string) error {
+	defer s.c.invalidate("character:" + charID)
+	return s.CharacterStore.DeleteCharacter(ctx, charID)
+}
+
+func copyValue[T any](v T) T {
+	return v
+}
+
+func copyRaritySettings(rs *models.RaritySettings) *models.RaritySettings {
+	c := *rs
+	c.DisabledRarities = append([]int{}, rs.DisabledRarities...)
+	if rs.Weights != nil {
+		c.Weights = make(map[int]int, len(rs.Weights))
+		for rarity, weight := range rs.Weights {
+			c.Weights[rarity] = weight
+		}
+	}
+	return &c
+}
+
+func copyLockedCharacters(locked []models.LockedCharacter) []models.LockedCharacter {
+	return append([]models.LockedCharacter{}, locked...)
+}
+
+func copySortPreference(sp *models.SortPreference) *models.SortPreference {
+	c := *sp
+	if sp.RarityFilter != nil {
+		filter := *sp.RarityFilter
+		c.RarityFilter = &filter
+	}
+	return &c
+}
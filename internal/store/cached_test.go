@@ -0,0 +1,317 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"senpai-waifu-bot/internal/models"
+)
+
+// newCachedTestStore returns a cached store and the memory store behind it; writes to the
+// memory store stand in for another process sharing the database
+func newCachedTestStore() (cached, backing *Store) {
+	backing = NewMemoryStore()
+	return NewCachedStore(backing, time.Hour), backing
+}
+
+func TestCachedCharacterMissNotCached(t *testing.T) {
+	ctx := context.Background()
+	cached, backing := newCachedTestStore()
+
+	if _, err := cached.Characters.GetCharacter(ctx, "001"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetCharacter() of a missing character: error = %v, want %v", err, ErrNotFound)
+	}
+	if err := backing.Characters.InsertCharacter(ctx, &models.Character{ID: "001", Name: "Shinobu Kochou"}); err != nil {
+		t.Fatal(err)
+	}
+	char, err := cached.Characters.GetCharacter(ctx, "001")
+	if err != nil {
+		t.Fatalf("GetCharacter() after another process inserted it: error = %v", err)
+	}
+	if char.Name != "Shinobu Kochou" {
+		t.Errorf("GetCharacter() = %+v", char)
+	}
+}
+
+// describe formats the result of a lookup for comparison, with misses as "not found"
+func describe(value any, err error) string {
+	if errors.Is(err, ErrNotFound) {
+		return "not found"
+	}
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return fmt.Sprint(value)
+}
+
+func TestCachedWritesInvalidate(t *testing.T) {
+	const chatID, userID = -100, 1
+	rarity := 3
+	type step struct {
+		name  string
+		write func(ctx context.Context, st *Store) error
+		want  string
+	}
+	tests := []struct {
+		name  string
+		read  func(ctx context.Context, st *Store) string
+		prime string
+		steps []step
+	}{
+		{
+			name: "rarity settings",
+			read: func(ctx context.Context, st *Store) string {
+				settings, err := st.Rarity.GetRaritySettings(ctx, chatID)
+				if err != nil {
+					return describe(nil, err)
+				}
+				return describe(fmt.Sprint(settings.DisabledRarities, settings.Weights), nil)
+			},
+			prime: "not found",
+			steps: []step{
+				{"InsertRaritySettings", func(ctx context.Context, st *Store) error {
+					return st.Rarity.InsertRaritySettings(ctx, &models.RaritySettings{ChatID: chatID, DisabledRarities: []int{}})
+				}, "[] map[]"},
+				{"DisableRarity", func(ctx context.Context, st *Store) error {
+					return st.Rarity.DisableRarity(ctx, chatID, 3)
+				}, "[3] map[]"},
+				{"EnableRarity", func(ctx context.Context, st *Store) error {
+					return st.Rarity.EnableRarity(ctx, chatID, 3)
+				}, "[] map[]"},
+				{"SetRarityWeight", func(ctx context.Context, st *Store) error {
+					return st.Rarity.SetRarityWeight(ctx, chatID, 2, 5)
+				}, "[] map[2:5]"},
+				{"ResetRarityWeight", func(ctx context.Context, st *Store) error {
+					return st.Rarity.ResetRarityWeight(ctx, chatID, 2)
+				}, "[] map[]"},
+			},
+		},
+		{
+			name: "locked characters",
+			read: func(ctx context.Context, st *Store) string {
+				locked, err := st.Rarity.LockedCharacters(ctx)
+				ids := []string{}
+				for _, lock := range locked {
+					ids = append(ids, lock.CharacterID)
+				}
+				isLocked, _ := st.Rarity.IsCharacterLocked(ctx, "001")
+				return describe(fmt.Sprint(ids, isLocked), err)
+			},
+			prime: "[] false",
+			steps: []step{
+				{"LockCharacter", func(ctx context.Context, st *Store) error {
+					return st.Rarity.LockCharacter(ctx, &models.LockedCharacter{CharacterID: "001"})
+				}, "[001] true"},
+				{"UnlockCharacter", func(ctx context.Context, st *Store) error {
+					return st.Rarity.UnlockCharacter(ctx, "001")
+				}, "[] false"},
+			},
+		},
+		{
+			name: "frequency and spawn settings",
+			read: func(ctx context.Context, st *Store) string {
+				frequency, err := st.Groups.GetMessageFrequency(ctx, chatID)
+				got := describe(frequency, err)
+				settings, err := st.Groups.GetSpawnSettings(ctx, chatID)
+				if err != nil {
+					return got + " " + describe(nil, err)
+				}
+				return got + " " + describe(fmt.Sprint(settings.MessageFrequency, settings.Interval), nil)
+			},
+			prime: "not found not found",
+			steps: []step{
+				{"SetMessageFrequency", func(ctx context.Context, st *Store) error {
+					return st.Groups.SetMessageFrequency(ctx, chatID, 50)
+				}, "50 50 0s"},
+				{"SetSpawnSettings", func(ctx context.Context, st *Store) error {
+					return st.Groups.SetSpawnSettings(ctx, &models.SpawnSettings{ChatID: chatID, MessageFrequency: 70, Interval: time.Minute})
+				}, "70 70 1m0s"},
+			},
+		},
+		{
+			name: "sort preferences",
+			read: func(ctx context.Context, st *Store) string {
+				pref, err := st.Users.GetSortPreference(ctx, userID)
+				if err != nil {
+					return describe(nil, err)
+				}
+				if pref.RarityFilter == nil {
+					return "all"
+				}
+				return describe(*pref.RarityFilter, nil)
+			},
+			prime: "not found",
+			steps: []step{
+				{"SetSortPreference", func(ctx context.Context, st *Store) error {
+					return st.Users.SetSortPreference(ctx, userID, &rarity)
+				}, "3"},
+				{"SetSortPreference to all", func(ctx context.Context, st *Store) error {
+					return st.Users.SetSortPreference(ctx, userID, nil)
+				}, "all"},
+			},
+		},
+		{
+			name: "characters",
+			read: func(ctx context.Context, st *Store) string {
+				char, err := st.Characters.GetCharacter(ctx, "001")
+				if err != nil {
+					return describe(nil, err)
+				}
+				return describe(char.Name, nil)
+			},
+			prime: "not found",
+			steps: []step{
+				{"InsertCharacter", func(ctx context.Context, st *Store) error {
+					return st.Characters.InsertCharacter(ctx, &models.Character{ID: "001", Name: "Shinobu Kochou"})
+				}, "Shinobu Kochou"},
+				{"UpdateCharacter", func(ctx context.Context, st *Store) error {
+					name := "Shinobu"
+					return st.Characters.UpdateCharacter(ctx, "001", CharacterUpdate{Name: &name})
+				}, "Shinobu"},
+				{"DeleteCharacter", func(ctx context.Context, st *Store) error {
+					return st.Characters.DeleteCharacter(ctx, "001")
+				}, "not found"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cached, _ := newCachedTestStore()
+			if got := tt.read(ctx, cached); got != tt.prime {
+				t.Fatalf("before any write: got %q, want %q", got, tt.prime)
+			}
+			for _, step := range tt.steps {
+				// Read once more so that the entry the write must invalidate is cached
+				tt.read(ctx, cached)
+				if err := step.write(ctx, cached); err != nil {
+					t.Fatalf("%s() error = %v", step.name, err)
+				}
+				if got := tt.read(ctx, cached); got != step.want {
+					t.Errorf("after %s(): got %q, want %q", step.name, got, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCachedHitsAreCopies(t *testing.T) {
+	ctx := context.Background()
+	cached, _ := newCachedTestStore()
+	rarity := 3
+	if err := cached.Rarity.InsertRaritySettings(ctx, &models.RaritySettings{ChatID: -100, DisabledRarities: []int{1}, Weights: map[int]int{2: 5}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cached.Rarity.LockCharacter(ctx, &models.LockedCharacter{CharacterID: "001"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cached.Groups.SetSpawnSettings(ctx, &models.SpawnSettings{ChatID: -100, QuietHours: &models.QuietHours{Start: 60, End: 480}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cached.Users.SetSortPreference(ctx, 1, &rarity); err != nil {
+		t.Fatal(err)
+	}
+	if err := cached.Characters.InsertCharacter(ctx, &models.Character{ID: "001", Name: "Shinobu Kochou", Aliases: []string{"shinobu"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every lookup runs three times: a miss that fills the cache, a hit whose result is
+	// modified, and a hit that must not see the modification
+	lookups := []struct {
+		name   string
+		get    func() (any, error)
+		modify func(v any)
+	}{
+		{"GetRaritySettings", func() (any, error) { return cached.Rarity.GetRaritySettings(ctx, -100) }, func(v any) {
+			settings := v.(*models.RaritySettings)
+			settings.DisabledRarities[0] = 4
+			settings.Weights[2] = 9
+		}},
+		{"LockedCharacters", func() (any, error) { return cached.Rarity.LockedCharacters(ctx) }, func(v any) {
+			v.([]models.LockedCharacter)[0].CharacterID = "002"
+		}},
+		{"GetSpawnSettings", func() (any, error) { return cached.Groups.GetSpawnSettings(ctx, -100) }, func(v any) {
+			v.(*models.SpawnSettings).QuietHours.Start = 0
+		}},
+		{"GetSortPreference", func() (any, error) { return cached.Users.GetSortPreference(ctx, 1) }, func(v any) {
+			*v.(*models.SortPreference).RarityFilter = 5
+		}},
+		{"GetCharacter", func() (any, error) { return cached.Characters.GetCharacter(ctx, "001") }, func(v any) {
+			char := v.(*models.Character)
+			char.Name = "Hu Tao"
+			char.Aliases[0] = "hutao"
+		}},
+	}
+	for _, lookup := range lookups {
+		want, err := lookup.get()
+		if err != nil {
+			t.Fatalf("%s() error = %v", lookup.name, err)
+		}
+		hit, err := lookup.get()
+		if err != nil {
+			t.Fatalf("%s() error = %v", lookup.name, err)
+		}
+		lookup.modify(hit)
+		got, err := lookup.get()
+		if err != nil {
+			t.Fatalf("%s() error = %v", lookup.name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s() after modifying a hit = %+v, want %+v", lookup.name, got, want)
+		}
+	}
+}
+
+// racingRarityStore runs write between reading rarity settings and returning them, the way a
+// write from another goroutine can land while a lookup waits on the database
+type racingRarityStore struct {
+	RarityStore
+	write func()
+}
+
+func (s *racingRarityStore) GetRaritySettings(ctx context.Context, chatID int64) (*models.RaritySettings, error) {
+	settings, err := s.RarityStore.GetRaritySettings(ctx, chatID)
+	if s.write != nil {
+		write := s.write
+		s.write = nil
+		write()
+	}
+	return settings, err
+}
+
+func TestCachedLookupRacingWrite(t *testing.T) {
+	ctx := context.Background()
+	backing := NewMemoryStore()
+	racing := &racingRarityStore{RarityStore: backing.Rarity}
+	hooked := *backing
+	hooked.Rarity = racing
+	cached := NewCachedStore(&hooked, time.Hour)
+
+	if err := cached.Rarity.InsertRaritySettings(ctx, &models.RaritySettings{ChatID: -100, DisabledRarities: []int{}}); err != nil {
+		t.Fatal(err)
+	}
+	racing.write = func() {
+		if err := cached.Rarity.DisableRarity(ctx, -100, 3); err != nil {
+			t.Error(err)
+		}
+	}
+	stale, err := cached.Rarity.GetRaritySettings(ctx, -100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale.DisabledRarities) != 0 {
+		t.Fatalf("racing lookup = %v, want the settings from before the write", stale.DisabledRarities)
+	}
+
+	settings, err := cached.Rarity.GetRaritySettings(ctx, -100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(settings.DisabledRarities, []int{3}) {
+		t.Errorf("lookup after a racing write = %v, want [3]", settings.DisabledRarities)
+	}
+}
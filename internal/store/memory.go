@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"senpai-waifu-bot/internal/models"
+)
+
+// memoryDB holds every in-memory collection behind a single lock so that
+// each store method behaves atomically, like a single-document MongoDB write
+type memoryDB struct {
+	mu  sync.RWMutex
+	rng *rand.Rand
+
+	users      map[int64]*models.User
+	sortPrefs  map[int64]*models.SortPreference
+	characters map[string]*models.Character
+
+	groupUserTotals   map[groupUserKey]*models.GroupUserTotal
+	topGlobalGroups   map[int64]*models.TopGlobalGroup
+	spawnSettings     map[int64]*models.SpawnSettings
+	chatLocales       map[int64]*models.ChatLocale
+	pmUsers           map[int64]*models.PMUser
+	dailyUserGuesses  map[dailyKey]*models.DailyUserGuess
+	dailyGroupGuesses map[dailyKey]*models.DailyGroupGuess
+
+	redeemCodes map[string]*models.RedeemCode
+	claimCodes  map[string]*models.ClaimCode
+
+	raritySettings map[int64]*models.RaritySettings
+	locked         map[string]*models.LockedCharacter
+
+	ledger []models.LedgerEntry
+
+	spawns          map[int64]*models.ActiveSpawn
+	messageCounters map[int64]int
+	payments        map[string]*models.PendingPayment
+	trades          map[string]*models.PendingTrade
+	gifts           map[string]*models.PendingGift
+
+	roles    []models.RoleGrant
+	audit    []models.AuditEntry
+	counters map[string]int64
+}
+
+type groupUserKey struct {
+	UserID  int64
+	GroupID int64
+}
+
+type dailyKey struct {
+	Date string
+	ID   int64
+}
+
+// NewMemoryStore creates a fully functional Store that keeps everything in process memory.
+// It is intended for tests and local development without MongoDB.
+func NewMemoryStore() *Store {
+	return NewSeededMemoryStore(time.Now().UnixNano())
+}
+
+// NewSeededMemoryStore creates a memory Store whose random samples are drawn from seed, so
+// that tests get the same characters on every run
+func NewSeededMemoryStore(seed int64) *Store {
+	db := &memoryDB{
+		rng:               rand.New(rand.NewSource(seed)),
+		users:             make(map[int64]*models.User),
+		sortPrefs:         make(map[int64]*models.SortPreference),
+		characters:        make(map[string]*models.Character),
+		groupUserTotals:   make(map[groupUserKey]*models.GroupUserTotal),
+		topGlobalGroups:   make(map[int64]*models.TopGlobalGroup),
+		spawnSettings:     make(map[int64]*models.SpawnSettings),
+		chatLocales:       make(map[int64]*models.ChatLocale),
+		pmUsers:           make(map[int64]*models.PMUser),
+		dailyUserGuesses:  make(map[dailyKey]*models.DailyUserGuess),
+		dailyGroupGuesses: make(map[dailyKey]*models.DailyGroupGuess),
+		redeemCodes:       make(map[string]*models.RedeemCode),
+		claimCodes:        make(map[string]*models.ClaimCode),
+		raritySettings:    make(map[int64]*models.RaritySettings),
+		locked:            make(map[string]*models.LockedCharacter),
+		spawns:            make(map[int64]*models.ActiveSpawn),
+		messageCounters:   make(map[int64]int),
+		payments:          make(map[string]*models.PendingPayment),
+		trades:            make(map[string]*models.PendingTrade),
+		gifts:             make(map[string]*models.PendingGift),
+		counters:          make(map[string]int64),
+	}
+
+	return &Store{
+		Users:      &memoryUserStore{db: db},
+		Characters: &memoryCharacterStore{db: db},
+		Groups:     &memoryGroupStore{db: db},
+		Codes:      &memoryCodeStore{db: db},
+		Rarity:     &memoryRarityStore{db: db},
+		Ledger:     &memoryLedgerStore{db: db},
+		State:      &memoryStateStore{db: db},
+		Roles:      &memoryRoleStore{db: db},
+		Audit:      &memoryAuditStore{db: db},
+		Counters:   &memoryCounterStore{db: db},
+		Tx:         memoryTransactor{},
+	}
+}
+
+// memoryTransactor behaves like a standalone mongod: every call reports that transactions are
+// unavailable so callers exercise their non-transactional fallback
+type memoryTransactor struct{}
+
+func (memoryTransactor) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return ErrTransactionsUnsupported
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+func copyDuration(d *time.Duration) *time.Duration {
+	if d == nil {
+		return nil
+	}
+	c := *d
+	return &c
+}
+
+func copyUser(u *models.User) *models.User {
+	c := *u
+	c.Characters = append([]models.UserCharacter{}, u.Characters...)
+	c.Favorites = append([]string{}, u.Favorites...)
+	c.LastSClaim = copyTime(u.LastSClaim)
+	c.LastClaim = copyTime(u.LastClaim)
+	if u.ShopData != nil {
+		shop := *u.ShopData
+		shop.Characters = append([]models.ShopCharacter{}, u.ShopData.Characters...)
+		c.ShopData = &shop
+	}
+	return &c
+}
+
+func copyCharacter(ch *models.Character) *models.Character {
+	c := *ch
+	c.Aliases = append([]string(nil), ch.Aliases...)
+	c.CreatedAt = copyTime(ch.CreatedAt)
+	c.UpdatedAt = copyTime(ch.UpdatedAt)
+	return &c
+}
+
+func copySpawnSettings(ss *models.SpawnSettings) *models.SpawnSettings {
+	c := *ss
+	c.MinGap = copyDuration(ss.MinGap)
+	c.MaxGap = copyDuration(ss.MaxGap)
+	if ss.QuietHours != nil {
+		quiet := *ss.QuietHours
+		c.QuietHours = &quiet
+	}
+	return &c
+}
+
+func copyRedeemCode(rc *models.RedeemCode) *models.RedeemCode {
+	c := *rc
+	c.UsedBy = append([]int64{}, rc.UsedBy...)
+	return &c
+}
+
+func copyClaimCode(cc *models.ClaimCode) *models.ClaimCode {
+	c := *cc
+	c.RedeemedAt = copyTime(cc.RedeemedAt)
+	return &c
+}
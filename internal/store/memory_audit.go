@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+
+	"senpai-waifu-bot/internal/models"
+)
+
+type memoryAuditStore struct {
+	db *memoryDB
+}
+
+func (s *memoryAuditStore) AppendAudit(ctx context.Context, entry *models.AuditEntry) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	c := *entry
+	c.Args = append([]string{}, entry.Args...)
+	s.db.audit = append(s.db.audit, c)
+	return nil
+}
+
+func (s *memoryAuditStore) ListAudit(ctx context.Context, filter AuditFilter, limit int) ([]models.AuditEntry, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var entries []models.AuditEntry
+	// Entries are appended in order, so walking backwards yields newest first
+	for i := len(s.db.audit) - 1; i >= 0 && len(entries) < limit; i-- {
+		entry := s.db.audit[i]
+		if filter.ActorID != 0 && entry.ActorID != filter.ActorID ||
+			filter.TargetUserID != 0 && entry.TargetUserID != filter.TargetUserID ||
+			filter.CharacterID != "" && entry.CharacterID != filter.CharacterID {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
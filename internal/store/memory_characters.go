@@ -0,0 +1,190 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/utils"
+)
+
+type memoryCharacterStore struct {
+	db *memoryDB
+}
+
+func matchesCharacterFilter(char *models.Character, f CharacterFilter) bool {
+	if len(f.Rarities) > 0 && !utils.ContainsInt(f.Rarities, char.Rarity) {
+		return false
+	}
+	if utils.ContainsInt(f.ExcludedRarities, char.Rarity) {
+		return false
+	}
+	if utils.ContainsString(f.ExcludedIDs, char.ID) {
+		return false
+	}
+	return true
+}
+
+// filterCharacters returns copies of the matching characters ordered by ID. Caller must hold the lock.
+func (s *memoryCharacterStore) filterCharacters(match func(*models.Character) bool) []models.Character {
+	var chars []models.Character
+	for _, char := range s.db.characters {
+		if match(char) {
+			chars = append(chars, *copyCharacter(char))
+		}
+	}
+	sort.Slice(chars, func(i, j int) bool { return utils.LessCharacterID(chars[i].ID, chars[j].ID) })
+	return chars
+}
+
+func (s *memoryCharacterStore) GetCharacter(ctx context.Context, charID string) (*models.Character, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	char, ok := s.db.characters[charID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyCharacter(char), nil
+}
+
+func (s *memoryCharacterStore) FindCharacters(ctx context.Context, filter CharacterFilter) ([]models.Character, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return s.filterCharacters(func(c *models.Character) bool {
+		return matchesCharacterFilter(c, filter)
+	}), nil
+}
+
+func (s *memoryCharacterStore) SampleCharacters(ctx context.Context, filter CharacterFilter, count int) ([]models.Character, error) {
+	// The random source is not safe for concurrent use, so sampling takes the write lock
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	chars := s.filterCharacters(func(c *models.Character) bool {
+		return matchesCharacterFilter(c, filter)
+	})
+	s.db.rng.Shuffle(len(chars), func(i, j int) { chars[i], chars[j] = chars[j], chars[i] })
+	if len(chars) > count {
+		chars = chars[:count]
+	}
+	return chars, nil
+}
+
+func (s *memoryCharacterStore) SearchCharacters(ctx context.Context, query string) ([]models.Character, error) {
+	re, err := regexp.Compile("(?i)" + regexp.QuoteMeta(query))
+	if err != nil {
+		return nil, err
+	}
+
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return s.filterCharacters(func(c *models.Character) bool {
+		return re.MatchString(c.Name) || re.MatchString(c.Anime)
+	}), nil
+}
+
+func (s *memoryCharacterStore) CountByRarity(ctx context.Context, filter CharacterFilter) (map[int]int64, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	counts := make(map[int]int64)
+	for _, char := range s.db.characters {
+		if matchesCharacterFilter(char, filter) {
+			counts[char.Rarity]++
+		}
+	}
+	return counts, nil
+}
+
+func (s *memoryCharacterStore) CountCharacters(ctx context.Context) (int64, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return int64(len(s.db.characters)), nil
+}
+
+func (s *memoryCharacterStore) MaxNumericID(ctx context.Context) (int64, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var max int64
+	for id := range s.db.characters {
+		if n, err := strconv.ParseInt(id, 10, 64); err == nil && n > max {
+			max = n
+		}
+	}
+	return max, nil
+}
+
+func (s *memoryCharacterStore) AnimeCounts(ctx context.Context, animes []string) (map[string]int64, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	counts := make(map[string]int64)
+	for _, char := range s.db.characters {
+		if utils.ContainsString(animes, char.Anime) {
+			counts[char.Anime]++
+		}
+	}
+	return counts, nil
+}
+
+func (s *memoryCharacterStore) InsertCharacter(ctx context.Context, char *models.Character) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, exists := s.db.characters[char.ID]; exists {
+		return ErrDuplicate
+	}
+	s.db.characters[char.ID] = copyCharacter(char)
+	return nil
+}
+
+func (s *memoryCharacterStore) UpdateCharacter(ctx context.Context, charID string, update CharacterUpdate) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	char, ok := s.db.characters[charID]
+	if !ok {
+		return ErrNotFound
+	}
+	if update.PrevAliases != nil && !slices.Equal(char.Aliases, *update.PrevAliases) {
+		return ErrNotFound
+	}
+	if update.Name != nil {
+		char.Name = *update.Name
+	}
+	if update.Anime != nil {
+		char.Anime = *update.Anime
+	}
+	if update.Rarity != nil {
+		char.Rarity = *update.Rarity
+	}
+	if update.ImgURL != nil {
+		char.ImgURL = *update.ImgURL
+	}
+	if update.Aliases != nil {
+		char.Aliases = append([]string(nil), *update.Aliases...)
+	}
+	updatedAt := update.UpdatedAt
+	char.UpdatedAt = &updatedAt
+	char.UpdatedBy = update.UpdatedBy
+	return nil
+}
+
+func (s *memoryCharacterStore) DeleteCharacter(ctx context.Context, charID string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.characters[charID]; !ok {
+		return ErrNotFound
+	}
+	delete(s.db.characters, charID)
+	return nil
+}
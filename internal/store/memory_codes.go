@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/utils"
+)
+
+type memoryCodeStore struct {
+	db *memoryDB
+}
+
+func (s *memoryCodeStore) InsertRedeemCode(ctx context.Context, code *models.RedeemCode) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, exists := s.db.redeemCodes[code.Code]; exists {
+		return ErrDuplicate
+	}
+	s.db.redeemCodes[code.Code] = copyRedeemCode(code)
+	return nil
+}
+
+func (s *memoryCodeStore) GetRedeemCode(ctx context.Context, code string) (*models.RedeemCode, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	redeemCode, ok := s.db.redeemCodes[code]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyRedeemCode(redeemCode), nil
+}
+
+func (s *memoryCodeStore) UseRedeemCode(ctx context.Context, code string, userID int64) (*models.RedeemCode, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	redeemCode, ok := s.db.redeemCodes[code]
+	if !ok || !redeemCode.IsActive ||
+		utils.ContainsInt64(redeemCode.UsedBy, userID) ||
+		len(redeemCode.UsedBy) >= redeemCode.MaxUses {
+		return nil, ErrNotFound
+	}
+	redeemCode.UsedBy = append(redeemCode.UsedBy, userID)
+	return copyRedeemCode(redeemCode), nil
+}
+
+func (s *memoryCodeStore) DeactivateRedeemCode(ctx context.Context, code string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if redeemCode, ok := s.db.redeemCodes[code]; ok {
+		redeemCode.IsActive = false
+	}
+	return nil
+}
+
+func (s *memoryCodeStore) HasRedeemed(ctx context.Context, code string, userID int64) (bool, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	redeemCode, ok := s.db.redeemCodes[code]
+	if !ok {
+		return false, nil
+	}
+	return utils.ContainsInt64(redeemCode.UsedBy, userID), nil
+}
+
+func (s *memoryCodeStore) InsertClaimCode(ctx context.Context, code *models.ClaimCode) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, exists := s.db.claimCodes[code.Code]; exists {
+		return ErrDuplicate
+	}
+	s.db.claimCodes[code.Code] = copyClaimCode(code)
+	return nil
+}
+
+func (s *memoryCodeStore) GetClaimCode(ctx context.Context, code string) (*models.ClaimCode, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	claimCode, ok := s.db.claimCodes[code]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyClaimCode(claimCode), nil
+}
+
+func (s *memoryCodeStore) RedeemClaimCode(ctx context.Context, code string, userID int64, at time.Time) (*models.ClaimCode, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	claimCode, ok := s.db.claimCodes[code]
+	if !ok || claimCode.UserID != userID || claimCode.IsRedeemed {
+		return nil, ErrNotFound
+	}
+	claimCode.IsRedeemed = true
+	claimCode.RedeemedAt = &at
+	return copyClaimCode(claimCode), nil
+}
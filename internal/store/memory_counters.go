@@ -0,0 +1,44 @@
+package store
+
+import "context"
+
+type memoryCounterStore struct {
+	db *memoryDB
+}
+
+func (s *memoryCounterStore) NextSequence(ctx context.Context, name string) (int64, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.counters[name]++
+	return s.db.counters[name], nil
+}
+
+func (s *memoryCounterStore) GetSequence(ctx context.Context, name string) (int64, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	value, ok := s.db.counters[name]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *memoryCounterStore) SetSequence(ctx context.Context, name string, value int64) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.counters[name] = value
+	return nil
+}
+
+func (s *memoryCounterStore) RaiseSequence(ctx context.Context, name string, value int64) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if value > s.db.counters[name] {
+		s.db.counters[name] = value
+	}
+	return nil
+}
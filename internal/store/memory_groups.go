@@ -0,0 +1,247 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"senpai-waifu-bot/internal/models"
+)
+
+type memoryGroupStore struct {
+	db *memoryDB
+}
+
+func (s *memoryGroupStore) IncGroupUserTotal(ctx context.Context, userID, groupID int64, username, firstName string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	key := groupUserKey{UserID: userID, GroupID: groupID}
+	total, ok := s.db.groupUserTotals[key]
+	if !ok {
+		total = &models.GroupUserTotal{UserID: userID, GroupID: groupID}
+		s.db.groupUserTotals[key] = total
+	}
+	total.Username = username
+	total.FirstName = firstName
+	total.Count++
+	return nil
+}
+
+func (s *memoryGroupStore) IncTopGlobalGroup(ctx context.Context, groupID int64, groupName string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	group, ok := s.db.topGlobalGroups[groupID]
+	if !ok {
+		group = &models.TopGlobalGroup{GroupID: groupID}
+		s.db.topGlobalGroups[groupID] = group
+	}
+	group.GroupName = groupName
+	group.Count++
+	return nil
+}
+
+func (s *memoryGroupStore) TopGroups(ctx context.Context, limit int) ([]models.TopGlobalGroup, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	groups := make([]models.TopGlobalGroup, 0, len(s.db.topGlobalGroups))
+	for _, group := range s.db.topGlobalGroups {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].GroupID < groups[j].GroupID
+	})
+	if limit > 0 && len(groups) > limit {
+		groups = groups[:limit]
+	}
+	return groups, nil
+}
+
+func (s *memoryGroupStore) GroupUserTotals(ctx context.Context, groupID int64, limit int) ([]models.GroupUserTotal, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var totals []models.GroupUserTotal
+	for key, total := range s.db.groupUserTotals {
+		if key.GroupID == groupID {
+			totals = append(totals, *total)
+		}
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Count != totals[j].Count {
+			return totals[i].Count > totals[j].Count
+		}
+		return totals[i].UserID < totals[j].UserID
+	})
+	if limit > 0 && len(totals) > limit {
+		totals = totals[:limit]
+	}
+	return totals, nil
+}
+
+func (s *memoryGroupStore) GetMessageFrequency(ctx context.Context, chatID int64) (int, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	settings, ok := s.db.spawnSettings[chatID]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return settings.MessageFrequency, nil
+}
+
+func (s *memoryGroupStore) SetMessageFrequency(ctx context.Context, chatID int64, frequency int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	settings, ok := s.db.spawnSettings[chatID]
+	if !ok {
+		settings = &models.SpawnSettings{ChatID: chatID}
+		s.db.spawnSettings[chatID] = settings
+	}
+	settings.MessageFrequency = frequency
+	return nil
+}
+
+func (s *memoryGroupStore) GetSpawnSettings(ctx context.Context, chatID int64) (*models.SpawnSettings, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	settings, ok := s.db.spawnSettings[chatID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copySpawnSettings(settings), nil
+}
+
+func (s *memoryGroupStore) SetSpawnSettings(ctx context.Context, settings *models.SpawnSettings) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.spawnSettings[settings.ChatID] = copySpawnSettings(settings)
+	return nil
+}
+
+func (s *memoryGroupStore) GetChatLocale(ctx context.Context, chatID int64) (*models.ChatLocale, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	locale, ok := s.db.chatLocales[chatID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *locale
+	return &c, nil
+}
+
+func (s *memoryGroupStore) SetChatLocale(ctx context.Context, locale *models.ChatLocale) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	c := *locale
+	s.db.chatLocales[locale.ChatID] = &c
+	return nil
+}
+
+func (s *memoryGroupStore) UpsertPMUser(ctx context.Context, userID int64, username, firstName string, startedAt time.Time) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	user, ok := s.db.pmUsers[userID]
+	if !ok {
+		user = &models.PMUser{ID: userID, StartedAt: &startedAt}
+		s.db.pmUsers[userID] = user
+	}
+	user.Username = username
+	user.FirstName = firstName
+	return nil
+}
+
+func (s *memoryGroupStore) CountPMUsers(ctx context.Context) (int64, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return int64(len(s.db.pmUsers)), nil
+}
+
+func (s *memoryGroupStore) IncDailyUserGuess(ctx context.Context, date string, userID int64, username, firstName string, at time.Time) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	key := dailyKey{Date: date, ID: userID}
+	guess, ok := s.db.dailyUserGuesses[key]
+	if !ok {
+		guess = &models.DailyUserGuess{Date: date, UserID: userID}
+		s.db.dailyUserGuesses[key] = guess
+	}
+	guess.Username = username
+	guess.FirstName = firstName
+	guess.LastUpdated = at
+	guess.Count++
+	return nil
+}
+
+func (s *memoryGroupStore) IncDailyGroupGuess(ctx context.Context, date string, groupID int64, groupName string, at time.Time) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	key := dailyKey{Date: date, ID: groupID}
+	guess, ok := s.db.dailyGroupGuesses[key]
+	if !ok {
+		guess = &models.DailyGroupGuess{Date: date, GroupID: groupID}
+		s.db.dailyGroupGuesses[key] = guess
+	}
+	guess.GroupName = groupName
+	guess.LastUpdated = at
+	guess.Count++
+	return nil
+}
+
+func (s *memoryGroupStore) TopDailyUsers(ctx context.Context, date string, limit int) ([]models.DailyUserGuess, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var guesses []models.DailyUserGuess
+	for key, guess := range s.db.dailyUserGuesses {
+		if key.Date == date {
+			guesses = append(guesses, *guess)
+		}
+	}
+	sort.Slice(guesses, func(i, j int) bool {
+		if guesses[i].Count != guesses[j].Count {
+			return guesses[i].Count > guesses[j].Count
+		}
+		return guesses[i].UserID < guesses[j].UserID
+	})
+	if limit > 0 && len(guesses) > limit {
+		guesses = guesses[:limit]
+	}
+	return guesses, nil
+}
+
+func (s *memoryGroupStore) TopDailyGroups(ctx context.Context, date string, limit int) ([]models.DailyGroupGuess, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var guesses []models.DailyGroupGuess
+	for key, guess := range s.db.dailyGroupGuesses {
+		if key.Date == date {
+			guesses = append(guesses, *guess)
+		}
+	}
+	sort.Slice(guesses, func(i, j int) bool {
+		if guesses[i].Count != guesses[j].Count {
+			return guesses[i].Count > guesses[j].Count
+		}
+		return guesses[i].GroupID < guesses[j].GroupID
+	})
+	if limit > 0 && len(guesses) > limit {
+		guesses = guesses[:limit]
+	}
+	return guesses, nil
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+
+	"senpai-waifu-bot/internal/models"
+)
+
+type memoryLedgerStore struct {
+	db *memoryDB
+}
+
+func (s *memoryLedgerStore) AppendEntries(ctx context.Context, entries ...models.LedgerEntry) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.ledger = append(s.db.ledger, entries...)
+	return nil
+}
+
+func (s *memoryLedgerStore) ListEntries(ctx context.Context, userID int64, skip, limit int) ([]models.LedgerEntry, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var entries []models.LedgerEntry
+	// Entries are appended in order, so walking backwards yields newest first
+	for i := len(s.db.ledger) - 1; i >= 0 && len(entries) < limit; i-- {
+		if s.db.ledger[i].UserID != userID {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		entries = append(entries, s.db.ledger[i])
+	}
+	return entries, nil
+}
+
+func (s *memoryLedgerStore) CountEntries(ctx context.Context, userID int64) (int64, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var count int64
+	for _, entry := range s.db.ledger {
+		if entry.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *memoryLedgerStore) Summarize(ctx context.Context, userID int64) (*LedgerSummary, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	summary := &LedgerSummary{}
+	for _, entry := range s.db.ledger {
+		if entry.UserID != userID {
+			continue
+		}
+		switch entry.Type {
+		case models.LedgerCoins:
+			summary.Coins += entry.Amount
+		case models.LedgerCharacter:
+			summary.Characters += entry.Amount
+		}
+		summary.Entries++
+	}
+	return summary, nil
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"senpai-waifu-bot/internal/models"
+	"senpai-waifu-bot/internal/utils"
+)
+
+type memoryRarityStore struct {
+	db *memoryDB
+}
+
+// upsertSettings returns the stored settings for a chat, creating them if needed. Caller must hold the write lock.
+func (s *memoryRarityStore) upsertSettings(chatID int64) *models.RaritySettings {
+	settings, ok := s.db.raritySettings[chatID]
+	if !ok {
+		settings = &models.RaritySettings{ChatID: chatID, DisabledRarities: []int{}}
+		s.db.raritySettings[chatID] = settings
+	}
+	return settings
+}
+
+func (s *memoryRarityStore) GetRaritySettings(ctx context.Context, chatID int64) (*models.RaritySettings, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	settings, ok := s.db.raritySettings[chatID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyRaritySettings(settings), nil
+}
+
+func (s *memoryRarityStore) InsertRaritySettings(ctx context.Context, settings *models.RaritySettings) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, exists := s.db.raritySettings[settings.ChatID]; exists {
+		return ErrDuplicate
+	}
+	s.db.raritySettings[settings.ChatID] = copyRaritySettings(settings)
+	return nil
+}
+
+func (s *memoryRarityStore) EnableRarity(ctx context.Context, chatID int64, rarity int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	settings := s.upsertSettings(chatID)
+	settings.DisabledRarities = utils.RemoveInt(settings.DisabledRarities, rarity)
+	if settings.DisabledRarities == nil {
+		settings.DisabledRarities = []int{}
+	}
+	return nil
+}
+
+func (s *memoryRarityStore) DisableRarity(ctx context.Context, chatID int64, rarity int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	settings := s.upsertSettings(chatID)
+	if !utils.ContainsInt(settings.DisabledRarities, rarity) {
+		settings.DisabledRarities = append(settings.DisabledRarities, rarity)
+	}
+	return nil
+}
+
+func (s *memoryRarityStore) SetRarityWeight(ctx context.Context, chatID int64, rarity, weight int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	settings := s.upsertSettings(chatID)
+	if settings.Weights == nil {
+		settings.Weights = make(map[int]int)
+	}
+	settings.Weights[rarity] = weight
+	return nil
+}
+
+func (s *memoryRarityStore) ResetRarityWeight(ctx context.Context, chatID int64, rarity int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if settings, ok := s.db.raritySettings[chatID]; ok {
+		delete(settings.Weights, rarity)
+	}
+	return nil
+}
+
+func (s *memoryRarityStore) LockCharacter(ctx context.Context, lock *models.LockedCharacter) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	c := *lock
+	s.db.locked[lock.CharacterID] = &c
+	return nil
+}
+
+func (s *memoryRarityStore) UnlockCharacter(ctx context.Context, charID string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	delete(s.db.locked, charID)
+	return nil
+}
+
+func (s *memoryRarityStore) IsCharacterLocked(ctx context.Context, charID string) (bool, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	_, ok := s.db.locked[charID]
+	return ok, nil
+}
+
+func (s *memoryRarityStore) LockedCharacters(ctx context.Context) ([]models.LockedCharacter, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	locked := make([]models.LockedCharacter, 0, len(s.db.locked))
+	for _, lock := range s.db.locked {
+		locked = append(locked, *lock)
+	}
+	sort.Slice(locked, func(i, j int) bool { return locked[i].LockedAt.Before(locked[j].LockedAt) })
+	return locked, nil
+}
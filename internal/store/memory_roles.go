@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+
+	"senpai-waifu-bot/internal/models"
+)
+
+type memoryRoleStore struct {
+	db *memoryDB
+}
+
+func (s *memoryRoleStore) GrantRole(ctx context.Context, grant *models.RoleGrant) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, g := range s.db.roles {
+		if g.UserID == grant.UserID && g.Role == grant.Role {
+			return ErrDuplicate
+		}
+	}
+	s.db.roles = append(s.db.roles, *grant)
+	return nil
+}
+
+func (s *memoryRoleStore) RevokeRole(ctx context.Context, userID int64, role string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for i, g := range s.db.roles {
+		if g.UserID == userID && g.Role == role {
+			s.db.roles = append(s.db.roles[:i], s.db.roles[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *memoryRoleStore) UserRoles(ctx context.Context, userID int64) ([]models.RoleGrant, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var grants []models.RoleGrant
+	for _, g := range s.db.roles {
+		if g.UserID == userID {
+			grants = append(grants, g)
+		}
+	}
+	return grants, nil
+}
+
+func (s *memoryRoleStore) RoleGrants(ctx context.Context) ([]models.RoleGrant, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return append([]models.RoleGrant{}, s.db.roles...), nil
+}
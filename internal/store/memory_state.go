@@ -0,0 +1,254 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"senpai-waifu-bot/internal/models"
+)
+
+type memoryStateStore struct {
+	db *memoryDB
+}
+
+func copySpawn(spawn *models.ActiveSpawn) *models.ActiveSpawn {
+	c := *spawn
+	c.FleesAt = copyTime(spawn.FleesAt)
+	c.FledAt = copyTime(spawn.FledAt)
+	c.GuessedAt = copyTime(spawn.GuessedAt)
+	return &c
+}
+
+// expired reports whether spawn is waiting to flee at at
+func expired(spawn *models.ActiveSpawn, at time.Time) bool {
+	return spawn.GuessedBy == 0 && spawn.FledAt == nil && spawn.FleesAt != nil && !spawn.FleesAt.After(at)
+}
+
+// canGuess reports whether nobody guessed spawn and it has not fled by at
+func canGuess(spawn *models.ActiveSpawn, at time.Time) bool {
+	return spawn.GuessedBy == 0 && spawn.FledAt == nil && (spawn.FleesAt == nil || spawn.FleesAt.After(at))
+}
+
+func (s *memoryStateStore) SetSpawn(ctx context.Context, spawn *models.ActiveSpawn) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.spawns[spawn.ChatID] = copySpawn(spawn)
+	return nil
+}
+
+func (s *memoryStateStore) GetSpawn(ctx context.Context, chatID int64) (*models.ActiveSpawn, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	spawn, ok := s.db.spawns[chatID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copySpawn(spawn), nil
+}
+
+func (s *memoryStateStore) ClaimSpawn(ctx context.Context, chatID, userID int64, at time.Time) (*models.ActiveSpawn, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	spawn, ok := s.db.spawns[chatID]
+	if !ok || !canGuess(spawn, at) {
+		return nil, ErrNotFound
+	}
+	spawn.GuessedBy = userID
+	spawn.GuessedAt = &at
+	return copySpawn(spawn), nil
+}
+
+func (s *memoryStateStore) SetSpawnMessage(ctx context.Context, chatID int64, messageID int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	spawn, ok := s.db.spawns[chatID]
+	if !ok {
+		return ErrNotFound
+	}
+	spawn.MessageID = messageID
+	return nil
+}
+
+func (s *memoryStateStore) ExpiredSpawns(ctx context.Context, at time.Time) ([]models.ActiveSpawn, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var spawns []models.ActiveSpawn
+	for _, spawn := range s.db.spawns {
+		if expired(spawn, at) {
+			spawns = append(spawns, *copySpawn(spawn))
+		}
+	}
+	return spawns, nil
+}
+
+func (s *memoryStateStore) FleeSpawn(ctx context.Context, chatID int64, spawnedAt, at time.Time) (*models.ActiveSpawn, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	spawn, ok := s.db.spawns[chatID]
+	if !ok || !spawn.SpawnedAt.Equal(spawnedAt) || !expired(spawn, at) {
+		return nil, ErrNotFound
+	}
+	spawn.FledAt = &at
+	return copySpawn(spawn), nil
+}
+
+func (s *memoryStateStore) AddSpawnHint(ctx context.Context, chatID int64, spawnedAt time.Time, limit int, at time.Time) (*models.ActiveSpawn, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	spawn, ok := s.db.spawns[chatID]
+	if !ok || !spawn.SpawnedAt.Equal(spawnedAt) || !canGuess(spawn, at) || spawn.Hints >= limit {
+		return nil, ErrNotFound
+	}
+	spawn.Hints++
+	return copySpawn(spawn), nil
+}
+
+func (s *memoryStateStore) IncMessageCounter(ctx context.Context, chatID int64) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.messageCounters[chatID]++
+	return s.db.messageCounters[chatID], nil
+}
+
+func (s *memoryStateStore) ResetMessageCounter(ctx context.Context, chatID int64) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	delete(s.db.messageCounters, chatID)
+	return nil
+}
+
+func (s *memoryStateStore) PutPayment(ctx context.Context, payment *models.PendingPayment) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if existing, ok := s.db.payments[payment.Token]; ok && time.Now().Before(existing.ExpiresAt) {
+		return ErrDuplicate
+	}
+	c := *payment
+	s.db.payments[payment.Token] = &c
+	return nil
+}
+
+func (s *memoryStateStore) GetPayment(ctx context.Context, token string) (*models.PendingPayment, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	payment, ok := s.db.payments[token]
+	if !ok || !time.Now().Before(payment.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	c := *payment
+	return &c, nil
+}
+
+func (s *memoryStateStore) SetPaymentMessage(ctx context.Context, token string, messageID int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if payment, ok := s.db.payments[token]; ok {
+		payment.MessageID = messageID
+	}
+	return nil
+}
+
+func (s *memoryStateStore) TakePayment(ctx context.Context, token string) (*models.PendingPayment, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	payment, ok := s.db.payments[token]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	delete(s.db.payments, token)
+	if !time.Now().Before(payment.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	return payment, nil
+}
+
+func (s *memoryStateStore) PutTrade(ctx context.Context, trade *models.PendingTrade) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if existing, ok := s.db.trades[trade.Key]; ok && time.Now().Before(existing.ExpiresAt) {
+		return ErrDuplicate
+	}
+	c := *trade
+	s.db.trades[trade.Key] = &c
+	return nil
+}
+
+func (s *memoryStateStore) GetTrade(ctx context.Context, key string) (*models.PendingTrade, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	trade, ok := s.db.trades[key]
+	if !ok || !time.Now().Before(trade.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	c := *trade
+	return &c, nil
+}
+
+func (s *memoryStateStore) TakeTrade(ctx context.Context, key string) (*models.PendingTrade, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	trade, ok := s.db.trades[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	delete(s.db.trades, key)
+	if !time.Now().Before(trade.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	return trade, nil
+}
+
+func (s *memoryStateStore) PutGift(ctx context.Context, gift *models.PendingGift) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if existing, ok := s.db.gifts[gift.Key]; ok && time.Now().Before(existing.ExpiresAt) {
+		return ErrDuplicate
+	}
+	c := *gift
+	s.db.gifts[gift.Key] = &c
+	return nil
+}
+
+func (s *memoryStateStore) GetGift(ctx context.Context, key string) (*models.PendingGift, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	gift, ok := s.db.gifts[key]
+	if !ok || !time.Now().Before(gift.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	c := *gift
+	return &c, nil
+}
+
+func (s *memoryStateStore) TakeGift(ctx context.Context, key string) (*models.PendingGift, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	gift, ok := s.db.gifts[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	delete(s.db.gifts, key)
+	if !time.Now().Before(gift.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	return gift, nil
+}
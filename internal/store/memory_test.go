@@ -0,0 +1,193 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"senpai-waifu-bot/internal/models"
+)
+
+var (
+	shinobu = models.UserCharacter{ID: "001", Name: "Shinobu Kochou", Anime: "Demon Slayer", Rarity: 3}
+	hutao   = models.UserCharacter{ID: "002", Name: "Hu Tao", Anime: "Genshin Impact", Rarity: 2}
+)
+
+func characterIDs(user *models.User) []string {
+	ids := []string{}
+	for _, char := range user.Characters {
+		ids = append(ids, char.ID)
+	}
+	return ids
+}
+
+func TestMemoryTakeCharacter(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+	user := &models.User{ID: 1, Characters: []models.UserCharacter{shinobu, hutao, shinobu}}
+	if err := st.Users.InsertUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	// One copy goes at a time
+	for _, want := range [][]string{{"002", "001"}, {"002"}} {
+		char, err := st.Users.TakeCharacter(ctx, user.ID, shinobu.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *char != shinobu {
+			t.Errorf("TakeCharacter() = %+v, want %+v", *char, shinobu)
+		}
+		got, err := st.Users.GetUser(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := characterIDs(got); !reflect.DeepEqual(ids, want) {
+			t.Errorf("user holds %q after TakeCharacter(), want %q", ids, want)
+		}
+	}
+
+	if _, err := st.Users.TakeCharacter(ctx, user.ID, shinobu.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("TakeCharacter() of a character not held: error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := st.Users.TakeCharacter(ctx, 2, shinobu.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("TakeCharacter() of an unknown user: error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestMemoryDebitBalance(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+	if err := st.Users.InsertUser(ctx, &models.User{ID: 1, Balance: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	if balance, err := st.Users.DebitBalance(ctx, 1, 101); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DebitBalance() over the balance = %d, %v, want %v", balance, err, ErrNotFound)
+	}
+	if balance, err := st.Users.DebitBalance(ctx, 2, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DebitBalance() of an unknown user = %d, %v, want %v", balance, err, ErrNotFound)
+	}
+	if balance, err := st.Users.DebitBalance(ctx, 1, 100); err != nil || balance != 0 {
+		t.Errorf("DebitBalance() of the whole balance = %d, %v, want 0", balance, err)
+	}
+	user, err := st.Users.GetUser(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Balance != 0 {
+		t.Errorf("balance = %d after the debits, want 0", user.Balance)
+	}
+}
+
+func TestMemorySpawnClaims(t *testing.T) {
+	spawnedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	fleesAt := spawnedAt.Add(10 * time.Minute)
+	now := spawnedAt.Add(time.Minute)
+	tests := []struct {
+		name   string
+		spawn  models.ActiveSpawn
+		at     time.Time
+		claims bool
+		hints  bool
+	}{
+		{"open", models.ActiveSpawn{}, now, true, true},
+		{"open until it flees", models.ActiveSpawn{FleesAt: &fleesAt}, now, true, true},
+		{"guessed", models.ActiveSpawn{GuessedBy: 7, GuessedAt: &now}, now, false, false},
+		{"fled", models.ActiveSpawn{FleesAt: &fleesAt, FledAt: &fleesAt}, now, false, false},
+		{"expired", models.ActiveSpawn{FleesAt: &fleesAt}, fleesAt, false, false},
+		{"out of hints", models.ActiveSpawn{Hints: models.HintTiers}, now, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			st := NewMemoryStore()
+			spawn := tt.spawn
+			spawn.ChatID = -100
+			spawn.Character = shinobu
+			spawn.SpawnedAt = spawnedAt
+			if err := st.State.SetSpawn(ctx, &spawn); err != nil {
+				t.Fatal(err)
+			}
+
+			hinted, err := st.State.AddSpawnHint(ctx, spawn.ChatID, spawnedAt, models.HintTiers, tt.at)
+			if tt.hints {
+				if err != nil || hinted.Hints != spawn.Hints+1 {
+					t.Errorf("AddSpawnHint() = %+v, %v, want %d hints", hinted, err, spawn.Hints+1)
+				}
+			} else if !errors.Is(err, ErrNotFound) {
+				t.Errorf("AddSpawnHint() error = %v, want %v", err, ErrNotFound)
+			}
+
+			claimed, err := st.State.ClaimSpawn(ctx, spawn.ChatID, 1, tt.at)
+			if tt.claims {
+				if err != nil || claimed.GuessedBy != 1 {
+					t.Errorf("ClaimSpawn() = %+v, %v, want a claim by user 1", claimed, err)
+				}
+			} else if !errors.Is(err, ErrNotFound) {
+				t.Errorf("ClaimSpawn() error = %v, want %v", err, ErrNotFound)
+			}
+
+			// A refused claim leaves the spawn as it was
+			got, err := st.State.GetSpawn(ctx, spawn.ChatID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.claims && got.GuessedBy != spawn.GuessedBy {
+				t.Errorf("refused claim set GuessedBy to %d, want %d", got.GuessedBy, spawn.GuessedBy)
+			}
+		})
+	}
+}
+
+func TestMemorySpawnClaimedOnce(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+	spawnedAt := time.Now()
+	if err := st.State.SetSpawn(ctx, &models.ActiveSpawn{ChatID: -100, Character: shinobu, SpawnedAt: spawnedAt}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := st.State.AddSpawnHint(ctx, -100, spawnedAt.Add(-time.Second), models.HintTiers, spawnedAt); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AddSpawnHint() for an earlier spawn: error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := st.State.ClaimSpawn(ctx, -100, 1, spawnedAt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.State.ClaimSpawn(ctx, -100, 2, spawnedAt); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second ClaimSpawn() error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := st.State.ClaimSpawn(ctx, -200, 1, spawnedAt); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ClaimSpawn() in a chat without a spawn: error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestMemoryInsertDuplicates(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+
+	char := &models.Character{ID: shinobu.ID, Name: shinobu.Name, Anime: shinobu.Anime, Rarity: shinobu.Rarity}
+	if err := st.Characters.InsertCharacter(ctx, char); err != nil {
+		t.Fatal(err)
+	}
+	other := &models.Character{ID: shinobu.ID, Name: hutao.Name, Anime: hutao.Anime, Rarity: hutao.Rarity}
+	if err := st.Characters.InsertCharacter(ctx, other); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("InsertCharacter() with a taken ID: error = %v, want %v", err, ErrDuplicate)
+	}
+	got, err := st.Characters.GetCharacter(ctx, shinobu.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != shinobu.Name {
+		t.Errorf("duplicate insert replaced %s with %s", shinobu.Name, got.Name)
+	}
+
+	if err := st.Users.InsertUser(ctx, &models.User{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Users.InsertUser(ctx, &models.User{ID: 1}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("InsertUser() with a taken ID: error = %v, want %v", err, ErrDuplicate)
+	}
+}
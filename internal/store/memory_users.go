@@ -0,0 +1,314 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"senpai-waifu-bot/internal/models"
+)
+
+type memoryUserStore struct {
+	db *memoryDB
+}
+
+// upsertUser returns the stored user, creating an empty one if needed. Caller must hold the write lock.
+func (s *memoryUserStore) upsertUser(userID int64) *models.User {
+	user, ok := s.db.users[userID]
+	if !ok {
+		user = &models.User{ID: userID}
+		s.db.users[userID] = user
+	}
+	return user
+}
+
+func (s *memoryUserStore) GetUser(ctx context.Context, userID int64) (*models.User, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	user, ok := s.db.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyUser(user), nil
+}
+
+func (s *memoryUserStore) InsertUser(ctx context.Context, user *models.User) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, exists := s.db.users[user.ID]; exists {
+		return ErrDuplicate
+	}
+	s.db.users[user.ID] = copyUser(user)
+	return nil
+}
+
+func (s *memoryUserStore) UpdateNames(ctx context.Context, userID int64, username, firstName string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	user, ok := s.db.users[userID]
+	if !ok {
+		return nil
+	}
+	if username != "" {
+		user.Username = username
+	}
+	if firstName != "" {
+		user.FirstName = firstName
+	}
+	return nil
+}
+
+func (s *memoryUserStore) IncBalance(ctx context.Context, userID int64, amount int64) (int64, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	user := s.upsertUser(userID)
+	user.Balance += amount
+	return user.Balance, nil
+}
+
+func (s *memoryUserStore) DebitBalance(ctx context.Context, userID int64, amount int64) (int64, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	user, ok := s.db.users[userID]
+	if !ok || user.Balance < amount {
+		return 0, ErrNotFound
+	}
+	user.Balance -= amount
+	return user.Balance, nil
+}
+
+func (s *memoryUserStore) PushCharacter(ctx context.Context, userID int64, char models.UserCharacter) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	user := s.upsertUser(userID)
+	user.Characters = append(user.Characters, char)
+	return nil
+}
+
+func (s *memoryUserStore) PullCharacter(ctx context.Context, userID int64, charID string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	user, ok := s.db.users[userID]
+	if !ok {
+		return nil
+	}
+	kept := user.Characters[:0]
+	for _, char := range user.Characters {
+		if char.ID != charID {
+			kept = append(kept, char)
+		}
+	}
+	user.Characters = kept
+	return nil
+}
+
+func (s *memoryUserStore) TakeCharacter(ctx context.Context, userID int64, charID string) (*models.UserCharacter, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	user, ok := s.db.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	for i, char := range user.Characters {
+		if char.ID == charID {
+			user.Characters = append(user.Characters[:i:i], user.Characters[i+1:]...)
+			return &char, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memoryUserStore) HasCharacter(ctx context.Context, userID int64, charID string) (bool, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	user, ok := s.db.users[userID]
+	if !ok {
+		return false, nil
+	}
+	for _, char := range user.Characters {
+		if char.ID == charID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryUserStore) AddFavorite(ctx context.Context, userID int64, charID string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	user, ok := s.db.users[userID]
+	if !ok {
+		return nil
+	}
+	for _, fav := range user.Favorites {
+		if fav == charID {
+			return nil
+		}
+	}
+	user.Favorites = append(user.Favorites, charID)
+	return nil
+}
+
+// sortedUsers returns copies of all users ordered by less, with ties broken by user ID
+func (s *memoryUserStore) sortedUsers(limit int, less func(a, b *models.User) bool) []models.User {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	all := make([]*models.User, 0, len(s.db.users))
+	for _, user := range s.db.users {
+		all = append(all, user)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if less(all[i], all[j]) {
+			return true
+		}
+		if less(all[j], all[i]) {
+			return false
+		}
+		return all[i].ID < all[j].ID
+	})
+
+	if limit > 0 && len(all) > limit {
+		all = all[:limit]
+	}
+	users := make([]models.User, len(all))
+	for i, user := range all {
+		users[i] = *copyUser(user)
+	}
+	return users
+}
+
+func (s *memoryUserStore) TopByBalance(ctx context.Context, limit int) ([]models.User, error) {
+	return s.sortedUsers(limit, func(a, b *models.User) bool {
+		return a.Balance > b.Balance
+	}), nil
+}
+
+func (s *memoryUserStore) TopByCharacters(ctx context.Context, limit int) ([]models.User, error) {
+	return s.sortedUsers(limit, func(a, b *models.User) bool {
+		return len(a.Characters) > len(b.Characters)
+	}), nil
+}
+
+func (s *memoryUserStore) SetLastSClaim(ctx context.Context, userID int64, at time.Time) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.upsertUser(userID).LastSClaim = &at
+	return nil
+}
+
+func (s *memoryUserStore) SetLastClaim(ctx context.Context, userID int64, at time.Time) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.upsertUser(userID).LastClaim = &at
+	return nil
+}
+
+func (s *memoryUserStore) SetShopData(ctx context.Context, userID int64, shopData *models.ShopData) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	user, ok := s.db.users[userID]
+	if !ok {
+		return nil
+	}
+	if shopData == nil {
+		user.ShopData = nil
+		return nil
+	}
+	shop := *shopData
+	shop.Characters = append([]models.ShopCharacter{}, shopData.Characters...)
+	user.ShopData = &shop
+	return nil
+}
+
+func (s *memoryUserStore) CountOwners(ctx context.Context, charID string) (int64, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var count int64
+	for _, user := range s.db.users {
+		for _, char := range user.Characters {
+			if char.ID == charID {
+				count++
+				break
+			}
+		}
+	}
+	return count, nil
+}
+
+func (s *memoryUserStore) TopGrabbers(ctx context.Context, charID string, limit int) ([]models.Grabber, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var grabbers []models.Grabber
+	for _, user := range s.db.users {
+		count := 0
+		for _, char := range user.Characters {
+			if char.ID == charID {
+				count++
+			}
+		}
+		if count > 0 {
+			grabbers = append(grabbers, models.Grabber{
+				UserID:    user.ID,
+				Username:  user.Username,
+				FirstName: user.FirstName,
+				Count:     count,
+			})
+		}
+	}
+
+	sort.Slice(grabbers, func(i, j int) bool {
+		if grabbers[i].Count != grabbers[j].Count {
+			return grabbers[i].Count > grabbers[j].Count
+		}
+		return grabbers[i].UserID < grabbers[j].UserID
+	})
+	if limit > 0 && len(grabbers) > limit {
+		grabbers = grabbers[:limit]
+	}
+	return grabbers, nil
+}
+
+func (s *memoryUserStore) GetSortPreference(ctx context.Context, userID int64) (*models.SortPreference, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	pref, ok := s.db.sortPrefs[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *pref
+	if pref.RarityFilter != nil {
+		rarity := *pref.RarityFilter
+		c.RarityFilter = &rarity
+	}
+	return &c, nil
+}
+
+func (s *memoryUserStore) SetSortPreference(ctx context.Context, userID int64, rarityFilter *int) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	pref := &models.SortPreference{UserID: userID}
+	if rarityFilter != nil {
+		rarity := *rarityFilter
+		pref.RarityFilter = &rarity
+	}
+	s.db.sortPrefs[userID] = pref
+	return nil
+}
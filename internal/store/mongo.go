@@ -0,0 +1,96 @@
+package store
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"senpai-waifu-bot/internal/database"
+)
+
+// NewMongoStore creates a Store backed by the collections opened by database.Connect
+func NewMongoStore() *Store {
+	return &Store{
+		Users: &mongoUserStore{
+			users:     database.UserCollection,
+			sortPrefs: database.SortPreferencesCollection,
+		},
+		Characters: &mongoCharacterStore{
+			characters: database.CharacterCollection,
+		},
+		Groups: &mongoGroupStore{
+			groupUserTotals:   database.GroupUserTotalsCollection,
+			topGlobalGroups:   database.TopGlobalGroupsCollection,
+			userTotals:        database.UserTotalsCollection,
+			chatLocales:       database.ChatLocalesCollection,
+			pmUsers:           database.PMUsersCollection,
+			dailyUserGuesses:  database.DailyUserGuessesCollection,
+			dailyGroupGuesses: database.DailyGroupGuessesCollection,
+		},
+		Codes: &mongoCodeStore{
+			redeemCodes: database.RedeemCodesCollection,
+			claimCodes:  database.ClaimCodesCollection,
+		},
+		Rarity: &mongoRarityStore{
+			settings: database.RaritySettingsCollection,
+			locked:   database.LockedCharactersCollection,
+		},
+		Ledger: &mongoLedgerStore{
+			ledger: database.LedgerCollection,
+		},
+		State: &mongoStateStore{
+			spawns:   database.ActiveSpawnsCollection,
+			counters: database.MessageCountersCollection,
+			payments: database.PendingPaymentsCollection,
+			trades:   database.PendingTradesCollection,
+			gifts:    database.PendingGiftsCollection,
+		},
+		Roles: &mongoRoleStore{
+			roles: database.RolesCollection,
+		},
+		Audit: &mongoAuditStore{
+			audit: database.AuditLogCollection,
+		},
+		Counters: &mongoCounterStore{
+			counters: database.CountersCollection,
+		},
+		Tx: &mongoTransactor{client: database.Client},
+	}
+}
+
+// mongoErr maps driver errors onto the store sentinel errors
+func mongoErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// characterFilterBSON converts a CharacterFilter into a MongoDB filter document
+func characterFilterBSON(f CharacterFilter) bson.M {
+	var clauses []bson.M
+	if len(f.Rarities) > 0 {
+		clauses = append(clauses, bson.M{"rarity": bson.M{"$in": f.Rarities}})
+	}
+	if len(f.ExcludedRarities) > 0 {
+		clauses = append(clauses, bson.M{"rarity": bson.M{"$nin": f.ExcludedRarities}})
+	}
+	if len(f.ExcludedIDs) > 0 {
+		clauses = append(clauses, bson.M{"id": bson.M{"$nin": f.ExcludedIDs}})
+	}
+
+	switch len(clauses) {
+	case 0:
+		return bson.M{}
+	case 1:
+		return clauses[0]
+	default:
+		return bson.M{"$and": clauses}
+	}
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"senpai-waifu-bot/internal/models"
+)
+
+type mongoAuditStore struct {
+	audit *mongo.Collection
+}
+
+func (s *mongoAuditStore) AppendAudit(ctx context.Context, entry *models.AuditEntry) error {
+	_, err := s.audit.InsertOne(ctx, entry)
+	return mongoErr(err)
+}
+
+func (s *mongoAuditStore) ListAudit(ctx context.Context, filter AuditFilter, limit int) ([]models.AuditEntry, error) {
+	query := bson.M{}
+	if filter.ActorID != 0 {
+		query["actor_id"] = filter.ActorID
+	}
+	if filter.TargetUserID != 0 {
+		query["target_user_id"] = filter.TargetUserID
+	}
+	if filter.CharacterID != "" {
+		query["character_id"] = filter.CharacterID
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := s.audit.Find(ctx, query, opts)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.AuditEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, mongoErr(err)
+	}
+	return entries, nil
+}
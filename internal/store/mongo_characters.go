@@ -0,0 +1,234 @@
+package store
+
+import (
+	"context"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"senpai-waifu-bot/internal/models"
+)
+
+type mongoCharacterStore struct {
+	characters *mongo.Collection
+}
+
+func (s *mongoCharacterStore) GetCharacter(ctx context.Context, charID string) (*models.Character, error) {
+	var char models.Character
+	if err := s.characters.FindOne(ctx, bson.M{"id": charID}).Decode(&char); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &char, nil
+}
+
+func (s *mongoCharacterStore) FindCharacters(ctx context.Context, filter CharacterFilter) ([]models.Character, error) {
+	cursor, err := s.characters.Find(ctx, characterFilterBSON(filter))
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var chars []models.Character
+	if err = cursor.All(ctx, &chars); err != nil {
+		return nil, mongoErr(err)
+	}
+	return chars, nil
+}
+
+func (s *mongoCharacterStore) SampleCharacters(ctx context.Context, filter CharacterFilter, count int) ([]models.Character, error) {
+	pipeline := []bson.M{
+		{"$match": characterFilterBSON(filter)},
+		{"$sample": bson.M{"size": count}},
+	}
+
+	cursor, err := s.characters.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var chars []models.Character
+	if err = cursor.All(ctx, &chars); err != nil {
+		return nil, mongoErr(err)
+	}
+	return chars, nil
+}
+
+func (s *mongoCharacterStore) SearchCharacters(ctx context.Context, query string) ([]models.Character, error) {
+	pattern := regexp.QuoteMeta(query)
+	filter := bson.M{
+		"$or": []bson.M{
+			{"name": bson.M{"$regex": pattern, "$options": "i"}},
+			{"anime": bson.M{"$regex": pattern, "$options": "i"}},
+		},
+	}
+
+	cursor, err := s.characters.Find(
+		ctx,
+		filter,
+		options.Find().SetProjection(bson.M{
+			"id":      1,
+			"name":    1,
+			"anime":   1,
+			"rarity":  1,
+			"img_url": 1,
+		}),
+	)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var chars []models.Character
+	if err = cursor.All(ctx, &chars); err != nil {
+		return nil, mongoErr(err)
+	}
+	return chars, nil
+}
+
+func (s *mongoCharacterStore) CountCharacters(ctx context.Context) (int64, error) {
+	count, err := s.characters.CountDocuments(ctx, bson.M{})
+	return count, mongoErr(err)
+}
+
+func (s *mongoCharacterStore) CountByRarity(ctx context.Context, filter CharacterFilter) (map[int]int64, error) {
+	pipeline := []bson.M{
+		{"$match": characterFilterBSON(filter)},
+		{"$group": bson.M{"_id": "$rarity", "count": bson.M{"$sum": 1}}},
+	}
+
+	cursor, err := s.characters.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Rarity int   `bson:"_id"`
+		Count  int64 `bson:"count"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, mongoErr(err)
+	}
+	counts := make(map[int]int64, len(results))
+	for _, r := range results {
+		counts[r.Rarity] = r.Count
+	}
+	return counts, nil
+}
+
+func (s *mongoCharacterStore) MaxNumericID(ctx context.Context) (int64, error) {
+	// IDs are strings, so the highest is found by converting the numeric ones
+	pipeline := []bson.M{
+		{"$match": bson.M{"id": bson.M{"$regex": "^[0-9]+$"}}},
+		{"$group": bson.M{
+			"_id": nil,
+			"max": bson.M{"$max": bson.M{"$toLong": "$id"}},
+		}},
+	}
+
+	cursor, err := s.characters.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Max int64 `bson:"max"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return 0, mongoErr(err)
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+	return results[0].Max, nil
+}
+
+func (s *mongoCharacterStore) AnimeCounts(ctx context.Context, animes []string) (map[string]int64, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"anime": bson.M{"$in": animes}}},
+		{"$group": bson.M{
+			"_id":   "$anime",
+			"count": bson.M{"$sum": 1},
+		}},
+	}
+
+	cursor, err := s.characters.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		ID    string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, mongoErr(err)
+	}
+
+	counts := make(map[string]int64)
+	for _, r := range results {
+		counts[r.ID] = r.Count
+	}
+	return counts, nil
+}
+
+func (s *mongoCharacterStore) InsertCharacter(ctx context.Context, char *models.Character) error {
+	_, err := s.characters.InsertOne(ctx, char)
+	return mongoErr(err)
+}
+
+func (s *mongoCharacterStore) UpdateCharacter(ctx context.Context, charID string, update CharacterUpdate) error {
+	set := bson.M{
+		"updated_at": update.UpdatedAt,
+		"updated_by": update.UpdatedBy,
+	}
+	if update.Name != nil {
+		set["name"] = *update.Name
+	}
+	if update.Anime != nil {
+		set["anime"] = *update.Anime
+	}
+	if update.Rarity != nil {
+		set["rarity"] = *update.Rarity
+	}
+	if update.ImgURL != nil {
+		set["img_url"] = *update.ImgURL
+	}
+	if update.Aliases != nil {
+		set["aliases"] = *update.Aliases
+	}
+
+	filter := bson.M{"id": charID}
+	if update.PrevAliases != nil {
+		if len(*update.PrevAliases) == 0 {
+			// Characters without aliases may have no aliases field at all
+			filter["aliases"] = bson.M{"$in": bson.A{nil, bson.A{}}}
+		} else {
+			filter["aliases"] = *update.PrevAliases
+		}
+	}
+
+	result, err := s.characters.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return mongoErr(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoCharacterStore) DeleteCharacter(ctx context.Context, charID string) error {
+	result, err := s.characters.DeleteOne(ctx, bson.M{"id": charID})
+	if err != nil {
+		return mongoErr(err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
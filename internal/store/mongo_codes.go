@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"senpai-waifu-bot/internal/models"
+)
+
+type mongoCodeStore struct {
+	redeemCodes *mongo.Collection
+	claimCodes  *mongo.Collection
+}
+
+func (s *mongoCodeStore) InsertRedeemCode(ctx context.Context, code *models.RedeemCode) error {
+	_, err := s.redeemCodes.InsertOne(ctx, code)
+	return mongoErr(err)
+}
+
+func (s *mongoCodeStore) GetRedeemCode(ctx context.Context, code string) (*models.RedeemCode, error) {
+	var redeemCode models.RedeemCode
+	if err := s.redeemCodes.FindOne(ctx, bson.M{"code": code}).Decode(&redeemCode); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &redeemCode, nil
+}
+
+func (s *mongoCodeStore) UseRedeemCode(ctx context.Context, code string, userID int64) (*models.RedeemCode, error) {
+	result := s.redeemCodes.FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"code":      code,
+			"is_active": true,
+			"used_by":   bson.M{"$ne": userID},
+			"$expr":     bson.M{"$lt": []interface{}{bson.M{"$size": "$used_by"}, "$max_uses"}},
+		},
+		bson.M{"$push": bson.M{"used_by": userID}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var redeemCode models.RedeemCode
+	if err := result.Decode(&redeemCode); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &redeemCode, nil
+}
+
+func (s *mongoCodeStore) DeactivateRedeemCode(ctx context.Context, code string) error {
+	_, err := s.redeemCodes.UpdateOne(
+		ctx,
+		bson.M{"code": code},
+		bson.M{"$set": bson.M{"is_active": false}},
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoCodeStore) HasRedeemed(ctx context.Context, code string, userID int64) (bool, error) {
+	count, err := s.redeemCodes.CountDocuments(ctx, bson.M{"code": code, "used_by": userID})
+	return count > 0, mongoErr(err)
+}
+
+func (s *mongoCodeStore) InsertClaimCode(ctx context.Context, code *models.ClaimCode) error {
+	_, err := s.claimCodes.InsertOne(ctx, code)
+	return mongoErr(err)
+}
+
+func (s *mongoCodeStore) GetClaimCode(ctx context.Context, code string) (*models.ClaimCode, error) {
+	var claimCode models.ClaimCode
+	if err := s.claimCodes.FindOne(ctx, bson.M{"code": code}).Decode(&claimCode); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &claimCode, nil
+}
+
+func (s *mongoCodeStore) RedeemClaimCode(ctx context.Context, code string, userID int64, at time.Time) (*models.ClaimCode, error) {
+	result := s.claimCodes.FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"code":        code,
+			"user_id":     userID,
+			"is_redeemed": false,
+		},
+		bson.M{
+			"$set": bson.M{
+				"is_redeemed": true,
+				"redeemed_at": at,
+			},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var claimCode models.ClaimCode
+	if err := result.Decode(&claimCode); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &claimCode, nil
+}
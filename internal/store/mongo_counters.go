@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoCounterStore struct {
+	counters *mongo.Collection
+}
+
+// counterDoc is a named sequence; its _id is the counter name
+type counterDoc struct {
+	Name  string `bson:"_id"`
+	Value int64  `bson:"seq"`
+}
+
+func (s *mongoCounterStore) NextSequence(ctx context.Context, name string) (int64, error) {
+	var counter counterDoc
+	err := s.counters.FindOneAndUpdate(ctx,
+		bson.M{"_id": name},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return 0, mongoErr(err)
+	}
+	return counter.Value, nil
+}
+
+func (s *mongoCounterStore) GetSequence(ctx context.Context, name string) (int64, error) {
+	var counter counterDoc
+	if err := s.counters.FindOne(ctx, bson.M{"_id": name}).Decode(&counter); err != nil {
+		return 0, mongoErr(err)
+	}
+	return counter.Value, nil
+}
+
+func (s *mongoCounterStore) SetSequence(ctx context.Context, name string, value int64) error {
+	return s.update(ctx, name, bson.M{"$set": bson.M{"seq": value}})
+}
+
+func (s *mongoCounterStore) RaiseSequence(ctx context.Context, name string, value int64) error {
+	return s.update(ctx, name, bson.M{"$max": bson.M{"seq": value}})
+}
+
+func (s *mongoCounterStore) update(ctx context.Context, name string, update bson.M) error {
+	_, err := s.counters.UpdateOne(ctx, bson.M{"_id": name}, update, options.Update().SetUpsert(true))
+	return mongoErr(err)
+}
@@ -0,0 +1,250 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"senpai-waifu-bot/internal/models"
+)
+
+type mongoGroupStore struct {
+	groupUserTotals   *mongo.Collection
+	topGlobalGroups   *mongo.Collection
+	userTotals        *mongo.Collection
+	chatLocales       *mongo.Collection
+	pmUsers           *mongo.Collection
+	dailyUserGuesses  *mongo.Collection
+	dailyGroupGuesses *mongo.Collection
+}
+
+func (s *mongoGroupStore) IncGroupUserTotal(ctx context.Context, userID, groupID int64, username, firstName string) error {
+	_, err := s.groupUserTotals.UpdateOne(
+		ctx,
+		bson.M{"user_id": userID, "group_id": groupID},
+		bson.M{
+			"$set": bson.M{
+				"username":   username,
+				"first_name": firstName,
+			},
+			"$inc": bson.M{"count": 1},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoGroupStore) IncTopGlobalGroup(ctx context.Context, groupID int64, groupName string) error {
+	_, err := s.topGlobalGroups.UpdateOne(
+		ctx,
+		bson.M{"group_id": groupID},
+		bson.M{
+			"$set": bson.M{"group_name": groupName},
+			"$inc": bson.M{"count": 1},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoGroupStore) TopGroups(ctx context.Context, limit int) ([]models.TopGlobalGroup, error) {
+	cursor, err := s.topGlobalGroups.Find(
+		ctx,
+		bson.M{},
+		options.Find().SetSort(bson.M{"count": -1}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var groups []models.TopGlobalGroup
+	if err = cursor.All(ctx, &groups); err != nil {
+		return nil, mongoErr(err)
+	}
+	return groups, nil
+}
+
+func (s *mongoGroupStore) GroupUserTotals(ctx context.Context, groupID int64, limit int) ([]models.GroupUserTotal, error) {
+	cursor, err := s.groupUserTotals.Find(
+		ctx,
+		bson.M{"group_id": groupID},
+		options.Find().SetSort(bson.M{"count": -1}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var totals []models.GroupUserTotal
+	if err = cursor.All(ctx, &totals); err != nil {
+		return nil, mongoErr(err)
+	}
+	return totals, nil
+}
+
+func (s *mongoGroupStore) GetMessageFrequency(ctx context.Context, chatID int64) (int, error) {
+	var total models.UserTotal
+	if err := s.userTotals.FindOne(ctx, bson.M{"chat_id": chatID}).Decode(&total); err != nil {
+		return 0, mongoErr(err)
+	}
+	return total.MessageFrequency, nil
+}
+
+func (s *mongoGroupStore) SetMessageFrequency(ctx context.Context, chatID int64, frequency int) error {
+	_, err := s.userTotals.UpdateOne(
+		ctx,
+		bson.M{"chat_id": chatID},
+		bson.M{"$set": bson.M{"message_frequency": frequency}},
+		options.Update().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoGroupStore) GetSpawnSettings(ctx context.Context, chatID int64) (*models.SpawnSettings, error) {
+	var settings models.SpawnSettings
+	if err := s.userTotals.FindOne(ctx, bson.M{"chat_id": chatID}).Decode(&settings); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &settings, nil
+}
+
+func (s *mongoGroupStore) SetSpawnSettings(ctx context.Context, settings *models.SpawnSettings) error {
+	// Zero fields and nil gaps fall back to the defaults, so they are removed rather than stored
+	set, unset := bson.M{}, bson.M{}
+	put := func(key string, value interface{}, present bool) {
+		if present {
+			set[key] = value
+		} else {
+			unset[key] = ""
+		}
+	}
+	put("message_frequency", settings.MessageFrequency, settings.MessageFrequency != 0)
+	put("spawn_mode", settings.Mode, settings.Mode != "")
+	put("spawn_interval", settings.Interval, settings.Interval != 0)
+	put("spawn_min_gap", settings.MinGap, settings.MinGap != nil)
+	put("spawn_max_gap", settings.MaxGap, settings.MaxGap != nil)
+	put("quiet_hours", settings.QuietHours, settings.QuietHours != nil)
+	put("spawn_expiry", settings.Expiry, settings.Expiry != 0)
+
+	// MongoDB rejects empty update operators
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	_, err := s.userTotals.UpdateOne(ctx, bson.M{"chat_id": settings.ChatID}, update, options.Update().SetUpsert(true))
+	return mongoErr(err)
+}
+
+func (s *mongoGroupStore) GetChatLocale(ctx context.Context, chatID int64) (*models.ChatLocale, error) {
+	var locale models.ChatLocale
+	if err := s.chatLocales.FindOne(ctx, bson.M{"chat_id": chatID}).Decode(&locale); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &locale, nil
+}
+
+func (s *mongoGroupStore) SetChatLocale(ctx context.Context, locale *models.ChatLocale) error {
+	_, err := s.chatLocales.ReplaceOne(
+		ctx,
+		bson.M{"chat_id": locale.ChatID},
+		locale,
+		options.Replace().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoGroupStore) UpsertPMUser(ctx context.Context, userID int64, username, firstName string, startedAt time.Time) error {
+	_, err := s.pmUsers.UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$set": bson.M{
+				"username":   username,
+				"first_name": firstName,
+			},
+			"$setOnInsert": bson.M{"started_at": startedAt},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoGroupStore) CountPMUsers(ctx context.Context) (int64, error) {
+	count, err := s.pmUsers.CountDocuments(ctx, bson.M{})
+	return count, mongoErr(err)
+}
+
+func (s *mongoGroupStore) IncDailyUserGuess(ctx context.Context, date string, userID int64, username, firstName string, at time.Time) error {
+	_, err := s.dailyUserGuesses.UpdateOne(
+		ctx,
+		bson.M{"date": date, "user_id": userID},
+		bson.M{
+			"$inc": bson.M{"count": 1},
+			"$set": bson.M{
+				"username":     username,
+				"first_name":   firstName,
+				"last_updated": at,
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoGroupStore) IncDailyGroupGuess(ctx context.Context, date string, groupID int64, groupName string, at time.Time) error {
+	_, err := s.dailyGroupGuesses.UpdateOne(
+		ctx,
+		bson.M{"date": date, "group_id": groupID},
+		bson.M{
+			"$inc": bson.M{"count": 1},
+			"$set": bson.M{
+				"group_name":   groupName,
+				"last_updated": at,
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoGroupStore) TopDailyUsers(ctx context.Context, date string, limit int) ([]models.DailyUserGuess, error) {
+	cursor, err := s.dailyUserGuesses.Find(
+		ctx,
+		bson.M{"date": date},
+		options.Find().SetSort(bson.M{"count": -1}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var guesses []models.DailyUserGuess
+	if err = cursor.All(ctx, &guesses); err != nil {
+		return nil, mongoErr(err)
+	}
+	return guesses, nil
+}
+
+func (s *mongoGroupStore) TopDailyGroups(ctx context.Context, date string, limit int) ([]models.DailyGroupGuess, error) {
+	cursor, err := s.dailyGroupGuesses.Find(
+		ctx,
+		bson.M{"date": date},
+		options.Find().SetSort(bson.M{"count": -1}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var guesses []models.DailyGroupGuess
+	if err = cursor.All(ctx, &guesses); err != nil {
+		return nil, mongoErr(err)
+	}
+	return guesses, nil
+}
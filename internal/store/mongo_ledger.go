@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"senpai-waifu-bot/internal/models"
+)
+
+type mongoLedgerStore struct {
+	ledger *mongo.Collection
+}
+
+func (s *mongoLedgerStore) AppendEntries(ctx context.Context, entries ...models.LedgerEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		docs[i] = entry
+	}
+	_, err := s.ledger.InsertMany(ctx, docs)
+	return mongoErr(err)
+}
+
+func (s *mongoLedgerStore) ListEntries(ctx context.Context, userID int64, skip, limit int) ([]models.LedgerEntry, error) {
+	cursor, err := s.ledger.Find(
+		ctx,
+		bson.M{"user_id": userID},
+		options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+			SetSkip(int64(skip)).
+			SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.LedgerEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, mongoErr(err)
+	}
+	return entries, nil
+}
+
+func (s *mongoLedgerStore) CountEntries(ctx context.Context, userID int64) (int64, error) {
+	count, err := s.ledger.CountDocuments(ctx, bson.M{"user_id": userID})
+	return count, mongoErr(err)
+}
+
+func (s *mongoLedgerStore) Summarize(ctx context.Context, userID int64) (*LedgerSummary, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"user_id": userID}},
+		{"$group": bson.M{
+			"_id":     "$type",
+			"total":   bson.M{"$sum": "$amount"},
+			"entries": bson.M{"$sum": 1},
+		}},
+	}
+
+	cursor, err := s.ledger.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Type    string `bson:"_id"`
+		Total   int64  `bson:"total"`
+		Entries int64  `bson:"entries"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, mongoErr(err)
+	}
+
+	summary := &LedgerSummary{}
+	for _, r := range results {
+		switch r.Type {
+		case models.LedgerCoins:
+			summary.Coins = r.Total
+		case models.LedgerCharacter:
+			summary.Characters = r.Total
+		}
+		summary.Entries += r.Entries
+	}
+	return summary, nil
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"senpai-waifu-bot/internal/models"
+)
+
+type mongoRarityStore struct {
+	settings *mongo.Collection
+	locked   *mongo.Collection
+}
+
+func (s *mongoRarityStore) GetRaritySettings(ctx context.Context, chatID int64) (*models.RaritySettings, error) {
+	var settings models.RaritySettings
+	if err := s.settings.FindOne(ctx, bson.M{"chat_id": chatID}).Decode(&settings); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &settings, nil
+}
+
+func (s *mongoRarityStore) InsertRaritySettings(ctx context.Context, settings *models.RaritySettings) error {
+	_, err := s.settings.InsertOne(ctx, settings)
+	return mongoErr(err)
+}
+
+func (s *mongoRarityStore) EnableRarity(ctx context.Context, chatID int64, rarity int) error {
+	_, err := s.settings.UpdateOne(
+		ctx,
+		bson.M{"chat_id": chatID},
+		bson.M{"$pull": bson.M{"disabled_rarities": rarity}},
+		options.Update().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoRarityStore) DisableRarity(ctx context.Context, chatID int64, rarity int) error {
+	_, err := s.settings.UpdateOne(
+		ctx,
+		bson.M{"chat_id": chatID},
+		bson.M{"$addToSet": bson.M{"disabled_rarities": rarity}},
+		options.Update().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoRarityStore) SetRarityWeight(ctx context.Context, chatID int64, rarity, weight int) error {
+	_, err := s.settings.UpdateOne(
+		ctx,
+		bson.M{"chat_id": chatID},
+		bson.M{"$set": bson.M{"weights." + strconv.Itoa(rarity): weight}},
+		options.Update().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoRarityStore) ResetRarityWeight(ctx context.Context, chatID int64, rarity int) error {
+	_, err := s.settings.UpdateOne(
+		ctx,
+		bson.M{"chat_id": chatID},
+		bson.M{"$unset": bson.M{"weights." + strconv.Itoa(rarity): ""}},
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoRarityStore) LockCharacter(ctx context.Context, lock *models.LockedCharacter) error {
+	_, err := s.locked.UpdateOne(
+		ctx,
+		bson.M{"character_id": lock.CharacterID},
+		bson.M{"$set": lock},
+		options.Update().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoRarityStore) UnlockCharacter(ctx context.Context, charID string) error {
+	_, err := s.locked.DeleteOne(ctx, bson.M{"character_id": charID})
+	return mongoErr(err)
+}
+
+func (s *mongoRarityStore) IsCharacterLocked(ctx context.Context, charID string) (bool, error) {
+	count, err := s.locked.CountDocuments(ctx, bson.M{"character_id": charID})
+	return count > 0, mongoErr(err)
+}
+
+func (s *mongoRarityStore) LockedCharacters(ctx context.Context) ([]models.LockedCharacter, error) {
+	cursor, err := s.locked.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var locked []models.LockedCharacter
+	if err = cursor.All(ctx, &locked); err != nil {
+		return nil, mongoErr(err)
+	}
+	return locked, nil
+}
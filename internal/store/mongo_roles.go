@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"senpai-waifu-bot/internal/models"
+)
+
+type mongoRoleStore struct {
+	roles *mongo.Collection
+}
+
+func (s *mongoRoleStore) GrantRole(ctx context.Context, grant *models.RoleGrant) error {
+	_, err := s.roles.InsertOne(ctx, grant)
+	return mongoErr(err)
+}
+
+func (s *mongoRoleStore) RevokeRole(ctx context.Context, userID int64, role string) error {
+	result, err := s.roles.DeleteOne(ctx, bson.M{"user_id": userID, "role": role})
+	if err != nil {
+		return mongoErr(err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoRoleStore) UserRoles(ctx context.Context, userID int64) ([]models.RoleGrant, error) {
+	return s.find(ctx, bson.M{"user_id": userID})
+}
+
+func (s *mongoRoleStore) RoleGrants(ctx context.Context) ([]models.RoleGrant, error) {
+	return s.find(ctx, bson.M{})
+}
+
+func (s *mongoRoleStore) find(ctx context.Context, filter bson.M) ([]models.RoleGrant, error) {
+	cursor, err := s.roles.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "granted_at", Value: 1}}))
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var grants []models.RoleGrant
+	if err = cursor.All(ctx, &grants); err != nil {
+		return nil, mongoErr(err)
+	}
+	return grants, nil
+}
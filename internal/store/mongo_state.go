@@ -0,0 +1,245 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"senpai-waifu-bot/internal/models"
+)
+
+// mongoStateStore keeps pending confirmations in collections with a TTL index on expires_at.
+// The TTL monitor only runs about once a minute, so every read also filters on the expiry.
+type mongoStateStore struct {
+	spawns   *mongo.Collection
+	counters *mongo.Collection
+	payments *mongo.Collection
+	trades   *mongo.Collection
+	gifts    *mongo.Collection
+}
+
+// unexpired matches the document with the given _id if it has not expired yet
+func unexpired(id string) bson.M {
+	return bson.M{"_id": id, "expires_at": bson.M{"$gt": time.Now()}}
+}
+
+func (s *mongoStateStore) SetSpawn(ctx context.Context, spawn *models.ActiveSpawn) error {
+	_, err := s.spawns.ReplaceOne(
+		ctx,
+		bson.M{"chat_id": spawn.ChatID},
+		spawn,
+		options.Replace().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoStateStore) GetSpawn(ctx context.Context, chatID int64) (*models.ActiveSpawn, error) {
+	var spawn models.ActiveSpawn
+	if err := s.spawns.FindOne(ctx, bson.M{"chat_id": chatID}).Decode(&spawn); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &spawn, nil
+}
+
+// guessable matches the spawn of chatID if nobody guessed it and it has not fled by at
+func guessable(chatID int64, at time.Time) bson.M {
+	return bson.M{
+		"chat_id":    chatID,
+		"guessed_by": 0,
+		"fled_at":    nil,
+		"$or":        bson.A{bson.M{"flees_at": nil}, bson.M{"flees_at": bson.M{"$gt": at}}},
+	}
+}
+
+func (s *mongoStateStore) ClaimSpawn(ctx context.Context, chatID, userID int64, at time.Time) (*models.ActiveSpawn, error) {
+	result := s.spawns.FindOneAndUpdate(
+		ctx,
+		guessable(chatID, at),
+		bson.M{"$set": bson.M{"guessed_by": userID, "guessed_at": at}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var spawn models.ActiveSpawn
+	if err := result.Decode(&spawn); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &spawn, nil
+}
+
+func (s *mongoStateStore) SetSpawnMessage(ctx context.Context, chatID int64, messageID int) error {
+	result, err := s.spawns.UpdateOne(ctx, bson.M{"chat_id": chatID}, bson.M{"$set": bson.M{"message_id": messageID}})
+	if err != nil {
+		return mongoErr(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoStateStore) ExpiredSpawns(ctx context.Context, at time.Time) ([]models.ActiveSpawn, error) {
+	cursor, err := s.spawns.Find(ctx, bson.M{
+		"guessed_by": 0,
+		"fled_at":    nil,
+		"flees_at":   bson.M{"$lte": at},
+	})
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var spawns []models.ActiveSpawn
+	if err := cursor.All(ctx, &spawns); err != nil {
+		return nil, mongoErr(err)
+	}
+	return spawns, nil
+}
+
+func (s *mongoStateStore) FleeSpawn(ctx context.Context, chatID int64, spawnedAt, at time.Time) (*models.ActiveSpawn, error) {
+	result := s.spawns.FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"chat_id":    chatID,
+			"spawned_at": spawnedAt,
+			"guessed_by": 0,
+			"fled_at":    nil,
+			"flees_at":   bson.M{"$lte": at},
+		},
+		bson.M{"$set": bson.M{"fled_at": at}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var spawn models.ActiveSpawn
+	if err := result.Decode(&spawn); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &spawn, nil
+}
+
+func (s *mongoStateStore) AddSpawnHint(ctx context.Context, chatID int64, spawnedAt time.Time, limit int, at time.Time) (*models.ActiveSpawn, error) {
+	filter := guessable(chatID, at)
+	filter["spawned_at"] = spawnedAt
+	// $not also matches spawns without hints, which do not store the field
+	filter["hints"] = bson.M{"$not": bson.M{"$gte": limit}}
+	result := s.spawns.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{"$inc": bson.M{"hints": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var spawn models.ActiveSpawn
+	if err := result.Decode(&spawn); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &spawn, nil
+}
+
+func (s *mongoStateStore) IncMessageCounter(ctx context.Context, chatID int64) (int, error) {
+	result := s.counters.FindOneAndUpdate(
+		ctx,
+		bson.M{"chat_id": chatID},
+		bson.M{"$inc": bson.M{"count": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	)
+
+	var counter struct {
+		Count int `bson:"count"`
+	}
+	if err := result.Decode(&counter); err != nil {
+		return 0, mongoErr(err)
+	}
+	return counter.Count, nil
+}
+
+func (s *mongoStateStore) ResetMessageCounter(ctx context.Context, chatID int64) error {
+	_, err := s.counters.UpdateOne(
+		ctx,
+		bson.M{"chat_id": chatID},
+		bson.M{"$set": bson.M{"count": 0}},
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoStateStore) PutPayment(ctx context.Context, payment *models.PendingPayment) error {
+	_, err := s.payments.InsertOne(ctx, payment)
+	return mongoErr(err)
+}
+
+func (s *mongoStateStore) GetPayment(ctx context.Context, token string) (*models.PendingPayment, error) {
+	var payment models.PendingPayment
+	if err := s.payments.FindOne(ctx, unexpired(token)).Decode(&payment); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &payment, nil
+}
+
+func (s *mongoStateStore) SetPaymentMessage(ctx context.Context, token string, messageID int) error {
+	_, err := s.payments.UpdateOne(
+		ctx,
+		unexpired(token),
+		bson.M{"$set": bson.M{"message_id": messageID}},
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoStateStore) TakePayment(ctx context.Context, token string) (*models.PendingPayment, error) {
+	var payment models.PendingPayment
+	if err := s.payments.FindOneAndDelete(ctx, unexpired(token)).Decode(&payment); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &payment, nil
+}
+
+// putPending inserts doc under id, replacing an expired entry but reporting ErrDuplicate for a live one
+func putPending(ctx context.Context, coll *mongo.Collection, id string, doc interface{}) error {
+	_, err := coll.ReplaceOne(
+		ctx,
+		bson.M{"_id": id, "expires_at": bson.M{"$lte": time.Now()}},
+		doc,
+		options.Replace().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoStateStore) PutTrade(ctx context.Context, trade *models.PendingTrade) error {
+	return putPending(ctx, s.trades, trade.Key, trade)
+}
+
+func (s *mongoStateStore) GetTrade(ctx context.Context, key string) (*models.PendingTrade, error) {
+	var trade models.PendingTrade
+	if err := s.trades.FindOne(ctx, unexpired(key)).Decode(&trade); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &trade, nil
+}
+
+func (s *mongoStateStore) TakeTrade(ctx context.Context, key string) (*models.PendingTrade, error) {
+	var trade models.PendingTrade
+	if err := s.trades.FindOneAndDelete(ctx, unexpired(key)).Decode(&trade); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &trade, nil
+}
+
+func (s *mongoStateStore) PutGift(ctx context.Context, gift *models.PendingGift) error {
+	return putPending(ctx, s.gifts, gift.Key, gift)
+}
+
+func (s *mongoStateStore) GetGift(ctx context.Context, key string) (*models.PendingGift, error) {
+	var gift models.PendingGift
+	if err := s.gifts.FindOne(ctx, unexpired(key)).Decode(&gift); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &gift, nil
+}
+
+func (s *mongoStateStore) TakeGift(ctx context.Context, key string) (*models.PendingGift, error) {
+	var gift models.PendingGift
+	if err := s.gifts.FindOneAndDelete(ctx, unexpired(key)).Decode(&gift); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &gift, nil
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// illegalOperationCode is the server error code returned when a transaction is started
+// against a standalone mongod
+const illegalOperationCode = 20
+
+type mongoTransactor struct {
+	client      *mongo.Client
+	unsupported atomic.Bool
+}
+
+func (t *mongoTransactor) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if t.unsupported.Load() {
+		return ErrTransactionsUnsupported
+	}
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return mongoErr(err)
+	}
+	defer session.EndSession(ctx)
+
+	// WithTransaction retries fn on transient errors, so fn must only touch the database
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	if isTransactionsUnsupported(err) {
+		t.unsupported.Store(true)
+		return ErrTransactionsUnsupported
+	}
+	return err
+}
+
+// isTransactionsUnsupported reports whether err means the deployment is not a replica set or sharded cluster
+func isTransactionsUnsupported(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Code == illegalOperationCode ||
+			strings.Contains(cmdErr.Message, "Transaction numbers are only allowed")
+	}
+	return false
+}
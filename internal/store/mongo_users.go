@@ -0,0 +1,276 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"senpai-waifu-bot/internal/models"
+)
+
+type mongoUserStore struct {
+	users     *mongo.Collection
+	sortPrefs *mongo.Collection
+}
+
+func (s *mongoUserStore) GetUser(ctx context.Context, userID int64) (*models.User, error) {
+	var user models.User
+	if err := s.users.FindOne(ctx, bson.M{"id": userID}).Decode(&user); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &user, nil
+}
+
+func (s *mongoUserStore) InsertUser(ctx context.Context, user *models.User) error {
+	_, err := s.users.InsertOne(ctx, user)
+	return mongoErr(err)
+}
+
+func (s *mongoUserStore) UpdateNames(ctx context.Context, userID int64, username, firstName string) error {
+	update := bson.M{}
+	if username != "" {
+		update["username"] = username
+	}
+	if firstName != "" {
+		update["first_name"] = firstName
+	}
+	if len(update) == 0 {
+		return nil
+	}
+
+	_, err := s.users.UpdateOne(ctx, bson.M{"id": userID}, bson.M{"$set": update})
+	return mongoErr(err)
+}
+
+func (s *mongoUserStore) IncBalance(ctx context.Context, userID int64, amount int64) (int64, error) {
+	result := s.users.FindOneAndUpdate(
+		ctx,
+		bson.M{"id": userID},
+		bson.M{"$inc": bson.M{"balance": amount}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	)
+
+	var user models.User
+	if err := result.Decode(&user); err != nil {
+		return 0, mongoErr(err)
+	}
+	return user.Balance, nil
+}
+
+// DebitBalance subtracts amount only if the balance covers it, returning ErrNotFound otherwise
+func (s *mongoUserStore) DebitBalance(ctx context.Context, userID int64, amount int64) (int64, error) {
+	result := s.users.FindOneAndUpdate(
+		ctx,
+		bson.M{"id": userID, "balance": bson.M{"$gte": amount}},
+		bson.M{"$inc": bson.M{"balance": -amount}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var user models.User
+	if err := result.Decode(&user); err != nil {
+		return 0, mongoErr(err)
+	}
+	return user.Balance, nil
+}
+
+func (s *mongoUserStore) PushCharacter(ctx context.Context, userID int64, char models.UserCharacter) error {
+	_, err := s.users.UpdateOne(
+		ctx,
+		bson.M{"id": userID},
+		bson.M{
+			"$push":        bson.M{"characters": char},
+			"$setOnInsert": bson.M{"id": userID, "balance": 0},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoUserStore) PullCharacter(ctx context.Context, userID int64, charID string) error {
+	_, err := s.users.UpdateOne(
+		ctx,
+		bson.M{"id": userID},
+		bson.M{"$pull": bson.M{"characters": bson.M{"id": charID}}},
+	)
+	return mongoErr(err)
+}
+
+// TakeCharacter removes a single copy of a character from the user's collection and returns it.
+// Unlike PullCharacter, duplicates of the same character are kept.
+func (s *mongoUserStore) TakeCharacter(ctx context.Context, userID int64, charID string) (*models.UserCharacter, error) {
+	remaining := bson.M{"$let": bson.M{
+		"vars": bson.M{"idx": bson.M{"$indexOfArray": []interface{}{"$characters.id", charID}}},
+		"in": bson.M{"$concatArrays": []interface{}{
+			bson.M{"$slice": []interface{}{"$characters", "$$idx"}},
+			bson.M{"$slice": []interface{}{
+				"$characters",
+				bson.M{"$add": []interface{}{"$$idx", 1}},
+				bson.M{"$size": "$characters"},
+			}},
+		}},
+	}}
+
+	result := s.users.FindOneAndUpdate(
+		ctx,
+		bson.M{"id": userID, "characters.id": charID},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"characters": remaining}}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	)
+
+	var user models.User
+	if err := result.Decode(&user); err != nil {
+		return nil, mongoErr(err)
+	}
+	for _, char := range user.Characters {
+		if char.ID == charID {
+			return &char, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *mongoUserStore) HasCharacter(ctx context.Context, userID int64, charID string) (bool, error) {
+	count, err := s.users.CountDocuments(ctx, bson.M{"id": userID, "characters.id": charID})
+	return count > 0, mongoErr(err)
+}
+
+func (s *mongoUserStore) AddFavorite(ctx context.Context, userID int64, charID string) error {
+	_, err := s.users.UpdateOne(
+		ctx,
+		bson.M{"id": userID},
+		bson.M{"$addToSet": bson.M{"favorites": charID}},
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoUserStore) TopByBalance(ctx context.Context, limit int) ([]models.User, error) {
+	cursor, err := s.users.Find(
+		ctx,
+		bson.M{},
+		options.Find().SetSort(bson.M{"balance": -1}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, mongoErr(err)
+	}
+	return users, nil
+}
+
+func (s *mongoUserStore) TopByCharacters(ctx context.Context, limit int) ([]models.User, error) {
+	pipeline := []bson.M{
+		{"$project": bson.M{
+			"id":         1,
+			"username":   1,
+			"first_name": 1,
+			"characters": 1,
+			"charCount":  bson.M{"$size": bson.M{"$ifNull": []interface{}{"$characters", []interface{}{}}}},
+		}},
+		{"$sort": bson.M{"charCount": -1}},
+		{"$limit": limit},
+	}
+
+	cursor, err := s.users.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, mongoErr(err)
+	}
+	return users, nil
+}
+
+func (s *mongoUserStore) SetLastSClaim(ctx context.Context, userID int64, at time.Time) error {
+	_, err := s.users.UpdateOne(
+		ctx,
+		bson.M{"id": userID},
+		bson.M{"$set": bson.M{"last_sclaim": at}},
+		options.Update().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoUserStore) SetLastClaim(ctx context.Context, userID int64, at time.Time) error {
+	_, err := s.users.UpdateOne(
+		ctx,
+		bson.M{"id": userID},
+		bson.M{"$set": bson.M{"last_claim": at}},
+		options.Update().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoUserStore) SetShopData(ctx context.Context, userID int64, shopData *models.ShopData) error {
+	_, err := s.users.UpdateOne(
+		ctx,
+		bson.M{"id": userID},
+		bson.M{"$set": bson.M{"shop_data": shopData}},
+	)
+	return mongoErr(err)
+}
+
+func (s *mongoUserStore) CountOwners(ctx context.Context, charID string) (int64, error) {
+	count, err := s.users.CountDocuments(ctx, bson.M{"characters.id": charID})
+	return count, mongoErr(err)
+}
+
+func (s *mongoUserStore) TopGrabbers(ctx context.Context, charID string, limit int) ([]models.Grabber, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"characters.id": charID}},
+		{"$project": bson.M{
+			"id":         1,
+			"username":   1,
+			"first_name": 1,
+			"count": bson.M{
+				"$size": bson.M{
+					"$filter": bson.M{
+						"input": "$characters",
+						"as":    "char",
+						"cond":  bson.M{"$eq": []interface{}{"$$char.id", charID}},
+					},
+				},
+			},
+		}},
+		{"$sort": bson.M{"count": -1}},
+		{"$limit": limit},
+	}
+
+	cursor, err := s.users.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, mongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var grabbers []models.Grabber
+	if err = cursor.All(ctx, &grabbers); err != nil {
+		return nil, mongoErr(err)
+	}
+	return grabbers, nil
+}
+
+func (s *mongoUserStore) GetSortPreference(ctx context.Context, userID int64) (*models.SortPreference, error) {
+	var pref models.SortPreference
+	if err := s.sortPrefs.FindOne(ctx, bson.M{"user_id": userID}).Decode(&pref); err != nil {
+		return nil, mongoErr(err)
+	}
+	return &pref, nil
+}
+
+func (s *mongoUserStore) SetSortPreference(ctx context.Context, userID int64, rarityFilter *int) error {
+	_, err := s.sortPrefs.UpdateOne(
+		ctx,
+		bson.M{"user_id": userID},
+		bson.M{"$set": bson.M{"rarity_filter": rarityFilter}},
+		options.Update().SetUpsert(true),
+	)
+	return mongoErr(err)
+}
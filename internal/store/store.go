@@ -0,0 +1,226 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"senpai-waifu-bot/internal/models"
+)
+
+var (
+	// ErrNotFound is returned when a document does not exist or does not match the requested condition
+	ErrNotFound = errors.New("store: not found")
+	// ErrDuplicate is returned when an insert violates a unique key
+	ErrDuplicate = errors.New("store: duplicate key")
+	// ErrTransactionsUnsupported is returned when the backend cannot run multi-document transactions
+	ErrTransactionsUnsupported = errors.New("store: transactions unsupported")
+)
+
+// Store groups the repositories for every aggregate the bot persists
+type Store struct {
+	Users      UserStore
+	Characters CharacterStore
+	Groups     GroupStore
+	Codes      CodeStore
+	Rarity     RarityStore
+	Ledger     LedgerStore
+	State      StateStore
+	Roles      RoleStore
+	Audit      AuditStore
+	Counters   CounterStore
+	Tx         Transactor
+}
+
+// Transactor runs a function inside a multi-document transaction. Store calls made with the
+// context passed to fn take part in the transaction, which is committed when fn returns nil
+// and aborted otherwise. Implementations return ErrTransactionsUnsupported without calling
+// fn when the backend cannot provide transactions.
+type Transactor interface {
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// CharacterFilter narrows down character queries
+type CharacterFilter struct {
+	Rarities         []int
+	ExcludedRarities []int
+	ExcludedIDs      []string
+}
+
+// CharacterUpdate holds the fields to change on a character; nil fields are left untouched
+type CharacterUpdate struct {
+	Name    *string
+	Anime   *string
+	Rarity  *int
+	ImgURL  *string
+	Aliases *[]string
+	// PrevAliases, when set, makes the update apply only while the character's aliases are
+	// still exactly these; otherwise UpdateCharacter returns ErrNotFound
+	PrevAliases *[]string
+	UpdatedBy   int64
+	UpdatedAt   time.Time
+}
+
+// AuditFilter narrows down audit log queries; zero fields match every entry
+type AuditFilter struct {
+	ActorID      int64
+	TargetUserID int64
+	CharacterID  string
+}
+
+// LedgerSummary is the net effect of a user's ledger entries
+type LedgerSummary struct {
+	Coins      int64
+	Characters int64
+	Entries    int64
+}
+
+// UserStore persists user documents, their collections and preferences
+type UserStore interface {
+	GetUser(ctx context.Context, userID int64) (*models.User, error)
+	InsertUser(ctx context.Context, user *models.User) error
+	UpdateNames(ctx context.Context, userID int64, username, firstName string) error
+	IncBalance(ctx context.Context, userID int64, amount int64) (int64, error)
+	DebitBalance(ctx context.Context, userID int64, amount int64) (int64, error)
+	PushCharacter(ctx context.Context, userID int64, char models.UserCharacter) error
+	PullCharacter(ctx context.Context, userID int64, charID string) error
+	TakeCharacter(ctx context.Context, userID int64, charID string) (*models.UserCharacter, error)
+	HasCharacter(ctx context.Context, userID int64, charID string) (bool, error)
+	AddFavorite(ctx context.Context, userID int64, charID string) error
+	TopByBalance(ctx context.Context, limit int) ([]models.User, error)
+	TopByCharacters(ctx context.Context, limit int) ([]models.User, error)
+	SetLastSClaim(ctx context.Context, userID int64, at time.Time) error
+	SetLastClaim(ctx context.Context, userID int64, at time.Time) error
+	SetShopData(ctx context.Context, userID int64, shopData *models.ShopData) error
+	CountOwners(ctx context.Context, charID string) (int64, error)
+	TopGrabbers(ctx context.Context, charID string, limit int) ([]models.Grabber, error)
+	GetSortPreference(ctx context.Context, userID int64) (*models.SortPreference, error)
+	SetSortPreference(ctx context.Context, userID int64, rarityFilter *int) error
+}
+
+// CharacterStore persists the character catalog
+type CharacterStore interface {
+	GetCharacter(ctx context.Context, charID string) (*models.Character, error)
+	FindCharacters(ctx context.Context, filter CharacterFilter) ([]models.Character, error)
+	SampleCharacters(ctx context.Context, filter CharacterFilter, count int) ([]models.Character, error)
+	SearchCharacters(ctx context.Context, query string) ([]models.Character, error)
+	CountCharacters(ctx context.Context) (int64, error)
+	CountByRarity(ctx context.Context, filter CharacterFilter) (map[int]int64, error)
+	MaxNumericID(ctx context.Context) (int64, error)
+	AnimeCounts(ctx context.Context, animes []string) (map[string]int64, error)
+	InsertCharacter(ctx context.Context, char *models.Character) error
+	UpdateCharacter(ctx context.Context, charID string, update CharacterUpdate) error
+	DeleteCharacter(ctx context.Context, charID string) error
+}
+
+// GroupStore persists per-group and per-day guess statistics and chat settings
+type GroupStore interface {
+	IncGroupUserTotal(ctx context.Context, userID, groupID int64, username, firstName string) error
+	IncTopGlobalGroup(ctx context.Context, groupID int64, groupName string) error
+	TopGroups(ctx context.Context, limit int) ([]models.TopGlobalGroup, error)
+	GroupUserTotals(ctx context.Context, groupID int64, limit int) ([]models.GroupUserTotal, error)
+	GetMessageFrequency(ctx context.Context, chatID int64) (int, error)
+	SetMessageFrequency(ctx context.Context, chatID int64, frequency int) error
+	GetSpawnSettings(ctx context.Context, chatID int64) (*models.SpawnSettings, error)
+	SetSpawnSettings(ctx context.Context, settings *models.SpawnSettings) error
+	GetChatLocale(ctx context.Context, chatID int64) (*models.ChatLocale, error)
+	SetChatLocale(ctx context.Context, locale *models.ChatLocale) error
+	UpsertPMUser(ctx context.Context, userID int64, username, firstName string, startedAt time.Time) error
+	CountPMUsers(ctx context.Context) (int64, error)
+	IncDailyUserGuess(ctx context.Context, date string, userID int64, username, firstName string, at time.Time) error
+	IncDailyGroupGuess(ctx context.Context, date string, groupID int64, groupName string, at time.Time) error
+	TopDailyUsers(ctx context.Context, date string, limit int) ([]models.DailyUserGuess, error)
+	TopDailyGroups(ctx context.Context, date string, limit int) ([]models.DailyGroupGuess, error)
+}
+
+// CodeStore persists redeem codes and claim codes
+type CodeStore interface {
+	InsertRedeemCode(ctx context.Context, code *models.RedeemCode) error
+	GetRedeemCode(ctx context.Context, code string) (*models.RedeemCode, error)
+	UseRedeemCode(ctx context.Context, code string, userID int64) (*models.RedeemCode, error)
+	DeactivateRedeemCode(ctx context.Context, code string) error
+	HasRedeemed(ctx context.Context, code string, userID int64) (bool, error)
+	InsertClaimCode(ctx context.Context, code *models.ClaimCode) error
+	GetClaimCode(ctx context.Context, code string) (*models.ClaimCode, error)
+	RedeemClaimCode(ctx context.Context, code string, userID int64, at time.Time) (*models.ClaimCode, error)
+}
+
+// RarityStore persists per-chat rarity settings and locked characters
+type RarityStore interface {
+	GetRaritySettings(ctx context.Context, chatID int64) (*models.RaritySettings, error)
+	InsertRaritySettings(ctx context.Context, settings *models.RaritySettings) error
+	EnableRarity(ctx context.Context, chatID int64, rarity int) error
+	DisableRarity(ctx context.Context, chatID int64, rarity int) error
+	SetRarityWeight(ctx context.Context, chatID int64, rarity, weight int) error
+	ResetRarityWeight(ctx context.Context, chatID int64, rarity int) error
+	LockCharacter(ctx context.Context, lock *models.LockedCharacter) error
+	UnlockCharacter(ctx context.Context, charID string) error
+	IsCharacterLocked(ctx context.Context, charID string) (bool, error)
+	LockedCharacters(ctx context.Context) ([]models.LockedCharacter, error)
+}
+
+// LedgerStore is an append-only record of balance and collection changes
+type LedgerStore interface {
+	AppendEntries(ctx context.Context, entries ...models.LedgerEntry) error
+	ListEntries(ctx context.Context, userID int64, skip, limit int) ([]models.LedgerEntry, error)
+	CountEntries(ctx context.Context, userID int64) (int64, error)
+	Summarize(ctx context.Context, userID int64) (*LedgerSummary, error)
+}
+
+// RoleStore persists the roles granted to users. GrantRole returns ErrDuplicate when the
+// user already holds the role and RevokeRole returns ErrNotFound when they do not.
+type RoleStore interface {
+	GrantRole(ctx context.Context, grant *models.RoleGrant) error
+	RevokeRole(ctx context.Context, userID int64, role string) error
+	UserRoles(ctx context.Context, userID int64) ([]models.RoleGrant, error)
+	RoleGrants(ctx context.Context) ([]models.RoleGrant, error)
+}
+
+// AuditStore is an append-only record of privileged commands. ListAudit returns the newest
+// matching entries first.
+type AuditStore interface {
+	AppendAudit(ctx context.Context, entry *models.AuditEntry) error
+	ListAudit(ctx context.Context, filter AuditFilter, limit int) ([]models.AuditEntry, error)
+}
+
+// CounterStore keeps named sequences. NextSequence atomically increments a counter, creating
+// it at zero first, and returns the new value. SetSequence sets the value the next call
+// increments from and RaiseSequence does the same only when value is higher.
+type CounterStore interface {
+	NextSequence(ctx context.Context, name string) (int64, error)
+	GetSequence(ctx context.Context, name string) (int64, error)
+	SetSequence(ctx context.Context, name string, value int64) error
+	RaiseSequence(ctx context.Context, name string, value int64) error
+}
+
+// StateStore persists the short-lived bot state that must survive a restart: the active spawn
+// and message counter of each chat and the pending payment, trade and gift confirmations.
+// Pending entries carry an expiry and behave as if they did not exist once it has passed.
+// ClaimSpawn records the first correct guess and returns ErrNotFound when there is no spawn or
+// it was already claimed; AddSpawnHint counts a /hint on a spawn that can still be guessed and
+// returns ErrNotFound once it has limit hints or can no longer be guessed; PutTrade and PutGift return ErrDuplicate while an unexpired entry
+// with the same key exists; the Take methods remove and return an entry in one step.
+type StateStore interface {
+	SetSpawn(ctx context.Context, spawn *models.ActiveSpawn) error
+	GetSpawn(ctx context.Context, chatID int64) (*models.ActiveSpawn, error)
+	ClaimSpawn(ctx context.Context, chatID, userID int64, at time.Time) (*models.ActiveSpawn, error)
+	SetSpawnMessage(ctx context.Context, chatID int64, messageID int) error
+	ExpiredSpawns(ctx context.Context, at time.Time) ([]models.ActiveSpawn, error)
+	FleeSpawn(ctx context.Context, chatID int64, spawnedAt, at time.Time) (*models.ActiveSpawn, error)
+	AddSpawnHint(ctx context.Context, chatID int64, spawnedAt time.Time, limit int, at time.Time) (*models.ActiveSpawn, error)
+	IncMessageCounter(ctx context.Context, chatID int64) (int, error)
+	ResetMessageCounter(ctx context.Context, chatID int64) error
+
+	PutPayment(ctx context.Context, payment *models.PendingPayment) error
+	GetPayment(ctx context.Context, token string) (*models.PendingPayment, error)
+	SetPaymentMessage(ctx context.Context, token string, messageID int) error
+	TakePayment(ctx context.Context, token string) (*models.PendingPayment, error)
+
+	PutTrade(ctx context.Context, trade *models.PendingTrade) error
+	GetTrade(ctx context.Context, key string) (*models.PendingTrade, error)
+	TakeTrade(ctx context.Context, key string) (*models.PendingTrade, error)
+
+	PutGift(ctx context.Context, gift *models.PendingGift) error
+	GetGift(ctx context.Context, key string) (*models.PendingGift, error)
+	TakeGift(ctx context.Context, key string) (*models.PendingGift, error)
+}
@@ -0,0 +1,79 @@
+package telegramtest
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Call is one Bot API request received by the server
+type Call struct {
+	Method string
+	Params url.Values
+	// Files names the multipart fields that carried an upload
+	Files []string
+	// MessageID is the ID of the message sent or edited, if any
+	MessageID int
+}
+
+// ChatID returns the chat the request was for
+func (c Call) ChatID() int64 {
+	id, _ := strconv.ParseInt(c.Params.Get("chat_id"), 10, 64)
+	return id
+}
+
+// Text returns the message text, or the caption for media
+func (c Call) Text() string {
+	if text := c.Params.Get("text"); text != "" {
+		return text
+	}
+	return c.Params.Get("caption")
+}
+
+// Keyboard returns the inline keyboard attached to the message, if any
+func (c Call) Keyboard() *tgbotapi.InlineKeyboardMarkup {
+	raw := c.Params.Get("reply_markup")
+	if raw == "" {
+		return nil
+	}
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(raw), &keyboard); err != nil || keyboard.InlineKeyboard == nil {
+		return nil
+	}
+	return &keyboard
+}
+
+// Button returns the callback data of the inline button labelled text, and whether it exists
+func (c Call) Button(text string) (string, bool) {
+	keyboard := c.Keyboard()
+	if keyboard == nil {
+		return "", false
+	}
+	for _, row := range keyboard.InlineKeyboard {
+		for _, button := range row {
+			if button.Text == text && button.CallbackData != nil {
+				return *button.CallbackData, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Buttons returns the callback data of every inline button, row by row
+func (c Call) Buttons() []string {
+	keyboard := c.Keyboard()
+	if keyboard == nil {
+		return nil
+	}
+	var data []string
+	for _, row := range keyboard.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData != nil {
+				data = append(data, *button.CallbackData)
+			}
+		}
+	}
+	return data
+}
@@ -0,0 +1,458 @@
+// Package telegramtest runs a fake Telegram Bot API over HTTP, so a real *tgbotapi.BotAPI
+// can be pointed at it and every request the bot makes can be asserted on.
+//
+// A conversation is scripted by building updates with the server and handing them to
+// the bot synchronously:
+//
+//	srv := telegramtest.NewServer()
+//	defer srv.Close()
+//	api := srv.Client()
+//	bot := handlers.NewBot(cfg, api, api.Self, store.NewMemoryStore(), zap.NewNop(), health.New(time.Minute))
+//	defer bot.Shutdown(context.Background())
+//
+//	group := srv.Group(-100, "Waifu Club")
+//	alice, bob := srv.User(1, "alice"), srv.User(2, "bob")
+//
+//	// A message counts towards spawning once a user has sent five in a row, so with
+//	// spawn.default_frequency = 1 the fifth message spawns a character
+//	for i := 0; i < 5; i++ {
+//		bot.HandleUpdate(srv.Message(group, alice, "hello"))
+//	}
+//	// The spawn is announced in the background once its image is ready
+//	announce := srv.WaitSent(1, time.Second)[0]
+//
+//	srv.Reset()
+//	bot.HandleUpdate(srv.Message(group, alice, "/guess "+name))
+//	// assert on srv.Sent()[0].Text() ...
+//
+//	// Bob says something, alice replies to it to offer a trade
+//	said := srv.Message(group, bob, "hi")
+//	bot.HandleUpdate(said)
+//	bot.HandleUpdate(srv.Reply(group, alice, said.Message, "/trade "+mine+" "+theirs))
+//	offer := srv.LastSent()
+//	data, _ := offer.Button("✅ Accept Trade")
+//	bot.HandleUpdate(srv.Callback(group, bob, offer.MessageID, data))
+//	// assert on srv.Edits(), srv.Answers() ...
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Token is the bot token the fake server accepts
+const Token = "123456:TEST"
+
+// maxPoll bounds how long getUpdates waits for an update, so clients stop promptly
+const maxPoll = time.Second
+
+// Server is a fake Bot API. It records every request, answers the methods the bot uses
+// with plausible results and serves queued updates to getUpdates.
+type Server struct {
+	*httptest.Server
+
+	// Bot is the account returned by getMe
+	Bot tgbotapi.User
+
+	mu        sync.Mutex
+	calls     []Call
+	chats     map[int64]tgbotapi.Chat
+	admins    map[int64]map[int64]bool
+	failures  map[string][]failure
+	updates   []tgbotapi.Update
+	updatesCh chan struct{}
+	nextMsgID int
+	nextUpdID int
+}
+
+// failure is a scripted error response
+type failure struct {
+	code        int
+	description string
+}
+
+// NewServer starts a fake Bot API server; call Close when done
+func NewServer() *Server {
+	s := &Server{
+		Bot:       tgbotapi.User{ID: 123456, IsBot: true, FirstName: "Senpai", UserName: "senpai_test_bot"},
+		chats:     make(map[int64]tgbotapi.Chat),
+		admins:    make(map[int64]map[int64]bool),
+		failures:  make(map[string][]failure),
+		updatesCh: make(chan struct{}, 1),
+		nextMsgID: 1000,
+		nextUpdID: 1,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Endpoint is the API endpoint to give tgbotapi.NewBotAPIWithAPIEndpoint
+func (s *Server) Endpoint() string {
+	return s.URL + "/bot%s/%s"
+}
+
+// Client returns a Bot API client authorized against the server
+func (s *Server) Client() *tgbotapi.BotAPI {
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint(Token, s.Endpoint())
+	if err != nil {
+		panic("telegramtest: " + err.Error())
+	}
+	return api
+}
+
+// User registers a private chat for a user, so getChat can find them, and returns the user
+func (s *Server) User(id int64, username string) *tgbotapi.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.chats[id] = tgbotapi.Chat{ID: id, Type: "private", UserName: username, FirstName: username}
+	return &tgbotapi.User{ID: id, UserName: username, FirstName: username}
+}
+
+// Group registers a supergroup and returns it
+func (s *Server) Group(id int64, title string) *tgbotapi.Chat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chat := tgbotapi.Chat{ID: id, Type: "supergroup", Title: title}
+	s.chats[id] = chat
+	return &chat
+}
+
+// Admin makes user an administrator of chat; everyone else is a plain member
+func (s *Server) Admin(chat *tgbotapi.Chat, user *tgbotapi.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.admins[chat.ID] == nil {
+		s.admins[chat.ID] = make(map[int64]bool)
+	}
+	s.admins[chat.ID][user.ID] = true
+}
+
+// Private returns the private chat with user
+func (s *Server) Private(user *tgbotapi.User) *tgbotapi.Chat {
+	return &tgbotapi.Chat{ID: user.ID, Type: "private", UserName: user.UserName, FirstName: user.FirstName}
+}
+
+// Message builds an update carrying a text message from user in chat. Text starting
+// with "/" is marked as a command.
+func (s *Server) Message(chat *tgbotapi.Chat, from *tgbotapi.User, text string) tgbotapi.Update {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg := &tgbotapi.Message{
+		MessageID: s.newMessageID(),
+		From:      from,
+		Chat:      chat,
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+	}
+	if strings.HasPrefix(text, "/") {
+		length := len(strings.Fields(text)[0])
+		msg.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: length}}
+	}
+	return tgbotapi.Update{UpdateID: s.newUpdateID(), Message: msg}
+}
+
+// Reply builds a message update that replies to replyTo
+func (s *Server) Reply(chat *tgbotapi.Chat, from *tgbotapi.User, replyTo *tgbotapi.Message, text string) tgbotapi.Update {
+	update := s.Message(chat, from, text)
+	update.Message.ReplyToMessage = replyTo
+	return update
+}
+
+// Callback builds an update for user pressing an inline button with data on the message
+// with ID messageID
+func (s *Server) Callback(chat *tgbotapi.Chat, from *tgbotapi.User, messageID int, data string) tgbotapi.Update {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.newUpdateID()
+	return tgbotapi.Update{
+		UpdateID: id,
+		CallbackQuery: &tgbotapi.CallbackQuery{
+			ID:      "cb" + strconv.Itoa(id),
+			From:    from,
+			Message: &tgbotapi.Message{MessageID: messageID, Chat: chat},
+			Data:    data,
+		},
+	}
+}
+
+// BotAdded builds an update for from adding the bot to chat as a member
+func (s *Server) BotAdded(chat *tgbotapi.Chat, from *tgbotapi.User) tgbotapi.Update {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return tgbotapi.Update{
+		UpdateID: s.newUpdateID(),
+		MyChatMember: &tgbotapi.ChatMemberUpdated{
+			Chat:          *chat,
+			From:          *from,
+			Date:          int(time.Now().Unix()),
+			OldChatMember: tgbotapi.ChatMember{User: &s.Bot, Status: "left"},
+			NewChatMember: tgbotapi.ChatMember{User: &s.Bot, Status: "member"},
+		},
+	}
+}
+
+// Push queues updates for getUpdates, for tests that run the bot's polling loop
+func (s *Server) Push(updates ...tgbotapi.Update) {
+	s.mu.Lock()
+	s.updates = append(s.updates, updates...)
+	s.mu.Unlock()
+
+	select {
+	case s.updatesCh <- struct{}{}:
+	default:
+	}
+}
+
+// Fail makes the next request to method fail with the given error code and description
+func (s *Server) Fail(method string, code int, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[method] = append(s.failures[method], failure{code: code, description: description})
+}
+
+// Reset forgets the recorded calls
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+}
+
+// Calls returns the recorded requests, oldest first, optionally only those to the given methods
+func (s *Server) Calls(methods ...string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var calls []Call
+	for _, call := range s.calls {
+		if len(methods) == 0 || contains(methods, call.Method) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Sent returns the messages sent, of any kind
+func (s *Server) Sent() []Call {
+	return s.Calls("sendMessage", "sendPhoto", "sendVideo", "sendAnimation", "sendDocument")
+}
+
+// LastSent returns the most recent message sent; it panics if there is none
+func (s *Server) LastSent() Call {
+	sent := s.Sent()
+	if len(sent) == 0 {
+		panic("telegramtest: no message was sent")
+	}
+	return sent[len(sent)-1]
+}
+
+// WaitSent waits up to timeout for at least n messages to have been sent, for replies the
+// bot sends in the background, and returns the messages sent so far
+func (s *Server) WaitSent(n int, timeout time.Duration) []Call {
+	deadline := time.Now().Add(timeout)
+	for {
+		sent := s.Sent()
+		if len(sent) >= n || time.Now().After(deadline) {
+			return sent
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// Edits returns the message edits
+func (s *Server) Edits() []Call {
+	return s.Calls("editMessageText", "editMessageCaption", "editMessageReplyMarkup")
+}
+
+// Answers returns the callback query answers
+func (s *Server) Answers() []Call {
+	return s.Calls("answerCallbackQuery")
+}
+
+// Deletions returns the message deletions
+func (s *Server) Deletions() []Call {
+	return s.Calls("deleteMessage")
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	// Paths look like /bot<token>/<method>
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) != 2 || parts[0] != "bot"+Token {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	method := parts[1]
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil && err != http.ErrNotMultipart {
+		writeError(w, http.StatusBadRequest, "Bad Request: "+err.Error())
+		return
+	}
+	params := r.Form
+	var files []string
+	if r.MultipartForm != nil {
+		for name := range r.MultipartForm.File {
+			files = append(files, name)
+		}
+	}
+
+	if method == "getUpdates" {
+		s.serveUpdates(w, params)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	call := Call{Method: method, Params: params, Files: files}
+	if queued := s.failures[method]; len(queued) > 0 {
+		s.failures[method] = queued[1:]
+		s.calls = append(s.calls, call)
+		writeError(w, queued[0].code, queued[0].description)
+		return
+	}
+
+	result, err := s.result(&call)
+	s.calls = append(s.calls, call)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request: "+err.Error())
+		return
+	}
+	writeResult(w, result)
+}
+
+// result answers a request; s.mu must be held
+func (s *Server) result(call *Call) (interface{}, error) {
+	switch call.Method {
+	case "getMe":
+		return s.Bot, nil
+	case "getChat":
+		id, _ := strconv.ParseInt(call.Params.Get("chat_id"), 10, 64)
+		chat, ok := s.chats[id]
+		if !ok {
+			return nil, fmt.Errorf("chat not found")
+		}
+		return chat, nil
+	case "getChatMember":
+		chatID, _ := strconv.ParseInt(call.Params.Get("chat_id"), 10, 64)
+		userID, _ := strconv.ParseInt(call.Params.Get("user_id"), 10, 64)
+		status := "member"
+		if s.admins[chatID][userID] {
+			status = "administrator"
+		}
+		return tgbotapi.ChatMember{User: &tgbotapi.User{ID: userID}, Status: status}, nil
+	case "getFile":
+		fileID := call.Params.Get("file_id")
+		return tgbotapi.File{FileID: fileID, FilePath: "photos/" + fileID + ".jpg"}, nil
+	case "sendMessage", "sendPhoto", "sendVideo", "sendAnimation", "sendDocument":
+		call.MessageID = s.newMessageID()
+		return s.message(call.MessageID, call), nil
+	case "editMessageText", "editMessageCaption", "editMessageReplyMarkup":
+		call.MessageID, _ = strconv.Atoi(call.Params.Get("message_id"))
+		return s.message(call.MessageID, call), nil
+	default:
+		// answerCallbackQuery, deleteMessage, setMyCommands, deleteWebhook, ... return true
+		return true, nil
+	}
+}
+
+// message is the Message Telegram would return for a send or edit
+func (s *Server) message(id int, call *Call) tgbotapi.Message {
+	chatID, _ := strconv.ParseInt(call.Params.Get("chat_id"), 10, 64)
+	chat, ok := s.chats[chatID]
+	if !ok {
+		chat = tgbotapi.Chat{ID: chatID}
+	}
+	return tgbotapi.Message{
+		MessageID: id,
+		From:      &s.Bot,
+		Chat:      &chat,
+		Date:      int(time.Now().Unix()),
+		Text:      call.Params.Get("text"),
+		Caption:   call.Params.Get("caption"),
+	}
+}
+
+// serveUpdates answers getUpdates with the queued updates at or after offset, waiting
+// briefly when there are none
+func (s *Server) serveUpdates(w http.ResponseWriter, params map[string][]string) {
+	offset := 0
+	if v := params["offset"]; len(v) > 0 {
+		offset, _ = strconv.Atoi(v[0])
+	}
+
+	deadline := time.After(maxPoll)
+	for {
+		s.mu.Lock()
+		var pending []tgbotapi.Update
+		kept := s.updates[:0]
+		for _, update := range s.updates {
+			if update.UpdateID >= offset {
+				pending = append(pending, update)
+				kept = append(kept, update)
+			}
+		}
+		s.updates = kept
+		s.mu.Unlock()
+
+		if len(pending) > 0 {
+			writeResult(w, pending)
+			return
+		}
+		select {
+		case <-s.updatesCh:
+		case <-deadline:
+			writeResult(w, []tgbotapi.Update{})
+			return
+		}
+	}
+}
+
+// newMessageID allocates a message ID; s.mu must be held
+func (s *Server) newMessageID() int {
+	s.nextMsgID++
+	return s.nextMsgID
+}
+
+// newUpdateID allocates an update ID; s.mu must be held
+func (s *Server) newUpdateID() int {
+	s.nextUpdID++
+	return s.nextUpdID
+}
+
+func writeResult(w http.ResponseWriter, result interface{}) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: true, Result: raw})
+}
+
+func writeError(w http.ResponseWriter, code int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: false, ErrorCode: code, Description: description})
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -7,7 +7,10 @@ import (
 	"math/big"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	// LoadLocation must not depend on a zone database on the host, which the runtime image lacks
+	_ "time/tzdata"
 )
 
 // SmallCapsMap maps regular letters to small caps Unicode
@@ -276,3 +279,41 @@ func RemoveInt(slice []int, val int) []int {
 	}
 	return result
 }
+
+// FormatCharacterID formats a character sequence number as an ID, padded to at least three digits (001, 010, 1000)
+func FormatCharacterID(seq int64) string {
+	return fmt.Sprintf("%03d", seq)
+}
+
+// LessCharacterID orders character IDs numerically, so that 999 sorts before 1000
+func LessCharacterID(a, b string) bool {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// IST is India Standard Time, which has no daylight saving time
+var IST = time.FixedZone("IST", 5*60*60+30*60)
+
+// locations caches the time zones loaded by LoadLocation
+var locations sync.Map
+
+// LoadLocation returns the time zone with an IANA name such as Asia/Kolkata. Zones are cached,
+// since loading one reads the zone database. Local and the empty name are refused so that no
+// setting depends on the host.
+func LoadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}